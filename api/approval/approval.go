@@ -22,7 +22,13 @@ func ListMyApprovalRequests(ac *client.AlpaconClient, status, requestType string
 	return fetchApprovalList(ac, myRequestsURL, status, requestType)
 }
 
-func fetchApprovalList(ac *client.AlpaconClient, endpoint, status, requestType string) ([]ApprovalRequestAttributes, error) {
+// ListApprovalRequestDetails returns the full requests, request data untruncated,
+// for callers that show one request at a time rather than a table.
+func ListApprovalRequestDetails(ac *client.AlpaconClient, status, requestType string) ([]ApprovalRequest, error) {
+	return fetchApprovalRequests(ac, approvalURL, status, requestType)
+}
+
+func fetchApprovalRequests(ac *client.AlpaconClient, endpoint, status, requestType string) ([]ApprovalRequest, error) {
 	params := map[string]string{}
 	if status != "" {
 		params["status"] = status
@@ -30,8 +36,11 @@ func fetchApprovalList(ac *client.AlpaconClient, endpoint, status, requestType s
 	if requestType != "" {
 		params["request_type"] = requestType
 	}
+	return api.FetchAllPages[ApprovalRequest](ac, endpoint, params)
+}
 
-	requests, err := api.FetchAllPages[ApprovalRequest](ac, endpoint, params)
+func fetchApprovalList(ac *client.AlpaconClient, endpoint, status, requestType string) ([]ApprovalRequestAttributes, error) {
+	requests, err := fetchApprovalRequests(ac, endpoint, status, requestType)
 	if err != nil {
 		return nil, err
 	}
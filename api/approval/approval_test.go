@@ -189,3 +189,19 @@ func TestCancelRequest_403PropagatesError(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "permission")
 }
+
+func TestListApprovalRequestDetails_KeepsFullRequestData(t *testing.T) {
+	data := strings.Repeat("x", 80)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/approvals/approvals/", r.URL.Path)
+		assert.Equal(t, "pending", r.URL.Query().Get("status"))
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(api.ListResponse[ApprovalRequest]{Count: 1, Results: []ApprovalRequest{{ID: "apr-1", RequestData: data}}})
+	}))
+	defer ts.Close()
+
+	list, err := ListApprovalRequestDetails(newTestClient(ts), "pending", "")
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, data, list[0].RequestData)
+}
@@ -1,8 +1,11 @@
 package audit
 
 import (
+	"time"
+
 	"github.com/alpacax/alpacon-cli/api"
 	"github.com/alpacax/alpacon-cli/api/iam"
+	"github.com/alpacax/alpacon-cli/api/logquery"
 	"github.com/alpacax/alpacon-cli/client"
 	"github.com/alpacax/alpacon-cli/utils"
 )
@@ -12,6 +15,21 @@ const (
 )
 
 func GetAuditLogList(ac *client.AlpaconClient, tail int, userName string, app string, model string) ([]AuditLogAttributes, error) {
+	params, err := AuditLogParams(ac, userName, app, model)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := GetAuditLogs(ac, params, tail, logquery.Filter{})
+	if err != nil {
+		return nil, err
+	}
+	return ToAuditLogAttributes(entries), nil
+}
+
+// AuditLogParams builds the server-side filters, resolving the username once
+// so a follow loop does not look it up on every poll.
+func AuditLogParams(ac *client.AlpaconClient, userName string, app string, model string) (map[string]string, error) {
 	params := map[string]string{}
 	if userName != "" {
 		userID, err := iam.GetUserIDByName(ac, userName)
@@ -26,12 +44,38 @@ func GetAuditLogList(ac *client.AlpaconClient, tail int, userName string, app st
 	if model != "" {
 		params["model"] = model
 	}
+	return params, nil
+}
 
-	entries, err := api.FetchCursorPages[AuditLogEntry](ac, auditURL, params, tail)
-	if err != nil {
-		return nil, err
+// GetAuditLogs returns the newest tail entries that pass the filter, newest
+// first.
+func GetAuditLogs(ac *client.AlpaconClient, params map[string]string, tail int, filter logquery.Filter) ([]AuditLogEntry, error) {
+	var check func(AuditLogEntry) api.FilterVerdict
+	if !filter.IsZero() {
+		check = func(e AuditLogEntry) api.FilterVerdict {
+			return filter.Check(e.AddedAt, e.Description, e.Username, e.Action, e.App, e.Model)
+		}
 	}
+	return api.FetchCursorPagesFiltered(ac, auditURL, params, tail, check)
+}
 
+// FollowAuditLogs returns a Follower for the audit logs that pass the filter.
+// The caller sets Emit and seeds it with what it has shown.
+func FollowAuditLogs(ac *client.AlpaconClient, params map[string]string, filter logquery.Filter, interval time.Duration) *logquery.Follower[AuditLogEntry] {
+	return &logquery.Follower[AuditLogEntry]{
+		Interval: interval,
+		Fetch: func(since time.Time) ([]AuditLogEntry, error) {
+			f := filter
+			f.Since = since
+			return GetAuditLogs(ac, params, api.FilterScanLimit, f)
+		},
+		Stamp: func(e AuditLogEntry) (time.Time, string) {
+			return e.AddedAt, e.ID
+		},
+	}
+}
+
+func ToAuditLogAttributes(entries []AuditLogEntry) []AuditLogAttributes {
 	var auditList []AuditLogAttributes
 	for _, entry := range entries {
 		auditList = append(auditList, AuditLogAttributes{
@@ -45,6 +89,5 @@ func GetAuditLogList(ac *client.AlpaconClient, tail int, userName string, app st
 			AddedAt:     utils.TimeUtils(entry.AddedAt),
 		})
 	}
-
-	return auditList, nil
+	return auditList
 }
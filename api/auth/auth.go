@@ -8,7 +8,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/alpacax/alpacon-cli/api"
 	"github.com/alpacax/alpacon-cli/client"
@@ -155,6 +157,37 @@ func GetAPITokenList(ac *client.AlpaconClient) ([]APITokenAttributes, error) {
 		return nil, err
 	}
 
+	return toAPITokenAttributes(tokens), nil
+}
+
+// GetAPITokens returns the tokens as the API lists them, with their scopes.
+func GetAPITokens(ac *client.AlpaconClient) ([]APITokenResponse, error) {
+	return api.FetchAllPages[APITokenResponse](ac, tokenURL, nil)
+}
+
+// GetExpiringAPITokenList lists the tokens whose expiry falls within the given
+// window from now, soonest first. Tokens already past expiry are included—they
+// are the most urgent to replace—and tokens without an expiry never are.
+func GetExpiringAPITokenList(ac *client.AlpaconClient, within time.Duration) ([]APITokenAttributes, error) {
+	tokens, err := api.FetchAllPages[APITokenResponse](ac, tokenURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(within)
+	var expiring []APITokenResponse
+	for _, token := range tokens {
+		if !token.ExpiresAt.IsZero() && token.ExpiresAt.Before(deadline) {
+			expiring = append(expiring, token)
+		}
+	}
+	slices.SortFunc(expiring, func(a, b APITokenResponse) int {
+		return a.ExpiresAt.Compare(b.ExpiresAt)
+	})
+	return toAPITokenAttributes(expiring), nil
+}
+
+func toAPITokenAttributes(tokens []APITokenResponse) []APITokenAttributes {
 	var tokenList []APITokenAttributes
 	for _, token := range tokens {
 		tokenList = append(tokenList, APITokenAttributes{
@@ -166,7 +199,7 @@ func GetAPITokenList(ac *client.AlpaconClient) ([]APITokenAttributes, error) {
 			Scopes:    strings.Join(token.Scopes, ", "),
 		})
 	}
-	return tokenList, nil
+	return tokenList
 }
 
 func GetAPITokenIDByName(ac *client.AlpaconClient, tokenName string) (string, error) {
@@ -207,20 +240,22 @@ func DeleteAPIToken(ac *client.AlpaconClient, tokenID string) error {
 	return nil
 }
 
-func DuplicateAPIToken(ac *client.AlpaconClient, tokenID, name string) (string, error) {
+// DuplicateAPIToken copies a token with its scopes and ACL rules. The response
+// carries the new token's ID, name, and key; the key is never returned again.
+func DuplicateAPIToken(ac *client.AlpaconClient, tokenID, name string) (*APITokenResponse, error) {
 	url := utils.BuildURL(tokenURL, tokenID+"/duplicate", nil)
 	req := APITokenDuplicateRequest{Name: name}
 	resp, err := ac.SendPostRequest(url, req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	var response APITokenResponse
 	if err = json.Unmarshal(resp, &response); err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return response.Key, nil
+	return &response, nil
 }
 
 func GetTokenScopes(ac *client.AlpaconClient) ([]TokenScopeAttributes, error) {
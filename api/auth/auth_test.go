@@ -374,6 +374,38 @@ func TestDeleteAPIToken(t *testing.T) {
 	}
 }
 
+func TestGetExpiringAPITokenList(t *testing.T) {
+	now := time.Now()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := api.ListResponse[APITokenResponse]{
+			Count: 4,
+			Results: []APITokenResponse{
+				{ID: "later", Name: "later", ExpiresAt: now.Add(30 * 24 * time.Hour)},
+				{ID: "soon", Name: "soon", ExpiresAt: now.Add(3 * 24 * time.Hour)},
+				{ID: "never", Name: "never"},
+				{ID: "expired", Name: "expired", ExpiresAt: now.Add(-time.Hour)},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer ts.Close()
+
+	ac := &client.AlpaconClient{HTTPClient: ts.Client(), BaseURL: ts.URL}
+	tokens, err := GetExpiringAPITokenList(ac, 14*24*time.Hour)
+	if err != nil {
+		t.Fatalf("GetExpiringAPITokenList error: %v", err)
+	}
+
+	var ids []string
+	for _, token := range tokens {
+		ids = append(ids, token.ID)
+	}
+	if strings.Join(ids, ",") != "expired,soon" {
+		t.Errorf("expected [expired soon] soonest first, got %v", ids)
+	}
+}
+
 func TestDuplicateAPIToken(t *testing.T) {
 	const wantKey = "duplicated-token-key-xyz"
 
@@ -419,12 +451,15 @@ func TestDuplicateAPIToken(t *testing.T) {
 			defer ts.Close()
 
 			ac := &client.AlpaconClient{HTTPClient: ts.Client(), BaseURL: ts.URL}
-			key, err := DuplicateAPIToken(ac, "source-token-id", tt.copyName)
+			token, err := DuplicateAPIToken(ac, "source-token-id", tt.copyName)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
-			if key != wantKey {
-				t.Errorf("expected key %q, got %q", wantKey, key)
+			if token.Key != wantKey {
+				t.Errorf("expected key %q, got %q", wantKey, token.Key)
+			}
+			if token.ID != "new-token-id" {
+				t.Errorf("expected ID %q, got %q", "new-token-id", token.ID)
 			}
 		})
 	}
@@ -1,6 +1,10 @@
 package auth
 
-import "time"
+import (
+	"time"
+
+	"github.com/alpacax/alpacon-cli/api/types"
+)
 
 // PrincipalTypeApplication is the whoami principal_type for service/application tokens.
 const PrincipalTypeApplication = "application"
@@ -36,6 +40,9 @@ type APITokenResponse struct {
 	UpdatedAt time.Time `json:"updated_at"`
 	ExpiresAt time.Time `json:"expires_at"`
 	Scopes    []string  `json:"scopes"`
+	// User is the token's owner. The server sends it when it lists tokens
+	// across users; a caller's list of its own tokens may leave it out.
+	User *types.UserSummary `json:"user,omitempty"`
 }
 
 type APITokenAttributes struct {
@@ -233,36 +233,61 @@ func GetCertificateList(ac *client.AlpaconClient) ([]CertificateAttributes, erro
 	return certList, nil
 }
 
-func DownloadCertificateByCSR(ac *client.AlpaconClient, csrId string, filePath string) error {
+// GetCertificates returns every certificate with its full attributes, for
+// callers that need the times rather than their display form.
+func GetCertificates(ac *client.AlpaconClient) ([]Certificate, error) {
+	return api.FetchAllPages[Certificate](ac, certURL, nil)
+}
+
+func GetCertificate(ac *client.AlpaconClient, certId string) (Certificate, error) {
+	body, err := GetCertificateDetail(ac, certId)
+	if err != nil {
+		return Certificate{}, err
+	}
+
+	var response Certificate
+	if err = json.Unmarshal(body, &response); err != nil {
+		return Certificate{}, err
+	}
+
+	return response, nil
+}
+
+// GetSignedCSR returns a CSR's detail once its certificate is issued, or an
+// error while it is not.
+func GetSignedCSR(ac *client.AlpaconClient, csrId string) (SignRequestDetail, error) {
 	body, err := GetCSRDetail(ac, csrId)
 	if err != nil {
-		return err
+		return SignRequestDetail{}, err
 	}
 
 	var detail SignRequestDetail
 	if err = json.Unmarshal(body, &detail); err != nil {
-		return err
+		return SignRequestDetail{}, err
 	}
 
 	if detail.Status != "signed" {
-		return fmt.Errorf("certificate not yet issued for this CSR (status: %s)", detail.Status)
+		return SignRequestDetail{}, fmt.Errorf("certificate not yet issued for this CSR (status: %s)", detail.Status)
 	}
 
 	if detail.CrtText == "" {
-		return fmt.Errorf("certificate text is empty for signed CSR (id: %s)", detail.ID)
+		return SignRequestDetail{}, fmt.Errorf("certificate text is empty for signed CSR (id: %s)", detail.ID)
 	}
 
-	return utils.SaveFile(filePath, []byte(detail.CrtText))
+	return detail, nil
 }
 
-func DownloadCertificate(ac *client.AlpaconClient, certId string, filePath string) error {
-	body, err := GetCertificateDetail(ac, certId)
+func DownloadCertificateByCSR(ac *client.AlpaconClient, csrId string, filePath string) error {
+	detail, err := GetSignedCSR(ac, csrId)
 	if err != nil {
 		return err
 	}
 
-	var response Certificate
-	err = json.Unmarshal(body, &response)
+	return utils.SaveFile(filePath, []byte(detail.CrtText))
+}
+
+func DownloadCertificate(ac *client.AlpaconClient, certId string, filePath string) error {
+	response, err := GetCertificate(ac, certId)
 	if err != nil {
 		return err
 	}
@@ -294,15 +319,23 @@ func UpdateAuthority(ac *client.AlpaconClient, authorityId string) ([]byte, erro
 	return responseBody, nil
 }
 
-func DownloadCRL(ac *client.AlpaconClient, authorityId string, filePath string) error {
+func GetCRL(ac *client.AlpaconClient, authorityId string) (CRLResponse, error) {
 	relativePath := path.Join(authorityId, "crl")
 	body, err := ac.SendGetRequest(utils.BuildURL(authorityURL, relativePath, nil))
 	if err != nil {
-		return err
+		return CRLResponse{}, err
 	}
 
 	var response CRLResponse
-	err = json.Unmarshal(body, &response)
+	if err = json.Unmarshal(body, &response); err != nil {
+		return CRLResponse{}, err
+	}
+
+	return response, nil
+}
+
+func DownloadCRL(ac *client.AlpaconClient, authorityId string, filePath string) error {
+	response, err := GetCRL(ac, authorityId)
 	if err != nil {
 		return err
 	}
@@ -310,22 +343,26 @@ func DownloadCRL(ac *client.AlpaconClient, authorityId string, filePath string)
 	return utils.SaveFile(filePath, []byte(response.CrlText))
 }
 
-func DownloadRootCertificate(ac *client.AlpaconClient, authorityId string, filePath string) error {
+// GetRootCertificate returns the authority's root certificate in PEM.
+func GetRootCertificate(ac *client.AlpaconClient, authorityId string) (string, error) {
 	body, err := ac.SendGetRequest(utils.BuildURL(authorityURL, authorityId, nil))
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	var response AuthorityDetails
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return err
+	if err = json.Unmarshal(body, &response); err != nil {
+		return "", err
 	}
 
-	err = utils.SaveFile(filePath, []byte(response.CrtText))
+	return response.CrtText, nil
+}
+
+func DownloadRootCertificate(ac *client.AlpaconClient, authorityId string, filePath string) error {
+	crtText, err := GetRootCertificate(ac, authorityId)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return utils.SaveFile(filePath, []byte(crtText))
 }
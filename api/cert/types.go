@@ -108,10 +108,11 @@ type AuthoritySummary struct {
 }
 
 type SignRequestDetail struct {
-	ID         string `json:"id"`
-	CommonName string `json:"common_name"`
-	Status     string `json:"status"`
-	CrtText    string `json:"crt_text"`
+	ID         string           `json:"id"`
+	Authority  AuthoritySummary `json:"authority"`
+	CommonName string           `json:"common_name"`
+	Status     string           `json:"status"`
+	CrtText    string           `json:"crt_text"`
 }
 
 type CSRSubmit struct {
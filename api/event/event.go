@@ -12,6 +12,7 @@ import (
 
 	"github.com/alpacax/alpacon-cli/api"
 	"github.com/alpacax/alpacon-cli/api/iam"
+	"github.com/alpacax/alpacon-cli/api/logquery"
 	"github.com/alpacax/alpacon-cli/api/server"
 	"github.com/alpacax/alpacon-cli/client"
 	"github.com/alpacax/alpacon-cli/utils"
@@ -185,27 +186,70 @@ func gapFillInterval(noProgress int) time.Duration {
 // GetEventList returns the newest tail commands. The endpoint sorts -scheduled_at,
 // so the newest ones arrive first and the walk stops as soon as tail is reached.
 func GetEventList(ac *client.AlpaconClient, tail int, serverName string, userName string) ([]EventAttributes, error) {
+	endpoint, err := EventListEndpoint(ac, serverName, userName)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := GetEvents(ac, endpoint, tail, logquery.Filter{})
+	if err != nil {
+		return nil, err
+	}
+	return ToEventAttributes(events), nil
+}
+
+// EventListEndpoint resolves the server and user filters, which the endpoint
+// takes as path segments, once so a follow loop does not look them up on every
+// poll.
+func EventListEndpoint(ac *client.AlpaconClient, serverName string, userName string) (string, error) {
 	var serverID, userID string
 	var err error
 	if serverName != "" {
 		serverID, err = server.GetServerIDByName(ac, serverName)
 		if err != nil {
-			return nil, err
+			return "", err
 		}
 	}
 	if userName != "" {
 		userID, err = iam.GetUserIDByName(ac, userName)
 		if err != nil {
-			return nil, err
+			return "", err
 		}
 	}
+	return path.Join(getEventURL, serverID, userID), nil
+}
 
-	endpoint := path.Join(getEventURL, serverID, userID)
-	events, err := api.FetchPagesUpTo[EventDetails](ac, endpoint, nil, tail)
-	if err != nil {
-		return nil, err
+// GetEvents returns the newest tail commands that pass the filter, newest
+// first. Commands are filtered by when they were requested; one scheduled
+// for later sorts by its scheduled time, so --since can end the walk before
+// reaching it.
+func GetEvents(ac *client.AlpaconClient, endpoint string, tail int, filter logquery.Filter) ([]EventDetails, error) {
+	var check func(EventDetails) api.FilterVerdict
+	if !filter.IsZero() {
+		check = func(e EventDetails) api.FilterVerdict {
+			return filter.Check(e.AddedAt, e.Line, e.Result)
+		}
 	}
+	return api.FetchPagesFiltered(ac, endpoint, nil, tail, check)
+}
+
+// FollowEvents returns a Follower for the commands that pass the filter. The
+// caller sets Emit and seeds it with what it has shown.
+func FollowEvents(ac *client.AlpaconClient, endpoint string, filter logquery.Filter, interval time.Duration) *logquery.Follower[EventDetails] {
+	return &logquery.Follower[EventDetails]{
+		Interval: interval,
+		Fetch: func(since time.Time) ([]EventDetails, error) {
+			f := filter
+			f.Since = since
+			return GetEvents(ac, endpoint, api.FilterScanLimit, f)
+		},
+		Stamp: func(e EventDetails) (time.Time, string) {
+			return e.AddedAt, e.ID
+		},
+	}
+}
 
+func ToEventAttributes(events []EventDetails) []EventAttributes {
 	eventList := make([]EventAttributes, 0, len(events))
 	for _, event := range events {
 		eventList = append(eventList, EventAttributes{
@@ -218,7 +262,7 @@ func GetEventList(ac *client.AlpaconClient, tail int, serverName string, userNam
 			RequestedAt: utils.TimeUtils(event.AddedAt),
 		})
 	}
-	return eventList, nil
+	return eventList
 }
 
 func SubmitCommand(ac *client.AlpaconClient, serverName, command string, username, groupname string, env map[string]string, workSessionID string) (CommandResponse, error) {
@@ -252,6 +252,12 @@ func GetGroupIDByName(ac *client.AlpaconClient, groupName string) (string, error
 	return response.Results[0].ID, nil
 }
 
+// Status is the user's highest standing: superuser, staff, active, or
+// inactive.
+func (u UserResponse) Status() string {
+	return getUserStatus(u.IsActive, u.IsStaff, u.IsSuperuser)
+}
+
 func getUserStatus(isActive bool, isStaff bool, isSuperuser bool) string {
 	if isSuperuser {
 		return "superuser"
@@ -426,3 +432,43 @@ func UsernameErrorMessage(code string) (string, bool) {
 func isRetryableUsernameError(code string) bool {
 	return usernameErrors[code].retryable
 }
+
+// GetUsers returns every user with their IDs, for callers that work on
+// users in bulk rather than one name at a time.
+func GetUsers(ac *client.AlpaconClient) ([]UserResponse, error) {
+	return api.FetchAllPages[UserResponse](ac, userURL, nil)
+}
+
+// GetGroups returns every group with their IDs.
+func GetGroups(ac *client.AlpaconClient) ([]GroupResponse, error) {
+	return api.FetchAllPages[GroupResponse](ac, groupURL, nil)
+}
+
+// GetMemberships returns every group membership in the workspace.
+func GetMemberships(ac *client.AlpaconClient) ([]MemberDetailResponse, error) {
+	return api.FetchAllPages[MemberDetailResponse](ac, membershipURL, nil)
+}
+
+// PatchUser updates the given fields of the user with this ID.
+func PatchUser(ac *client.AlpaconClient, userID string, fields map[string]string) error {
+	_, err := ac.SendPatchRequest(utils.BuildURL(userURL, userID, nil), fields)
+	return err
+}
+
+// PatchGroup updates the given fields of the group with this ID.
+func PatchGroup(ac *client.AlpaconClient, groupID string, fields map[string]string) error {
+	_, err := ac.SendPatchRequest(utils.BuildURL(groupURL, groupID, nil), fields)
+	return err
+}
+
+// SetMemberRole changes the role of an existing membership.
+func SetMemberRole(ac *client.AlpaconClient, membershipID, role string) error {
+	_, err := ac.SendPatchRequest(utils.BuildURL(membershipURL, membershipID, nil), map[string]string{"role": role})
+	return err
+}
+
+// DeleteMembership removes a membership by its ID.
+func DeleteMembership(ac *client.AlpaconClient, membershipID string) error {
+	_, err := ac.SendDeleteRequest(utils.BuildURL(membershipURL, membershipID, nil))
+	return err
+}
@@ -389,3 +389,21 @@ func TestAddMember(t *testing.T) {
 		t.Error("membership POST was not called")
 	}
 }
+
+func TestSetMemberRole(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPatch, r.Method)
+		assert.Equal(t, membershipURL+"member-uuid-1/", r.URL.Path)
+		var body map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]string{"role": "manager"}, body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	ac := &client.AlpaconClient{HTTPClient: ts.Client(), BaseURL: ts.URL}
+	if err := SetMemberRole(ac, "member-uuid-1", "manager"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
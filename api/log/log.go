@@ -2,8 +2,11 @@ package log
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/alpacax/alpacon-cli/api"
+	"github.com/alpacax/alpacon-cli/api/logquery"
 	"github.com/alpacax/alpacon-cli/api/server"
 	"github.com/alpacax/alpacon-cli/client"
 	"github.com/alpacax/alpacon-cli/utils"
@@ -13,35 +16,123 @@ const (
 	getSystemLogURL = "/api/history/logs/"
 )
 
+// Query narrows a server's logs. The server filters by server only; the rest
+// is applied to each page as it arrives.
+type Query struct {
+	logquery.Filter
+	Level   LevelRange
+	Program string
+}
+
+// LevelRange keeps entries whose level is within [Min, Max]. The zero value
+// keeps every level.
+type LevelRange struct {
+	Min int
+	Max int
+}
+
+var levelNames = map[string]int{
+	"debug":    10,
+	"info":     20,
+	"warn":     30,
+	"warning":  30,
+	"error":    40,
+	"critical": 50,
+}
+
+// ParseLevel reads a --level value: a level name for that level alone, or a
+// name with a trailing + for that level and above (warn+).
+func ParseLevel(raw string) (LevelRange, error) {
+	if raw == "" {
+		return LevelRange{}, nil
+	}
+	name, orAbove := strings.CutSuffix(strings.ToLower(strings.TrimSpace(raw)), "+")
+	level, ok := levelNames[name]
+	if !ok {
+		return LevelRange{}, fmt.Errorf("invalid --level %q: must be debug, info, warn, error, or critical, optionally followed by +", raw)
+	}
+	if orAbove {
+		return LevelRange{Min: level}, nil
+	}
+	return LevelRange{Min: level, Max: level}, nil
+}
+
+func (r LevelRange) contains(level int) bool {
+	return level >= r.Min && (r.Max == 0 || level <= r.Max)
+}
+
+func (q Query) isZero() bool {
+	return q.Filter.IsZero() && q.Level == LevelRange{} && q.Program == ""
+}
+
+func (q Query) check(e LogEntry) api.FilterVerdict {
+	if v := q.Filter.Check(e.Date, e.Msg, e.Program, e.Process); v != api.FilterKeep {
+		return v
+	}
+	if !q.Level.contains(e.Level) || (q.Program != "" && e.Program != q.Program) {
+		return api.FilterSkip
+	}
+	return api.FilterKeep
+}
+
 func GetSystemLogList(ac *client.AlpaconClient, serverName string, tail int) ([]LogAttributes, error) {
 	serverID, err := server.GetServerIDByName(ac, serverName)
 	if err != nil {
 		return nil, err
 	}
 
+	entries, err := GetSystemLogs(ac, serverID, tail, Query{})
+	if err != nil {
+		return nil, err
+	}
+	return ToLogAttributes(entries), nil
+}
+
+// GetSystemLogs returns the newest tail entries of a server's logs that match
+// the query, newest first.
+func GetSystemLogs(ac *client.AlpaconClient, serverID string, tail int, q Query) ([]LogEntry, error) {
 	params := map[string]string{
 		"server": serverID,
 	}
 
-	entries, err := api.FetchCursorPages[LogEntry](ac, getSystemLogURL, params, tail)
-	if err != nil {
-		return nil, err
+	var filter func(LogEntry) api.FilterVerdict
+	if !q.isZero() {
+		filter = q.check
 	}
+	return api.FetchCursorPagesFiltered(ac, getSystemLogURL, params, tail, filter)
+}
 
+// FollowSystemLogs returns a Follower for a server's logs that match the
+// query. The caller sets Emit and seeds it with what it has shown.
+func FollowSystemLogs(ac *client.AlpaconClient, serverID string, q Query, interval time.Duration) *logquery.Follower[LogEntry] {
+	return &logquery.Follower[LogEntry]{
+		Interval: interval,
+		Fetch: func(since time.Time) ([]LogEntry, error) {
+			q := q
+			q.Since = since
+			return GetSystemLogs(ac, serverID, api.FilterScanLimit, q)
+		},
+		Stamp: func(e LogEntry) (time.Time, string) {
+			return e.Date, fmt.Sprint(e.ID)
+		},
+	}
+}
+
+func ToLogAttributes(entries []LogEntry) []LogAttributes {
 	var logList []LogAttributes
 	for _, log := range entries {
 		logList = append(logList, LogAttributes{
 			Program: log.Program,
-			Level:   getLogLevel(log.Level),
+			Level:   LevelName(log.Level),
 			Message: fmt.Sprintf("[%s] %s", log.Process, log.Msg),
 			Date:    utils.TimeUtils(log.Date),
 		})
 	}
-
-	return logList, nil
+	return logList
 }
 
-func getLogLevel(level int) string {
+// LevelName is the display name of a numeric log level.
+func LevelName(level int) string {
 	switch level {
 	case 10:
 		return "DEBUG"
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/alpacax/alpacon-cli/api"
 	"github.com/alpacax/alpacon-cli/api/server"
@@ -87,3 +89,66 @@ func TestGetSystemLogList_NoExtraPagination(t *testing.T) {
 		t.Errorf("expected 25 logs, got %d", len(logs))
 	}
 }
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want LevelRange
+	}{
+		{"", LevelRange{}},
+		{"warn", LevelRange{Min: 30, Max: 30}},
+		{"WARNING+", LevelRange{Min: 30}},
+		{"error+", LevelRange{Min: 40}},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.raw)
+		if err != nil {
+			t.Fatalf("ParseLevel(%q) error: %v", tt.raw, err)
+		}
+		if got != tt.want {
+			t.Errorf("ParseLevel(%q) = %+v, want %+v", tt.raw, got, tt.want)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestGetSystemLogs_AppliesQuery(t *testing.T) {
+	base := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if pageSize := r.URL.Query().Get("page_size"); pageSize != "100" {
+			t.Errorf("expected a filtered walk to read full pages, got page_size=%s", pageSize)
+		}
+		// Newest first, as the cursor endpoint returns them.
+		_ = json.NewEncoder(w).Encode(api.CursorListResponse[LogEntry]{
+			Next: "MORE",
+			Results: []LogEntry{
+				{ID: 5, Date: base.Add(4 * time.Minute), Program: "sshd", Level: 40, Msg: "Failed password for root"},
+				{ID: 4, Date: base.Add(3 * time.Minute), Program: "cron", Level: 40, Msg: "Failed to run job"},
+				{ID: 3, Date: base.Add(2 * time.Minute), Program: "sshd", Level: 20, Msg: "Failed password for bob"},
+				{ID: 2, Date: base.Add(time.Minute), Program: "sshd", Level: 30, Msg: "Failed password for eve"},
+				{ID: 1, Date: base.Add(-time.Minute), Program: "sshd", Level: 40, Msg: "Failed password for mallory"},
+			},
+		})
+	}))
+	defer ts.Close()
+
+	ac := &client.AlpaconClient{HTTPClient: ts.Client(), BaseURL: ts.URL}
+	q := Query{Level: LevelRange{Min: 30}, Program: "sshd"}
+	q.Since = base
+	q.Match = regexp.MustCompile("Failed password")
+
+	entries, err := GetSystemLogs(ac, "srv-1", 25, q)
+	if err != nil {
+		t.Fatalf("GetSystemLogs error: %v", err)
+	}
+	var ids []int
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+	if fmt.Sprint(ids) != "[5 2]" {
+		t.Errorf("expected entries [5 2], got %v", ids)
+	}
+}
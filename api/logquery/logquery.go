@@ -0,0 +1,174 @@
+// Package logquery holds the filtering and follow mode shared by the history
+// listings: server logs, audit logs, WebFTP logs, and command history.
+package logquery
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alpacax/alpacon-cli/api"
+	"github.com/alpacax/alpacon-cli/utils"
+)
+
+// Filter narrows a listing to a time range and a pattern. The zero value
+// keeps everything.
+type Filter struct {
+	Since time.Time
+	Until time.Time
+	Match *regexp.Regexp
+}
+
+// IsZero reports whether the filter keeps everything.
+func (f Filter) IsZero() bool {
+	return f.Since.IsZero() && f.Until.IsZero() && f.Match == nil
+}
+
+// Check decides one entry from its time and the text --match searches.
+func (f Filter) Check(at time.Time, text ...string) api.FilterVerdict {
+	if !f.Since.IsZero() && at.Before(f.Since) {
+		return api.FilterStop
+	}
+	if !f.Until.IsZero() && at.After(f.Until) {
+		return api.FilterSkip
+	}
+	if f.Match != nil {
+		for _, s := range text {
+			if f.Match.MatchString(s) {
+				return api.FilterKeep
+			}
+		}
+		return api.FilterSkip
+	}
+	return api.FilterKeep
+}
+
+// ParseTime reads a --since or --until value: a duration back from now
+// ("15m", "2h", "7d"), an RFC3339 time, or a date (local midnight).
+func ParseTime(flagName, raw string, now time.Time) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, raw, time.Local); err == nil {
+		return t, nil
+	}
+	d, err := utils.ParseDayDuration(flagName, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s value %q: expected a duration (15m, 2h, 7d), an RFC3339 time, or a date (2006-01-02)", flagName, raw)
+	}
+	return now.Add(-d), nil
+}
+
+// ParseMatch compiles a --match pattern.
+func ParseMatch(raw string) (*regexp.Regexp, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --match pattern: %w", err)
+	}
+	return re, nil
+}
+
+// Follower polls a listing for entries newer than the ones already shown.
+// Entries come back newest first; Follower hands them on oldest first, once.
+type Follower[T any] struct {
+	Interval time.Duration
+	// Fetch returns the entries at or after since, newest first.
+	Fetch func(since time.Time) ([]T, error)
+	// Stamp returns an entry's time and a key that tells apart entries with
+	// the same time.
+	Stamp func(T) (time.Time, string)
+	Emit  func(T)
+	// Warn hears a failed poll; following goes on at the next interval.
+	Warn func(error)
+
+	last time.Time
+	seen map[string]bool // keys of the entries at last
+}
+
+// Position is how far a Follower has got: the time of the newest entry it
+// has passed on and the keys of the entries at that time. Saved between runs,
+// it lets a scheduled job pick up where the last one stopped.
+type Position struct {
+	Time time.Time `json:"time"`
+	Keys []string  `json:"keys,omitempty"`
+}
+
+// Position returns how far the follower has got.
+func (f *Follower[T]) Position() Position {
+	p := Position{Time: f.last}
+	for key := range f.seen {
+		p.Keys = append(p.Keys, key)
+	}
+	sort.Strings(p.Keys)
+	return p
+}
+
+// Resume continues from a saved position.
+func (f *Follower[T]) Resume(p Position) {
+	f.last, f.seen = p.Time, map[string]bool{}
+	for _, key := range p.Keys {
+		f.seen[key] = true
+	}
+}
+
+// Seed marks entries as already shown. With none, following starts at now.
+func (f *Follower[T]) Seed(entries []T, now time.Time) {
+	f.last, f.seen = now, map[string]bool{}
+	if len(entries) > 0 {
+		f.last = time.Time{}
+	}
+	for _, e := range entries {
+		f.mark(e)
+	}
+}
+
+func (f *Follower[T]) mark(e T) {
+	at, key := f.Stamp(e)
+	switch {
+	case at.After(f.last):
+		f.last, f.seen = at, map[string]bool{key: true}
+	case at.Equal(f.last):
+		f.seen[key] = true
+	}
+}
+
+// Poll fetches once and emits what is new. A fetch cut short by the scan
+// limit still emits what it found, then returns api.ErrScanLimit.
+func (f *Follower[T]) Poll() error {
+	entries, err := f.Fetch(f.last)
+	if err != nil && !errors.Is(err, api.ErrScanLimit) {
+		return err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		at, key := f.Stamp(entries[i])
+		if at.Before(f.last) || (at.Equal(f.last) && f.seen[key]) {
+			continue
+		}
+		f.mark(entries[i])
+		f.Emit(entries[i])
+	}
+	return err
+}
+
+// Run polls every Interval until stop is closed.
+func (f *Follower[T]) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(f.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := f.Poll(); err != nil && f.Warn != nil {
+				f.Warn(err)
+			}
+		}
+	}
+}
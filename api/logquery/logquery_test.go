@@ -0,0 +1,149 @@
+package logquery
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/alpacax/alpacon-cli/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter_Check(t *testing.T) {
+	base := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	f := Filter{Since: base, Until: base.Add(time.Hour), Match: regexp.MustCompile(`(?i)failed`)}
+
+	assert.Equal(t, api.FilterKeep, f.Check(base.Add(time.Minute), "ok", "Failed password"))
+	assert.Equal(t, api.FilterSkip, f.Check(base.Add(time.Minute), "accepted"))
+	assert.Equal(t, api.FilterSkip, f.Check(base.Add(2*time.Hour), "failed"), "after --until")
+	assert.Equal(t, api.FilterStop, f.Check(base.Add(-time.Second), "failed"), "before --since")
+	assert.Equal(t, api.FilterKeep, Filter{}.Check(base, "anything"))
+	assert.True(t, Filter{}.IsZero())
+	assert.False(t, f.IsZero())
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		raw  string
+		want time.Time
+	}{
+		{"15m", now.Add(-15 * time.Minute)},
+		{"2h", now.Add(-2 * time.Hour)},
+		{"7d", now.AddDate(0, 0, -7)},
+		{"2026-10-01T08:30:00Z", time.Date(2026, 10, 1, 8, 30, 0, 0, time.UTC)},
+		{"2026-10-01", time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseTime("--since", tt.raw, now)
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "got %s", got)
+		})
+	}
+
+	_, err := ParseTime("--since", "yesterday", now)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid --since value")
+}
+
+func TestParseMatch(t *testing.T) {
+	re, err := ParseMatch("")
+	require.NoError(t, err)
+	assert.Nil(t, re)
+
+	_, err = ParseMatch("(")
+	require.Error(t, err)
+}
+
+type entry struct {
+	at  time.Time
+	key string
+}
+
+func TestFollower_EmitsNewEntriesOnceOldestFirst(t *testing.T) {
+	base := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	var pages [][]entry
+	var sinces []time.Time
+	var emitted []string
+	f := &Follower[entry]{
+		Fetch: func(since time.Time) ([]entry, error) {
+			sinces = append(sinces, since)
+			page := pages[0]
+			pages = pages[1:]
+			return page, nil
+		},
+		Stamp: func(e entry) (time.Time, string) { return e.at, e.key },
+		Emit:  func(e entry) { emitted = append(emitted, e.key) },
+	}
+
+	// Seed newest first, as a listing returns it.
+	f.Seed([]entry{{base.Add(time.Second), "b"}, {base, "a"}}, base.Add(time.Hour))
+
+	// "b" is already shown; "c" shares its time, "d" is newer.
+	pages = append(pages, []entry{{base.Add(2 * time.Second), "d"}, {base.Add(time.Second), "c"}, {base.Add(time.Second), "b"}})
+	require.NoError(t, f.Poll())
+	assert.Equal(t, []string{"c", "d"}, emitted)
+
+	pages = append(pages, []entry{{base.Add(2 * time.Second), "d"}})
+	require.NoError(t, f.Poll())
+	assert.Equal(t, []string{"c", "d"}, emitted, "nothing new")
+
+	assert.Equal(t, []time.Time{base.Add(time.Second), base.Add(2 * time.Second)}, sinces)
+}
+
+func TestFollower_EmptySeedStartsNow(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	var since time.Time
+	f := &Follower[entry]{
+		Fetch: func(s time.Time) ([]entry, error) { since = s; return nil, errors.New("offline") },
+		Stamp: func(e entry) (time.Time, string) { return e.at, e.key },
+		Emit:  func(entry) {},
+	}
+	f.Seed(nil, now)
+	require.Error(t, f.Poll())
+	assert.Equal(t, now, since)
+}
+
+func TestFollower_ResumesFromASavedPosition(t *testing.T) {
+	base := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	var emitted []string
+	newFollower := func() *Follower[entry] {
+		return &Follower[entry]{
+			Fetch: func(time.Time) ([]entry, error) {
+				return []entry{{base.Add(time.Second), "c"}, {base, "b"}, {base, "a"}}, nil
+			},
+			Stamp: func(e entry) (time.Time, string) { return e.at, e.key },
+			Emit:  func(e entry) { emitted = append(emitted, e.key) },
+		}
+	}
+
+	f := newFollower()
+	f.Resume(Position{Time: base, Keys: []string{"a"}})
+	require.NoError(t, f.Poll())
+	assert.Equal(t, []string{"b", "c"}, emitted)
+	assert.Equal(t, Position{Time: base.Add(time.Second), Keys: []string{"c"}}, f.Position())
+
+	emitted = nil
+	g := newFollower()
+	g.Resume(f.Position())
+	require.NoError(t, g.Poll())
+	assert.Empty(t, emitted)
+}
+
+func TestFollower_ScanLimitEmitsWhatWasFound(t *testing.T) {
+	base := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	var emitted []string
+	f := &Follower[entry]{
+		Fetch: func(time.Time) ([]entry, error) {
+			return []entry{{base.Add(time.Second), "b"}, {base, "a"}}, api.ErrScanLimit
+		},
+		Stamp: func(e entry) (time.Time, string) { return e.at, e.key },
+		Emit:  func(e entry) { emitted = append(emitted, e.key) },
+	}
+	f.Seed(nil, base.Add(-time.Minute))
+	assert.ErrorIs(t, f.Poll(), api.ErrScanLimit)
+	assert.Equal(t, []string{"a", "b"}, emitted)
+}
@@ -28,8 +28,20 @@ func packageEntryURL(packageType string) string {
 	return systemPackageEntryURL
 }
 
+// GetSystemPackageDetails returns every system package, with the ID that
+// DeletePackage takes.
+func GetSystemPackageDetails(ac *client.AlpaconClient) ([]SystemPackageDetail, error) {
+	return api.FetchAllPages[SystemPackageDetail](ac, systemPackageEntryURL, nil)
+}
+
+// GetPythonPackageDetails returns every Python package, with the ID that
+// DeletePackage takes.
+func GetPythonPackageDetails(ac *client.AlpaconClient) ([]PythonPackageDetail, error) {
+	return api.FetchAllPages[PythonPackageDetail](ac, pythonPackageEntryURL, nil)
+}
+
 func GetSystemPackageEntry(ac *client.AlpaconClient) ([]SystemPackage, error) {
-	packages, err := api.FetchAllPages[SystemPackageDetail](ac, systemPackageEntryURL, nil)
+	packages, err := GetSystemPackageDetails(ac)
 	if err != nil {
 		return nil, err
 	}
@@ -48,7 +60,7 @@ func GetSystemPackageEntry(ac *client.AlpaconClient) ([]SystemPackage, error) {
 }
 
 func GetPythonPackageEntry(ac *client.AlpaconClient) ([]PythonPackage, error) {
-	packages, err := api.FetchAllPages[PythonPackageDetail](ac, pythonPackageEntryURL, nil)
+	packages, err := GetPythonPackageDetails(ac)
 	if err != nil {
 		return nil, err
 	}
@@ -128,6 +140,11 @@ func UploadPackage(ac *client.AlpaconClient, file string, packageType string) er
 	return nil
 }
 
+func DeletePackage(ac *client.AlpaconClient, packageID string, packageType string) error {
+	_, err := ac.SendDeleteRequest(utils.BuildURL(packageEntryURL(packageType), packageID, nil))
+	return err
+}
+
 func packageDownloadResponseError(resp *http.Response) error {
 	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
 	if message := strings.TrimSpace(string(body)); message != "" {
@@ -452,3 +452,24 @@ func TestGetPythonPackageEntry_Pagination(t *testing.T) {
 		t.Errorf("expected 150 packages, got %d", len(packages))
 	}
 }
+
+func TestDeletePackage(t *testing.T) {
+	var gotMethod, gotPath string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath = r.Method, r.URL.Path
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	ac := &client.AlpaconClient{
+		HTTPClient: ts.Client(),
+		BaseURL:    ts.URL,
+	}
+
+	if err := DeletePackage(ac, "ppkg-1", "python"); err != nil {
+		t.Fatalf("DeletePackage error: %v", err)
+	}
+	if gotMethod != http.MethodDelete || gotPath != "/api/packages/python/entries/ppkg-1/" {
+		t.Errorf("expected DELETE /api/packages/python/entries/ppkg-1/, got %s %s", gotMethod, gotPath)
+	}
+}
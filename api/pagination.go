@@ -105,3 +105,124 @@ func FetchCursorPages[T any](ac *client.AlpaconClient, endpoint string, params m
 	}
 	return result, nil
 }
+
+// FilterVerdict tells a filtered walk what to do with one item.
+type FilterVerdict int
+
+const (
+	FilterKeep FilterVerdict = iota
+	FilterSkip
+	// FilterStop ends the walk: pages come newest first, so an item older than
+	// the wanted range means every item after it is older too.
+	FilterStop
+)
+
+// FilterScanLimit bounds how many items a filtered walk reads, so a filter that
+// rarely matches does not page through a workspace's whole history.
+const FilterScanLimit = 10000
+
+// ErrScanLimit is returned, with the items found so far, by a filtered walk
+// that read FilterScanLimit items before it had enough or reached the end:
+// older items may match too.
+var ErrScanLimit = fmt.Errorf("stopped after reading %d entries; older entries were not searched", FilterScanLimit)
+
+// FetchCursorPagesFiltered is FetchCursorPages keeping only the items filter
+// accepts: it walks full pages until it has limit of them, filter stops it, or
+// it has read FilterScanLimit items, which returns ErrScanLimit with what it
+// found. A nil filter is FetchCursorPages.
+func FetchCursorPagesFiltered[T any](ac *client.AlpaconClient, endpoint string, params map[string]string, limit int, filter func(T) FilterVerdict) ([]T, error) {
+	if filter == nil {
+		return FetchCursorPages[T](ac, endpoint, params, limit)
+	}
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	params = copyParams(params)
+	delete(params, "cursor")
+	params["page_size"] = strconv.Itoa(maxPageSize)
+
+	var result []T
+	for scanned := 0; ; {
+		if scanned >= FilterScanLimit {
+			return result, ErrScanLimit
+		}
+		responseBody, err := ac.SendGetRequest(utils.BuildURL(endpoint, "", params))
+		if err != nil {
+			return nil, fmt.Errorf("fetching cursor page from %s: %w", endpoint, err)
+		}
+
+		var page CursorListResponse[T]
+		if err = json.Unmarshal(responseBody, &page); err != nil {
+			return nil, fmt.Errorf("decoding cursor page from %s: %w", endpoint, err)
+		}
+
+		var done bool
+		if result, done = applyFilter(result, page.Results, limit, filter); done {
+			break
+		}
+		scanned += len(page.Results)
+		if page.Next == "" || len(page.Results) == 0 {
+			break
+		}
+		params["cursor"] = page.Next
+	}
+	return result, nil
+}
+
+// FetchPagesFiltered is FetchCursorPagesFiltered for PageNumber endpoints.
+func FetchPagesFiltered[T any](ac *client.AlpaconClient, endpoint string, params map[string]string, limit int, filter func(T) FilterVerdict) ([]T, error) {
+	if filter == nil {
+		return FetchPagesUpTo[T](ac, endpoint, params, limit)
+	}
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	params = copyParams(params)
+	params["page_size"] = strconv.Itoa(maxPageSize)
+
+	var result []T
+	for page := 1; ; page++ {
+		if (page-1)*maxPageSize >= FilterScanLimit {
+			return result, ErrScanLimit
+		}
+		params["page"] = strconv.Itoa(page)
+
+		responseBody, err := ac.SendGetRequest(utils.BuildURL(endpoint, "", params))
+		if err != nil {
+			return nil, fmt.Errorf("fetching page %d from %s: %w", page, endpoint, err)
+		}
+
+		var response ListResponse[T]
+		if err = json.Unmarshal(responseBody, &response); err != nil {
+			return nil, fmt.Errorf("decoding page %d from %s: %w", page, endpoint, err)
+		}
+
+		var done bool
+		if result, done = applyFilter(result, response.Results, limit, filter); done {
+			break
+		}
+		if response.Next == 0 || len(response.Results) == 0 {
+			break
+		}
+	}
+	return result, nil
+}
+
+// applyFilter appends the items of one page that filter keeps, reporting
+// whether the walk is over.
+func applyFilter[T any](result, page []T, limit int, filter func(T) FilterVerdict) ([]T, bool) {
+	for _, item := range page {
+		switch filter(item) {
+		case FilterStop:
+			return result, true
+		case FilterKeep:
+			result = append(result, item)
+			if len(result) == limit {
+				return result, true
+			}
+		}
+	}
+	return result, false
+}
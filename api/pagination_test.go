@@ -451,3 +451,75 @@ func TestFetchAllPages_WalksEveryPageAtTheCap(t *testing.T) {
 	assert.Equal(t, []string{"100", "100", "100"}, rec.queried("page_size"))
 	assert.Equal(t, []string{"1", "2", "3"}, rec.queried("page"))
 }
+
+func TestFetchCursorPagesFiltered_KeepsSkipsAndStops(t *testing.T) {
+	rec := &requestRecorder{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec.record(r)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("cursor") == "" {
+			_ = json.NewEncoder(w).Encode(CursorListResponse[cursorItem]{
+				Next:    "TOKEN2",
+				Results: []cursorItem{{Name: "keep-1"}, {Name: "skip"}},
+			})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(CursorListResponse[cursorItem]{
+			Next:    "TOKEN3",
+			Results: []cursorItem{{Name: "keep-2"}, {Name: "stop"}, {Name: "keep-3"}},
+		})
+	}))
+	defer ts.Close()
+
+	filter := func(item cursorItem) FilterVerdict {
+		switch item.Name {
+		case "skip":
+			return FilterSkip
+		case "stop":
+			return FilterStop
+		}
+		return FilterKeep
+	}
+	ac := &client.AlpaconClient{HTTPClient: ts.Client(), BaseURL: ts.URL}
+	items, err := FetchCursorPagesFiltered(ac, "/api/history/logs/", nil, 10, filter)
+	require.NoError(t, err)
+	assert.Equal(t, []cursorItem{{Name: "keep-1"}, {Name: "keep-2"}}, items)
+	assert.Equal(t, []string{"100", "100"}, rec.queried("page_size"), "a filtered walk reads full pages")
+	assert.Equal(t, []string{"", "TOKEN2"}, rec.queried("cursor"))
+}
+
+func TestFetchCursorPagesFiltered_StopsAtTheScanLimit(t *testing.T) {
+	rec := &requestRecorder{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec.record(r)
+		w.Header().Set("Content-Type", "application/json")
+		results := make([]cursorItem, maxPageSize)
+		_ = json.NewEncoder(w).Encode(CursorListResponse[cursorItem]{Next: "MORE", Results: results})
+	}))
+	defer ts.Close()
+
+	ac := &client.AlpaconClient{HTTPClient: ts.Client(), BaseURL: ts.URL}
+	items, err := FetchCursorPagesFiltered(ac, "/api/history/logs/", nil, 10, func(cursorItem) FilterVerdict { return FilterSkip })
+	assert.ErrorIs(t, err, ErrScanLimit, "a walk cut short says so")
+	assert.Empty(t, items)
+	assert.Equal(t, FilterScanLimit/maxPageSize, rec.count())
+}
+
+func TestFetchPagesFiltered_StopsAtTheLimit(t *testing.T) {
+	rec := &requestRecorder{}
+	ts := newPageServer(t, 500, rec)
+	defer ts.Close()
+
+	even := func(item pageItem) FilterVerdict {
+		if n, _ := strconv.Atoi(item.ID); n%2 == 0 {
+			return FilterKeep
+		}
+		return FilterSkip
+	}
+	ac := &client.AlpaconClient{HTTPClient: ts.Client(), BaseURL: ts.URL}
+	items, err := FetchPagesFiltered(ac, "/api/events/commands/", nil, 60, even)
+	require.NoError(t, err)
+	require.Len(t, items, 60)
+	assert.Equal(t, "118", items[59].ID)
+	assert.Equal(t, []string{"1", "2"}, rec.queried("page"))
+}
@@ -29,6 +29,9 @@ const (
 	ActionUpdateInformation = "update_information"
 )
 
+// ErrServerNotFound is returned when no server matches the given name.
+var ErrServerNotFound = errors.New("no server found with the given name")
+
 // ErrRegistrationTokenNotFound is returned when no registration token matches the given name.
 var ErrRegistrationTokenNotFound = errors.New("no registration token found with the given name")
 
@@ -59,6 +62,46 @@ func GetServerList(ac *client.AlpaconClient) ([]ServerAttributes, error) {
 	return serverList, nil
 }
 
+// GetServerInventory returns every server with the fields an inventory export
+// needs. Group UUIDs are resolved to names with one batched lookup; on lookup
+// failure they are kept as-is.
+func GetServerInventory(ac *client.AlpaconClient) ([]InventoryHost, error) {
+	servers, err := api.FetchAllPages[ServerDetails](ac, serverURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	groupMap := map[string]string{}
+	for _, s := range servers {
+		if len(s.Groups) > 0 {
+			groupMap = buildGroupUUIDToNameMap(ac)
+			break
+		}
+	}
+
+	hosts := make([]InventoryHost, 0, len(servers))
+	for _, s := range servers {
+		groups := make([]string, 0, len(s.Groups))
+		for _, id := range s.Groups {
+			if n, ok := groupMap[id]; ok {
+				groups = append(groups, n)
+			} else {
+				groups = append(groups, id)
+			}
+		}
+		hosts = append(hosts, InventoryHost{
+			Name:      s.Name,
+			IP:        s.RemoteIP,
+			OSName:    s.OSName,
+			OSVersion: s.OSVersion,
+			Connected: s.IsConnected,
+			Owner:     s.Owner.Name,
+			Groups:    groups,
+		})
+	}
+	return hosts, nil
+}
+
 func GetServerDetail(ac *client.AlpaconClient, serverName string) ([]byte, error) {
 	serverID, err := GetServerIDByName(ac, serverName)
 	if err != nil {
@@ -114,7 +157,7 @@ func GetServerIDByName(ac *client.AlpaconClient, serverName string) (string, err
 	}
 
 	if response.Count == 0 {
-		return "", errors.New("no server found with the given name")
+		return "", ErrServerNotFound
 	}
 
 	return response.Results[0].ID, nil
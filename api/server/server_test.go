@@ -567,3 +567,46 @@ func TestRequestServerAction(t *testing.T) {
 		})
 	}
 }
+
+func TestGetServerInventory_ResolvesGroupNames(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(r.URL.Path, "/api/iam/groups/") {
+			_ = json.NewEncoder(w).Encode(api.ListResponse[groupSummary]{
+				Count:   1,
+				Results: []groupSummary{{ID: "uuid-g1", Name: "web"}},
+			})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(api.ListResponse[ServerDetails]{
+			Count: 1,
+			Results: []ServerDetails{{
+				ID:          "id-1",
+				Name:        "web-1",
+				RemoteIP:    "10.0.0.1",
+				IsConnected: true,
+				OSName:      "Ubuntu",
+				OSVersion:   "22.04",
+				Owner:       types.UserSummary{Name: "alice"},
+				Groups:      []string{"uuid-g1", "uuid-gone"},
+			}},
+		})
+	}))
+	defer ts.Close()
+
+	ac := &client.AlpaconClient{HTTPClient: ts.Client(), BaseURL: ts.URL}
+	hosts, err := GetServerInventory(ac)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hosts) != 1 {
+		t.Fatalf("expected 1 host, got %d", len(hosts))
+	}
+	h := hosts[0]
+	if h.Name != "web-1" || h.IP != "10.0.0.1" || h.Owner != "alice" || !h.Connected || h.OSName != "Ubuntu" {
+		t.Errorf("unexpected host: %+v", h)
+	}
+	if strings.Join(h.Groups, ",") != "web,uuid-gone" {
+		t.Errorf("expected groups web,uuid-gone, got %v", h.Groups)
+	}
+}
@@ -14,6 +14,17 @@ type ServerAttributes struct {
 	Owner     string `json:"owner"`
 }
 
+// InventoryHost is a server as an inventory export describes it.
+type InventoryHost struct {
+	Name      string   `json:"name"`
+	IP        string   `json:"ip"`
+	OSName    string   `json:"os_name"`
+	OSVersion string   `json:"os_version"`
+	Connected bool     `json:"connected"`
+	Owner     string   `json:"owner"`
+	Groups    []string `json:"groups"`
+}
+
 // RegistrationTokenRequest is used to create a new server registration token.
 // ExpiresAt is an RFC3339 timestamp; omit to create a non-expiring token.
 type RegistrationTokenRequest struct {
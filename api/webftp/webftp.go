@@ -2,9 +2,11 @@ package webftp
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/alpacax/alpacon-cli/api"
 	"github.com/alpacax/alpacon-cli/api/iam"
+	"github.com/alpacax/alpacon-cli/api/logquery"
 	"github.com/alpacax/alpacon-cli/api/server"
 	"github.com/alpacax/alpacon-cli/client"
 	"github.com/alpacax/alpacon-cli/utils"
@@ -15,6 +17,21 @@ const (
 )
 
 func GetWebFTPLogList(ac *client.AlpaconClient, tail int, serverName string, userName string, action string) ([]WebFTPLogAttributes, error) {
+	params, err := WebFTPLogParams(ac, serverName, userName, action)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := GetWebFTPLogs(ac, params, tail, logquery.Filter{})
+	if err != nil {
+		return nil, err
+	}
+	return ToWebFTPLogAttributes(entries), nil
+}
+
+// WebFTPLogParams builds the server-side filters, resolving names once so a
+// follow loop does not look them up on every poll.
+func WebFTPLogParams(ac *client.AlpaconClient, serverName string, userName string, action string) (map[string]string, error) {
 	params := map[string]string{}
 	if serverName != "" {
 		serverID, err := server.GetServerIDByName(ac, serverName)
@@ -33,12 +50,46 @@ func GetWebFTPLogList(ac *client.AlpaconClient, tail int, serverName string, use
 	if action != "" {
 		params["action"] = action
 	}
+	return params, nil
+}
 
-	entries, err := api.FetchCursorPages[WebFTPLogEntry](ac, webftpLogURL, params, tail)
-	if err != nil {
-		return nil, err
+// GetWebFTPLogs returns the newest tail entries that pass the filter, newest
+// first.
+func GetWebFTPLogs(ac *client.AlpaconClient, params map[string]string, tail int, filter logquery.Filter) ([]WebFTPLogEntry, error) {
+	var check func(WebFTPLogEntry) api.FilterVerdict
+	if !filter.IsZero() {
+		check = func(e WebFTPLogEntry) api.FilterVerdict {
+			return filter.Check(e.AddedAt, e.FileName, e.Message, e.Action)
+		}
 	}
+	return api.FetchCursorPagesFiltered(ac, webftpLogURL, params, tail, check)
+}
 
+// FollowWebFTPLogs returns a Follower for the WebFTP logs that pass the
+// filter. The caller sets Emit and seeds it with what it has shown.
+func FollowWebFTPLogs(ac *client.AlpaconClient, params map[string]string, filter logquery.Filter, interval time.Duration) *logquery.Follower[WebFTPLogEntry] {
+	return &logquery.Follower[WebFTPLogEntry]{
+		Interval: interval,
+		Fetch: func(since time.Time) ([]WebFTPLogEntry, error) {
+			f := filter
+			f.Since = since
+			return GetWebFTPLogs(ac, params, api.FilterScanLimit, f)
+		},
+		// Entries carry no ID; what was moved where, by whom, tells them apart.
+		Stamp: func(e WebFTPLogEntry) (time.Time, string) {
+			key := e.Action + "\x00" + e.FileName + "\x00" + e.RemoteIP
+			if e.Server != nil {
+				key += "\x00" + e.Server.ID
+			}
+			if e.User != nil {
+				key += "\x00" + e.User.ID
+			}
+			return e.AddedAt, key
+		},
+	}
+}
+
+func ToWebFTPLogAttributes(entries []WebFTPLogEntry) []WebFTPLogAttributes {
 	var logList []WebFTPLogAttributes
 	for _, entry := range entries {
 		entryServerName := ""
@@ -60,6 +111,5 @@ func GetWebFTPLogList(ac *client.AlpaconClient, tail int, serverName string, use
 			AddedAt:  utils.TimeUtils(entry.AddedAt),
 		})
 	}
-
-	return logList, nil
+	return logList
 }
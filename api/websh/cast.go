@@ -0,0 +1,73 @@
+package websh
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/pkg/asciicast"
+)
+
+// CastRecordLimit caps how many records go into one cast. It is far above
+// the table default because a cast with records missing plays as nonsense.
+const CastRecordLimit = 100000
+
+// GetSessionCast builds a cast from a session's records, sized to the
+// session's terminal when the detail lookup succeeds.
+func GetSessionCast(ac *client.AlpaconClient, sessionID string, limit int) (*asciicast.Cast, error) {
+	records, err := GetSessionRecords(ac, sessionID, "", limit)
+	if err != nil {
+		return nil, err
+	}
+	var detail SessionDetailResponse
+	if body, err := GetSessionDetail(ac, sessionID); err == nil {
+		_ = json.Unmarshal(body, &detail) // best-effort: the size falls back to 80x24
+	}
+	return RecordsToCast(records, detail), nil
+}
+
+// RecordsToCast orders records by time and turns each into an output event
+// offset from the first. A record whose timestamp does not parse takes the
+// time of the record before it rather than being dropped.
+func RecordsToCast(records []SessionRecord, detail SessionDetailResponse) *asciicast.Cast {
+	type timed struct {
+		at   time.Time
+		data string
+	}
+	items := make([]timed, len(records))
+	var last time.Time
+	for i, r := range records {
+		if at, err := time.Parse(time.RFC3339Nano, r.AddedAt); err == nil {
+			last = at
+		}
+		items[i] = timed{at: last, data: r.Record}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].at.Before(items[j].at) })
+
+	cast := &asciicast.Cast{Header: asciicast.Header{
+		Width:  detail.Cols,
+		Height: detail.Rows,
+	}}
+	if cast.Header.Width <= 0 || cast.Header.Height <= 0 {
+		cast.Header.Width, cast.Header.Height = asciicast.DefaultWidth, asciicast.DefaultHeight
+	}
+	if detail.ID != "" {
+		cast.Header.Title = fmt.Sprintf("websh %s@%s (%s)", detail.Username, detail.Server.Name, detail.ID)
+	}
+
+	var start time.Time
+	for _, item := range items {
+		if start.IsZero() && !item.at.IsZero() {
+			start = item.at
+			cast.Header.Timestamp = start.Unix()
+		}
+		offset := 0.0
+		if !item.at.IsZero() {
+			offset = item.at.Sub(start).Seconds()
+		}
+		cast.Events = append(cast.Events, asciicast.Event{Time: offset, Type: asciicast.EventOutput, Data: item.data})
+	}
+	return cast
+}
@@ -0,0 +1,39 @@
+package websh
+
+import (
+	"testing"
+
+	"github.com/alpacax/alpacon-cli/api/types"
+	"github.com/alpacax/alpacon-cli/pkg/asciicast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordsToCast_OrdersAndOffsets(t *testing.T) {
+	records := []SessionRecord{
+		{AddedAt: "2026-01-01T10:00:02.5Z", Record: "second"},
+		{AddedAt: "2026-01-01T10:00:00Z", Record: "first"},
+		{AddedAt: "not a time", Record: "after first"},
+	}
+	detail := SessionDetailResponse{ID: "sess-1", Rows: 40, Cols: 120, Username: "root", Server: types.ServerSummary{Name: "web-01"}}
+
+	cast := RecordsToCast(records, detail)
+
+	assert.Equal(t, 120, cast.Header.Width)
+	assert.Equal(t, 40, cast.Header.Height)
+	assert.Equal(t, "websh root@web-01 (sess-1)", cast.Header.Title)
+	assert.EqualValues(t, 1767261600, cast.Header.Timestamp)
+	require.Len(t, cast.Events, 3)
+	assert.Equal(t, asciicast.Event{Time: 0, Type: asciicast.EventOutput, Data: "first"}, cast.Events[0])
+	// An unparsed timestamp takes the one before it, so the record stays with its neighbour.
+	assert.Equal(t, asciicast.Event{Time: 0, Type: asciicast.EventOutput, Data: "after first"}, cast.Events[1])
+	assert.Equal(t, asciicast.Event{Time: 2.5, Type: asciicast.EventOutput, Data: "second"}, cast.Events[2])
+}
+
+func TestRecordsToCast_DefaultSize(t *testing.T) {
+	cast := RecordsToCast([]SessionRecord{{AddedAt: "2026-01-01T10:00:00Z", Record: "x"}}, SessionDetailResponse{})
+
+	assert.Equal(t, asciicast.DefaultWidth, cast.Header.Width)
+	assert.Equal(t, asciicast.DefaultHeight, cast.Header.Height)
+	assert.Empty(t, cast.Header.Title)
+}
@@ -0,0 +1,122 @@
+package websh
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// DefaultEscapeChar opens an escape sequence, as in ssh.
+const DefaultEscapeChar = '~'
+
+const escapePrompt = "alpacon> "
+
+// escapeAction is what the input loop does once an escape sequence is read.
+type escapeAction struct {
+	send       string // forwarded to the server; empty sends nothing
+	disconnect bool
+}
+
+// readEscape handles the rune after the escape character. The escape
+// character only ever reaches this point at the start of a line, so a
+// wedged remote program can always be left with Enter, then ~. .
+func (wsClient *WebsocketClient) readEscape(reader *bufio.Reader, next rune) escapeAction {
+	escapeChar := wsClient.opts.EscapeChar
+	switch next {
+	case '.':
+		wsClient.escapeNotice("Connection to %s closed.", wsClient.opts.ServerName)
+		return escapeAction{disconnect: true}
+	case '?':
+		wsClient.escapeNotice("%s", escapeHelp(escapeChar, wsClient.opts.Command != nil))
+	case '#':
+		wsClient.escapeNotice("Websh session %s on %s", wsClient.opts.SessionID, wsClient.opts.ServerName)
+	case 'C':
+		if wsClient.opts.Command == nil {
+			return escapeAction{send: string(escapeChar) + string(next)}
+		}
+		wsClient.runEscapeCommand(reader)
+	case escapeChar:
+		return escapeAction{send: string(escapeChar)}
+	default:
+		return escapeAction{send: string(escapeChar) + string(next)}
+	}
+	return escapeAction{}
+}
+
+func escapeHelp(escapeChar rune, commands bool) string {
+	e := string(escapeChar)
+	lines := []string{
+		"Supported escape sequences:",
+		" " + e + ".   - terminate the session",
+		" " + e + "?   - show this message",
+		" " + e + "#   - show the session ID and server",
+	}
+	if commands {
+		lines = append(lines, " "+e+"C   - open a command line (file transfer or tunnel)")
+	}
+	lines = append(lines,
+		" "+e+e+"   - send the escape character by typing it twice",
+		"(Escapes are only recognized immediately after a newline.)",
+	)
+	return strings.Join(lines, "\n")
+}
+
+// runEscapeCommand reads one line at the alpacon> prompt and hands it to
+// the command handler. The terminal is in raw mode, so the line is echoed
+// and edited here. Ctrl+C, Ctrl+D, or ESC abandons it.
+func (wsClient *WebsocketClient) runEscapeCommand(reader *bufio.Reader) {
+	out := wsClient.escapeOutput()
+	_, _ = fmt.Fprint(out, "\r\n"+escapePrompt)
+
+	var line []rune
+	for {
+		r, _, err := reader.ReadRune()
+		if err != nil {
+			_, _ = fmt.Fprint(out, "\r\n")
+			return
+		}
+		switch {
+		case r == '\r' || r == '\n':
+			_, _ = fmt.Fprint(out, "\r\n")
+			input := strings.TrimSpace(string(line))
+			if input == "" {
+				return
+			}
+			result, err := wsClient.opts.Command(input)
+			if err != nil {
+				wsClient.escapeNotice("%s", err)
+			} else if result != "" {
+				wsClient.escapeNotice("%s", result)
+			}
+			return
+		case r == ctrlC || r == 0x04 || r == 0x1b:
+			_, _ = fmt.Fprint(out, "\r\n")
+			return
+		case r == 0x7f || r == 0x08:
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+				_, _ = fmt.Fprint(out, "\b \b")
+			}
+		case unicode.IsPrint(r):
+			line = append(line, r)
+			_, _ = fmt.Fprint(out, string(r))
+		}
+	}
+}
+
+// escapeNotice prints a message from an escape sequence. The terminal is in
+// raw mode, so every line ends in \r\n.
+func (wsClient *WebsocketClient) escapeNotice(format string, a ...any) {
+	msg := strings.ReplaceAll(fmt.Sprintf(format, a...), "\n", "\r\n")
+	_, _ = fmt.Fprint(wsClient.escapeOutput(), "\r\n"+msg+"\r\n")
+}
+
+func (wsClient *WebsocketClient) escapeOutput() io.Writer {
+	if wsClient.escapeOut != nil {
+		return wsClient.escapeOut
+	}
+	return os.Stderr
+}
@@ -0,0 +1,86 @@
+package websh
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runInput feeds input to readUserInput with the given options and returns
+// what was forwarded to the server and what the escapes printed. The input
+// ends with EOF, which ends the session if nothing else did.
+func runInput(t *testing.T, opts TerminalOptions, input string) (string, string) {
+	t.Helper()
+	pipeWrite := pipeStdin(t)
+	_, err := pipeWrite.WriteString(input)
+	require.NoError(t, err)
+	require.NoError(t, pipeWrite.Close())
+
+	var printed bytes.Buffer
+	wsClient := newWebsocketClient(nil)
+	wsClient.opts = opts
+	wsClient.escapeOut = &printed
+
+	inputChan := make(chan string, len(input)+1)
+	awaitReturn(t, "readUserInput parked", func() { wsClient.readUserInput(inputChan) })
+	assertReported(t, wsClient)
+	assert.NoError(t, wsClient.err)
+
+	close(inputChan)
+	var sent strings.Builder
+	for s := range inputChan {
+		sent.WriteString(s)
+	}
+	return sent.String(), printed.String()
+}
+
+func TestReadUserInput_EscapeDisconnects(t *testing.T) {
+	sent, printed := runInput(t, TerminalOptions{EscapeChar: DefaultEscapeChar, ServerName: "web-01"}, "ls\r~.never sent")
+
+	assert.Equal(t, "ls\r", sent)
+	assert.Contains(t, printed, "Connection to web-01 closed.")
+}
+
+func TestReadUserInput_EscapeOnlyAtLineStart(t *testing.T) {
+	sent, _ := runInput(t, TerminalOptions{EscapeChar: DefaultEscapeChar}, "cd ~.\r~~/x\r~x")
+
+	assert.Equal(t, "cd ~.\r~/x\r~x", sent, "~~ sends one ~ and an unknown escape passes through")
+}
+
+func TestReadUserInput_EscapesDisabled(t *testing.T) {
+	sent, printed := runInput(t, TerminalOptions{}, "~.~?")
+
+	assert.Equal(t, "~.~?", sent)
+	assert.Empty(t, printed)
+}
+
+func TestReadUserInput_HelpAndInfoSendNothing(t *testing.T) {
+	opts := TerminalOptions{EscapeChar: DefaultEscapeChar, SessionID: "sess-1", ServerName: "web-01"}
+	sent, printed := runInput(t, opts, "~?~#x")
+
+	assert.Equal(t, "x", sent, "the line start survives an escape that sends nothing")
+	assert.Contains(t, printed, "~.   - terminate the session")
+	assert.NotContains(t, printed, "~C", "no command handler, no ~C")
+	assert.Contains(t, printed, "Websh session sess-1 on web-01")
+	assert.NotContains(t, strings.ReplaceAll(printed, "\r\n", ""), "\n", "raw mode needs \\r\\n line ends")
+}
+
+func TestReadUserInput_CommandLine(t *testing.T) {
+	var got string
+	opts := TerminalOptions{
+		EscapeChar: DefaultEscapeChar,
+		Command: func(line string) (string, error) {
+			got = line
+			return "done", nil
+		},
+	}
+	sent, printed := runInput(t, opts, "~Cget /etx\x7fc/hosts\r~Cabandoned\x03x")
+
+	assert.Equal(t, "get /etc/hosts", got)
+	assert.Equal(t, "x", sent)
+	assert.Contains(t, printed, escapePrompt)
+	assert.Contains(t, printed, "done")
+}
@@ -0,0 +1,313 @@
+package websh
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alpacax/alpacon-cli/pkg/asciicast"
+	"golang.org/x/term"
+)
+
+const (
+	LogFormatPlain     = "plain"
+	LogFormatAsciicast = "asciicast"
+)
+
+const logTimeLayout = time.RFC3339
+
+// SessionLog keeps the user's own copy of an interactive session. A nil
+// *SessionLog logs nothing, so the terminal loop calls it unconditionally.
+//
+// Logging never interrupts the session: the first write error stops the log
+// and is reported by Close, once the user is back at their own prompt.
+type SessionLog struct {
+	mu           sync.Mutex
+	file         *os.File
+	buf          *bufio.Writer
+	includeInput bool
+	now          func() time.Time
+	err          error
+
+	cast    *asciicast.Writer
+	partial []byte // an output rune split across two messages, held for the next
+
+	text      textLog
+	inputLine strings.Builder
+}
+
+// OpenSessionLog creates (or truncates) path with owner-only permissions and
+// writes the log header. Input is logged only when includeInput is set: it
+// carries whatever was typed, including at prompts that do not echo, such
+// as a password prompt.
+func OpenSessionLog(path, format, title string, includeInput bool) (*SessionLog, error) {
+	if format != LogFormatPlain && format != LogFormatAsciicast {
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	// O_CREATE's mode applies only to a new file; an existing one keeps its own.
+	if err = file.Chmod(0o600); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	width, height, err := term.GetSize(int(os.Stdin.Fd()))
+	if err != nil {
+		width, height = asciicast.DefaultWidth, asciicast.DefaultHeight
+	}
+	l, err := newSessionLog(file, format, title, includeInput, width, height, time.Now)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+func newSessionLog(file *os.File, format, title string, includeInput bool, width, height int, now func() time.Time) (*SessionLog, error) {
+	l := &SessionLog{file: file, buf: bufio.NewWriter(file), includeInput: includeInput, now: now}
+	start := now()
+	if format == LogFormatAsciicast {
+		header := asciicast.Header{Width: width, Height: height, Title: title}
+		if t := os.Getenv("TERM"); t != "" {
+			header.Env = map[string]string{"TERM": t}
+		}
+		cast, err := asciicast.NewWriter(l.buf, header, start)
+		if err != nil {
+			return nil, err
+		}
+		l.cast = cast
+	} else {
+		l.text.w = l.buf
+		if _, err := fmt.Fprintf(l.buf, "# %s, started %s\n", title, start.Format(logTimeLayout)); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// output logs what the server sent.
+func (l *SessionLog) output(p []byte) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return
+	}
+	at := l.now()
+	if l.cast != nil {
+		data := append(l.partial, p...)
+		cut := completeUTF8(data)
+		l.partial = append([]byte(nil), data[cut:]...)
+		if cut > 0 {
+			l.err = l.cast.WriteEvent(at, asciicast.EventOutput, string(data[:cut]))
+		}
+	} else {
+		l.err = l.text.write(at, p)
+	}
+	l.flush()
+}
+
+// input logs what the user typed, when the log was opened to include it.
+func (l *SessionLog) input(p []byte) {
+	if l == nil || !l.includeInput {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return
+	}
+	at := l.now()
+	if l.cast != nil {
+		l.err = l.cast.WriteEvent(at, asciicast.EventInput, string(p))
+		l.flush()
+		return
+	}
+	// Plain logs take input a line at a time: keystroke by keystroke, it
+	// would break up every output line it landed in.
+	for _, b := range p {
+		l.inputLine.WriteByte(b)
+		if b == '\r' || b == '\n' {
+			l.err = l.writeInputLine(at)
+			if l.err != nil {
+				return
+			}
+		}
+	}
+	l.flush()
+}
+
+func (l *SessionLog) writeInputLine(at time.Time) error {
+	line := l.inputLine.String()
+	l.inputLine.Reset()
+	// Quoted, so arrow keys, tabs, and Ctrl combinations stay visible.
+	_, err := fmt.Fprintf(l.buf, "[%s] input: %s\n", at.Format(logTimeLayout), strconv.Quote(line))
+	return err
+}
+
+// flush pushes each message to disk as it comes, so the log survives the
+// CLI being killed mid-session.
+func (l *SessionLog) flush() {
+	if l.err == nil {
+		l.err = l.buf.Flush()
+	}
+}
+
+// Close writes what is still buffered and closes the file. It returns the
+// first error the log hit, if any.
+func (l *SessionLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err == nil {
+		at := l.now()
+		if l.cast != nil {
+			if len(l.partial) > 0 {
+				l.err = l.cast.WriteEvent(at, asciicast.EventOutput, string(l.partial))
+			}
+		} else {
+			if l.inputLine.Len() > 0 {
+				l.err = l.writeInputLine(at)
+			}
+			if l.err == nil {
+				l.err = l.text.close(at)
+			}
+		}
+		l.flush()
+	}
+	if err := l.file.Close(); l.err == nil {
+		l.err = err
+	}
+	return l.err
+}
+
+// completeUTF8 returns the length of p less any rune cut off at its end.
+func completeUTF8(p []byte) int {
+	for i := len(p) - 1; i >= 0 && i >= len(p)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(p[i]) {
+			if utf8.FullRune(p[i:]) {
+				return len(p)
+			}
+			return i
+		}
+	}
+	return len(p)
+}
+
+type textState int
+
+const (
+	textPlain     textState = iota
+	textEsc                 // saw ESC
+	textCSI                 // inside a CSI sequence
+	textString              // inside an OSC, DCS, APC, PM, or SOS string
+	textStringEsc           // saw ESC inside a control string
+)
+
+// textLog turns a terminal stream into timestamped lines of plain text.
+// Escape sequences are dropped and backspaces applied, so the log reads the
+// way the screen did rather than the way the bytes arrived. A carriage
+// return not followed by a newline starts the line over, which leaves a
+// progress bar at its final state.
+type textLog struct {
+	w         *bufio.Writer
+	state     textState
+	line      []byte
+	lineStart time.Time
+	pendingCR bool
+}
+
+func (t *textLog) write(at time.Time, p []byte) error {
+	for _, b := range p {
+		switch t.state {
+		case textEsc:
+			switch b {
+			case '[':
+				t.state = textCSI
+			case ']', 'P', '_', '^', 'X':
+				t.state = textString
+			default:
+				t.state = textPlain
+			}
+			continue
+		case textCSI:
+			if b >= 0x40 && b <= 0x7e {
+				t.state = textPlain
+			}
+			continue
+		case textString:
+			switch b {
+			case 0x07:
+				t.state = textPlain
+			case 0x1b:
+				t.state = textStringEsc
+			}
+			continue
+		case textStringEsc:
+			if b == '\\' {
+				t.state = textPlain
+			} else if b != 0x1b {
+				t.state = textString
+			}
+			continue
+		}
+
+		if t.pendingCR && b != '\n' && b != '\r' {
+			t.line = t.line[:0]
+		}
+		t.pendingCR = false
+		switch {
+		case b == 0x1b:
+			t.state = textEsc
+		case b == '\n':
+			if err := t.writeLine(at); err != nil {
+				return err
+			}
+		case b == '\r':
+			t.pendingCR = true
+		case b == 0x08:
+			if len(t.line) > 0 {
+				_, size := utf8.DecodeLastRune(t.line)
+				t.line = t.line[:len(t.line)-size]
+			}
+		case b == '\t' || (b >= 0x20 && b != 0x7f):
+			if len(t.line) == 0 {
+				t.lineStart = at
+			}
+			t.line = append(t.line, b)
+		}
+	}
+	return nil
+}
+
+func (t *textLog) writeLine(at time.Time) error {
+	start := t.lineStart
+	if len(t.line) == 0 {
+		start = at
+	}
+	_, err := fmt.Fprintf(t.w, "[%s] %s\n", start.Format(logTimeLayout), strings.TrimRight(string(t.line), " "))
+	t.line = t.line[:0]
+	return err
+}
+
+func (t *textLog) close(at time.Time) error {
+	if len(t.line) > 0 {
+		if err := t.writeLine(at); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(t.w, "# ended %s\n", at.Format(logTimeLayout))
+	return err
+}
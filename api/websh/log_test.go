@@ -0,0 +1,110 @@
+package websh
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpacax/alpacon-cli/pkg/asciicast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// steppedClock hands out times one second apart, starting at 10:00:00 UTC.
+func steppedClock() func() time.Time {
+	at := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		now := at
+		at = at.Add(time.Second)
+		return now
+	}
+}
+
+func openTestLog(t *testing.T, format string, includeInput bool) (*SessionLog, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "session.log")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0o600)
+	require.NoError(t, err)
+	l, err := newSessionLog(file, format, "websh root@web-01", includeInput, 100, 30, steppedClock())
+	require.NoError(t, err)
+	return l, path
+}
+
+func TestSessionLog_Plain(t *testing.T) {
+	l, path := openTestLog(t, LogFormatPlain, true)
+
+	l.output([]byte("\x1b]0;root@web-01\x07\x1b[01;32mroot@web-01\x1b[0m:~# "))
+	l.input([]byte("ls"))
+	l.input([]byte("x\x7f\r"))
+	l.output([]byte("lsx\b \b\r\n"))
+	l.output([]byte("10%\r50%\r100%\r\nfile.txt\r\n"))
+	l.output([]byte("root@web-01:~# "))
+	require.NoError(t, l.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Join([]string{
+		"# websh root@web-01, started 2026-01-01T10:00:00Z",
+		`[2026-01-01T10:00:03Z] input: "lsx\x7f\r"`,
+		"[2026-01-01T10:00:01Z] root@web-01:~# ls",
+		"[2026-01-01T10:00:05Z] 100%",
+		"[2026-01-01T10:00:05Z] file.txt",
+		"[2026-01-01T10:00:06Z] root@web-01:~#",
+		"# ended 2026-01-01T10:00:07Z",
+		"",
+	}, "\n"), string(content))
+}
+
+func TestSessionLog_PlainSkipsInputUnlessAsked(t *testing.T) {
+	l, path := openTestLog(t, LogFormatPlain, false)
+	l.input([]byte("hunter2\r"))
+	require.NoError(t, l.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "hunter2")
+}
+
+func TestSessionLog_AsciicastHoldsSplitRunes(t *testing.T) {
+	l, path := openTestLog(t, LogFormatAsciicast, true)
+
+	l.output([]byte("caf\xc3"))
+	l.output([]byte("\xa9\r\n"))
+	l.input([]byte("q"))
+	require.NoError(t, l.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+	cast, err := asciicast.Read(f)
+	require.NoError(t, err)
+	assert.Equal(t, 100, cast.Header.Width)
+	assert.Equal(t, "websh root@web-01", cast.Header.Title)
+	assert.Equal(t, []asciicast.Event{
+		{Time: 1, Type: asciicast.EventOutput, Data: "caf"},
+		{Time: 2, Type: asciicast.EventOutput, Data: "é\r\n"},
+		{Time: 3, Type: asciicast.EventInput, Data: "q"},
+	}, cast.Events)
+}
+
+func TestOpenSessionLog_OwnerOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.cast")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0o644))
+
+	l, err := OpenSessionLog(path, LogFormatAsciicast, "websh web-01", false)
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "an existing file is narrowed too")
+}
+
+func TestSessionLog_NilIsANoOp(t *testing.T) {
+	var l *SessionLog
+	l.output([]byte("x"))
+	l.input([]byte("x"))
+	assert.NoError(t, l.Close())
+}
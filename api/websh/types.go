@@ -1,6 +1,7 @@
 package websh
 
 import (
+	"io"
 	"net/http"
 	"sync"
 	"time"
@@ -15,6 +16,22 @@ type WebsocketClient struct {
 	done       chan struct{} // closed once the first outcome is recorded
 	err        error
 	finishOnce sync.Once
+	opts       TerminalOptions
+	escapeOut  io.Writer // where escape sequences print; nil means stderr
+}
+
+// TerminalOptions configures an interactive terminal beyond the session itself.
+type TerminalOptions struct {
+	// Log receives the session as it runs; the caller closes it.
+	Log *SessionLog
+	// EscapeChar opens an escape sequence at the start of a line; 0 disables escapes.
+	EscapeChar rune
+	// SessionID and ServerName are what the # escape shows.
+	SessionID  string
+	ServerName string
+	// Command runs a line typed at the C escape's prompt and returns what to
+	// show; nil leaves the C escape out.
+	Command func(line string) (string, error)
 }
 
 type SessionRequest struct {
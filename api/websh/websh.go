@@ -11,6 +11,7 @@ import (
 	"os"
 	"os/signal"
 	"path"
+	"strings"
 	"syscall"
 	"time"
 
@@ -298,8 +299,10 @@ func (wsClient *WebsocketClient) readCtrlC() {
 // OpenNewTerminal opens an interactive terminal on the session.
 // Input is forwarded to the server. Terminal echo is suppressed via raw mode.
 // Ends cleanly on the remote close, on Ctrl+D, or on a signal.
-func OpenNewTerminal(ac *client.AlpaconClient, sessionResponse SessionResponse) error {
+// With an escape character set, ~. at the start of a line also ends it.
+func OpenNewTerminal(ac *client.AlpaconClient, sessionResponse SessionResponse, opts TerminalOptions) error {
 	wsClient := newWebsocketClient(ac.SetWebsocketHeader())
+	wsClient.opts = opts
 	if err := wsClient.dial(sessionResponse.WebsocketURL); err != nil {
 		return err
 	}
@@ -361,13 +364,17 @@ func (wsClient *WebsocketClient) readFromServer() {
 			return
 		}
 		_, _ = os.Stdout.Write(message)
+		wsClient.opts.Log.output(message)
 	}
 }
 
 // readUserInput cannot be released mid-read: a goroutine parked in ReadRune stays
 // there until the next keystroke, and only closing stdin would change that.
+// The escape character is held back at the start of a line until the rune
+// after it says whether it opens an escape sequence.
 func (wsClient *WebsocketClient) readUserInput(inputChan chan<- string) {
 	reader := bufio.NewReader(os.Stdin)
+	atLineStart := true
 	for {
 		char, _, err := reader.ReadRune()
 		if err != nil {
@@ -377,9 +384,30 @@ func (wsClient *WebsocketClient) readUserInput(inputChan chan<- string) {
 			wsClient.finish(err)
 			return
 		}
+		send := string(char)
+		if atLineStart && wsClient.opts.EscapeChar != 0 && char == wsClient.opts.EscapeChar {
+			next, _, err := reader.ReadRune()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					err = nil
+				}
+				wsClient.finish(err)
+				return
+			}
+			action := wsClient.readEscape(reader, next)
+			if action.disconnect {
+				wsClient.finish(nil)
+				return
+			}
+			if action.send == "" {
+				continue // still at the start of the line
+			}
+			send = action.send
+		}
+		atLineStart = strings.HasSuffix(send, "\r") || strings.HasSuffix(send, "\n")
 		// After teardown writeToServer is gone, so an unguarded send would park here.
 		select {
-		case inputChan <- string(char):
+		case inputChan <- send:
 		case <-wsClient.done:
 			return
 		}
@@ -401,11 +429,13 @@ func (wsClient *WebsocketClient) writeToServer(inputChan <-chan string) {
 			inputBuffer = append(inputBuffer, []rune(input)...)
 		case <-ticker.C:
 			if len(inputBuffer) > 0 {
-				err := wsClient.conn.WriteMessage(websocket.BinaryMessage, []byte(string(inputBuffer)))
+				data := []byte(string(inputBuffer))
+				err := wsClient.conn.WriteMessage(websocket.BinaryMessage, data)
 				if err != nil {
 					wsClient.finish(err)
 					return
 				}
+				wsClient.opts.Log.input(data)
 				inputBuffer = []rune{}
 			}
 		}
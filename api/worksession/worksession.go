@@ -37,6 +37,12 @@ func GetWorkSessionList(ac *client.AlpaconClient, status, requesterType, assigne
 	return result, nil
 }
 
+// GetWorkSessions returns the full work sessions matching params, sudo
+// policies included.
+func GetWorkSessions(ac *client.AlpaconClient, params map[string]string) ([]WorkSession, error) {
+	return api.FetchAllPages[WorkSession](ac, workSessionURL, params)
+}
+
 // ProjectAttributes converts a full WorkSession into the WorkSessionAttributes
 // shape used by table outputs (ls, current). Single source of truth for column projection.
 func ProjectAttributes(ws *WorkSession) WorkSessionAttributes {
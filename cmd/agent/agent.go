@@ -14,7 +14,7 @@ var AgentCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		return errors.New("a subcommand is required. Use 'alpacon agent upgrade', 'alpacon agent restart', or 'alpacon agent shutdown' to manage the server agent. Run 'alpacon agent --help' for more information")
+		return errors.New("a subcommand is required. Use 'alpacon agent upgrade', 'alpacon agent rollout', 'alpacon agent restart', or 'alpacon agent shutdown' to manage the server agent. Run 'alpacon agent --help' for more information")
 	},
 }
 
@@ -22,4 +22,5 @@ func init() {
 	AgentCmd.AddCommand(upgradeAgentCmd)
 	AgentCmd.AddCommand(restartAgentCmd)
 	AgentCmd.AddCommand(shutdownAgentCmd)
+	AgentCmd.AddCommand(rolloutAgentCmd)
 }
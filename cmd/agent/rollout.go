@@ -0,0 +1,487 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/alpacax/alpacon-cli/api/agent"
+	"github.com/alpacax/alpacon-cli/api/server"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
+	"github.com/alpacax/alpacon-cli/config"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+// RolloutStateFileName is the default record of an agent rollout, kept in
+// ~/.alpacon so an interrupted rollout can be resumed.
+const RolloutStateFileName = "agent-rollout.json"
+
+// Statuses of a server in a rollout.
+const (
+	rolloutPending   = "pending"
+	rolloutUpgrading = "upgrading"
+	rolloutUpgraded  = "upgraded"
+	rolloutFailed    = "failed"
+	rolloutSkipped   = "skipped"
+)
+
+// rolloutPollInterval is how often a wave checks whether its servers are back;
+// a var so tests can shorten it.
+var rolloutPollInterval = 10 * time.Second
+
+var errRolloutInterrupted = errors.New("rollout interrupted")
+
+var (
+	rolloutServers   []string
+	rolloutBatch     string
+	rolloutPause     string
+	rolloutTimeout   string
+	rolloutSettle    string
+	rolloutResume    bool
+	rolloutStatePath string
+	rolloutYes       bool
+)
+
+var rolloutAgentCmd = &cobra.Command{
+	Use:   "rollout",
+	Short: "Upgrade the agent on many servers in waves",
+	Long: `Upgrade the agent (alpamon) on every server matching --servers, a wave at a
+time. Each wave requests the upgrades, then waits for its servers to reconnect:
+a server is back once it is connected again after dropping its connection, or
+after --settle if the restart was too quick to notice. A server that does not
+come back within --timeout, or whose upgrade request fails, halts the rollout
+before the next wave.
+
+--batch is the size of a wave, as a number of servers or a percentage of them.
+Servers that are disconnected when the rollout starts are skipped.
+
+Progress is kept in a state file. After a halt or Ctrl+C, fix what went wrong
+and run 'alpacon agent rollout --resume' to continue with the servers not yet
+upgraded, in the same waves. The state file is removed once every server is
+upgraded.`,
+	Example: `  alpacon agent rollout --servers 'web-*' --batch 10% --pause 5m
+  alpacon agent rollout --servers 'web-*,api-*' --batch 2 --timeout 15m -y
+  alpacon agent rollout --resume`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		pause, err := parseNonNegativeDuration("--pause", rolloutPause)
+		if err != nil {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+		}
+		timeout, err := utils.ParsePositiveDuration("--timeout", rolloutTimeout)
+		if err != nil {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+		}
+		settle, err := parseNonNegativeDuration("--settle", rolloutSettle)
+		if err != nil {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+		}
+		if rolloutResume && (cmd.Flags().Changed("servers") || cmd.Flags().Changed("batch")) {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "--resume continues the recorded rollout; --servers and --batch cannot be changed.")
+		}
+		if !rolloutResume && len(rolloutServers) == 0 {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "--servers is required.")
+		}
+		batch, err := parseBatch(rolloutBatch)
+		if err != nil {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+		}
+
+		statePath := rolloutStatePath
+		if statePath == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				utils.CliErrorWithExit("Failed to find the home directory: %s. Pass --state.", err)
+			}
+			statePath = filepath.Join(home, config.ConfigFileDir, RolloutStateFileName)
+		}
+		state, err := readRolloutState(statePath)
+		if err != nil {
+			utils.CliErrorWithExit("Failed to read the state file %s: %s.", statePath, err)
+		}
+		if rolloutResume && state == nil {
+			utils.CliErrorWithExit("No rollout to resume: %s does not exist.", statePath)
+		}
+		if !rolloutResume && state != nil {
+			utils.CliErrorWithExit("An unfinished rollout is recorded in %s. Continue it with --resume, or delete the file to start over.", statePath)
+		}
+
+		alpaconClient, err := client.NewAlpaconAPIClient()
+		if err != nil {
+			utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
+		}
+
+		if state == nil {
+			servers, err := server.GetServerList(alpaconClient)
+			if err != nil {
+				utils.CliErrorWithExit("Failed to retrieve the servers: %s.", err)
+			}
+			state, err = planRollout(servers, rolloutServers, batch, time.Now())
+			if err != nil {
+				utils.CliErrorWithExit("%s.", err)
+			}
+		}
+
+		remaining, waves := state.remaining()
+		if !rolloutYes {
+			utils.ConfirmAction("Upgrade the agent on %d servers in %d waves?", remaining, waves)
+		}
+
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sigChan)
+
+		r := &rollout{
+			state:     state,
+			statePath: statePath,
+			pause:     pause,
+			timeout:   timeout,
+			settle:    settle,
+			listServers: func() ([]server.ServerAttributes, error) {
+				return server.GetServerList(alpaconClient)
+			},
+			upgrade: func(name string) error {
+				return agent.RequestAgentAction(alpaconClient, name, "upgrade")
+			},
+			now: time.Now,
+			sleep: func(d time.Duration) bool {
+				timer := time.NewTimer(d)
+				defer timer.Stop()
+				select {
+				case <-timer.C:
+					return true
+				case <-sigChan:
+					return false
+				}
+			},
+		}
+		err = r.run()
+		utils.PrintTable(state.report())
+		switch {
+		case errors.Is(err, errRolloutInterrupted):
+			utils.CliErrorWithExit("Rollout interrupted. Continue it with 'alpacon agent rollout --resume'.")
+		case err != nil:
+			utils.CliErrorWithExit("%s. Fix the servers, then continue with 'alpacon agent rollout --resume'.", err)
+		}
+		if err := os.Remove(statePath); err != nil && !os.IsNotExist(err) {
+			utils.CliWarning("Failed to remove the state file %s: %s", statePath, err)
+		}
+		utils.CliSuccess("Agent rollout finished.")
+	},
+}
+
+func init() {
+	rolloutAgentCmd.Flags().StringSliceVar(&rolloutServers, "servers", nil, "Server names or glob patterns to upgrade (comma-separated, e.g. 'web-*')")
+	rolloutAgentCmd.Flags().StringVar(&rolloutBatch, "batch", "10%", "Servers per wave, as a count or a percentage")
+	rolloutAgentCmd.Flags().StringVar(&rolloutPause, "pause", "0s", "Pause between waves")
+	rolloutAgentCmd.Flags().StringVar(&rolloutTimeout, "timeout", "10m", "How long a wave waits for its servers to reconnect")
+	rolloutAgentCmd.Flags().StringVar(&rolloutSettle, "settle", "30s", "How long a server that never showed as disconnected must wait before it counts as back")
+	rolloutAgentCmd.Flags().BoolVar(&rolloutResume, "resume", false, "Continue the rollout recorded in the state file")
+	rolloutAgentCmd.Flags().StringVar(&rolloutStatePath, "state", "", "State file of the rollout (default ~/.alpacon/"+RolloutStateFileName+")")
+	rolloutAgentCmd.Flags().BoolVarP(&rolloutYes, "yes", "y", false, "Skip confirmation prompt")
+	_ = rolloutAgentCmd.RegisterFlagCompletionFunc("servers", complete.CommaSeparated(complete.Servers))
+}
+
+// rolloutHost is a server's place and progress in a rollout.
+type rolloutHost struct {
+	Name        string     `json:"name"`
+	Wave        int        `json:"wave"`
+	Status      string     `json:"status"`
+	Detail      string     `json:"detail,omitempty"`
+	RequestedAt *time.Time `json:"requested_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+type rolloutState struct {
+	Patterns  []string      `json:"patterns"`
+	Batch     string        `json:"batch"`
+	StartedAt time.Time     `json:"started_at"`
+	Servers   []rolloutHost `json:"servers"`
+}
+
+type rolloutRow struct {
+	Server string
+	Wave   int
+	Status string
+	Detail string
+}
+
+// batchSize is a wave size, as a count or a percentage of the servers.
+type batchSize struct {
+	raw     string
+	count   int
+	percent int
+}
+
+func parseBatch(raw string) (batchSize, error) {
+	raw = strings.TrimSpace(raw)
+	if p, ok := strings.CutSuffix(raw, "%"); ok {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 || n > 100 {
+			return batchSize{}, fmt.Errorf("invalid --batch value %q: a percentage must be between 1%% and 100%%", raw)
+		}
+		return batchSize{raw: raw, percent: n}, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		return batchSize{}, fmt.Errorf("invalid --batch value %q: must be a positive count or a percentage such as 10%%", raw)
+	}
+	return batchSize{raw: raw, count: n}, nil
+}
+
+// of returns the wave size for total servers, at least one.
+func (b batchSize) of(total int) int {
+	if b.count > 0 {
+		return b.count
+	}
+	return max(1, int(math.Ceil(float64(total)*float64(b.percent)/100)))
+}
+
+func parseNonNegativeDuration(flagName, raw string) (time.Duration, error) {
+	d, err := time.ParseDuration(strings.TrimSpace(raw))
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value %q: %w", flagName, raw, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid %s value %q: must not be negative", flagName, raw)
+	}
+	return d, nil
+}
+
+// planRollout puts the servers matching patterns into waves, in name order.
+// Servers that are disconnected now are skipped.
+func planRollout(servers []server.ServerAttributes, patterns []string, batch batchSize, now time.Time) (*rolloutState, error) {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid --servers pattern %q", p)
+		}
+	}
+	var matched []server.ServerAttributes
+	for _, s := range servers {
+		if slices.ContainsFunc(patterns, func(p string) bool {
+			ok, _ := path.Match(p, s.Name)
+			return ok
+		}) {
+			matched = append(matched, s)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("no server matches %s", strings.Join(patterns, ", "))
+	}
+	slices.SortFunc(matched, func(a, b server.ServerAttributes) int { return strings.Compare(a.Name, b.Name) })
+
+	connected := 0
+	for _, s := range matched {
+		if s.Connected {
+			connected++
+		}
+	}
+	size := batch.of(connected)
+	state := &rolloutState{Patterns: patterns, Batch: batch.raw, StartedAt: now}
+	i := 0
+	for _, s := range matched {
+		if !s.Connected {
+			state.Servers = append(state.Servers, rolloutHost{Name: s.Name, Status: rolloutSkipped, Detail: "disconnected when the rollout started"})
+			continue
+		}
+		state.Servers = append(state.Servers, rolloutHost{Name: s.Name, Wave: i/size + 1, Status: rolloutPending})
+		i++
+	}
+	return state, nil
+}
+
+func readRolloutState(path string) (*rolloutState, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state rolloutState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// remaining counts the servers still to upgrade and the waves they are in.
+func (s *rolloutState) remaining() (servers, waves int) {
+	seen := map[int]bool{}
+	for _, h := range s.Servers {
+		if h.Status != rolloutUpgraded && h.Status != rolloutSkipped {
+			servers++
+			seen[h.Wave] = true
+		}
+	}
+	return servers, len(seen)
+}
+
+func (s *rolloutState) report() []rolloutRow {
+	rows := make([]rolloutRow, 0, len(s.Servers))
+	for _, h := range s.Servers {
+		rows = append(rows, rolloutRow{Server: h.Name, Wave: h.Wave, Status: h.Status, Detail: h.Detail})
+	}
+	return rows
+}
+
+type rollout struct {
+	state     *rolloutState
+	statePath string
+	pause     time.Duration
+	timeout   time.Duration
+	settle    time.Duration
+
+	listServers func() ([]server.ServerAttributes, error)
+	upgrade     func(name string) error
+	now         func() time.Time
+	// sleep waits for d and reports false if the rollout was interrupted.
+	sleep func(d time.Duration) bool
+}
+
+// run upgrades the remaining waves in order, stopping after a wave in which
+// any server failed.
+func (r *rollout) run() error {
+	lastWave := 0
+	for _, h := range r.state.Servers {
+		lastWave = max(lastWave, h.Wave)
+	}
+	ran := false
+	for wave := 1; wave <= lastWave; wave++ {
+		hosts := r.waveHosts(wave)
+		if len(hosts) == 0 {
+			continue
+		}
+		if ran && r.pause > 0 {
+			utils.CliInfo("Pausing %s before wave %d.", r.pause, wave)
+			if !r.sleep(r.pause) {
+				return errRolloutInterrupted
+			}
+		}
+		ran = true
+
+		names := make([]string, len(hosts))
+		for i, h := range hosts {
+			names[i] = h.Name
+		}
+		utils.CliInfo("Wave %d/%d: upgrading %s.", wave, lastWave, strings.Join(names, ", "))
+		failed, err := r.runWave(hosts)
+		if saveErr := r.saveState(); saveErr != nil {
+			utils.CliWarning("Failed to save the state file %s: %s", r.statePath, saveErr)
+		}
+		if err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("rollout halted in wave %d: %d of %d servers failed", wave, failed, len(hosts))
+		}
+	}
+	return nil
+}
+
+// waveHosts returns the servers of a wave that are not yet upgraded.
+func (r *rollout) waveHosts(wave int) []*rolloutHost {
+	var hosts []*rolloutHost
+	for i := range r.state.Servers {
+		h := &r.state.Servers[i]
+		if h.Wave == wave && h.Status != rolloutUpgraded && h.Status != rolloutSkipped {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
+
+// runWave requests the upgrades of one wave and waits for the servers to come
+// back, returning how many failed.
+func (r *rollout) runWave(hosts []*rolloutHost) (int, error) {
+	var waiting []*rolloutHost
+	for _, h := range hosts {
+		now := r.now()
+		h.RequestedAt, h.FinishedAt, h.Detail = &now, nil, ""
+		if err := r.upgrade(h.Name); err != nil {
+			r.finish(h, rolloutFailed, fmt.Sprintf("upgrade request failed: %s", err))
+			continue
+		}
+		h.Status = rolloutUpgrading
+		waiting = append(waiting, h)
+	}
+	if err := r.saveState(); err != nil {
+		utils.CliWarning("Failed to save the state file %s: %s", r.statePath, err)
+	}
+
+	deadline := r.now().Add(r.timeout)
+	wentDown := map[string]bool{}
+	for len(waiting) > 0 {
+		if !r.sleep(rolloutPollInterval) {
+			return 0, errRolloutInterrupted
+		}
+		servers, err := r.listServers()
+		if err != nil {
+			utils.CliWarning("Failed to check the servers: %s", err)
+		}
+		connected := map[string]bool{}
+		for _, s := range servers {
+			connected[s.Name] = s.Connected
+		}
+
+		now := r.now()
+		waiting = slices.DeleteFunc(waiting, func(h *rolloutHost) bool {
+			switch {
+			case err != nil:
+				return false
+			case !connected[h.Name]:
+				wentDown[h.Name] = true
+				return false
+			case wentDown[h.Name] || now.Sub(*h.RequestedAt) >= r.settle:
+				r.finish(h, rolloutUpgraded, fmt.Sprintf("back after %s", now.Sub(*h.RequestedAt).Round(time.Second)))
+				utils.CliInfo("%s is back.", h.Name)
+				return true
+			}
+			return false
+		})
+		if len(waiting) > 0 && !now.Before(deadline) {
+			for _, h := range waiting {
+				r.finish(h, rolloutFailed, fmt.Sprintf("did not reconnect within %s", r.timeout))
+			}
+			waiting = nil
+		}
+	}
+
+	failed := 0
+	for _, h := range hosts {
+		if h.Status == rolloutFailed {
+			failed++
+		}
+	}
+	return failed, nil
+}
+
+func (r *rollout) finish(h *rolloutHost, status, detail string) {
+	now := r.now()
+	h.Status, h.Detail, h.FinishedAt = status, detail, &now
+	if status == rolloutFailed {
+		utils.CliWarning("%s: %s", h.Name, detail)
+	}
+}
+
+func (r *rollout) saveState() error {
+	data, err := json.MarshalIndent(r.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = utils.SaveStreamAtomic(r.statePath, bytes.NewReader(append(data, '\n')), 0600)
+	return err
+}
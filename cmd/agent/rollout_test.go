@@ -0,0 +1,199 @@
+package agent
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alpacax/alpacon-cli/api/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBatch(t *testing.T) {
+	b, err := parseBatch("10%")
+	require.NoError(t, err)
+	assert.Equal(t, 1, b.of(5))
+	assert.Equal(t, 3, b.of(21))
+
+	b, err = parseBatch("4")
+	require.NoError(t, err)
+	assert.Equal(t, 4, b.of(100))
+
+	for _, raw := range []string{"0", "-1", "0%", "101%", "ten"} {
+		_, err := parseBatch(raw)
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestPlanRollout(t *testing.T) {
+	servers := []server.ServerAttributes{
+		{Name: "web-3", Connected: true},
+		{Name: "db-1", Connected: true},
+		{Name: "web-1", Connected: true},
+		{Name: "web-2", Connected: false},
+		{Name: "web-4", Connected: true},
+		{Name: "api-1", Connected: true},
+	}
+	batch, _ := parseBatch("2")
+	state, err := planRollout(servers, []string{"web-*", "api-1"}, batch, time.Now())
+	require.NoError(t, err)
+
+	var got [][3]any
+	for _, h := range state.Servers {
+		got = append(got, [3]any{h.Name, h.Wave, h.Status})
+	}
+	assert.Equal(t, [][3]any{
+		{"api-1", 1, rolloutPending},
+		{"web-1", 1, rolloutPending},
+		{"web-2", 0, rolloutSkipped},
+		{"web-3", 2, rolloutPending},
+		{"web-4", 2, rolloutPending},
+	}, got)
+	n, waves := state.remaining()
+	assert.Equal(t, 4, n)
+	assert.Equal(t, 2, waves)
+
+	_, err = planRollout(servers, []string{"mail-*"}, batch, time.Now())
+	assert.ErrorContains(t, err, "no server matches")
+	_, err = planRollout(servers, []string{"web-["}, batch, time.Now())
+	assert.ErrorContains(t, err, "invalid --servers pattern")
+}
+
+// fakeFleet simulates servers that drop their connection for a while after an
+// upgrade request; a negative downtime means they never come back.
+type fakeFleet struct {
+	clock     time.Time
+	downtime  map[string]time.Duration
+	upgradeAt map[string]time.Time
+	failing   map[string]bool
+	requests  []string
+}
+
+func (f *fakeFleet) rollout(t *testing.T, state *rolloutState) *rollout {
+	return &rollout{
+		state:     state,
+		statePath: filepath.Join(t.TempDir(), RolloutStateFileName),
+		timeout:   5 * time.Minute,
+		settle:    30 * time.Second,
+		pause:     time.Minute,
+		listServers: func() ([]server.ServerAttributes, error) {
+			var out []server.ServerAttributes
+			for _, h := range state.Servers {
+				connected := true
+				if at, ok := f.upgradeAt[h.Name]; ok {
+					d := f.downtime[h.Name]
+					connected = d >= 0 && !f.clock.Before(at.Add(d))
+				}
+				out = append(out, server.ServerAttributes{Name: h.Name, Connected: connected})
+			}
+			return out, nil
+		},
+		upgrade: func(name string) error {
+			f.requests = append(f.requests, name)
+			if f.failing[name] {
+				return errors.New("server busy")
+			}
+			f.upgradeAt[name] = f.clock
+			return nil
+		},
+		now: func() time.Time { return f.clock },
+		sleep: func(d time.Duration) bool {
+			f.clock = f.clock.Add(d)
+			return true
+		},
+	}
+}
+
+func TestRolloutRun(t *testing.T) {
+	f := &fakeFleet{
+		clock:     time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		downtime:  map[string]time.Duration{"a": 20 * time.Second, "b": 0, "c": time.Minute},
+		upgradeAt: map[string]time.Time{},
+	}
+	state := &rolloutState{Servers: []rolloutHost{
+		{Name: "a", Wave: 1, Status: rolloutPending},
+		{Name: "b", Wave: 1, Status: rolloutPending},
+		{Name: "c", Wave: 2, Status: rolloutPending},
+	}}
+	r := f.rollout(t, state)
+	require.NoError(t, r.run())
+	for _, h := range state.Servers {
+		assert.Equal(t, rolloutUpgraded, h.Status, h.Name)
+	}
+	// b never showed as disconnected, so it waited for --settle.
+	assert.Equal(t, "back after 30s", state.Servers[1].Detail)
+	assert.Equal(t, "back after 1m0s", state.Servers[2].Detail)
+
+	saved, err := readRolloutState(r.statePath)
+	require.NoError(t, err)
+	assert.Equal(t, rolloutUpgraded, saved.Servers[2].Status)
+}
+
+func TestRolloutRun_HaltsAndResumes(t *testing.T) {
+	f := &fakeFleet{
+		clock:     time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		downtime:  map[string]time.Duration{"a": 10 * time.Second, "b": -1, "c": 10 * time.Second},
+		upgradeAt: map[string]time.Time{},
+		failing:   map[string]bool{},
+	}
+	state := &rolloutState{Servers: []rolloutHost{
+		{Name: "a", Wave: 1, Status: rolloutPending},
+		{Name: "b", Wave: 1, Status: rolloutPending},
+		{Name: "c", Wave: 2, Status: rolloutPending},
+	}}
+	r := f.rollout(t, state)
+	err := r.run()
+	assert.ErrorContains(t, err, "rollout halted in wave 1: 1 of 2 servers failed")
+	assert.Equal(t, rolloutUpgraded, state.Servers[0].Status)
+	assert.Equal(t, rolloutFailed, state.Servers[1].Status)
+	assert.Equal(t, "did not reconnect within 5m0s", state.Servers[1].Detail)
+	assert.Equal(t, rolloutPending, state.Servers[2].Status)
+
+	saved, err := readRolloutState(r.statePath)
+	require.NoError(t, err)
+	assert.Equal(t, rolloutFailed, saved.Servers[1].Status)
+
+	// The server is fixed; resuming retries it and continues with wave 2.
+	f.downtime["b"] = 10 * time.Second
+	f.requests = nil
+	resumed := f.rollout(t, saved)
+	require.NoError(t, resumed.run())
+	assert.Equal(t, []string{"b", "c"}, f.requests)
+	for _, h := range saved.Servers {
+		assert.Equal(t, rolloutUpgraded, h.Status, h.Name)
+	}
+}
+
+func TestRolloutRun_RequestFailureHalts(t *testing.T) {
+	f := &fakeFleet{
+		clock:     time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		downtime:  map[string]time.Duration{},
+		upgradeAt: map[string]time.Time{},
+		failing:   map[string]bool{"a": true},
+	}
+	state := &rolloutState{Servers: []rolloutHost{
+		{Name: "a", Wave: 1, Status: rolloutPending},
+		{Name: "b", Wave: 2, Status: rolloutPending},
+	}}
+	err := f.rollout(t, state).run()
+	assert.ErrorContains(t, err, "rollout halted in wave 1")
+	assert.Equal(t, "upgrade request failed: server busy", state.Servers[0].Detail)
+	assert.Equal(t, []string{"a"}, f.requests)
+}
+
+func TestRolloutRun_Interrupted(t *testing.T) {
+	f := &fakeFleet{
+		clock:     time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		downtime:  map[string]time.Duration{},
+		upgradeAt: map[string]time.Time{},
+	}
+	state := &rolloutState{Servers: []rolloutHost{{Name: "a", Wave: 1, Status: rolloutPending}}}
+	r := f.rollout(t, state)
+	r.sleep = func(time.Duration) bool { return false }
+	assert.ErrorIs(t, r.run(), errRolloutInterrupted)
+	saved, err := readRolloutState(r.statePath)
+	require.NoError(t, err)
+	assert.Equal(t, rolloutUpgrading, saved.Servers[0].Status)
+}
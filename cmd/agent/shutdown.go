@@ -3,15 +3,17 @@ package agent
 import (
 	"github.com/alpacax/alpacon-cli/api/agent"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
 
 var shutdownAgentCmd = &cobra.Command{
-	Use:     "shutdown SERVER",
-	Short:   "Shutdown server's agent(alpamon)",
-	Example: `alpacon agent shutdown myserver`,
-	Args:    cobra.ExactArgs(1),
+	Use:               "shutdown SERVER",
+	Short:             "Shutdown server's agent(alpamon)",
+	Example:           `alpacon agent shutdown myserver`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Servers),
 	Run: func(cmd *cobra.Command, args []string) {
 		serverName := args[0]
 
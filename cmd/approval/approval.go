@@ -22,18 +22,22 @@ surface only; use it to create requests and track their status.
 Subcommands for tracking:
   ls        List approval requests (--my for your own)
   describe  Show details of a request
+  review    Work through the pending queue in a terminal view (superuser)
+  auto      Triage the pending queue against a rules file (superuser)
   cancel    Cancel a pending request you submitted`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := cmd.Help(); err != nil {
 			return err
 		}
-		return errors.New("a subcommand is required. Use 'alpacon approval ls', 'alpacon approval describe', or 'alpacon approval cancel'. Approve and reject happen in the Alpacon console (web). Run 'alpacon approval --help' for more information")
+		return errors.New("a subcommand is required. Use 'alpacon approval ls', 'alpacon approval describe', 'alpacon approval review', 'alpacon approval auto', or 'alpacon approval cancel'. Approve and reject happen in the Alpacon console (web). Run 'alpacon approval --help' for more information")
 	},
 }
 
 func init() {
 	ApprovalCmd.AddCommand(approvalListCmd)
 	ApprovalCmd.AddCommand(approvalDescribeCmd)
+	ApprovalCmd.AddCommand(approvalReviewCmd)
+	ApprovalCmd.AddCommand(approvalAutoCmd)
 	ApprovalCmd.AddCommand(approvalApproveCmd)
 	ApprovalCmd.AddCommand(approvalRejectCmd)
 	ApprovalCmd.AddCommand(approvalCancelCmd)
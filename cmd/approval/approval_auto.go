@@ -0,0 +1,285 @@
+package approval
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	approvalapi "github.com/alpacax/alpacon-cli/api/approval"
+	eventapi "github.com/alpacax/alpacon-cli/api/event"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/config"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+// AutoLogFileName is the default decision log, kept in ~/.alpacon.
+const AutoLogFileName = "approval-auto.log"
+
+var (
+	autoRules    string
+	autoType     string
+	autoInterval string
+	autoLog      string
+	autoOnce     bool
+	autoDryRun   bool
+)
+
+var approvalAutoCmd = &cobra.Command{
+	Use:   "auto",
+	Short: "Triage pending approval requests against a rules file",
+	Long: `Watch the workspace's pending approval requests and match each one against
+reviewer-defined rules. The first rule that matches decides the request:
+approve or reject. Every decision, including "no rule matched", is printed
+and appended to a local log (JSON lines, owner-only permissions), and each
+request is decided once: requests already in the log are skipped.
+
+The decisions are reported, not carried out. Approving and rejecting happen
+in the Alpacon console (web), not the CLI: the server refuses both from the
+CLI credential channel. Use the report to clear matched requests in bulk in
+the console and leave the rest for a closer look.
+
+Rules file:
+  rules:
+    - name: status-checks
+      decision: approve          # approve | reject
+      types: [sudo]              # request types
+      requesters: [alice, bob@example.com]
+      commands: ["systemctl status *"]
+      servers: ["web-*"]
+      window:                    # when the request was made, local time
+        days: [mon, tue, wed, thu, fri]
+        from: "09:00"
+        to: "18:00"
+
+Every criterion a rule sets must match; one it leaves out matches anything.
+In commands, * and ? never match shell metacharacters (; & | < > $ and
+friends), so "systemctl status *" does not match a chained command. A
+request naming several servers matches only if every server does.
+
+Requires superuser privileges: the workspace queue is superuser-only.`,
+	Example: `  alpacon approval auto --rules rules.yaml
+  alpacon approval auto --rules rules.yaml --type sudo --interval 1m
+  alpacon approval auto --rules rules.yaml --once`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if autoRules == "" {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "--rules is required.")
+		}
+		if err := validateTypeFilter(autoType); err != nil {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+		}
+		interval, err := utils.ParsePositiveDuration("--interval", autoInterval)
+		if err != nil {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+		}
+		rules, err := readRulesFile(autoRules)
+		if err != nil {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "Failed to read %s: %s.", autoRules, err)
+		}
+		// Kept as a flag only to say so: a --dry-run that did nothing would
+		// suggest a run without it acts.
+		if autoDryRun {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "approval auto has no --dry-run: it only reports decisions and never approves or rejects. Run it without --dry-run.")
+		}
+		logPath := autoLog
+		if logPath == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				utils.CliErrorWithExit("Failed to find the home directory: %s. Pass --log.", err)
+			}
+			logPath = filepath.Join(home, config.ConfigFileDir, AutoLogFileName)
+		}
+
+		ac, err := client.NewAlpaconAPIClient()
+		if err != nil {
+			utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
+		}
+		if err = ac.LoadCurrentUser(); err != nil {
+			utils.CliErrorWithExit("Failed to load current user: %s.", err)
+		}
+		if ac.Privileges != "superuser" {
+			utils.CliErrorWithExit("approval auto needs superuser privileges.")
+		}
+
+		log, err := openDecisionLog(logPath)
+		if err != nil {
+			utils.CliErrorWithExit("Failed to open the decision log %s: %s.", logPath, err)
+		}
+		defer func() { _ = log.Close() }()
+
+		if err = runAuto(ac, rules, log, interval, autoOnce); err != nil {
+			utils.CliErrorWithExit("%s.", err)
+		}
+	},
+}
+
+func init() {
+	approvalAutoCmd.Flags().StringVar(&autoRules, "rules", "", "Rules file (YAML)")
+	approvalAutoCmd.Flags().StringVar(&autoType, "type", "", "Only triage one request type: sudo|work_session|username|groupname|service_token|svc_token_mod|app_username|work_session_mod|sudo_policy")
+	approvalAutoCmd.Flags().StringVar(&autoInterval, "interval", "30s", "Reload the queue this often, in case a live update was missed")
+	approvalAutoCmd.Flags().StringVar(&autoLog, "log", "", "Decision log file (default ~/.alpacon/"+AutoLogFileName+")")
+	approvalAutoCmd.Flags().BoolVar(&autoOnce, "once", false, "Triage the current queue and exit instead of watching")
+	approvalAutoCmd.Flags().BoolVar(&autoDryRun, "dry-run", false, "Not supported: decisions are only ever reported")
+	_ = approvalAutoCmd.Flags().MarkHidden("dry-run")
+}
+
+// autoDecision is one line of the decision log.
+type autoDecision struct {
+	Time        time.Time `json:"time"`
+	RequestID   string    `json:"request_id"`
+	RequestType string    `json:"request_type"`
+	RequestedBy string    `json:"requested_by"`
+	RequestData string    `json:"request_data"`
+	Rule        string    `json:"rule,omitempty"`
+	Decision    string    `json:"decision"` // approve, reject, or none
+}
+
+// decisionLog appends decisions and remembers which requests it holds.
+type decisionLog struct {
+	file    *os.File
+	decided map[string]bool
+}
+
+func openDecisionLog(path string) (*decisionLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err = file.Chmod(0o600); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	l := &decisionLog{file: file, decided: loggedRequests(file)}
+	return l, nil
+}
+
+// loggedRequests reads the request IDs already in a log. Lines it cannot
+// parse are skipped; the log is the user's to edit.
+func loggedRequests(r io.Reader) map[string]bool {
+	decided := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var d autoDecision
+		if json.Unmarshal(scanner.Bytes(), &d) == nil && d.RequestID != "" {
+			decided[d.RequestID] = true
+		}
+	}
+	return decided
+}
+
+func (l *decisionLog) write(d autoDecision) error {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	if _, err = l.file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	l.decided[d.RequestID] = true
+	return nil
+}
+
+func (l *decisionLog) Close() error {
+	return l.file.Close()
+}
+
+// decide triages every request not yet in the log, logs it, and prints it.
+func decide(w io.Writer, rules []triageRule, log *decisionLog, requests []approvalapi.ApprovalRequest, now time.Time) (int, error) {
+	matched := 0
+	for i := range requests {
+		r := &requests[i]
+		if log.decided[r.ID] {
+			continue
+		}
+		d := autoDecision{
+			Time:        now,
+			RequestID:   r.ID,
+			RequestType: r.RequestType,
+			RequestedBy: requesterName(r),
+			RequestData: r.RequestData,
+			Decision:    "none",
+		}
+		verdict := "no rule matched"
+		if rule := triage(rules, r); rule != nil {
+			d.Rule, d.Decision = rule.Name, rule.Decision
+			verdict = fmt.Sprintf("%s (rule %s)", rule.Decision, clean(rule.Name))
+			matched++
+		}
+		if err := log.write(d); err != nil {
+			return matched, fmt.Errorf("failed to write the decision log: %w", err)
+		}
+		_, _ = fmt.Fprintf(w, "%s  %s  %s  %s  %s: %s\n", now.Format(time.RFC3339), shortID(r.ID),
+			clean(r.RequestType), clean(d.RequestedBy), verdict, clean(firstLine(r.RequestData)))
+	}
+	return matched, nil
+}
+
+func runAuto(ac *client.AlpaconClient, rules []triageRule, log *decisionLog, interval time.Duration, once bool) error {
+	consoleURL := ac.BaseURL
+	pass := func() error {
+		requests, err := approvalapi.ListApprovalRequestDetails(ac, "pending", autoType)
+		if err != nil {
+			return fmt.Errorf("failed to list approval requests: %w", err)
+		}
+		matched, err := decide(os.Stdout, rules, log, requests, time.Now())
+		if matched > 0 {
+			utils.CliInfo("%d request(s) matched a rule. Approve or reject them in the Alpacon console: %s", matched, consoleURL)
+		}
+		return err
+	}
+
+	if err := pass(); err != nil {
+		return err
+	}
+	if once {
+		return nil
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	// Live updates are a bonus over the interval, as in approval review.
+	var frames <-chan []byte
+	watcher := eventapi.NewWatcher(ac, reviewEventType, "")
+	watcher.Start()
+	defer watcher.Stop()
+	connected := make(chan bool, 1)
+	go func() { connected <- watcher.WaitConnected(reviewConnectTimeout) }()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	utils.CliInfo("Watching pending approval requests (press Ctrl+C to stop).")
+	for {
+		select {
+		case <-sigChan:
+			return nil
+		case ok := <-connected:
+			if ok {
+				frames = watcher.Frames()
+			} else {
+				utils.CliWarning("Live updates unavailable; reloading every %s.", interval)
+			}
+		case <-frames:
+			if err := pass(); err != nil {
+				utils.CliWarning("%s.", err)
+			}
+		case <-ticker.C:
+			if err := pass(); err != nil {
+				utils.CliWarning("%s.", err)
+			}
+		}
+	}
+}
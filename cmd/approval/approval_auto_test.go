@@ -0,0 +1,159 @@
+package approval
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	approvalapi "github.com/alpacax/alpacon-cli/api/approval"
+	"github.com/alpacax/alpacon-cli/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRules = `
+rules:
+  - name: status-checks
+    decision: approve
+    types: [sudo]
+    requesters: [bob@example.com]
+    commands: ["systemctl status *"]
+    servers: ["web-*"]
+    window:
+      days: [mon, tue, wed, thu, fri]
+      from: "09:00"
+      to: "18:00"
+  - name: no-shutdown
+    decision: reject
+    commands: ["shutdown*", "reboot*"]
+`
+
+func sudoRequest(id, command, servers string, at time.Time) approvalapi.ApprovalRequest {
+	return approvalapi.ApprovalRequest{
+		ID: id, RequestType: "sudo", AddedAt: at,
+		RequestedBy: &types.UserSummary{ID: "u-2", Name: "bob", Email: "bob@example.com"},
+		RequestData: `{"command": "` + command + `", ` + servers + `}`,
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := parseRules([]byte(testRules))
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, "status-checks", rules[0].Name)
+	assert.Equal(t, decisionReject, rules[1].Decision)
+
+	tests := []struct {
+		name, yaml, wantErr string
+	}{
+		{"empty", "", "no rules defined"},
+		{"unknown field", "rules:\n  - decision: approve\n    user: bob\n", "field user not found"},
+		{"bad decision", "rules:\n  - decision: allow\n", "decision must be approve or reject"},
+		{"bad type", "rules:\n  - decision: approve\n    types: [ssh]\n", "unknown request type"},
+		{"duplicate name", "rules:\n  - {name: a, decision: approve}\n  - {name: a, decision: reject}\n", "defined twice"},
+		{"bad day", "rules:\n  - decision: approve\n    window: {days: [someday]}\n", "unknown day"},
+		{"half window", "rules:\n  - decision: approve\n    window: {from: \"09:00\"}\n", "both from and to"},
+		{"bad time", "rules:\n  - decision: approve\n    window: {from: \"9am\", to: \"18:00\"}\n", "expected HH:MM"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseRules([]byte(tt.yaml))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestTriage(t *testing.T) {
+	rules, err := parseRules([]byte(testRules))
+	require.NoError(t, err)
+
+	monday := time.Date(2026, 10, 19, 10, 30, 0, 0, time.Local)
+	web := `"server": {"id": "s-1", "name": "web-01"}`
+	tests := []struct {
+		name string
+		req  approvalapi.ApprovalRequest
+		want string
+	}{
+		{"matches", sudoRequest("a", "systemctl status nginx", web, monday), "status-checks"},
+		{"chained command", sudoRequest("a", "systemctl status x; rm -rf /", web, monday), ""},
+		{"substituted command", sudoRequest("a", "systemctl status $(id)", web, monday), ""},
+		{"other server", sudoRequest("a", "systemctl status nginx", `"server": {"name": "db-01"}`, monday), ""},
+		{"one server outside", sudoRequest("a", "systemctl status nginx", `"servers": [{"name": "web-01"}, {"name": "db-01"}]`, monday), ""},
+		{"all servers inside", sudoRequest("a", "systemctl status nginx", `"servers": [{"name": "web-01"}, {"name": "web-02"}]`, monday), "status-checks"},
+		{"no server", sudoRequest("a", "systemctl status nginx", `"note": "x"`, monday), ""},
+		{"after hours", sudoRequest("a", "systemctl status nginx", web, monday.Add(8*time.Hour)), ""},
+		{"weekend", sudoRequest("a", "systemctl status nginx", web, monday.AddDate(0, 0, -1)), ""},
+		{"second rule", sudoRequest("a", "reboot now", web, monday), "no-shutdown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if rule := triage(rules, &tt.req); rule != nil {
+				got = rule.Name
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	other := sudoRequest("a", "systemctl status nginx", web, monday)
+	other.RequestedBy = &types.UserSummary{Name: "mallory"}
+	assert.Nil(t, triage(rules, &other), "requester must match")
+}
+
+func TestTimeWindow_OverMidnight(t *testing.T) {
+	w := &timeWindow{From: "22:00", To: "06:00"}
+	require.NoError(t, w.compile())
+	day := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	assert.True(t, w.contains(day.Add(23*time.Hour)))
+	assert.True(t, w.contains(day.Add(5*time.Hour)))
+	assert.False(t, w.contains(day.Add(12*time.Hour)))
+}
+
+func TestDecide_LogsOnce(t *testing.T) {
+	rules, err := parseRules([]byte(testRules))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "auto.log")
+	log, err := openDecisionLog(path)
+	require.NoError(t, err)
+
+	monday := time.Date(2026, 10, 19, 10, 30, 0, 0, time.Local)
+	requests := []approvalapi.ApprovalRequest{
+		sudoRequest("apr-1", "systemctl status nginx", `"server": {"name": "web-01"}`, monday),
+		sudoRequest("apr-2", "cat /etc/shadow", `"server": {"name": "web-01"}`, monday),
+	}
+	var out bytes.Buffer
+	matched, err := decide(&out, rules, log, requests, monday)
+	require.NoError(t, err)
+	assert.Equal(t, 1, matched)
+	assert.Contains(t, out.String(), "apr-1  sudo  bob  approve (rule status-checks)")
+	assert.Contains(t, out.String(), "apr-2  sudo  bob  no rule matched")
+
+	out.Reset()
+	matched, err = decide(&out, rules, log, requests, monday)
+	require.NoError(t, err)
+	assert.Zero(t, matched)
+	assert.Empty(t, out.String(), "a request is decided once")
+	require.NoError(t, log.Close())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// A new run picks up where the log left off.
+	log, err = openDecisionLog(path)
+	require.NoError(t, err)
+	defer func() { _ = log.Close() }()
+	assert.True(t, log.decided["apr-1"])
+	assert.True(t, log.decided["apr-2"])
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"decision":"approve"`)
+	assert.Contains(t, lines[1], `"decision":"none"`)
+}
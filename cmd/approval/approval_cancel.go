@@ -3,6 +3,7 @@ package approval
 import (
 	approvalapi "github.com/alpacax/alpacon-cli/api/approval"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -16,8 +17,9 @@ pending request.
 
 Cancelling a work_session request also cancels the linked work
 session.`,
-	Args:    cobra.ExactArgs(1),
-	Example: `  alpacon approval cancel apr-abc123`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Approvals),
+	Example:           `  alpacon approval cancel apr-abc123`,
 	Run: func(cmd *cobra.Command, args []string) {
 		ac, err := client.NewAlpaconAPIClient()
 		if err != nil {
@@ -3,6 +3,7 @@ package approval
 import (
 	approvalapi "github.com/alpacax/alpacon-cli/api/approval"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -20,7 +21,8 @@ var approvalDescribeCmd = &cobra.Command{
 
 Use --output json to see the full raw response including nested
 work session details that are omitted from the table view.`,
-	Args: cobra.ExactArgs(1),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Approvals),
 	Example: `  alpacon approval describe apr-abc123
   alpacon approval desc apr-abc123`,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -1,6 +1,7 @@
 package approval
 
 import (
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -13,7 +14,8 @@ var approvalRejectCmd = &cobra.Command{
 The CLI is an execution and request surface only; a human approves or rejects
 out of band in the web console or Slack. The server rejects approve/reject from
 the CLI credential channel. Use 'alpacon approval ls' to track status.`,
-	Args: cobra.ArbitraryArgs,
+	Args:              cobra.ArbitraryArgs,
+	ValidArgsFunction: complete.FirstArg(complete.Approvals),
 	Example: `  # Reject in the Alpacon console (web), then track status here:
   alpacon approval ls --status rejected`,
 	Run: func(cmd *cobra.Command, args []string) {
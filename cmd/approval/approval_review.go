@@ -0,0 +1,582 @@
+package approval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	approvalapi "github.com/alpacax/alpacon-cli/api/approval"
+	eventapi "github.com/alpacax/alpacon-cli/api/event"
+	wsapi "github.com/alpacax/alpacon-cli/api/worksession"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+const (
+	// reviewEventType is subscribed to for live updates: a new or settled request
+	// notifies its reviewers. Any frame triggers a reload, so the payload shape
+	// does not matter here.
+	reviewEventType = "notification"
+
+	reviewRecentSessions = 5
+	reviewConnectTimeout = 15 * time.Second
+)
+
+var (
+	reviewType    string
+	reviewRefresh string
+)
+
+var approvalReviewCmd = &cobra.Command{
+	Use:   "review",
+	Short: "Work through pending approval requests in a terminal view",
+	Long: `Open a keyboard-driven view of the workspace's pending approval requests.
+Each request shows its request data parsed into fields, and the requester's
+recent work sessions, so a reviewer can work through the queue without
+opening each request. The queue reloads when the event channel reports a
+change, and every --refresh interval in case an event was missed.
+
+Approving (with adjusted scopes or servers) and rejecting happen in the
+Alpacon console (web), not the CLI: the server refuses both from the CLI
+credential channel. Press a, e, or r to open the console on the queue.
+
+Keys:
+  j/k or ↓/↑   move between requests
+  s            skip to the next request
+  a / e / r    approve / adjust and approve / reject in the console
+  o            open the Alpacon console
+  R            reload the queue now
+  q            quit
+
+Requires superuser privileges: the workspace queue is superuser-only.`,
+	Example: `  alpacon approval review
+  alpacon approval review --type sudo
+  alpacon approval review --refresh 10s`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := validateTypeFilter(reviewType); err != nil {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+		}
+		refresh, err := utils.ParsePositiveDuration("--refresh", reviewRefresh)
+		if err != nil {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+		}
+		if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "approval review needs a terminal. Use 'alpacon approval ls' in scripts.")
+		}
+
+		ac, err := client.NewAlpaconAPIClient()
+		if err != nil {
+			utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
+		}
+		if err = ac.LoadCurrentUser(); err != nil {
+			utils.CliErrorWithExit("Failed to load current user: %s.", err)
+		}
+		if ac.Privileges != "superuser" {
+			utils.CliErrorWithExit("approval review needs superuser privileges. Use 'alpacon approval ls --my' to track your own requests.")
+		}
+
+		// Fail before the screen is taken over, where an error is readable.
+		requests, err := approvalapi.ListApprovalRequestDetails(ac, "pending", reviewType)
+		if err != nil {
+			utils.CliErrorWithExit("Failed to list approval requests: %s.", err)
+		}
+
+		if err = runReview(ac, requests, refresh); err != nil {
+			utils.CliErrorWithExit("%s.", err)
+		}
+	},
+}
+
+func init() {
+	approvalReviewCmd.Flags().StringVar(&reviewType, "type", "", "Only review one request type: sudo|work_session|username|groupname|service_token|svc_token_mod|app_username|work_session_mod|sudo_policy")
+	approvalReviewCmd.Flags().StringVar(&reviewRefresh, "refresh", "30s", "Reload the queue at least this often")
+}
+
+type reviewKey int
+
+const (
+	keyNone reviewKey = iota
+	keyUp
+	keyDown
+	keySkip
+	keyApprove
+	keyAdjust
+	keyReject
+	keyOpen
+	keyReload
+	keyQuit
+)
+
+// decodeKeys maps one read from a raw-mode terminal to keys. Arrow keys
+// arrive as ESC [ A / ESC [ B; a lone ESC quits.
+func decodeKeys(buf []byte) []reviewKey {
+	var keys []reviewKey
+	for i := 0; i < len(buf); i++ {
+		switch b := buf[i]; b {
+		case 0x1b:
+			if i+2 < len(buf) && (buf[i+1] == '[' || buf[i+1] == 'O') {
+				switch buf[i+2] {
+				case 'A':
+					keys = append(keys, keyUp)
+				case 'B':
+					keys = append(keys, keyDown)
+				}
+				i += 2
+				continue
+			}
+			keys = append(keys, keyQuit)
+		case 'k':
+			keys = append(keys, keyUp)
+		case 'j':
+			keys = append(keys, keyDown)
+		case 's', 'n', ' ':
+			keys = append(keys, keySkip)
+		case 'a':
+			keys = append(keys, keyApprove)
+		case 'e':
+			keys = append(keys, keyAdjust)
+		case 'r':
+			keys = append(keys, keyReject)
+		case 'o':
+			keys = append(keys, keyOpen)
+		case 'R':
+			keys = append(keys, keyReload)
+		case 'q', 0x03, 0x04:
+			keys = append(keys, keyQuit)
+		}
+	}
+	return keys
+}
+
+// recentSessions is what the view knows about one requester's work sessions.
+type recentSessions struct {
+	loaded   bool
+	sessions []wsapi.WorkSessionAttributes
+	err      error
+}
+
+// reviewQueue is the state of the view, kept apart from the terminal so the
+// key handling and rendering can be exercised directly.
+type reviewQueue struct {
+	requests   []approvalapi.ApprovalRequest
+	cursor     int
+	skipped    map[string]bool
+	sessions   map[string]*recentSessions // by requester ID
+	loadedAt   time.Time
+	loadErr    error
+	status     string
+	consoleURL string
+}
+
+func newReviewQueue(consoleURL string) *reviewQueue {
+	return &reviewQueue{
+		skipped:    map[string]bool{},
+		sessions:   map[string]*recentSessions{},
+		consoleURL: consoleURL,
+	}
+}
+
+// setRequests replaces the queue and keeps the cursor on the request it was
+// on, if that is still pending.
+func (q *reviewQueue) setRequests(requests []approvalapi.ApprovalRequest, at time.Time) {
+	current := ""
+	if sel := q.selected(); sel != nil {
+		current = sel.ID
+	}
+	sort.SliceStable(requests, func(i, j int) bool { return requests[i].AddedAt.Before(requests[j].AddedAt) })
+	q.requests = requests
+	q.loadedAt = at
+	q.loadErr = nil
+	q.cursor = 0
+	for i, r := range requests {
+		if r.ID == current {
+			q.cursor = i
+			break
+		}
+	}
+}
+
+func (q *reviewQueue) selected() *approvalapi.ApprovalRequest {
+	if q.cursor < 0 || q.cursor >= len(q.requests) {
+		return nil
+	}
+	return &q.requests[q.cursor]
+}
+
+// handleKey applies a key and reports whether the view should open the
+// console, reload, or quit.
+func (q *reviewQueue) handleKey(k reviewKey) reviewKey {
+	q.status = ""
+	sel := q.selected()
+	switch k {
+	case keyUp:
+		if q.cursor > 0 {
+			q.cursor--
+		}
+	case keyDown:
+		if q.cursor < len(q.requests)-1 {
+			q.cursor++
+		}
+	case keySkip:
+		if sel == nil {
+			return keyNone
+		}
+		q.skipped[sel.ID] = true
+		if q.cursor < len(q.requests)-1 {
+			q.cursor++
+		} else {
+			q.status = "End of the queue."
+		}
+	case keyApprove, keyAdjust, keyReject:
+		if sel == nil {
+			return keyNone
+		}
+		verb := map[reviewKey]string{
+			keyApprove: "Approve",
+			keyAdjust:  "Adjust scopes or servers and approve",
+			keyReject:  "Reject",
+		}[k]
+		q.status = fmt.Sprintf("%s %s in the Alpacon console; the CLI cannot. Opening %s", verb, sel.ID, q.consoleURL)
+		return keyOpen
+	case keyOpen:
+		q.status = "Opening " + q.consoleURL
+		return keyOpen
+	case keyReload:
+		q.status = "Reloading..."
+		return keyReload
+	case keyQuit:
+		return keyQuit
+	}
+	return keyNone
+}
+
+type requestField struct {
+	Key   string
+	Value string
+}
+
+// parseRequestData spreads a request's data over fields. It is usually a JSON
+// object; anything else is shown whole.
+func parseRequestData(raw string) []requestField {
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(raw), &obj); err != nil || obj == nil {
+		if strings.TrimSpace(raw) == "" {
+			return nil
+		}
+		return []requestField{{Key: "data", Value: raw}}
+	}
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fields := make([]requestField, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, requestField{Key: k, Value: fieldValue(obj[k])})
+	}
+	return fields
+}
+
+func fieldValue(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "-"
+	case string:
+		return val
+	case []any:
+		parts := make([]string, len(val))
+		for i, item := range val {
+			parts[i] = fieldValue(item)
+		}
+		return strings.Join(parts, ", ")
+	case map[string]any:
+		// A server is usually {id, name}; its name is what a reviewer reads.
+		if name, ok := val["name"].(string); ok {
+			return name
+		}
+		b, _ := json.Marshal(val)
+		return string(b)
+	default:
+		b, _ := json.Marshal(val)
+		return string(b)
+	}
+}
+
+// render draws the whole screen. Everything from the server is sanitized:
+// request data is written by the requester, not the reviewer.
+func (q *reviewQueue) render(w io.Writer, width, height int) {
+	var lines []string
+	add := func(format string, a ...any) {
+		lines = append(lines, fmt.Sprintf(format, a...))
+	}
+
+	header := fmt.Sprintf("Approval review: %d pending", len(q.requests))
+	if !q.loadedAt.IsZero() {
+		header += ", loaded " + q.loadedAt.Local().Format("15:04:05")
+	}
+	add("%s", header)
+	if q.loadErr != nil {
+		add("Reload failed: %s", utils.SanitizeTerminalText(q.loadErr.Error()))
+	}
+	add("")
+
+	listRows := max(3, height/3)
+	start := 0
+	if q.cursor >= listRows {
+		start = q.cursor - listRows + 1
+	}
+	if len(q.requests) == 0 {
+		add("  No pending requests.")
+	}
+	for i := start; i < len(q.requests) && i < start+listRows; i++ {
+		r := q.requests[i]
+		marker := "  "
+		if i == q.cursor {
+			marker = "> "
+		}
+		note := ""
+		if q.skipped[r.ID] {
+			note = " (skipped)"
+		}
+		add("%s%-16s %-14s %-16s %s%s", marker, clean(r.RequestType), clean(requesterName(&r)),
+			r.AddedAt.Local().Format("2006-01-02 15:04"), clean(firstLine(r.Description)), note)
+	}
+
+	if sel := q.selected(); sel != nil {
+		add("")
+		add("ID:            %s", clean(sel.ID))
+		add("Type:          %s", clean(sel.RequestType))
+		add("Requested by:  %s", clean(requesterName(sel)))
+		add("Added at:      %s (%s ago)", sel.AddedAt.Local().Format("2006-01-02 15:04"), time.Since(sel.AddedAt).Round(time.Minute))
+		if sel.Description != "" {
+			add("Description:   %s", clean(sel.Description))
+		}
+		if fields := parseRequestData(sel.RequestData); len(fields) > 0 {
+			add("Request:")
+			for _, f := range fields {
+				add("  %-20s %s", clean(f.Key)+":", clean(f.Value))
+			}
+		}
+		add("Recent work sessions of the requester:")
+		lines = append(lines, q.sessionLines(sel)...)
+	}
+
+	footer := []string{"", "j/k move  s skip  a approve  e adjust  r reject  o console  R reload  q quit"}
+	if q.status != "" {
+		footer = append(footer, utils.SanitizeTerminalText(q.status))
+	}
+	// The footer always shows: the detail pane gives way first.
+	if room := height - len(footer); room >= 0 && len(lines) > room {
+		lines = lines[:room]
+	}
+	lines = append(lines, footer...)
+
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(clip(line, width))
+	}
+	_, _ = io.WriteString(w, b.String())
+}
+
+func (q *reviewQueue) sessionLines(sel *approvalapi.ApprovalRequest) []string {
+	if sel.RequestedBy == nil || sel.RequestedBy.ID == "" {
+		return []string{"  (requester unknown)"}
+	}
+	state := q.sessions[sel.RequestedBy.ID]
+	switch {
+	case state == nil || !state.loaded:
+		return []string{"  loading..."}
+	case state.err != nil:
+		return []string{"  unavailable: " + utils.SanitizeTerminalText(state.err.Error())}
+	case len(state.sessions) == 0:
+		return []string{"  none"}
+	}
+	lines := make([]string, 0, len(state.sessions))
+	for _, s := range state.sessions {
+		lines = append(lines, fmt.Sprintf("  %-10s %-10s %-24s %-24s %s", clean(s.Status), clean(shortID(s.ID)),
+			clean(s.Scopes), clean(s.Servers), clean(s.Description)))
+	}
+	return lines
+}
+
+func requesterName(r *approvalapi.ApprovalRequest) string {
+	if r.RequestedBy == nil {
+		return "-"
+	}
+	return r.RequestedBy.Name
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+func clean(s string) string {
+	return utils.SanitizeTerminalText(s)
+}
+
+// clip cuts a line to the terminal width so it never wraps and pushes the
+// footer off the screen.
+func clip(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width])
+}
+
+type queueLoad struct {
+	requests []approvalapi.ApprovalRequest
+	err      error
+}
+
+type sessionsLoad struct {
+	userID   string
+	sessions []wsapi.WorkSessionAttributes
+	err      error
+}
+
+func runReview(ac *client.AlpaconClient, initial []approvalapi.ApprovalRequest, refresh time.Duration) error {
+	q := newReviewQueue(ac.BaseURL)
+	q.setRequests(initial, time.Now())
+
+	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return fmt.Errorf("failed to enter raw mode: %w", err)
+	}
+	// Alternate screen, cursor hidden; both undone on the way out.
+	_, _ = fmt.Fprint(os.Stdout, "\x1b[?1049h\x1b[?25l")
+	defer func() {
+		_, _ = fmt.Fprint(os.Stdout, "\x1b[?25h\x1b[?1049l")
+		_ = term.Restore(int(os.Stdin.Fd()), oldState)
+	}()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	keys := make(chan []reviewKey)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				keys <- []reviewKey{keyQuit}
+				return
+			}
+			keys <- decodeKeys(buf[:n])
+		}
+	}()
+
+	loads := make(chan queueLoad, 1)
+	loading := false
+	reload := func() {
+		if loading {
+			return
+		}
+		loading = true
+		go func() {
+			requests, err := approvalapi.ListApprovalRequestDetails(ac, "pending", reviewType)
+			loads <- queueLoad{requests: requests, err: err}
+		}()
+	}
+
+	sessionLoads := make(chan sessionsLoad, 4)
+	loadSessions := func() {
+		sel := q.selected()
+		if sel == nil || sel.RequestedBy == nil || sel.RequestedBy.ID == "" {
+			return
+		}
+		userID := sel.RequestedBy.ID
+		if _, seen := q.sessions[userID]; seen {
+			return
+		}
+		q.sessions[userID] = &recentSessions{}
+		go func() {
+			sessions, err := wsapi.GetWorkSessionList(ac, "", "", userID)
+			if len(sessions) > reviewRecentSessions {
+				sessions = sessions[:reviewRecentSessions]
+			}
+			sessionLoads <- sessionsLoad{userID: userID, sessions: sessions, err: err}
+		}()
+	}
+
+	// Live updates are a bonus over the refresh interval, so a watcher that
+	// cannot connect is dropped rather than ending the review.
+	var frames <-chan []byte
+	watcher := eventapi.NewWatcher(ac, reviewEventType, "")
+	watcher.Start()
+	defer watcher.Stop()
+	connected := make(chan bool, 1)
+	go func() { connected <- watcher.WaitConnected(reviewConnectTimeout) }()
+
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+
+	for {
+		loadSessions()
+		width, height, err := term.GetSize(int(os.Stdout.Fd()))
+		if err != nil {
+			width, height = 80, 24
+		}
+		q.render(os.Stdout, width, height)
+
+		select {
+		case <-sigChan:
+			return nil
+		case batch := <-keys:
+			for _, k := range batch {
+				switch q.handleKey(k) {
+				case keyQuit:
+					return nil
+				case keyOpen:
+					utils.OpenBrowser(q.consoleURL)
+				case keyReload:
+					reload()
+				}
+			}
+		case load := <-loads:
+			loading = false
+			if load.err != nil {
+				q.loadErr = load.err
+			} else {
+				q.setRequests(load.requests, time.Now())
+			}
+		case s := <-sessionLoads:
+			q.sessions[s.userID] = &recentSessions{loaded: true, sessions: s.sessions, err: s.err}
+		case ok := <-connected:
+			if ok {
+				frames = watcher.Frames()
+			} else {
+				q.status = fmt.Sprintf("Live updates unavailable; reloading every %s.", refresh)
+			}
+		case <-frames:
+			reload()
+		case <-ticker.C:
+			reload()
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package approval
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	approvalapi "github.com/alpacax/alpacon-cli/api/approval"
+	"github.com/alpacax/alpacon-cli/api/types"
+	wsapi "github.com/alpacax/alpacon-cli/api/worksession"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func reviewRequests() []approvalapi.ApprovalRequest {
+	base := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	return []approvalapi.ApprovalRequest{
+		{ID: "apr-2", RequestType: "sudo", AddedAt: base.Add(time.Minute), RequestedBy: &types.UserSummary{ID: "u-2", Name: "bob"},
+			RequestData: `{"command": "systemctl restart nginx", "server": {"id": "s-1", "name": "web-01"}}`},
+		{ID: "apr-1", RequestType: "work_session", AddedAt: base, RequestedBy: &types.UserSummary{ID: "u-1", Name: "alice"},
+			RequestData: `{"scopes": ["command", "sudo"], "expires_in": 3600}`, Description: "rotate certs\nsecond line"},
+	}
+}
+
+func TestDecodeKeys(t *testing.T) {
+	assert.Equal(t, []reviewKey{keyDown, keyUp, keyDown, keySkip, keyApprove, keyReload, keyQuit},
+		decodeKeys([]byte("jk\x1b[Bsa"+"R\x1b")))
+	assert.Equal(t, []reviewKey{keyQuit}, decodeKeys([]byte{0x03}))
+	assert.Empty(t, decodeKeys([]byte("xyz")))
+}
+
+func TestReviewQueue_Navigation(t *testing.T) {
+	q := newReviewQueue("https://ws.example.com")
+	q.setRequests(reviewRequests(), time.Now())
+
+	require.Equal(t, "apr-1", q.selected().ID, "oldest request first")
+	assert.Equal(t, keyNone, q.handleKey(keyUp))
+	assert.Equal(t, "apr-1", q.selected().ID)
+
+	q.handleKey(keySkip)
+	assert.True(t, q.skipped["apr-1"])
+	assert.Equal(t, "apr-2", q.selected().ID)
+	q.handleKey(keySkip)
+	assert.Equal(t, "End of the queue.", q.status)
+
+	// A reload keeps the cursor on the same request even when it moved.
+	q.setRequests(append([]approvalapi.ApprovalRequest{{ID: "apr-0", AddedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}}, reviewRequests()...), time.Now())
+	assert.Equal(t, "apr-2", q.selected().ID)
+}
+
+func TestReviewQueue_DecisionsGoToTheConsole(t *testing.T) {
+	q := newReviewQueue("https://ws.example.com")
+	q.setRequests(reviewRequests(), time.Now())
+
+	for _, k := range []reviewKey{keyApprove, keyAdjust, keyReject} {
+		assert.Equal(t, keyOpen, q.handleKey(k))
+		assert.Contains(t, q.status, "apr-1 in the Alpacon console")
+	}
+
+	empty := newReviewQueue("https://ws.example.com")
+	assert.Equal(t, keyNone, empty.handleKey(keyApprove))
+}
+
+func TestParseRequestData(t *testing.T) {
+	assert.Equal(t, []requestField{
+		{Key: "command", Value: "systemctl restart nginx"},
+		{Key: "server", Value: "web-01"},
+	}, parseRequestData(reviewRequests()[0].RequestData))
+	assert.Equal(t, []requestField{
+		{Key: "expires_in", Value: "3600"},
+		{Key: "scopes", Value: "command, sudo"},
+	}, parseRequestData(reviewRequests()[1].RequestData))
+	assert.Equal(t, []requestField{{Key: "data", Value: "deploy access"}}, parseRequestData("deploy access"))
+	assert.Nil(t, parseRequestData(""))
+}
+
+func TestReviewQueue_Render(t *testing.T) {
+	q := newReviewQueue("https://ws.example.com")
+	requests := reviewRequests()
+	requests[1].RequestData = `{"note": "\u001b]52;c;ZXZpbA==\u0007clipboard"}`
+	q.setRequests(requests, time.Now())
+	q.sessions["u-1"] = &recentSessions{loaded: true, sessions: []wsapi.WorkSessionAttributes{
+		{ID: "ses-abcdef123", Status: "completed", Scopes: "command", Servers: "web-01", Description: "last week"},
+	}}
+
+	var buf bytes.Buffer
+	q.render(&buf, 100, 40)
+	out := buf.String()
+
+	assert.Contains(t, out, "Approval review: 2 pending")
+	assert.Contains(t, out, "> work_session")
+	assert.Contains(t, out, "rotate certs")
+	assert.Contains(t, out, "ses-abcd")
+	assert.NotContains(t, out, "\x1b]52", "request data cannot reach the terminal as control sequences")
+	assert.Contains(t, out, "j/k move")
+
+	buf.Reset()
+	q.render(&buf, 30, 6)
+	lines := strings.Split(strings.TrimPrefix(buf.String(), "\x1b[H\x1b[2J"), "\r\n")
+	assert.Len(t, lines, 6, "the screen never scrolls")
+	assert.Contains(t, lines[len(lines)-1], "j/k move", "the key help survives a short terminal")
+	for _, line := range lines {
+		assert.LessOrEqual(t, len([]rune(line)), 30)
+	}
+}
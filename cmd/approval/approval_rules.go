@@ -0,0 +1,273 @@
+package approval
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	approvalapi "github.com/alpacax/alpacon-cli/api/approval"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	decisionApprove = "approve"
+	decisionReject  = "reject"
+)
+
+type rulesFile struct {
+	Rules []triageRule `yaml:"rules"`
+}
+
+// triageRule decides a request when every criterion it sets matches. A
+// criterion left empty matches anything.
+type triageRule struct {
+	Name       string      `yaml:"name"`
+	Decision   string      `yaml:"decision"`
+	Types      []string    `yaml:"types,omitempty"`
+	Requesters []string    `yaml:"requesters,omitempty"`
+	Commands   []string    `yaml:"commands,omitempty"`
+	Servers    []string    `yaml:"servers,omitempty"`
+	Window     *timeWindow `yaml:"window,omitempty"`
+	commands   []*regexp.Regexp
+	servers    []*regexp.Regexp
+}
+
+// timeWindow limits a rule to when a request was submitted, in local time.
+// A window whose from is after its to runs over midnight.
+type timeWindow struct {
+	Days []string `yaml:"days,omitempty"`
+	From string   `yaml:"from,omitempty"`
+	To   string   `yaml:"to,omitempty"`
+	days []time.Weekday
+	from int // minutes after midnight
+	to   int
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func readRulesFile(path string) ([]triageRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseRules(data)
+}
+
+func parseRules(data []byte) ([]triageRule, error) {
+	var file rulesFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if len(file.Rules) == 0 {
+		return nil, errors.New("no rules defined")
+	}
+	seen := map[string]bool{}
+	for i := range file.Rules {
+		r := &file.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if seen[r.Name] {
+			return nil, fmt.Errorf("rule %q is defined twice", r.Name)
+		}
+		seen[r.Name] = true
+		if err := r.compile(); err != nil {
+			return nil, fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+	}
+	return file.Rules, nil
+}
+
+func (r *triageRule) compile() error {
+	if r.Decision != decisionApprove && r.Decision != decisionReject {
+		return fmt.Errorf("decision must be %s or %s, not %q", decisionApprove, decisionReject, r.Decision)
+	}
+	for _, t := range r.Types {
+		if !slices.Contains(validTypes, t) {
+			return fmt.Errorf("unknown request type %q: must be one of %s", t, strings.Join(validTypes, ", "))
+		}
+	}
+	for _, p := range r.Commands {
+		r.commands = append(r.commands, commandPattern(p))
+	}
+	for _, p := range r.Servers {
+		r.servers = append(r.servers, globPattern(p))
+	}
+	if r.Window != nil {
+		if err := r.Window.compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *timeWindow) compile() error {
+	for _, d := range w.Days {
+		day, ok := weekdays[strings.ToLower(d)]
+		if !ok {
+			return fmt.Errorf("unknown day %q in window (use sun, mon, ... sat)", d)
+		}
+		w.days = append(w.days, day)
+	}
+	if (w.From == "") != (w.To == "") {
+		return errors.New("a window needs both from and to, or neither")
+	}
+	if w.From == "" {
+		return nil
+	}
+	var err error
+	if w.from, err = clockMinutes(w.From); err != nil {
+		return err
+	}
+	w.to, err = clockMinutes(w.To)
+	return err
+}
+
+func clockMinutes(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q in window: expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (w *timeWindow) contains(at time.Time) bool {
+	if len(w.days) > 0 && !slices.Contains(w.days, at.Weekday()) {
+		return false
+	}
+	if w.From == "" {
+		return true
+	}
+	m := at.Hour()*60 + at.Minute()
+	if w.from <= w.to {
+		return m >= w.from && m < w.to
+	}
+	return m >= w.from || m < w.to
+}
+
+// shellMeta is what a * or ? in a command pattern never stands for, so
+// "systemctl status *" does not match "systemctl status x; rm -rf /".
+const shellMeta = ";&|<>$`()\\\n\r"
+
+// commandPattern compiles a command glob: * is any run of characters and ?
+// any one, except shell metacharacters; everything else is literal.
+func commandPattern(p string) *regexp.Regexp {
+	wild := "[^" + regexp.QuoteMeta(shellMeta) + "]"
+	return compileGlob(p, wild)
+}
+
+// globPattern compiles a plain glob such as a server name pattern.
+func globPattern(p string) *regexp.Regexp {
+	return compileGlob(p, ".")
+}
+
+func compileGlob(p, wild string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range p {
+		switch r {
+		case '*':
+			b.WriteString(wild + "*")
+		case '?':
+			b.WriteString(wild)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// requestFacts is what rules are matched against, pulled out of a request.
+type requestFacts struct {
+	Type       string
+	Requesters []string // name and email
+	Command    string
+	Servers    []string
+	AddedAt    time.Time
+}
+
+func factsOf(r *approvalapi.ApprovalRequest) requestFacts {
+	f := requestFacts{Type: r.RequestType, AddedAt: r.AddedAt.Local()}
+	if r.RequestedBy != nil {
+		for _, s := range []string{r.RequestedBy.Name, r.RequestedBy.Email} {
+			if s != "" {
+				f.Requesters = append(f.Requesters, s)
+			}
+		}
+	}
+	for _, field := range parseRequestData(r.RequestData) {
+		switch field.Key {
+		case "command":
+			f.Command = strings.TrimSpace(field.Value)
+		case "server":
+			f.Servers = append(f.Servers, field.Value)
+		case "servers":
+			for _, s := range strings.Split(field.Value, ", ") {
+				if s != "" {
+					f.Servers = append(f.Servers, s)
+				}
+			}
+		}
+	}
+	return f
+}
+
+func (r *triageRule) matches(f requestFacts) bool {
+	if len(r.Types) > 0 && !slices.Contains(r.Types, f.Type) {
+		return false
+	}
+	if len(r.Requesters) > 0 && !slices.ContainsFunc(f.Requesters, func(s string) bool {
+		return slices.Contains(r.Requesters, s)
+	}) {
+		return false
+	}
+	if len(r.commands) > 0 && !anyMatch(r.commands, f.Command) {
+		return false
+	}
+	// Every server the request names must be covered, or a request for
+	// web-01 and db-01 would pass a rule written for web-*.
+	if len(r.servers) > 0 {
+		if len(f.Servers) == 0 {
+			return false
+		}
+		for _, s := range f.Servers {
+			if !anyMatch(r.servers, s) {
+				return false
+			}
+		}
+	}
+	if r.Window != nil && !r.Window.contains(f.AddedAt) {
+		return false
+	}
+	return true
+}
+
+func anyMatch(patterns []*regexp.Regexp, s string) bool {
+	if s == "" {
+		return false
+	}
+	return slices.ContainsFunc(patterns, func(re *regexp.Regexp) bool { return re.MatchString(s) })
+}
+
+// triage returns the first rule that matches the request, or nil.
+func triage(rules []triageRule, r *approvalapi.ApprovalRequest) *triageRule {
+	f := factsOf(r)
+	for i := range rules {
+		if rules[i].matches(f) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
@@ -1,8 +1,12 @@
 package audit
 
 import (
+	"fmt"
+
 	"github.com/alpacax/alpacon-cli/api/audit"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
+	logcmd "github.com/alpacax/alpacon-cli/cmd/log"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -14,12 +18,20 @@ var AuditCmd = &cobra.Command{
 	Long: `
 	Retrieve and display audit activity logs from the Alpacon, with options to filter by user,
 	application, and model. Use the '--tail' flag to show the newest N entries.
+
+	Narrow the entries with --since/--until (a duration such as 15m, an RFC3339 time,
+	or a date) and --match (a regular expression over the description, user, and
+	action). Use -f/--follow to keep printing new entries; press Ctrl+C to stop.
+
+	To ship full records to a SIEM, use 'alpacon audit export'.
 	`,
 	Example: `
 	alpacon audit
 	alpacon audit-log
 	alpacon audit --tail 10 --user admin
 	alpacon audit --tail=50 --app=cert --model=authority
+	alpacon audit --since 24h --match 'delete'
+	alpacon audit --user admin -f
 	`,
 	Run: runAudit,
 }
@@ -34,6 +46,10 @@ func init() {
 	AuditCmd.Flags().StringVarP(&userName, "user", "u", "", "Filter by username")
 	AuditCmd.Flags().StringVarP(&app, "app", "a", "", "Filter by application")
 	AuditCmd.Flags().StringVarP(&model, "model", "m", "", "Filter by model")
+	_ = AuditCmd.RegisterFlagCompletionFunc("user", complete.Users)
+	logcmd.AddFilterFlags(AuditCmd)
+
+	AuditCmd.AddCommand(auditExportCmd)
 }
 
 func runAudit(cmd *cobra.Command, args []string) {
@@ -42,16 +58,30 @@ func runAudit(cmd *cobra.Command, args []string) {
 	userName, _ := cmd.Flags().GetString("user")
 	app, _ := cmd.Flags().GetString("app")
 	model, _ := cmd.Flags().GetString("model")
+	filter, interval := logcmd.FilterFromFlags(cmd)
 
 	alpaconClient, err := client.NewAlpaconAPIClient()
 	if err != nil {
 		utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
 	}
 
-	auditList, err := audit.GetAuditLogList(alpaconClient, tail, userName, app, model)
+	params, err := audit.AuditLogParams(alpaconClient, userName, app, model)
 	if err != nil {
 		utils.CliErrorWithExit("Failed to get audit logs: %s.", err)
 	}
 
-	utils.PrintTable(auditList)
+	entries, err := audit.GetAuditLogs(alpaconClient, params, tail, filter)
+	if err = logcmd.WarnIfTruncated(err); err != nil {
+		utils.CliErrorWithExit("Failed to get audit logs: %s.", err)
+	}
+
+	if interval > 0 {
+		logcmd.Follow(audit.FollowAuditLogs(alpaconClient, params, filter, interval), entries, auditLine)
+		return
+	}
+	utils.PrintTable(audit.ToAuditLogAttributes(entries))
+}
+
+func auditLine(e audit.AuditLogEntry) string {
+	return fmt.Sprintf("%s  %s  %s %s.%s  %d  %s  %s", logcmd.FollowTime(e.AddedAt), e.Username, e.Action, e.App, e.Model, e.StatusCode, e.IP, e.Description)
 }
@@ -0,0 +1,347 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alpacax/alpacon-cli/api"
+	"github.com/alpacax/alpacon-cli/api/audit"
+	"github.com/alpacax/alpacon-cli/api/event"
+	"github.com/alpacax/alpacon-cli/api/logquery"
+	"github.com/alpacax/alpacon-cli/api/webftp"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+const exportDialTimeout = 10 * time.Second
+
+var auditExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export full audit, command, and WebFTP records for a SIEM",
+	Long: `Export audit log entries, command history, and WebFTP transfers as full
+records, for ingestion into a SIEM. A record holds the fields the CLI reads
+from each entry, not only the columns the list commands show.
+
+Formats (--format):
+  ndjson   one JSON object per line: {"source", "time", "record"}
+  cef      ArcSight Common Event Format, one event per line
+  syslog   RFC 5424 messages (facility local0) carrying the record as JSON
+
+Destinations (--to):
+  -                    standard output (the default)
+  PATH                 append to a file, created with owner-only permissions
+  tcp://HOST:PORT      a TCP collector; syslog uses octet-counting framing
+  udp://HOST:PORT      a UDP collector; one record per datagram
+
+With --checkpoint, the export records how far it got in that file and the
+next run ships only newer records, so a scheduled job neither skips nor
+repeats events. The checkpoint is written only after every record was sent.
+A source with no checkpoint yet starts at --since.`,
+	Example: `  alpacon audit export --since 1h
+  alpacon audit export --format cef --to tcp://siem.example.com:5140 --checkpoint ~/.alpacon/siem.json
+  alpacon audit export --format syslog --to udp://127.0.0.1:514 --include audit,commands
+  alpacon audit export --to /var/log/alpacon/audit.ndjson --checkpoint /var/lib/alpacon/audit.ckpt`,
+	Args: cobra.NoArgs,
+	Run:  runAuditExport,
+}
+
+func init() {
+	auditExportCmd.Flags().String("format", formatNDJSON, "Record format: ndjson, cef, or syslog")
+	auditExportCmd.Flags().String("to", "-", "Destination: -, a file path, tcp://HOST:PORT, or udp://HOST:PORT")
+	auditExportCmd.Flags().String("checkpoint", "", "File that remembers what was exported, so the next run ships only newer records")
+	auditExportCmd.Flags().String("since", "24h", "Where a source without a checkpoint starts: a duration back from now, an RFC3339 time, or a date")
+	auditExportCmd.Flags().StringSlice("include", exportSources, "Sources to export: audit, commands, webftp")
+}
+
+func runAuditExport(cmd *cobra.Command, args []string) {
+	format, _ := cmd.Flags().GetString("format")
+	to, _ := cmd.Flags().GetString("to")
+	checkpointPath, _ := cmd.Flags().GetString("checkpoint")
+	sinceRaw, _ := cmd.Flags().GetString("since")
+	include, _ := cmd.Flags().GetStringSlice("include")
+
+	if !slices.Contains(exportFormats, format) {
+		utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "Invalid --format %q: must be one of %s.", format, strings.Join(exportFormats, ", "))
+	}
+	if len(include) == 0 {
+		utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "--include needs at least one source.")
+	}
+	for _, source := range include {
+		if !slices.Contains(exportSources, source) {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "Invalid --include %q: must be one of %s.", source, strings.Join(exportSources, ", "))
+		}
+	}
+	since, err := logquery.ParseTime("--since", sinceRaw, time.Now())
+	if err != nil {
+		utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+	}
+	dest, err := parseDestination(to)
+	if err != nil {
+		utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+	}
+
+	checkpoint := exportCheckpoint{Sources: map[string]logquery.Position{}}
+	if checkpointPath != "" {
+		if checkpoint, err = readCheckpoint(checkpointPath); err != nil {
+			utils.CliErrorWithExit("Failed to read the checkpoint %s: %s.", checkpointPath, err)
+		}
+	}
+
+	alpaconClient, err := client.NewAlpaconAPIClient()
+	if err != nil {
+		utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
+	}
+
+	records, err := collectRecords(alpaconClient, include, checkpoint, since)
+	if err != nil {
+		utils.CliErrorWithExit("Failed to collect records: %s.", err)
+	}
+	if len(records) == 0 {
+		utils.CliInfo("No new records to export.")
+		return
+	}
+
+	sink, err := dest.open(format)
+	if err != nil {
+		utils.CliErrorWithExit("Failed to open %s: %s.", to, err)
+	}
+	sent, err := sendRecords(sink, newFormatter(format), records)
+	if closeErr := sink.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		utils.CliErrorWithExit("Export stopped after %d of %d records: %s. The checkpoint was not updated; the next run sends them again.", sent, len(records), err)
+	}
+
+	if checkpointPath != "" {
+		if err = writeCheckpoint(checkpointPath, checkpoint); err != nil {
+			utils.CliErrorWithExit("Exported %d records, but failed to save the checkpoint %s: %s. The next run sends them again.", sent, checkpointPath, err)
+		}
+	}
+	// Keep stdout for the records themselves.
+	if dest.kind != destStdout {
+		utils.CliSuccess("Exported %d records to %s.", sent, to)
+	}
+}
+
+// collectRecords fetches each source's records past its checkpoint position,
+// advancing the positions in checkpoint, and returns them oldest first.
+func collectRecords(ac *client.AlpaconClient, include []string, checkpoint exportCheckpoint, since time.Time) ([]exportRecord, error) {
+	var records []exportRecord
+	for _, source := range include {
+		pos, ok := checkpoint.Sources[source]
+		if !ok {
+			pos = logquery.Position{Time: since}
+		}
+		var err error
+		switch source {
+		case sourceAudit:
+			pos, err = collect(audit.FollowAuditLogs(ac, nil, logquery.Filter{}, 0), pos, auditRecord, &records)
+		case sourceCommands:
+			var endpoint string
+			if endpoint, err = event.EventListEndpoint(ac, "", ""); err == nil {
+				pos, err = collect(event.FollowEvents(ac, endpoint, logquery.Filter{}, 0), pos, commandRecord, &records)
+			}
+		case sourceWebFTP:
+			pos, err = collect(webftp.FollowWebFTPLogs(ac, nil, logquery.Filter{}, 0), pos, webftpRecord, &records)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		checkpoint.Sources[source] = pos
+	}
+	sortRecords(records)
+	return records, nil
+}
+
+// collect polls a source once from pos, appending what is new. A fetch walks
+// at most api.FilterScanLimit entries back from the newest, so a full one may
+// have stopped short of pos; collect then fails rather than move the position
+// past records it never read.
+func collect[T any](f *logquery.Follower[T], pos logquery.Position, toRecord func(T) exportRecord, records *[]exportRecord) (logquery.Position, error) {
+	fetch := f.Fetch
+	f.Fetch = func(since time.Time) ([]T, error) {
+		entries, err := fetch(since)
+		if errors.Is(err, api.ErrScanLimit) || (err == nil && len(entries) >= api.FilterScanLimit) {
+			return nil, fmt.Errorf("more than %d records since %s, more than one run can reach; the position was not advanced. Export with a --since or checkpoint closer to now, and more often", api.FilterScanLimit, since.Format(time.RFC3339))
+		}
+		return entries, err
+	}
+	f.Emit = func(e T) { *records = append(*records, toRecord(e)) }
+	f.Resume(pos)
+	if err := f.Poll(); err != nil {
+		return pos, err
+	}
+	return f.Position(), nil
+}
+
+func sendRecords(sink exportSink, format recordFormatter, records []exportRecord) (int, error) {
+	for i, r := range records {
+		msg, err := format(r)
+		if err != nil {
+			return i, err
+		}
+		if err = sink.Send(msg); err != nil {
+			return i, err
+		}
+	}
+	return len(records), nil
+}
+
+// exportCheckpoint is the --checkpoint file: each source's position.
+type exportCheckpoint struct {
+	Sources map[string]logquery.Position `json:"sources"`
+}
+
+func readCheckpoint(path string) (exportCheckpoint, error) {
+	checkpoint := exportCheckpoint{Sources: map[string]logquery.Position{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return checkpoint, nil
+	}
+	if err != nil {
+		return checkpoint, err
+	}
+	if err = json.Unmarshal(data, &checkpoint); err != nil {
+		return checkpoint, err
+	}
+	if checkpoint.Sources == nil {
+		checkpoint.Sources = map[string]logquery.Position{}
+	}
+	return checkpoint, nil
+}
+
+// writeCheckpoint replaces the file whole, so a run killed mid-write leaves
+// the previous checkpoint rather than a torn one.
+func writeCheckpoint(path string, checkpoint exportCheckpoint) error {
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = utils.SaveStreamAtomic(path, bytes.NewReader(append(data, '\n')), 0o600)
+	return err
+}
+
+type destKind int
+
+const (
+	destStdout destKind = iota
+	destFile
+	destTCP
+	destUDP
+)
+
+type destination struct {
+	kind destKind
+	addr string // file path or host:port
+}
+
+func parseDestination(raw string) (destination, error) {
+	switch {
+	case raw == "" || raw == "-":
+		return destination{kind: destStdout}, nil
+	case strings.Contains(raw, "://"):
+		u, err := url.Parse(raw)
+		if err != nil {
+			return destination{}, fmt.Errorf("invalid --to %q: %w", raw, err)
+		}
+		if u.Host == "" || u.Port() == "" {
+			return destination{}, fmt.Errorf("invalid --to %q: expected %s://HOST:PORT", raw, u.Scheme)
+		}
+		switch u.Scheme {
+		case "tcp":
+			return destination{kind: destTCP, addr: u.Host}, nil
+		case "udp":
+			return destination{kind: destUDP, addr: u.Host}, nil
+		}
+		return destination{}, fmt.Errorf("invalid --to %q: the scheme must be tcp or udp", raw)
+	default:
+		return destination{kind: destFile, addr: raw}, nil
+	}
+}
+
+// exportSink frames and delivers one formatted record at a time.
+type exportSink interface {
+	Send(msg []byte) error
+	Close() error
+}
+
+func (d destination) open(format string) (exportSink, error) {
+	switch d.kind {
+	case destFile:
+		file, err := os.OpenFile(d.addr, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		return &lineSink{w: bufio.NewWriter(file), closer: file}, nil
+	case destTCP:
+		conn, err := net.DialTimeout("tcp", d.addr, exportDialTimeout)
+		if err != nil {
+			return nil, err
+		}
+		// RFC 6587 octet counting: a syslog message may itself hold a newline.
+		return &lineSink{w: bufio.NewWriter(conn), closer: conn, octetCounted: format == formatSyslog}, nil
+	case destUDP:
+		conn, err := net.DialTimeout("udp", d.addr, exportDialTimeout)
+		if err != nil {
+			return nil, err
+		}
+		return &datagramSink{conn: conn}, nil
+	default:
+		return &lineSink{w: bufio.NewWriter(os.Stdout)}, nil
+	}
+}
+
+type lineSink struct {
+	w            *bufio.Writer
+	closer       io.Closer
+	octetCounted bool
+}
+
+func (s *lineSink) Send(msg []byte) error {
+	var err error
+	if s.octetCounted {
+		_, err = s.w.WriteString(strconv.Itoa(len(msg)) + " ")
+		if err == nil {
+			_, err = s.w.Write(msg)
+		}
+	} else {
+		_, err = s.w.Write(append(msg, '\n'))
+	}
+	return err
+}
+
+func (s *lineSink) Close() error {
+	err := s.w.Flush()
+	if s.closer != nil {
+		if closeErr := s.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+type datagramSink struct {
+	conn net.Conn
+}
+
+func (s *datagramSink) Send(msg []byte) error {
+	_, err := s.conn.Write(msg)
+	return err
+}
+
+func (s *datagramSink) Close() error {
+	return s.conn.Close()
+}
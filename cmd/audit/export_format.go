@@ -0,0 +1,197 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alpacax/alpacon-cli/api/audit"
+	"github.com/alpacax/alpacon-cli/api/event"
+	"github.com/alpacax/alpacon-cli/api/webftp"
+	"github.com/alpacax/alpacon-cli/utils"
+)
+
+const (
+	formatNDJSON = "ndjson"
+	formatCEF    = "cef"
+	formatSyslog = "syslog"
+
+	sourceAudit    = "audit"
+	sourceCommands = "commands"
+	sourceWebFTP   = "webftp"
+)
+
+var (
+	exportFormats = []string{formatNDJSON, formatCEF, formatSyslog}
+	exportSources = []string{sourceAudit, sourceCommands, sourceWebFTP}
+)
+
+// exportRecord is one exported event: the full record as the API returned
+// it, plus the few fields CEF and syslog put in their headers.
+type exportRecord struct {
+	Source string    `json:"source"`
+	Time   time.Time `json:"time"`
+	Record any       `json:"record"`
+
+	signature string
+	name      string
+	user      string
+	srcIP     string
+	server    string
+	action    string
+	message   string
+	failed    bool
+}
+
+func auditRecord(e audit.AuditLogEntry) exportRecord {
+	return exportRecord{
+		Source:    sourceAudit,
+		Time:      e.AddedAt,
+		Record:    e,
+		signature: "audit:" + e.App + "." + e.Action,
+		name:      strings.TrimSpace(e.Action + " " + e.App + "." + e.Model),
+		user:      e.Username,
+		srcIP:     e.IP,
+		action:    e.Action,
+		message:   e.Description,
+		failed:    e.StatusCode >= 400,
+	}
+}
+
+func commandRecord(e event.EventDetails) exportRecord {
+	return exportRecord{
+		Source:    sourceCommands,
+		Time:      e.AddedAt,
+		Record:    e,
+		signature: "command",
+		name:      "Command executed",
+		user:      e.RequestedBy.Name,
+		server:    e.Server.Name,
+		action:    "run",
+		message:   e.Line,
+		failed:    e.Success != nil && !*e.Success,
+	}
+}
+
+func webftpRecord(e webftp.WebFTPLogEntry) exportRecord {
+	r := exportRecord{
+		Source:    sourceWebFTP,
+		Time:      e.AddedAt,
+		Record:    e,
+		signature: "webftp:" + e.Action,
+		name:      "File " + e.Action,
+		srcIP:     e.RemoteIP,
+		action:    e.Action,
+		message:   e.FileName,
+		failed:    !e.Success,
+	}
+	if e.User != nil {
+		r.user = e.User.Name
+	}
+	if e.Server != nil {
+		r.server = e.Server.Name
+	}
+	return r
+}
+
+func sortRecords(records []exportRecord) {
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+}
+
+// recordFormatter renders one record as one message, without a trailing
+// newline; the sink frames it.
+type recordFormatter func(exportRecord) ([]byte, error)
+
+func newFormatter(format string) recordFormatter {
+	switch format {
+	case formatCEF:
+		return formatCEFRecord
+	case formatSyslog:
+		host, err := os.Hostname()
+		if err != nil || host == "" {
+			host = "-"
+		}
+		return func(r exportRecord) ([]byte, error) { return formatSyslogRecord(r, host) }
+	default:
+		return func(r exportRecord) ([]byte, error) { return json.Marshal(r) }
+	}
+}
+
+// formatCEFRecord renders an ArcSight Common Event Format line:
+// CEF:0|Vendor|Product|Version|Signature ID|Name|Severity|Extension.
+func formatCEFRecord(r exportRecord) ([]byte, error) {
+	severity := 3
+	outcome := "success"
+	if r.failed {
+		severity, outcome = 6, "failure"
+	}
+	ext := []string{"rt=" + strconv.FormatInt(r.Time.UnixMilli(), 10)}
+	add := func(key, value string) {
+		if value != "" {
+			ext = append(ext, key+"="+cefExtension(value))
+		}
+	}
+	add("suser", r.user)
+	add("src", r.srcIP)
+	add("dhost", r.server)
+	add("act", r.action)
+	add("outcome", outcome)
+	add("msg", r.message)
+	add("cs1Label", "source")
+	add("cs1", r.Source)
+
+	line := fmt.Sprintf("CEF:0|AlpacaX|Alpacon|%s|%s|%s|%d|%s",
+		cefHeader(utils.Version), cefHeader(r.signature), cefHeader(r.name), severity, strings.Join(ext, " "))
+	return []byte(line), nil
+}
+
+// cefHeader escapes a header field: backslash and pipe.
+func cefHeader(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ").Replace(s)
+}
+
+// cefExtension escapes an extension value: backslash, equals, and newlines.
+func cefExtension(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r\n", `\n`, "\n", `\n`, "\r", `\r`).Replace(s)
+}
+
+// formatSyslogRecord renders an RFC 5424 message from facility local0 whose
+// MSG is the record as JSON. There is no structured data: the JSON carries
+// every field, and SD-IDs outside the IANA registry need an enterprise number.
+func formatSyslogRecord(r exportRecord, host string) ([]byte, error) {
+	const facilityLocal0 = 16
+	severity := 5 // notice
+	if r.failed {
+		severity = 4 // warning
+	}
+	msg, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	header := fmt.Sprintf("<%d>1 %s %s alpacon %d %s - ",
+		facilityLocal0*8+severity, r.Time.UTC().Format("2006-01-02T15:04:05.000000Z07:00"), syslogField(host), os.Getpid(), r.Source)
+	return append([]byte(header), msg...), nil
+}
+
+// syslogField keeps a header field to printable ASCII without spaces, as
+// RFC 5424 requires, and to at most 255 characters.
+func syslogField(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if c > 32 && c < 127 {
+			b.WriteRune(c)
+		}
+	}
+	if b.Len() == 0 {
+		return "-"
+	}
+	out := b.String()
+	if len(out) > 255 {
+		out = out[:255]
+	}
+	return out
+}
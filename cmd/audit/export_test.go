@@ -0,0 +1,199 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpacax/alpacon-cli/api"
+	auditapi "github.com/alpacax/alpacon-cli/api/audit"
+	"github.com/alpacax/alpacon-cli/api/event"
+	"github.com/alpacax/alpacon-cli/api/logquery"
+	"github.com/alpacax/alpacon-cli/api/types"
+	"github.com/alpacax/alpacon-cli/api/webftp"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var exportBase = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+func exportServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/audit/activity"):
+			_ = json.NewEncoder(w).Encode(api.CursorListResponse[auditapi.AuditLogEntry]{Results: []auditapi.AuditLogEntry{
+				{ID: "a-2", Username: "alice", User: &types.UserSummary{ID: "u-1", Name: "alice"}, App: "iam", Action: "delete", Model: "user", StatusCode: 403, AddedAt: exportBase.Add(3 * time.Minute)},
+				{ID: "a-1", Username: "alice", App: "iam", Action: "create", Model: "user", StatusCode: 201, AddedAt: exportBase.Add(time.Minute)},
+				{ID: "a-0", Username: "alice", App: "iam", Action: "create", Model: "user", StatusCode: 201, AddedAt: exportBase.Add(-time.Hour)},
+			}})
+		case strings.HasPrefix(r.URL.Path, "/api/events/commands"):
+			_ = json.NewEncoder(w).Encode(api.ListResponse[event.EventDetails]{Results: []event.EventDetails{
+				{ID: "c-1", Line: "uptime", AddedAt: exportBase.Add(2 * time.Minute), Server: types.ServerSummary{Name: "web-01"}, RequestedBy: types.UserSummary{Name: "bob"}},
+			}})
+		case strings.HasPrefix(r.URL.Path, "/api/history/webftp-logs"):
+			_ = json.NewEncoder(w).Encode(api.CursorListResponse[webftp.WebFTPLogEntry]{})
+		default:
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+	}))
+}
+
+func TestCollectRecords_OldestFirstAndAdvancesCheckpoint(t *testing.T) {
+	ts := exportServer(t)
+	defer ts.Close()
+	ac := &client.AlpaconClient{HTTPClient: ts.Client(), BaseURL: ts.URL}
+
+	checkpoint := exportCheckpoint{Sources: map[string]logquery.Position{}}
+	records, err := collectRecords(ac, exportSources, checkpoint, exportBase)
+	require.NoError(t, err)
+
+	var order []string
+	for _, r := range records {
+		order = append(order, r.Source)
+	}
+	assert.Equal(t, []string{sourceAudit, sourceCommands, sourceAudit}, order, "merged oldest first, before --since dropped")
+	assert.Equal(t, logquery.Position{Time: exportBase.Add(3 * time.Minute), Keys: []string{"a-2"}}, checkpoint.Sources[sourceAudit])
+	assert.Equal(t, logquery.Position{Time: exportBase.Add(2 * time.Minute), Keys: []string{"c-1"}}, checkpoint.Sources[sourceCommands])
+	assert.Equal(t, logquery.Position{Time: exportBase}, checkpoint.Sources[sourceWebFTP], "an empty source keeps its start")
+
+	records, err = collectRecords(ac, exportSources, checkpoint, exportBase)
+	require.NoError(t, err)
+	assert.Empty(t, records, "a second run ships nothing already shipped")
+}
+
+func TestCollect_FullFetchKeepsPosition(t *testing.T) {
+	f := &logquery.Follower[auditapi.AuditLogEntry]{
+		Fetch: func(since time.Time) ([]auditapi.AuditLogEntry, error) {
+			entries := make([]auditapi.AuditLogEntry, api.FilterScanLimit)
+			for i := range entries {
+				entries[i] = auditapi.AuditLogEntry{ID: strconv.Itoa(i), AddedAt: exportBase.Add(time.Duration(len(entries)-i) * time.Second)}
+			}
+			return entries, nil
+		},
+		Stamp: func(e auditapi.AuditLogEntry) (time.Time, string) { return e.AddedAt, e.ID },
+	}
+
+	pos := logquery.Position{Time: exportBase}
+	var records []exportRecord
+	got, err := collect(f, pos, auditRecord, &records)
+	assert.Error(t, err, "the oldest records may lie past the scan limit")
+	assert.Equal(t, pos, got)
+	assert.Empty(t, records)
+}
+
+func TestCheckpoint_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "siem.json")
+	missing, err := readCheckpoint(path)
+	require.NoError(t, err)
+	assert.Empty(t, missing.Sources)
+
+	want := exportCheckpoint{Sources: map[string]logquery.Position{
+		sourceAudit: {Time: exportBase, Keys: []string{"a-1", "a-2"}},
+	}}
+	require.NoError(t, writeCheckpoint(path, want))
+	got, err := readCheckpoint(path)
+	require.NoError(t, err)
+	assert.True(t, want.Sources[sourceAudit].Time.Equal(got.Sources[sourceAudit].Time))
+	assert.Equal(t, want.Sources[sourceAudit].Keys, got.Sources[sourceAudit].Keys)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestFormatCEFRecord(t *testing.T) {
+	r := auditRecord(auditapi.AuditLogEntry{
+		ID: "a-1", Username: "alice", IP: "10.0.0.1", App: "iam", Action: "delete", Model: "user|group",
+		StatusCode: 403, AddedAt: exportBase, Description: "removed a=b\nnext",
+	})
+	line, err := formatCEFRecord(r)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(line), "CEF:0|AlpacaX|Alpacon|"))
+	assert.Contains(t, string(line), `|audit:iam.delete|delete iam.user\|group|6|`)
+	assert.Contains(t, string(line), "rt=1792324800000 suser=alice src=10.0.0.1 act=delete outcome=failure")
+	assert.Contains(t, string(line), `msg=removed a\=b\nnext`)
+	assert.NotContains(t, string(line), "\n")
+}
+
+func TestFormatSyslogRecord(t *testing.T) {
+	success := true
+	r := commandRecord(event.EventDetails{ID: "c-1", Line: "uptime", Success: &success, AddedAt: exportBase})
+	msg, err := formatSyslogRecord(r, "host name")
+	require.NoError(t, err)
+
+	header, body, ok := strings.Cut(string(msg), " - {")
+	require.True(t, ok)
+	fields := strings.Fields(header)
+	require.Len(t, fields, 6, "SD is the \"-\" cut off above")
+	assert.Equal(t, "<133>1", fields[0], "local0.notice")
+	assert.Equal(t, "2026-10-18T12:00:00.000000Z", fields[1])
+	assert.Equal(t, "hostname", fields[2])
+	assert.Equal(t, "alpacon", fields[3])
+	assert.Equal(t, sourceCommands, fields[5])
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal([]byte("{"+body), &decoded))
+	assert.Equal(t, sourceCommands, decoded["source"])
+	assert.Equal(t, "uptime", decoded["record"].(map[string]any)["line"])
+}
+
+func TestParseDestination(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    destination
+		wantErr string
+	}{
+		{"-", destination{kind: destStdout}, ""},
+		{"out.ndjson", destination{kind: destFile, addr: "out.ndjson"}, ""},
+		{"tcp://siem:5140", destination{kind: destTCP, addr: "siem:5140"}, ""},
+		{"udp://127.0.0.1:514", destination{kind: destUDP, addr: "127.0.0.1:514"}, ""},
+		{"udp://siem", destination{}, "expected udp://HOST:PORT"},
+		{"http://siem:80", destination{}, "must be tcp or udp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := parseDestination(tt.raw)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTCPSink_OctetCountsSyslog(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		data, _ := io.ReadAll(bufio.NewReader(conn))
+		received <- string(data)
+	}()
+
+	sink, err := destination{kind: destTCP, addr: ln.Addr().String()}.open(formatSyslog)
+	require.NoError(t, err)
+	require.NoError(t, sink.Send([]byte("<133>1 a")))
+	require.NoError(t, sink.Send([]byte("multi\nline")))
+	require.NoError(t, sink.Close())
+
+	assert.Equal(t, "8 <133>1 a10 multi\nline", <-received)
+}
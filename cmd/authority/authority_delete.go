@@ -3,6 +3,7 @@ package authority
 import (
 	"github.com/alpacax/alpacon-cli/api/cert"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -20,7 +21,8 @@ var authorityDeleteCmd = &cobra.Command{
 	alpacon authority rm my-authority
 	alpacon authority delete my-authority -y
 	`,
-	Args: cobra.ExactArgs(1),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Authorities),
 	Run: func(cmd *cobra.Command, args []string) {
 		authorityName := args[0]
 
@@ -3,6 +3,7 @@ package authority
 import (
 	"github.com/alpacax/alpacon-cli/api/cert"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -18,7 +19,8 @@ var authorityDownloadCmd = &cobra.Command{
 	Example: `
 	alpacon authority download-crt "Root CA" --out=/path/to/root.crt
 	`,
-	Args: cobra.ExactArgs(1),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Authorities),
 	Run: func(cmd *cobra.Command, args []string) {
 		authorityName := args[0]
 		filePath, _ := cmd.Flags().GetString("out")
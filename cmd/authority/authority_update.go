@@ -3,6 +3,7 @@ package authority
 import (
 	"github.com/alpacax/alpacon-cli/api/cert"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -19,7 +20,8 @@ var authorityUpdateCmd = &cobra.Command{
 	Example: `
 	alpacon authority update "Root CA"
 	`,
-	Args: cobra.ExactArgs(1),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Authorities),
 	Run: func(cmd *cobra.Command, args []string) {
 		authorityName := args[0]
 
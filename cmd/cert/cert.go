@@ -9,13 +9,13 @@ import (
 var CertCmd = &cobra.Command{
 	Use:     "cert",
 	Aliases: []string{"certificate"},
-	Short:   "List, describe, and download certificates",
+	Short:   "List, inspect, download, deploy, and renew certificates",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		err := cmd.Help()
 		if err != nil {
 			return err
 		}
-		return errors.New("a subcommand is required. Use 'alpacon cert ls', 'alpacon cert describe', or 'alpacon cert download' to manage SSL/TLS certificates. Run 'alpacon cert --help' for more information")
+		return errors.New("a subcommand is required. Use 'alpacon cert ls', 'alpacon cert describe', 'alpacon cert download', 'alpacon cert check', 'alpacon cert inspect', 'alpacon cert deploy', or 'alpacon cert renew' to manage SSL/TLS certificates. Run 'alpacon cert --help' for more information")
 	},
 }
 
@@ -23,4 +23,8 @@ func init() {
 	CertCmd.AddCommand(certListCmd)
 	CertCmd.AddCommand(certDetailCmd)
 	CertCmd.AddCommand(certDownloadCmd)
+	CertCmd.AddCommand(certRenewCmd)
+	CertCmd.AddCommand(certCheckCmd)
+	CertCmd.AddCommand(certInspectCmd)
+	CertCmd.AddCommand(certDeployCmd)
 }
@@ -0,0 +1,151 @@
+package cert
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	certApi "github.com/alpacax/alpacon-cli/api/cert"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of cert check, after the Nagios plugin convention.
+const (
+	checkExitWarning  = 1
+	checkExitCritical = 2
+	checkExitUnknown  = 3
+)
+
+const (
+	checkStatusOK       = "ok"
+	checkStatusWarning  = "warning"
+	checkStatusCritical = "critical"
+	checkStatusExpired  = "expired"
+	checkStatusRevoked  = "revoked"
+	checkStatusRenewed  = "renewed"
+)
+
+var certCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check certificates for upcoming expiry",
+	Long: `List every certificate with the days left until it expires, and exit
+non-zero when one is close, for cron jobs and monitoring:
+
+  0  every certificate is valid for longer than --warning
+  1  one expires within --warning
+  2  one expires within --critical, or has expired
+  3  the certificates could not be checked: an invalid flag, a failed
+     login, or an API error
+
+Revoked certificates, and those already renewed, are left out; --all lists
+them without counting them.`,
+	Example: `  alpacon cert check
+  alpacon cert check --warning 45d --critical 14d
+  alpacon cert check --output json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		warningRaw, _ := cmd.Flags().GetString("warning")
+		criticalRaw, _ := cmd.Flags().GetString("critical")
+		all, _ := cmd.Flags().GetBool("all")
+		warning, err := utils.ParseDayDuration("--warning", warningRaw)
+		if err != nil {
+			utils.CliErrorWithExitCode(checkExitUnknown, "%s.", err)
+		}
+		critical, err := utils.ParseDayDuration("--critical", criticalRaw)
+		if err != nil {
+			utils.CliErrorWithExitCode(checkExitUnknown, "%s.", err)
+		}
+		if critical > warning {
+			utils.CliErrorWithExitCode(checkExitUnknown, "--critical must not be longer than --warning.")
+		}
+
+		alpaconClient, err := client.NewAlpaconAPIClient()
+		if err != nil {
+			utils.CliErrorWithExitCode(checkExitUnknown, "Connection to Alpacon API failed: %s. Consider re-logging.", err)
+		}
+
+		certs, err := certApi.GetCertificates(alpaconClient)
+		if err != nil {
+			utils.CliErrorWithExitCode(checkExitUnknown, "Failed to retrieve the certificate list: %s.", err)
+		}
+
+		rows := checkCertificates(certs, warning, critical, all, time.Now())
+		utils.PrintTable(rows)
+
+		counts := map[string]int{}
+		for _, row := range rows {
+			counts[row.Status]++
+		}
+		if n := counts[checkStatusCritical] + counts[checkStatusExpired]; n > 0 {
+			utils.CliErrorWithExitCode(checkExitCritical, "%d certificates have expired or expire within %s.", n, criticalRaw)
+		}
+		if n := counts[checkStatusWarning]; n > 0 {
+			utils.CliWarning("%d certificates expire within %s.", n, warningRaw)
+			os.Exit(checkExitWarning)
+		}
+	},
+}
+
+func init() {
+	certCheckCmd.Flags().String("warning", "30d", "Warn about certificates expiring within this long")
+	certCheckCmd.Flags().String("critical", "7d", "Fail on certificates expiring within this long")
+	certCheckCmd.Flags().Bool("all", false, "Also list revoked and renewed certificates")
+}
+
+type checkRow struct {
+	ID         string `json:"id" table:"ID"`
+	CommonName string `json:"common_name" table:"Common Name"`
+	Authority  string `json:"authority"`
+	ExpiresAt  string `json:"expires_at" table:"Expires At"`
+	DaysLeft   int    `json:"days_left" table:"Days Left"`
+	Status     string `json:"status"`
+}
+
+// checkCertificates rates each certificate against the thresholds, soonest
+// expiry first.
+func checkCertificates(certs []certApi.Certificate, warning, critical time.Duration, all bool, now time.Time) []checkRow {
+	rows := []checkRow{}
+	for _, c := range certs {
+		left := c.ExpiresAt.Sub(now)
+		status := checkStatusOK
+		switch {
+		case c.IsRevoked:
+			status = checkStatusRevoked
+		case c.RenewedBy != "":
+			status = checkStatusRenewed
+		case left <= 0:
+			status = checkStatusExpired
+		case left <= critical:
+			status = checkStatusCritical
+		case left <= warning:
+			status = checkStatusWarning
+		}
+		if !all && (status == checkStatusRevoked || status == checkStatusRenewed) {
+			continue
+		}
+		rows = append(rows, checkRow{
+			ID:         c.ID,
+			CommonName: c.CommonName,
+			Authority:  c.Authority.Name,
+			ExpiresAt:  c.ExpiresAt.UTC().Format(time.RFC3339),
+			DaysLeft:   daysLeft(c.ExpiresAt, now),
+			Status:     status,
+		})
+	}
+	// RFC 3339 in UTC sorts as text.
+	slices.SortStableFunc(rows, func(a, b checkRow) int { return strings.Compare(a.ExpiresAt, b.ExpiresAt) })
+	return rows
+}
+
+// daysLeft counts whole days until t, negative once it has passed.
+func daysLeft(t, now time.Time) int {
+	d := t.Sub(now)
+	days := int(d / (24 * time.Hour))
+	if d < 0 && d%(24*time.Hour) != 0 {
+		days--
+	}
+	return days
+}
@@ -0,0 +1,45 @@
+package cert
+
+import (
+	"testing"
+	"time"
+
+	certApi "github.com/alpacax/alpacon-cli/api/cert"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckCertificates(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	certs := []certApi.Certificate{
+		{ID: "ok", CommonName: "ok.com", ExpiresAt: now.Add(90 * day)},
+		{ID: "warn", CommonName: "warn.com", ExpiresAt: now.Add(20 * day)},
+		{ID: "crit", CommonName: "crit.com", ExpiresAt: now.Add(3 * day)},
+		{ID: "expired", CommonName: "old.com", ExpiresAt: now.Add(-36 * time.Hour)},
+		{ID: "revoked", CommonName: "rev.com", ExpiresAt: now.Add(day), IsRevoked: true},
+		{ID: "renewed", CommonName: "ren.com", ExpiresAt: now.Add(day), RenewedBy: "next"},
+	}
+
+	rows := checkCertificates(certs, 30*day, 7*day, false, now)
+	var got [][2]any
+	for _, r := range rows {
+		got = append(got, [2]any{r.ID, r.Status})
+	}
+	assert.Equal(t, [][2]any{
+		{"expired", checkStatusExpired},
+		{"crit", checkStatusCritical},
+		{"warn", checkStatusWarning},
+		{"ok", checkStatusOK},
+	}, got)
+	assert.Equal(t, -2, rows[0].DaysLeft)
+	assert.Equal(t, 3, rows[1].DaysLeft)
+
+	all := checkCertificates(certs, 30*day, 7*day, true, now)
+	assert.Len(t, all, 6)
+	statuses := map[string]string{}
+	for _, r := range all {
+		statuses[r.ID] = r.Status
+	}
+	assert.Equal(t, checkStatusRevoked, statuses["revoked"])
+	assert.Equal(t, checkStatusRenewed, statuses["renewed"])
+}
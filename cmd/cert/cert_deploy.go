@@ -0,0 +1,340 @@
+package cert
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/alpacax/alpacon-cli/api/cert"
+	ftpapi "github.com/alpacax/alpacon-cli/api/ftp"
+	"github.com/alpacax/alpacon-cli/api/iam"
+	"github.com/alpacax/alpacon-cli/api/mfa"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
+	"github.com/alpacax/alpacon-cli/cmd/csr"
+	execCmd "github.com/alpacax/alpacon-cli/cmd/exec"
+	"github.com/alpacax/alpacon-cli/cmd/worksession"
+	"github.com/alpacax/alpacon-cli/config"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+var fileModePattern = regexp.MustCompile(`^[0-7]{3,4}$`)
+
+var certDeployCmd = &cobra.Command{
+	Use:   "deploy CERT_ID --server [USER@]SERVER:DIR",
+	Short: "Install a certificate and its key on servers",
+	Long: `
+	Upload an issued certificate, its chain, and its private key to a directory on
+	one or more servers, set their modes, and run an optional post-deploy command.
+
+	Three files are written to each DIR, named after --name (default: the common name):
+	NAME.crt holds the certificate, NAME.chain.crt the authority's root certificate,
+	and NAME.key the private key, read from --key (default: where csr create wrote it).
+	The key is checked against the certificate before anything is uploaded. Pass
+	--no-key when the key was generated on the server itself.
+
+	Files are uploaded over WebFTP as -u/--username and -g/--groupname, which own
+	them; --mode is then applied with chmod, and --post-deploy runs as the same
+	user, both through exec. The key is uploaded into a new owner-only directory
+	in DIR, given --key-mode there, and only then moved into place, so it never
+	sits at NAME.key with a looser mode. Every step is attached to the active
+	work session, which needs the webftp and command scopes under browser login.
+
+	Servers are deployed to one at a time; the first failure stops the rollout.`,
+	Example: `
+	alpacon cert deploy 550e8400-e29b-41d4-a716-446655440000 --server web-1:/etc/ssl/app
+	alpacon cert deploy 550e8400-e29b-41d4-a716-446655440000 --server root@web-1:/etc/nginx/tls --server root@web-2:/etc/nginx/tls \
+		--name api -g nginx --key-mode 0640 --post-deploy "systemctl reload nginx"
+	`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		certId := args[0]
+		servers, _ := cmd.Flags().GetStringArray("server")
+		username, _ := cmd.Flags().GetString("username")
+		groupname, _ := cmd.Flags().GetString("groupname")
+		name, _ := cmd.Flags().GetString("name")
+		keyPath, _ := cmd.Flags().GetString("key")
+		keyPassphraseEnv, _ := cmd.Flags().GetString("key-passphrase-env")
+		noKey, _ := cmd.Flags().GetBool("no-key")
+		mode, _ := cmd.Flags().GetString("mode")
+		keyMode, _ := cmd.Flags().GetString("key-mode")
+		postDeploy, _ := cmd.Flags().GetString("post-deploy")
+		flagWorkSession, _ := cmd.Flags().GetString("work-session")
+
+		targets, err := parseDeployTargets(servers, username)
+		if err == nil {
+			err = validateDeployFlags(name, mode, keyMode, noKey, keyPath, keyPassphraseEnv)
+		}
+		if err != nil {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+		}
+
+		workSessionID := worksession.ResolveOrExit(flagWorkSession)
+		authMethod := config.ResolveAuthMethod()
+
+		alpaconClient, err := client.NewAlpaconAPIClient()
+		if err != nil {
+			utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
+		}
+
+		certificate, err := cert.GetCertificate(alpaconClient, certId)
+		if err != nil {
+			utils.CliErrorWithExit("Failed to get the certificate: %s.", err)
+		}
+		if certificate.IsRevoked {
+			utils.CliErrorWithExit("Certificate %s is revoked and will not be deployed.", certId)
+		}
+		rootText, err := cert.GetRootCertificate(alpaconClient, certificate.Authority.ID)
+		if err != nil {
+			utils.CliErrorWithExit("Failed to get the authority's root certificate: %s.", err)
+		}
+
+		if name == "" {
+			name = certificate.CommonName
+		}
+		if !noKey && keyPath == "" {
+			keyPath = csr.DefaultKeyPath(certificate.CommonName)
+		}
+		files, cleanup, err := stageDeployFiles(certificate.CrtText, rootText, name, keyPath, csr.KeyPassphrase(keyPassphraseEnv, false))
+		if err != nil {
+			utils.CliErrorWithExit("Failed to prepare the certificate: %s.", err)
+		}
+		defer cleanup()
+
+		deps := realDeployDeps(alpaconClient, groupname, workSessionID, authMethod)
+		for _, target := range targets {
+			if err := runDeploy(target, files, deployOptions{Mode: mode, KeyMode: keyMode, PostDeploy: postDeploy}, deps); err != nil {
+				cleanup()
+				utils.CliErrorWithExit("Failed to deploy to %s: %s.", target, err)
+			}
+			utils.CliSuccess("Certificate deployed to %s", target)
+		}
+	},
+}
+
+func init() {
+	certDeployCmd.Flags().StringArray("server", nil, "Destination as [USER@]SERVER:DIR (repeatable)")
+	certDeployCmd.Flags().StringP("username", "u", "", "User that owns the files and runs the post-deploy command")
+	certDeployCmd.Flags().StringP("groupname", "g", "", "Group that owns the files")
+	certDeployCmd.Flags().String("name", "", "Base name of the deployed files (default: the common name)")
+	certDeployCmd.Flags().StringP("key", "k", "", "Private key to deploy (default: where csr create writes it)")
+	certDeployCmd.Flags().String("key-passphrase-env", "", "Environment variable holding the private key passphrase, if the key is encrypted")
+	certDeployCmd.Flags().Bool("no-key", false, "Deploy only the certificate and chain")
+	certDeployCmd.Flags().String("mode", "0644", "Mode of the certificate and chain files")
+	certDeployCmd.Flags().String("key-mode", "0600", "Mode of the private key file")
+	certDeployCmd.Flags().String("post-deploy", "", "Command to run on each server after the files are in place (e.g. \"systemctl reload nginx\")")
+	certDeployCmd.Flags().String("work-session", "", "Attach the deployment to a work-session (overrides 'work-session use')")
+	_ = certDeployCmd.MarkFlagRequired("server")
+	_ = certDeployCmd.RegisterFlagCompletionFunc("server", complete.RemotePaths)
+	_ = certDeployCmd.RegisterFlagCompletionFunc("work-session", complete.WorkSessions)
+}
+
+type deployTarget struct {
+	Server   string
+	Dir      string
+	Username string
+}
+
+func (t deployTarget) String() string {
+	return t.Server + ":" + t.Dir
+}
+
+type deployFile struct {
+	LocalPath string
+	Name      string
+	Key       bool
+}
+
+type deployOptions struct {
+	Mode       string
+	KeyMode    string
+	PostDeploy string
+}
+
+type deployDeps struct {
+	upload func(target deployTarget, localPath, remotePath string) error
+	run    func(target deployTarget, command string) error
+	// stageName returns the name of the directory the key is staged in.
+	stageName func() string
+}
+
+func realDeployDeps(ac *client.AlpaconClient, groupname, workSessionID, authMethod string) deployDeps {
+	return deployDeps{
+		upload: func(target deployTarget, localPath, remotePath string) error {
+			upload := func() error {
+				return ftpapi.UploadLocalFileAs(ac, localPath, target.Server, remotePath, target.Username, groupname, workSessionID)
+			}
+			err := upload()
+			if err != nil {
+				err = utils.HandleCommonErrors(err, target.Server, utils.ErrorHandlerCallbacks{
+					OnMFARequired: func(srv string) error {
+						return mfa.HandleMFAError(ac, srv)
+					},
+					OnUsernameRequired: func() error {
+						_, err := iam.HandleUsernameRequired()
+						return err
+					},
+					CheckMFACompleted: func() (bool, error) {
+						return mfa.CheckMFACompletion(ac)
+					},
+					RefreshToken:   ac.RefreshToken,
+					RetryOperation: upload,
+				})
+			}
+			utils.HandleWorkSessionError(err, "webftp", target.Server, authMethod, workSessionID)
+			return err
+		},
+		run: func(target deployTarget, command string) error {
+			err := execCmd.RunCommandWithRetry(ac, target.Server, command, target.Username, groupname, nil, workSessionID, os.Stdout)
+			utils.HandleWorkSessionError(err, "command", target.Server, authMethod, workSessionID)
+			return err
+		},
+		stageName: func() string {
+			b := make([]byte, 8)
+			_, _ = rand.Read(b)
+			return ".alpacon-deploy-" + hex.EncodeToString(b)
+		},
+	}
+}
+
+// parseDeployTargets reads each --server value; -u wins over a USER@ prefix,
+// as in cp and edit.
+func parseDeployTargets(values []string, usernameFlag string) ([]deployTarget, error) {
+	var targets []deployTarget
+	for _, value := range values {
+		if !utils.IsRemoteTarget(value) {
+			return nil, fmt.Errorf("--server %q must be in format [USER@]SERVER:DIR", value)
+		}
+		sshTarget := utils.ParseSSHTarget(value)
+		if sshTarget.Host == "" || sshTarget.Path == "" {
+			return nil, fmt.Errorf("--server %q must include both server and directory", value)
+		}
+		username := usernameFlag
+		if username == "" {
+			username = sshTarget.User
+		}
+		targets = append(targets, deployTarget{Server: sshTarget.Host, Dir: sshTarget.Path, Username: username})
+	}
+	return targets, nil
+}
+
+func validateDeployFlags(name, mode, keyMode string, noKey bool, keyPath, keyPassphraseEnv string) error {
+	if strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("--name %q must be a file name, not a path", name)
+	}
+	if !fileModePattern.MatchString(mode) {
+		return fmt.Errorf("invalid --mode %q: must be an octal mode such as 0644", mode)
+	}
+	if !fileModePattern.MatchString(keyMode) {
+		return fmt.Errorf("invalid --key-mode %q: must be an octal mode such as 0600", keyMode)
+	}
+	if noKey && (keyPath != "" || keyPassphraseEnv != "") {
+		return fmt.Errorf("--key and --key-passphrase-env cannot be combined with --no-key")
+	}
+	return nil
+}
+
+// stageDeployFiles writes the certificate and chain to a private temporary
+// directory and checks that keyPath, when given, belongs to the certificate.
+// The key is uploaded from where it is, as it is.
+func stageDeployFiles(crtText, rootText, name, keyPath string, passphrase func() ([]byte, error)) ([]deployFile, func(), error) {
+	dir, err := os.MkdirTemp("", "alpacon-deploy-")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { _ = os.RemoveAll(dir) }
+
+	files := []deployFile{
+		{LocalPath: filepath.Join(dir, "cert.crt"), Name: name + ".crt"},
+		{LocalPath: filepath.Join(dir, "chain.crt"), Name: name + ".chain.crt"},
+	}
+	for i, text := range []string{crtText, rootText} {
+		if err := os.WriteFile(files[i].LocalPath, []byte(strings.TrimSpace(text)+"\n"), 0600); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	}
+	if keyPath != "" {
+		if err := checkKeyPair(files[0].LocalPath, keyPath, passphrase); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		files = append(files, deployFile{LocalPath: keyPath, Name: name + ".key", Key: true})
+	}
+	return files, cleanup, nil
+}
+
+// runDeploy uploads the files to one server, fixes their modes, and runs the
+// post-deploy command. Keys go through a staging directory only the owner can
+// enter, and reach DIR by rename once their mode is set.
+func runDeploy(target deployTarget, files []deployFile, opts deployOptions, deps deployDeps) error {
+	var stage string
+	for _, f := range files {
+		if f.Key && stage == "" {
+			stage = path.Join(target.Dir, deps.stageName())
+			if err := deps.run(target, "mkdir -m 0700 "+quoteRemotePath(stage)); err != nil {
+				return fmt.Errorf("failed to create %s: %w", stage, err)
+			}
+		}
+	}
+	removeStage := func() {
+		if stage != "" {
+			_ = deps.run(target, "rm -rf "+quoteRemotePath(stage))
+		}
+	}
+
+	for _, f := range files {
+		remotePath := path.Join(target.Dir, f.Name)
+		if f.Key {
+			remotePath = path.Join(stage, f.Name)
+		}
+		if err := deps.upload(target, f.LocalPath, remotePath); err != nil {
+			removeStage()
+			return fmt.Errorf("failed to upload %s: %w", remotePath, err)
+		}
+	}
+	if err := deps.run(target, chmodCommand(target.Dir, stage, files, opts)); err != nil {
+		removeStage()
+		return fmt.Errorf("failed to set file modes: %w", err)
+	}
+	if opts.PostDeploy != "" {
+		if err := deps.run(target, opts.PostDeploy); err != nil {
+			return fmt.Errorf("the files are in place, but the post-deploy command failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// chmodCommand sets the certificates' modes in dir, then each staged key's,
+// before moving the keys into dir and removing the staging directory.
+func chmodCommand(dir, stage string, files []deployFile, opts deployOptions) string {
+	var certs []string
+	var keys []deployFile
+	for _, f := range files {
+		if f.Key {
+			keys = append(keys, f)
+		} else {
+			certs = append(certs, quoteRemotePath(path.Join(dir, f.Name)))
+		}
+	}
+	command := "chmod " + opts.Mode + " " + strings.Join(certs, " ")
+	for _, f := range keys {
+		staged := quoteRemotePath(path.Join(stage, f.Name))
+		command += " && chmod " + opts.KeyMode + " " + staged + " && mv -f " + staged + " " + quoteRemotePath(path.Join(dir, f.Name))
+	}
+	if len(keys) > 0 {
+		command += " && rmdir " + quoteRemotePath(stage)
+	}
+	return command
+}
+
+// quoteRemotePath single-quotes p for the remote shell.
+func quoteRemotePath(p string) string {
+	return "'" + strings.ReplaceAll(p, "'", `'\''`) + "'"
+}
@@ -0,0 +1,131 @@
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDeployTargets(t *testing.T) {
+	targets, err := parseDeployTargets([]string{"web-1:/etc/ssl/app", "root@web-2:/etc/nginx/tls"}, "")
+	require.NoError(t, err)
+	assert.Equal(t, []deployTarget{
+		{Server: "web-1", Dir: "/etc/ssl/app"},
+		{Server: "web-2", Dir: "/etc/nginx/tls", Username: "root"},
+	}, targets)
+
+	targets, err = parseDeployTargets([]string{"root@web-2:/etc/nginx/tls"}, "deploy")
+	require.NoError(t, err)
+	assert.Equal(t, "deploy", targets[0].Username)
+
+	_, err = parseDeployTargets([]string{"/etc/ssl/app"}, "")
+	assert.ErrorContains(t, err, "[USER@]SERVER:DIR")
+}
+
+func TestValidateDeployFlags(t *testing.T) {
+	assert.NoError(t, validateDeployFlags("api", "0644", "600", false, "", ""))
+	assert.ErrorContains(t, validateDeployFlags("tls/api", "0644", "0600", false, "", ""), "must be a file name")
+	assert.ErrorContains(t, validateDeployFlags("", "rw-r--r--", "0600", false, "", ""), "invalid --mode")
+	assert.ErrorContains(t, validateDeployFlags("", "0644", "0999", false, "", ""), "invalid --key-mode")
+	assert.ErrorContains(t, validateDeployFlags("", "0644", "0600", true, "api.key", ""), "--no-key")
+}
+
+func TestStageDeployFiles(t *testing.T) {
+	ca := newTestCA(t, "Test CA")
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{SerialNumber: ca.cert.SerialNumber, NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	keyPath := filepath.Join(t.TempDir(), "api.key")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	files, cleanup, err := stageDeployFiles(string(pemOf(leaf)), string(pemOf(ca.cert)), "api", keyPath, nil)
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	assert.Equal(t, []string{"api.crt", "api.chain.crt", "api.key"}, []string{files[0].Name, files[1].Name, files[2].Name})
+	assert.Equal(t, keyPath, files[2].LocalPath)
+	assert.True(t, files[2].Key)
+	chain, err := os.ReadFile(files[1].LocalPath)
+	require.NoError(t, err)
+	assert.Equal(t, string(pemOf(ca.cert)), string(chain))
+	cleanup()
+	_, err = os.Stat(files[0].LocalPath)
+	assert.True(t, os.IsNotExist(err))
+
+	files, cleanup, err = stageDeployFiles(string(pemOf(leaf)), string(pemOf(ca.cert)), "api", "", nil)
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+	cleanup()
+
+	other := ca.issue(t, 9, time.Now().Add(time.Hour))
+	_, _, err = stageDeployFiles(string(pemOf(other)), string(pemOf(ca.cert)), "api", keyPath, nil)
+	assert.ErrorContains(t, err, "does not match")
+}
+
+func TestRunDeploy(t *testing.T) {
+	files := []deployFile{
+		{LocalPath: "/tmp/x/cert.crt", Name: "api.crt"},
+		{LocalPath: "/tmp/x/chain.crt", Name: "api.chain.crt"},
+		{LocalPath: "/home/me/api.key", Name: "api.key", Key: true},
+	}
+	target := deployTarget{Server: "web-1", Dir: "/etc/ssl/my app", Username: "root"}
+	opts := deployOptions{Mode: "0644", KeyMode: "0640", PostDeploy: "systemctl reload nginx"}
+
+	var steps []string
+	deps := deployDeps{
+		upload: func(target deployTarget, localPath, remotePath string) error {
+			steps = append(steps, "upload "+localPath+" "+remotePath)
+			return nil
+		},
+		run: func(target deployTarget, command string) error {
+			steps = append(steps, "run "+command)
+			return nil
+		},
+		stageName: func() string { return ".stage" },
+	}
+	require.NoError(t, runDeploy(target, files, opts, deps))
+	assert.Equal(t, []string{
+		"run mkdir -m 0700 '/etc/ssl/my app/.stage'",
+		"upload /tmp/x/cert.crt /etc/ssl/my app/api.crt",
+		"upload /tmp/x/chain.crt /etc/ssl/my app/api.chain.crt",
+		"upload /home/me/api.key /etc/ssl/my app/.stage/api.key",
+		"run chmod 0644 '/etc/ssl/my app/api.crt' '/etc/ssl/my app/api.chain.crt'" +
+			" && chmod 0640 '/etc/ssl/my app/.stage/api.key' && mv -f '/etc/ssl/my app/.stage/api.key' '/etc/ssl/my app/api.key'" +
+			" && rmdir '/etc/ssl/my app/.stage'",
+		"run systemctl reload nginx",
+	}, steps)
+
+	steps = nil
+	deps.upload = func(target deployTarget, localPath, remotePath string) error {
+		steps = append(steps, "upload "+remotePath)
+		return errors.New("permission denied")
+	}
+	err := runDeploy(target, files, opts, deps)
+	assert.ErrorContains(t, err, "failed to upload /etc/ssl/my app/api.crt: permission denied")
+	assert.Equal(t, []string{
+		"run mkdir -m 0700 '/etc/ssl/my app/.stage'",
+		"upload /etc/ssl/my app/api.crt",
+		"run rm -rf '/etc/ssl/my app/.stage'",
+	}, steps, "the staging directory is removed")
+}
+
+func TestChmodCommand(t *testing.T) {
+	files := []deployFile{{Name: "it's.crt"}}
+	assert.Equal(t, `chmod 0644 '/tls/it'\''s.crt'`, chmodCommand("/tls", "", files, deployOptions{Mode: "0644", KeyMode: "0600"}))
+}
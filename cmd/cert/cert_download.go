@@ -3,23 +3,37 @@ package cert
 import (
 	"github.com/alpacax/alpacon-cli/api/cert"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/csr"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
 
+var downloadBundle csr.BundleOptions
+
 var certDownloadCmd = &cobra.Command{
 	Use:   "download CERT_ID",
 	Short: "Download a certificate",
 	Long: `
 	Download a certificate from the server and save it to a specified file path. 
 	The path argument should include the file name and extension where the certificate will be stored. 
-	For example, '/path/to/certificate.crt'. The recommended file extension for certificates is '.crt'.`,
+	For example, '/path/to/certificate.crt'. The recommended file extension for certificates is '.crt'.
+
+	--format fullchain appends the authority's root certificate. --format p12
+	bundles the certificate, the root, and the private key into a
+	password-protected PKCS#12 file ('.p12'); jks-compatible-p12 uses the older
+	encryption every Java version reads. The password comes from the variable
+	named by --password-env, or is asked for.`,
 	Example: `
 	alpacon cert download 550e8400-e29b-41d4-a716-446655440000 --out=/path/to/certificate.crt
+	alpacon cert download 550e8400-e29b-41d4-a716-446655440000 --format fullchain --out=/etc/nginx/tls/api.pem
+	alpacon cert download 550e8400-e29b-41d4-a716-446655440000 --format jks-compatible-p12 --key api.key --out=api.p12
 	`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		certId := args[0]
+		if err := downloadBundle.Validate(); err != nil {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+		}
 		filePath, _ := cmd.Flags().GetString("out")
 		if filePath == "" {
 			filePath = promptForCertificate()
@@ -30,9 +44,20 @@ var certDownloadCmd = &cobra.Command{
 			utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
 		}
 
-		err = cert.DownloadCertificate(alpaconClient, certId, filePath)
-		if err != nil {
-			utils.CliErrorWithExit("Failed to download the certificate from authority: %s.", err)
+		if downloadBundle.Format == csr.FormatPEM {
+			err = cert.DownloadCertificate(alpaconClient, certId, filePath)
+			if err != nil {
+				utils.CliErrorWithExit("Failed to download the certificate from authority: %s.", err)
+			}
+		} else {
+			certificate, err := cert.GetCertificate(alpaconClient, certId)
+			if err != nil {
+				utils.CliErrorWithExit("Failed to download the certificate from authority: %s.", err)
+			}
+			err = csr.WriteBundle(alpaconClient, certificate.CrtText, certificate.Authority.ID, certificate.CommonName, filePath, downloadBundle)
+			if err != nil {
+				utils.CliErrorWithExit("Failed to write the certificate: %s.", err)
+			}
 		}
 
 		utils.CliSuccess("Certificate downloaded: %s", filePath)
@@ -42,6 +67,7 @@ var certDownloadCmd = &cobra.Command{
 func init() {
 	var filePath string
 	certDownloadCmd.Flags().StringVarP(&filePath, "out", "o", "", "path where certificate should be stored")
+	csr.AddBundleFlags(certDownloadCmd, &downloadBundle)
 }
 
 func promptForCertificate() string {
@@ -0,0 +1,388 @@
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	certApi "github.com/alpacax/alpacon-cli/api/cert"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+var certInspectCmd = &cobra.Command{
+	Use:   "inspect FILE|CERT_ID",
+	Short: "Show a certificate's details and check its chain and revocation",
+	Long: `Parse a PEM certificate, from a file or by certificate ID, and show its
+subject, SANs, issuer, key type, validity, and fingerprints. Further
+certificates in the file are taken as its chain.
+
+The certificate is then checked against its Alpacon authority: the chain
+must lead to the authority's root, and the authority's CRL must not list
+it. For a file, the authority is found by its issuer unless --authority
+names it. --offline skips both checks.
+
+Exits 1 when the chain does not verify or the certificate is revoked.`,
+	Example: `  alpacon cert inspect /etc/nginx/tls/api.crt
+  alpacon cert inspect 550e8400-e29b-41d4-a716-446655440000
+  alpacon cert inspect api.crt --authority internal-ca
+  alpacon cert inspect api.crt --offline --output json`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		authority, _ := cmd.Flags().GetString("authority")
+		offline, _ := cmd.Flags().GetBool("offline")
+		source := args[0]
+
+		var alpaconClient *client.AlpaconClient
+		connect := func() *client.AlpaconClient {
+			if alpaconClient == nil {
+				var err error
+				alpaconClient, err = client.NewAlpaconAPIClient()
+				if err != nil {
+					utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
+				}
+			}
+			return alpaconClient
+		}
+
+		output := inspectOutput{Source: source}
+		var pemText string
+		if data, err := os.ReadFile(source); err == nil {
+			pemText = string(data)
+		} else if !os.IsNotExist(err) {
+			utils.CliErrorWithExit("Failed to read %s: %s.", source, err)
+		} else {
+			if offline {
+				utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s is not a file; --offline inspects files only.", source)
+			}
+			crt, err := certApi.GetCertificate(connect(), source)
+			if err != nil {
+				utils.CliErrorWithExit("Failed to retrieve the certificate %s: %s.", source, err)
+			}
+			pemText = crt.CrtText
+			if authority == "" {
+				authority = crt.Authority.ID
+				output.Authority = crt.Authority.Name
+			}
+		}
+
+		certs, err := parseCertificates([]byte(pemText))
+		if err != nil {
+			utils.CliErrorWithExit("Failed to parse %s: %s.", source, err)
+		}
+		now := time.Now()
+		for _, c := range certs {
+			output.Certificates = append(output.Certificates, describeCertificate(c, now))
+		}
+
+		if !offline {
+			ac := connect()
+			var root *x509.Certificate
+			if authority != "" {
+				id, err := certApi.GetAuthorityIDByName(ac, authority)
+				if err != nil {
+					utils.CliErrorWithExit("Failed to find the authority %s: %s.", authority, err)
+				}
+				if output.Authority == "" {
+					output.Authority = authority
+				}
+				root, err = authorityRoot(ac, id)
+				if err != nil {
+					utils.CliErrorWithExit("Failed to get the root certificate of %s: %s.", authority, err)
+				}
+				authority = id
+			} else {
+				authority, output.Authority, root, err = findAuthority(ac, certs[len(certs)-1])
+				if err != nil {
+					utils.CliErrorWithExit("Failed to look up the authorities: %s.", err)
+				}
+			}
+
+			output.Chain = verifyChain(certs, root, now)
+			if root != nil {
+				crl, err := certApi.GetCRL(ac, authority)
+				if err != nil {
+					output.Revocation = &checkResult{Detail: fmt.Sprintf("failed to get the CRL: %s", err)}
+				} else {
+					output.Revocation = checkRevocation(certs[0], root, crl.CrlText, now)
+				}
+			}
+		}
+
+		printInspect(output)
+		if output.failed() {
+			utils.CliErrorWithExitCode(utils.ExitCodeGeneralError, "The certificate did not pass inspection.")
+		}
+	},
+}
+
+func init() {
+	certInspectCmd.Flags().String("authority", "", "Authority (name or ID) to check the chain and revocation against")
+	certInspectCmd.Flags().Bool("offline", false, "Only parse the file; skip the chain and revocation checks")
+	_ = certInspectCmd.RegisterFlagCompletionFunc("authority", complete.Authorities)
+}
+
+type inspectOutput struct {
+	Source       string       `json:"source"`
+	Certificates []certInfo   `json:"certificates"`
+	Authority    string       `json:"authority,omitempty"`
+	Chain        *checkResult `json:"chain,omitempty"`
+	Revocation   *checkResult `json:"revocation,omitempty"`
+}
+
+type certInfo struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	SerialNumber       string    `json:"serial_number"`
+	DNSNames           []string  `json:"dns_names"`
+	IPAddresses        []string  `json:"ip_addresses"`
+	KeyType            string    `json:"key_type"`
+	SignatureAlgorithm string    `json:"signature_algorithm"`
+	IsCA               bool      `json:"is_ca"`
+	NotBefore          time.Time `json:"not_before"`
+	NotAfter           time.Time `json:"not_after"`
+	DaysLeft           int       `json:"days_left"`
+	SHA256             string    `json:"sha256_fingerprint"`
+	SHA1               string    `json:"sha1_fingerprint"`
+}
+
+// checkResult is the outcome of the chain or revocation check. A check that
+// could not be made is not OK, but only a failed one fails the inspection.
+type checkResult struct {
+	OK     bool   `json:"ok"`
+	Failed bool   `json:"failed"`
+	Detail string `json:"detail"`
+}
+
+func (o inspectOutput) failed() bool {
+	return (o.Chain != nil && o.Chain.Failed) || (o.Revocation != nil && o.Revocation.Failed)
+}
+
+// parseCertificates reads every certificate in PEM data, in order.
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, c)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM certificate found")
+	}
+	return certs, nil
+}
+
+func describeCertificate(c *x509.Certificate, now time.Time) certInfo {
+	sha256Sum := sha256.Sum256(c.Raw)
+	sha1Sum := sha1.Sum(c.Raw)
+	info := certInfo{
+		Subject:            c.Subject.String(),
+		Issuer:             c.Issuer.String(),
+		SerialNumber:       fingerprint(c.SerialNumber.Bytes()),
+		DNSNames:           c.DNSNames,
+		IPAddresses:        []string{},
+		KeyType:            publicKeyType(c.PublicKey),
+		SignatureAlgorithm: c.SignatureAlgorithm.String(),
+		IsCA:               c.IsCA,
+		NotBefore:          c.NotBefore.UTC(),
+		NotAfter:           c.NotAfter.UTC(),
+		DaysLeft:           daysLeft(c.NotAfter, now),
+		SHA256:             fingerprint(sha256Sum[:]),
+		SHA1:               fingerprint(sha1Sum[:]),
+	}
+	if info.DNSNames == nil {
+		info.DNSNames = []string{}
+	}
+	for _, ip := range c.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+	return info
+}
+
+func publicKeyType(key any) string {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", k.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + k.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	}
+	return fmt.Sprintf("%T", key)
+}
+
+// fingerprint formats bytes as openssl does: colon-separated uppercase hex.
+func fingerprint(b []byte) string {
+	parts := make([]string, len(b))
+	for i, v := range b {
+		parts[i] = strings.ToUpper(hex.EncodeToString([]byte{v}))
+	}
+	return strings.Join(parts, ":")
+}
+
+func authorityRoot(ac *client.AlpaconClient, authorityID string) (*x509.Certificate, error) {
+	crtText, err := certApi.GetRootCertificate(ac, authorityID)
+	if err != nil {
+		return nil, err
+	}
+	certs, err := parseCertificates([]byte(crtText))
+	if err != nil {
+		return nil, err
+	}
+	return certs[0], nil
+}
+
+// findAuthority finds the authority whose root signed top, the last
+// certificate of the chain. It returns empty values when none did.
+func findAuthority(ac *client.AlpaconClient, top *x509.Certificate) (string, string, *x509.Certificate, error) {
+	authorities, err := certApi.GetAuthorityList(ac)
+	if err != nil {
+		return "", "", nil, err
+	}
+	for _, a := range authorities {
+		root, err := authorityRoot(ac, a.ID)
+		if err != nil {
+			utils.CliWarning("Skipping authority %s: %s.", a.Name, err)
+			continue
+		}
+		if top.Equal(root) || top.CheckSignatureFrom(root) == nil {
+			return a.ID, a.Name, root, nil
+		}
+	}
+	return "", "", nil, nil
+}
+
+// verifyChain checks that certs, leaf first, lead to root and are valid now.
+func verifyChain(certs []*x509.Certificate, root *x509.Certificate, now time.Time) *checkResult {
+	if root == nil {
+		return &checkResult{Failed: true, Detail: "not issued by any Alpacon authority"}
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return &checkResult{Failed: true, Detail: err.Error()}
+	}
+	return &checkResult{OK: true, Detail: "valid, up to " + root.Subject.String()}
+}
+
+// checkRevocation looks for leaf in the authority's CRL, once the CRL's own
+// signature checks out against root.
+func checkRevocation(leaf, root *x509.Certificate, crlText string, now time.Time) *checkResult {
+	block, _ := pem.Decode([]byte(crlText))
+	if block == nil || block.Type != "X509 CRL" {
+		return &checkResult{Detail: "the authority returned no PEM CRL"}
+	}
+	crl, err := x509.ParseRevocationList(block.Bytes)
+	if err != nil {
+		return &checkResult{Detail: fmt.Sprintf("failed to parse the CRL: %s", err)}
+	}
+	if err = crl.CheckSignatureFrom(root); err != nil {
+		return &checkResult{Failed: true, Detail: fmt.Sprintf("the CRL's signature is invalid: %s", err)}
+	}
+	for _, entry := range crl.RevokedCertificateEntries {
+		if entry.SerialNumber.Cmp(leaf.SerialNumber) == 0 {
+			return &checkResult{Failed: true, Detail: "revoked on " + entry.RevocationTime.UTC().Format(time.RFC3339)}
+		}
+	}
+	detail := "not revoked (CRL of " + crl.ThisUpdate.UTC().Format(time.RFC3339) + ")"
+	if !crl.NextUpdate.IsZero() && now.After(crl.NextUpdate) {
+		detail += "; the CRL is past its next update, so it may be stale"
+	}
+	return &checkResult{OK: true, Detail: detail}
+}
+
+func printInspect(output inspectOutput) {
+	if utils.OutputFormat == utils.OutputFormatJSON {
+		if err := utils.PrintJSONValue(os.Stdout, output); err != nil {
+			utils.CliErrorWithExit("Failed to marshal the inspection: %s.", err)
+		}
+		return
+	}
+
+	leaf := output.Certificates[0]
+	lines := [][2]string{
+		{"Subject", leaf.Subject},
+		{"DNS names", strings.Join(leaf.DNSNames, ", ")},
+		{"IP addresses", strings.Join(leaf.IPAddresses, ", ")},
+		{"Issuer", leaf.Issuer},
+		{"Serial", leaf.SerialNumber},
+		{"Key", leaf.KeyType},
+		{"Signature", leaf.SignatureAlgorithm},
+		{"Valid from", leaf.NotBefore.Format(time.RFC3339)},
+		{"Valid until", fmt.Sprintf("%s (%d days left)", leaf.NotAfter.Format(time.RFC3339), leaf.DaysLeft)},
+		{"SHA-256", leaf.SHA256},
+		{"SHA-1", leaf.SHA1},
+	}
+	for i, c := range output.Certificates[1:] {
+		lines = append(lines, [2]string{fmt.Sprintf("Chain %d", i+1), fmt.Sprintf("%s (until %s)", c.Subject, c.NotAfter.Format(time.RFC3339))})
+	}
+	if output.Authority != "" {
+		lines = append(lines, [2]string{"Authority", output.Authority})
+	}
+	// Sanitized before the check results are added, whose colors must stay.
+	for i := range lines {
+		lines[i][1] = utils.SanitizeTerminalText(lines[i][1])
+	}
+	if output.Chain != nil {
+		lines = append(lines, [2]string{"Chain check", resultText(output.Chain)})
+	}
+	if output.Revocation != nil {
+		lines = append(lines, [2]string{"Revocation", resultText(output.Revocation)})
+	}
+
+	pad := 0
+	for _, l := range lines {
+		pad = max(pad, len(l[0])+2)
+	}
+	for _, l := range lines {
+		if l[1] == "" {
+			continue
+		}
+		fmt.Printf("%-*s%s\n", pad, l[0]+":", l[1])
+	}
+}
+
+// resultText colors a check's outcome; the detail, which can quote the
+// certificate, is sanitized.
+func resultText(r *checkResult) string {
+	detail := utils.SanitizeTerminalText(r.Detail)
+	switch {
+	case r.OK:
+		return utils.Green("OK") + ", " + detail
+	case r.Failed:
+		return utils.Red("FAILED") + ", " + detail
+	}
+	return utils.Yellow("NOT CHECKED") + ", " + detail
+}
@@ -0,0 +1,162 @@
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	key  *ecdsa.PrivateKey
+	cert *x509.Certificate
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{key: key, cert: cert}
+}
+
+func (ca *testCA) issue(t *testing.T, serial int64, notAfter time.Time) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "api.internal", Organization: []string{"Alpaca"}},
+		DNSNames:     []string{"api.internal"},
+		IPAddresses:  []net.IP{net.ParseIP("10.0.0.5")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func (ca *testCA) crl(t *testing.T, revoked ...int64) string {
+	var entries []x509.RevocationListEntry
+	for _, serial := range revoked {
+		entries = append(entries, x509.RevocationListEntry{SerialNumber: big.NewInt(serial), RevocationTime: time.Now().Add(-time.Minute)})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(1),
+		ThisUpdate:                time.Now().Add(-time.Hour),
+		NextUpdate:                time.Now().Add(24 * time.Hour),
+		RevokedCertificateEntries: entries,
+	}, ca.cert, ca.key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}))
+}
+
+func pemOf(certs ...*x509.Certificate) []byte {
+	var out []byte
+	for _, c := range certs {
+		out = append(out, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})...)
+	}
+	return out
+}
+
+func TestParseAndDescribeCertificate(t *testing.T) {
+	ca := newTestCA(t, "Test CA")
+	leaf := ca.issue(t, 0x1234, time.Now().Add(10*24*time.Hour+time.Hour))
+
+	data := append([]byte("# bundle\n"), pemOf(leaf, ca.cert)...)
+	certs, err := parseCertificates(data)
+	require.NoError(t, err)
+	require.Len(t, certs, 2)
+
+	info := describeCertificate(certs[0], time.Now())
+	assert.Equal(t, "CN=api.internal,O=Alpaca", info.Subject)
+	assert.Equal(t, "CN=Test CA", info.Issuer)
+	assert.Equal(t, "12:34", info.SerialNumber)
+	assert.Equal(t, []string{"api.internal"}, info.DNSNames)
+	assert.Equal(t, []string{"10.0.0.5"}, info.IPAddresses)
+	assert.Equal(t, "ECDSA P-384", info.KeyType)
+	assert.Equal(t, "ECDSA-SHA256", info.SignatureAlgorithm)
+	assert.Equal(t, 10, info.DaysLeft)
+	assert.Len(t, info.SHA256, 32*3-1)
+	assert.Len(t, info.SHA1, 20*3-1)
+
+	_, err = parseCertificates([]byte("nothing here"))
+	assert.ErrorContains(t, err, "no PEM certificate")
+}
+
+func TestVerifyChain(t *testing.T) {
+	ca := newTestCA(t, "Test CA")
+	other := newTestCA(t, "Other CA")
+	now := time.Now()
+	leaf := ca.issue(t, 2, now.Add(24*time.Hour))
+
+	assert.True(t, verifyChain([]*x509.Certificate{leaf}, ca.cert, now).OK)
+
+	result := verifyChain([]*x509.Certificate{leaf}, other.cert, now)
+	assert.True(t, result.Failed)
+
+	result = verifyChain([]*x509.Certificate{leaf}, ca.cert, now.Add(48*time.Hour))
+	assert.True(t, result.Failed)
+	assert.Contains(t, result.Detail, "expired")
+
+	result = verifyChain([]*x509.Certificate{leaf}, nil, now)
+	assert.True(t, result.Failed)
+	assert.Contains(t, result.Detail, "not issued by any Alpacon authority")
+}
+
+func TestCheckRevocation(t *testing.T) {
+	ca := newTestCA(t, "Test CA")
+	other := newTestCA(t, "Other CA")
+	now := time.Now()
+	leaf := ca.issue(t, 7, now.Add(24*time.Hour))
+
+	result := checkRevocation(leaf, ca.cert, ca.crl(t, 3), now)
+	assert.True(t, result.OK)
+	assert.Contains(t, result.Detail, "not revoked")
+
+	result = checkRevocation(leaf, ca.cert, ca.crl(t, 3, 7), now)
+	assert.True(t, result.Failed)
+	assert.Contains(t, result.Detail, "revoked on")
+
+	result = checkRevocation(leaf, ca.cert, other.crl(t), now)
+	assert.True(t, result.Failed)
+	assert.Contains(t, result.Detail, "signature is invalid")
+
+	result = checkRevocation(leaf, ca.cert, "", now)
+	assert.False(t, result.OK)
+	assert.False(t, result.Failed)
+
+	result = checkRevocation(leaf, ca.cert, ca.crl(t), now.Add(48*time.Hour))
+	assert.Contains(t, result.Detail, "may be stale")
+}
+
+func TestResultText_SanitizesDetailOnly(t *testing.T) {
+	text := resultText(&checkResult{Failed: true, Detail: "issuer \x1b]0;title\x07evil"})
+	assert.True(t, strings.HasPrefix(text, utils.Red("FAILED")+", "), "the outcome keeps its color")
+	assert.NotContains(t, text, "\x1b]0;")
+	assert.NotContains(t, text, "\x07")
+}
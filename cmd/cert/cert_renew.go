@@ -0,0 +1,461 @@
+package cert
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"syscall"
+	"time"
+
+	certApi "github.com/alpacax/alpacon-cli/api/cert"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/csr"
+	"github.com/alpacax/alpacon-cli/config"
+	"github.com/alpacax/alpacon-cli/pkg/cert"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+// RenewStateFileName is the default record of renewals awaiting approval,
+// kept in ~/.alpacon.
+const RenewStateFileName = "cert-renew.json"
+
+// renewRetryDelay is how long a denied renewal, or a certificate that did not
+// install, waits before the renewer asks again, so it is not answered by a new
+// request every pass. Errors reaching the server are retried on the next pass.
+const renewRetryDelay = 24 * time.Hour
+
+var (
+	renewWatch    string
+	renewInterval string
+	renewOnce     bool
+	renewState    string
+)
+
+var certRenewCmd = &cobra.Command{
+	Use:   "renew",
+	Short: "Renew certificates before they expire",
+	Long: `Keep the certificates listed in a file fresh. On every pass, each certificate
+on disk is checked, and once it is within renew_before of expiry (or missing),
+a new sign request and CSR are made. When the request is approved and
+signed, the certificate is downloaded, checked against its key, and swapped
+into place with the key, then the reload command runs.
+
+Approval can take longer than a pass: the request is remembered in a state
+file and picked up on the next pass, also across restarts. A denied request
+is retried after a day.
+
+Certificates file:
+  renew_before: 30d                # default for every certificate
+  certificates:
+    - name: api                    # default: the first domain or IP
+      domains: [api.internal]
+      ips: [10.0.0.5]
+      valid_days: 90               # default 365
+      cert: /etc/nginx/tls/api.crt
+      key: /etc/nginx/tls/api.key
+      key_type: ecdsa-p256         # rsa2048 (default), rsa4096, ecdsa-p384, ed25519
+      reuse_key: false             # true keeps the key; by default each renewal makes a new one
+      key_passphrase_env: API_KEY_PASS  # encrypt new keys with this variable's value
+      renew_before: 14d
+      reload: systemctl reload nginx
+
+A reload command shared by several certificates runs once per pass. Run
+under cron with --once, or as a service without it.`,
+	Example: `  alpacon cert renew --watch certs.yaml
+  alpacon cert renew --watch certs.yaml --interval 6h
+  alpacon cert renew --watch certs.yaml --once`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if renewWatch == "" {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "--watch is required.")
+		}
+		interval, err := utils.ParsePositiveDuration("--interval", renewInterval)
+		if err != nil {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+		}
+		entries, err := readRenewFile(renewWatch)
+		if err != nil {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "Failed to read %s: %s.", renewWatch, err)
+		}
+		statePath := renewState
+		if statePath == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				utils.CliErrorWithExit("Failed to find the home directory: %s. Pass --state.", err)
+			}
+			statePath = filepath.Join(home, config.ConfigFileDir, RenewStateFileName)
+		}
+		state, err := readRenewState(statePath)
+		if err != nil {
+			utils.CliErrorWithExit("Failed to read the state file %s: %s.", statePath, err)
+		}
+
+		ac, err := client.NewAlpaconAPIClient()
+		if err != nil {
+			utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
+		}
+		csr.EnsureSecureConnection(ac)
+
+		r := &renewer{ac: ac, state: state, statePath: statePath, now: time.Now, runHook: runReloadHook}
+		if err = r.run(entries, interval, renewOnce); err != nil {
+			utils.CliErrorWithExit("%s.", err)
+		}
+	},
+}
+
+func init() {
+	certRenewCmd.Flags().StringVar(&renewWatch, "watch", "", "File of certificates to keep renewed (YAML)")
+	certRenewCmd.Flags().StringVar(&renewInterval, "interval", "1h", "How often to check the certificates")
+	certRenewCmd.Flags().BoolVar(&renewOnce, "once", false, "Check once and exit instead of watching")
+	certRenewCmd.Flags().StringVar(&renewState, "state", "", "State file for pending renewals (default ~/.alpacon/"+RenewStateFileName+")")
+}
+
+// pendingRenewal is a certificate's renewal in flight, or its last failure.
+type pendingRenewal struct {
+	CSRID       string     `json:"csr_id,omitempty"`
+	RequestedAt time.Time  `json:"requested_at,omitzero"`
+	NewKey      bool       `json:"new_key,omitempty"`
+	FailedAt    *time.Time `json:"failed_at,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+func readRenewState(path string) (map[string]pendingRenewal, error) {
+	state := map[string]pendingRenewal{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return state, nil
+	}
+	if err = json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+type renewer struct {
+	ac        *client.AlpaconClient
+	state     map[string]pendingRenewal
+	statePath string
+	now       func() time.Time
+	runHook   func(command string) error
+}
+
+func (r *renewer) run(entries []renewEntry, interval time.Duration, once bool) error {
+	failed := r.pass(entries)
+	if once {
+		if failed > 0 {
+			return fmt.Errorf("%d errors while renewing %d certificates", failed, len(entries))
+		}
+		return nil
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	utils.CliInfo("Watching %d certificates, checking every %s (press Ctrl+C to stop).", len(entries), interval)
+	for {
+		select {
+		case <-sigChan:
+			return nil
+		case <-ticker.C:
+			r.pass(entries)
+		}
+	}
+}
+
+// pass checks every certificate once, runs the reload commands of those
+// renewed, and saves the state. It returns the number that failed.
+func (r *renewer) pass(entries []renewEntry) int {
+	failed := 0
+	var hooks []string
+	for _, e := range entries {
+		renewed, err := r.check(e)
+		if err != nil {
+			utils.CliWarning("%s: %s.", e.Name, err)
+			failed++
+			continue
+		}
+		if renewed && e.Reload != "" && !slices.Contains(hooks, e.Reload) {
+			hooks = append(hooks, e.Reload)
+		}
+	}
+	for _, hook := range hooks {
+		if err := r.runHook(hook); err != nil {
+			utils.CliWarning("Reload command %q failed: %s.", hook, err)
+			failed++
+		}
+	}
+	if err := r.saveState(); err != nil {
+		utils.CliWarning("Failed to save the state file %s: %s.", r.statePath, err)
+	}
+	return failed
+}
+
+// check moves one certificate along: it collects a signed renewal, or
+// starts one when the certificate is due. It reports whether the
+// certificate was replaced.
+func (r *renewer) check(e renewEntry) (bool, error) {
+	now := r.now()
+	pending, ok := r.state[e.Name]
+	if ok && pending.CSRID != "" {
+		return r.collect(e, pending)
+	}
+	if ok && pending.FailedAt != nil && now.Before(pending.FailedAt.Add(renewRetryDelay)) {
+		utils.CliDebug("%s: last renewal failed (%s); retrying after %s.", e.Name, pending.Error, utils.TimeUtils(pending.FailedAt.Add(renewRetryDelay)))
+		return false, nil
+	}
+
+	expiresAt, err := certificateExpiry(e.Cert)
+	if err != nil {
+		return false, err
+	}
+	if expiresAt != nil && now.Add(e.renewBefore).Before(*expiresAt) {
+		utils.CliDebug("%s: valid until %s.", e.Name, utils.TimeUtils(*expiresAt))
+		return false, nil
+	}
+	return false, r.request(e)
+}
+
+// request makes the sign request and CSR. A new key is written beside the
+// current one, which stays in use until the certificate for it arrives. What
+// can be checked locally is checked before the sign request is made, and a
+// failure after it is recorded, so a persistent local fault does not leave a
+// new sign request behind on every pass.
+func (r *renewer) request(e renewEntry) error {
+	opts := keyOptions(e)
+	if opts.Passphrase != nil {
+		if _, err := opts.Passphrase(); err != nil {
+			return r.fail(e, err)
+		}
+	}
+	keyPath := e.Key
+	if !e.ReuseKey {
+		keyPath = pendingKeyPath(e)
+		if err := os.Remove(keyPath); err != nil && !os.IsNotExist(err) {
+			return r.fail(e, err)
+		}
+	}
+
+	response, err := certApi.CreateSignRequest(r.ac, certApi.SignRequest{
+		DomainList: nonNil(e.Domains),
+		IpList:     nonNil(e.IPs),
+		ValidDays:  e.ValidDays,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create the sign request: %w", err)
+	}
+	csrPEM, err := cert.CreateCSR(response, cert.CertificatePath{PrivateKeyPath: keyPath, CSRPath: e.Cert + ".csr"}, opts)
+	if err == nil {
+		err = certApi.SubmitCSR(r.ac, csrPEM, response.SubmitURL)
+		if err != nil {
+			err = fmt.Errorf("failed to submit CSR %s: %w", response.ID, err)
+		}
+	} else {
+		err = fmt.Errorf("failed to create the CSR for sign request %s: %w", response.ID, err)
+	}
+	if err != nil {
+		if !e.ReuseKey {
+			_ = os.Remove(keyPath)
+		}
+		return r.fail(e, err)
+	}
+
+	r.state[e.Name] = pendingRenewal{CSRID: response.ID, RequestedAt: r.now().UTC(), NewKey: !e.ReuseKey}
+	utils.CliInfo("%s: renewal requested (CSR %s); waiting for approval.", e.Name, response.ID)
+	return nil
+}
+
+// collect checks a pending request and, once it is signed, installs the
+// certificate.
+func (r *renewer) collect(e renewEntry, pending pendingRenewal) (bool, error) {
+	body, err := certApi.GetCSRDetail(r.ac, pending.CSRID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check CSR %s: %w", pending.CSRID, err)
+	}
+	var detail certApi.SignRequestDetail
+	if err = json.Unmarshal(body, &detail); err != nil {
+		return false, err
+	}
+
+	switch detail.Status {
+	case "signed":
+	case "denied", "canceled":
+		return false, r.fail(e, fmt.Errorf("CSR %s was %s", pending.CSRID, detail.Status))
+	default:
+		utils.CliInfo("%s: CSR %s is %s; waiting.", e.Name, pending.CSRID, detail.Status)
+		return false, nil
+	}
+
+	if err = r.install(e, pending); err != nil {
+		return false, r.fail(e, err)
+	}
+	delete(r.state, e.Name)
+	utils.CliSuccess("%s: certificate renewed (CSR %s): %s", e.Name, pending.CSRID, e.Cert)
+	return true, nil
+}
+
+// install downloads the certificate beside the current one, checks that it
+// matches its key, and renames both into place, putting the old key back if
+// the certificate cannot follow it.
+func (r *renewer) install(e renewEntry, pending pendingRenewal) error {
+	keyPath := e.Key
+	if pending.NewKey {
+		keyPath = pendingKeyPath(e)
+	}
+	certNext := e.Cert + ".next"
+	defer func() { _ = os.Remove(certNext) }()
+
+	if err := certApi.DownloadCertificateByCSR(r.ac, pending.CSRID, certNext); err != nil {
+		return fmt.Errorf("failed to download the certificate: %w", err)
+	}
+	if err := checkKeyPair(certNext, keyPath, keyOptions(e).Passphrase); err != nil {
+		return err
+	}
+	if !pending.NewKey {
+		if err := os.Rename(certNext, e.Cert); err != nil {
+			return fmt.Errorf("failed to replace the certificate: %w", err)
+		}
+		return nil
+	}
+
+	// The old key is set aside rather than overwritten, so a certificate
+	// that cannot be put in place leaves the old pair as it was.
+	keyPrev := e.Key + ".prev"
+	hadKey := true
+	if err := os.Rename(e.Key, keyPrev); os.IsNotExist(err) {
+		hadKey = false
+	} else if err != nil {
+		return fmt.Errorf("failed to set the old key aside: %w", err)
+	}
+	restore := func() {
+		_ = os.Rename(e.Key, keyPath)
+		if hadKey {
+			_ = os.Rename(keyPrev, e.Key)
+		}
+	}
+	if err := os.Rename(keyPath, e.Key); err != nil {
+		restore()
+		return fmt.Errorf("failed to replace the key: %w", err)
+	}
+	if err := os.Rename(certNext, e.Cert); err != nil {
+		restore()
+		return fmt.Errorf("failed to replace the certificate: %w", err)
+	}
+	_ = os.Remove(keyPrev)
+	return nil
+}
+
+// fail records a failed renewal so it is retried after renewRetryDelay, and
+// returns err.
+func (r *renewer) fail(e renewEntry, err error) error {
+	if r.state[e.Name].NewKey {
+		_ = os.Remove(pendingKeyPath(e))
+	}
+	failedAt := r.now().UTC()
+	r.state[e.Name] = pendingRenewal{FailedAt: &failedAt, Error: err.Error()}
+	return err
+}
+
+func (r *renewer) saveState() error {
+	data, err := json.MarshalIndent(r.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = utils.SaveStreamAtomic(r.statePath, bytes.NewReader(append(data, '\n')), 0600)
+	return err
+}
+
+func keyOptions(e renewEntry) cert.KeyOptions {
+	opts := cert.KeyOptions{Type: e.keyType, RequireType: !e.ReuseKey}
+	if e.KeyPassphraseEnv != "" {
+		opts.Encrypt = true
+		opts.Passphrase = csr.KeyPassphrase(e.KeyPassphraseEnv, false)
+	}
+	return opts
+}
+
+func pendingKeyPath(e renewEntry) string {
+	return e.Key + ".next"
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// certificateExpiry reads the expiry of the certificate at path, or nil if
+// there is none yet.
+func certificateExpiry(path string) (*time.Time, error) {
+	crt, err := readCertificate(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &crt.NotAfter, nil
+}
+
+func readCertificate(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no PEM certificate found in %s", path)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// checkKeyPair makes sure a certificate was issued for the key, so a service
+// is never reloaded with a pair it cannot use.
+func checkKeyPair(certPath, keyPath string, passphrase func() ([]byte, error)) error {
+	crt, err := readCertificate(certPath)
+	if err != nil {
+		return err
+	}
+	key, err := cert.ReadPrivateKey(keyPath, passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to read the key %s: %w", keyPath, err)
+	}
+	public, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !public.Equal(crt.PublicKey) {
+		return errors.New("the issued certificate does not match the key")
+	}
+	return nil
+}
+
+func runReloadHook(command string) error {
+	var hook *exec.Cmd
+	if runtime.GOOS == "windows" {
+		hook = exec.Command("cmd", "/C", command)
+	} else {
+		hook = exec.Command("sh", "-c", command)
+	}
+	hook.Stdout = os.Stdout
+	hook.Stderr = os.Stderr
+	utils.CliInfo("Running %s", command)
+	return hook.Run()
+}
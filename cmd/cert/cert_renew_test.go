@@ -0,0 +1,339 @@
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	certApi "github.com/alpacax/alpacon-cli/api/cert"
+	"github.com/alpacax/alpacon-cli/client"
+	pkgcert "github.com/alpacax/alpacon-cli/pkg/cert"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRenewFile(t *testing.T) {
+	entries, err := parseRenewFile([]byte(`
+renew_before: 20d
+certificates:
+  - domains: [api.internal]
+    cert: /tls/api.crt
+    key: /tls/api.key
+  - name: db
+    ips: [10.0.0.5]
+    valid_days: 90
+    key_type: ed25519
+    renew_before: 7d
+    cert: /tls/db.crt
+    key: /tls/db.key
+    reload: systemctl reload pgbouncer
+`))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "api.internal", entries[0].Name)
+	assert.Equal(t, 365, entries[0].ValidDays)
+	assert.Equal(t, pkgcert.KeyTypeRSA2048, entries[0].keyType)
+	assert.Equal(t, 20*24*time.Hour, entries[0].renewBefore)
+
+	assert.Equal(t, "db", entries[1].Name)
+	assert.Equal(t, pkgcert.KeyTypeEd25519, entries[1].keyType)
+	assert.Equal(t, 7*24*time.Hour, entries[1].renewBefore)
+}
+
+func TestParseRenewFile_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"empty", ``, "no certificates defined"},
+		{"unknown field", "certificates:\n  - domain: a.com\n", "field domain not found"},
+		{"no san", "certificates:\n  - cert: a.crt\n    key: a.key\n", "at least one domain or IP"},
+		{"no paths", "certificates:\n  - domains: [a.com]\n", "cert and key paths are required"},
+		{"duplicate", "certificates:\n  - {domains: [a.com], cert: a, key: b}\n  - {domains: [a.com], cert: c, key: d}\n", "defined twice"},
+		{"key type", "certificates:\n  - {domains: [a.com], cert: a, key: b, key_type: dsa}\n", "invalid key type"},
+		{"renew window", "certificates:\n  - {domains: [a.com], cert: a, key: b, valid_days: 30, renew_before: 30d}\n", "not shorter than valid_days"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseRenewFile([]byte(tt.yaml))
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+// fakeAuthority serves sign requests and signs submitted CSRs with a test CA
+// once status is set to signed.
+type fakeAuthority struct {
+	t        *testing.T
+	mu       sync.Mutex
+	caKey    *ecdsa.PrivateKey
+	caCert   *x509.Certificate
+	requests int
+	csrText  string
+	status   string
+	notAfter time.Time
+}
+
+func newFakeAuthority(t *testing.T) (*fakeAuthority, *client.AlpaconClient) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(10 * 365 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	fa := &fakeAuthority{t: t, caKey: caKey, caCert: caCert, status: "requested"}
+	ts := httptest.NewServer(http.HandlerFunc(fa.serve))
+	t.Cleanup(ts.Close)
+	return fa, &client.AlpaconClient{HTTPClient: ts.Client(), BaseURL: ts.URL, Username: "tester"}
+}
+
+func (fa *fakeAuthority) serve(w http.ResponseWriter, r *http.Request) {
+	fa.mu.Lock()
+	defer fa.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/api/cert/sign-requests/":
+		var req certApi.SignRequest
+		require.NoError(fa.t, json.NewDecoder(r.Body).Decode(&req))
+		fa.requests++
+		fa.status = "requested"
+		_ = json.NewEncoder(w).Encode(certApi.SignRequestResponse{
+			ID:         "csr-" + string(rune('0'+fa.requests)),
+			CommonName: req.DomainList[0],
+			DomainList: req.DomainList,
+			IpList:     req.IpList,
+			SubmitURL:  "/api/cert/sign-requests/submit/",
+		})
+	case r.Method == http.MethodPatch && r.URL.Path == "/api/cert/sign-requests/submit/":
+		var body certApi.CSRSubmit
+		require.NoError(fa.t, json.NewDecoder(r.Body).Decode(&body))
+		fa.csrText = body.CsrText
+		_, _ = w.Write([]byte("{}"))
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/cert/sign-requests/csr-"):
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/cert/sign-requests/"), "/")
+		detail := certApi.SignRequestDetail{ID: id, Status: fa.status}
+		if fa.status == "signed" {
+			detail.CrtText = fa.sign()
+		}
+		_ = json.NewEncoder(w).Encode(detail)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (fa *fakeAuthority) sign() string {
+	block, _ := pem.Decode([]byte(fa.csrText))
+	require.NotNil(fa.t, block)
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	require.NoError(fa.t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(int64(fa.requests + 1)),
+		Subject:      csr.Subject,
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     fa.notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, fa.caCert, csr.PublicKey, fa.caKey)
+	require.NoError(fa.t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func (fa *fakeAuthority) set(status string) {
+	fa.mu.Lock()
+	defer fa.mu.Unlock()
+	fa.status = status
+}
+
+func TestRenewer(t *testing.T) {
+	fa, ac := newFakeAuthority(t)
+	dir := t.TempDir()
+	entries, err := parseRenewFile([]byte(`
+certificates:
+  - domains: [api.internal]
+    valid_days: 90
+    key_type: ecdsa-p256
+    renew_before: 30d
+    cert: ` + filepath.Join(dir, "api.crt") + `
+    key: ` + filepath.Join(dir, "api.key") + `
+    reload: reload-nginx
+`))
+	require.NoError(t, err)
+	e := entries[0]
+
+	now := time.Now()
+	fa.notAfter = now.Add(90 * 24 * time.Hour)
+	var hooks []string
+	r := &renewer{
+		ac:        ac,
+		state:     map[string]pendingRenewal{},
+		statePath: filepath.Join(dir, "state.json"),
+		now:       func() time.Time { return now },
+		runHook:   func(command string) error { hooks = append(hooks, command); return nil },
+	}
+
+	// No certificate yet: a request is made, and the new key waits aside.
+	assert.Equal(t, 0, r.pass(entries))
+	assert.Equal(t, 1, fa.requests)
+	assert.Equal(t, "csr-1", r.state[e.Name].CSRID)
+	assert.FileExists(t, e.Key+".next")
+	assert.NoFileExists(t, e.Key)
+
+	// Until signed, the renewer waits.
+	assert.Equal(t, 0, r.pass(entries))
+	assert.Equal(t, 1, fa.requests)
+	assert.Empty(t, hooks)
+
+	fa.set("signed")
+	assert.Equal(t, 0, r.pass(entries))
+	assert.Equal(t, []string{"reload-nginx"}, hooks)
+	assert.Empty(t, r.state)
+	assert.NoFileExists(t, e.Key+".next")
+	assert.NoFileExists(t, e.Cert+".next")
+	require.NoError(t, checkKeyPair(e.Cert, e.Key, nil))
+
+	saved, err := readRenewState(r.statePath)
+	require.NoError(t, err)
+	assert.Empty(t, saved)
+
+	// Fresh: nothing to do.
+	assert.Equal(t, 0, r.pass(entries))
+	assert.Equal(t, 1, fa.requests)
+
+	// Within renew_before: renewed again. A denial waits a day.
+	now = now.Add(61 * 24 * time.Hour)
+	assert.Equal(t, 0, r.pass(entries))
+	assert.Equal(t, 2, fa.requests)
+
+	fa.set("denied")
+	assert.Equal(t, 1, r.pass(entries))
+	require.NotNil(t, r.state[e.Name].FailedAt)
+	assert.Contains(t, r.state[e.Name].Error, "csr-2 was denied")
+	assert.NoFileExists(t, e.Key+".next")
+
+	now = now.Add(time.Hour)
+	assert.Equal(t, 0, r.pass(entries))
+	assert.Equal(t, 2, fa.requests)
+
+	now = now.Add(renewRetryDelay)
+	assert.Equal(t, 0, r.pass(entries))
+	assert.Equal(t, 3, fa.requests)
+	assert.Len(t, hooks, 1)
+}
+
+func TestRenewer_LocalFailureIsRecorded(t *testing.T) {
+	fa, ac := newFakeAuthority(t)
+	dir := t.TempDir()
+	// The CSR cannot be saved where a directory stands.
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "blocked.crt.csr"), 0700))
+	t.Setenv("RENEW_TEST_PASSPHRASE", "")
+	entries, err := parseRenewFile([]byte(`
+certificates:
+  - domains: [locked.internal]
+    cert: ` + filepath.Join(dir, "locked.crt") + `
+    key: ` + filepath.Join(dir, "locked.key") + `
+    key_passphrase_env: RENEW_TEST_PASSPHRASE
+  - domains: [blocked.internal]
+    cert: ` + filepath.Join(dir, "blocked.crt") + `
+    key: ` + filepath.Join(dir, "blocked.key") + `
+`))
+	require.NoError(t, err)
+
+	now := time.Now()
+	r := &renewer{
+		ac:        ac,
+		state:     map[string]pendingRenewal{},
+		statePath: filepath.Join(dir, "state.json"),
+		now:       func() time.Time { return now },
+		runHook:   func(string) error { return nil },
+	}
+
+	assert.Equal(t, 2, r.pass(entries))
+	assert.Equal(t, 1, fa.requests, "an unset passphrase is caught before the sign request")
+	for _, e := range entries {
+		require.NotNil(t, r.state[e.Name].FailedAt, e.Name)
+	}
+	assert.Contains(t, r.state[entries[1].Name].Error, "csr-1")
+	assert.NoFileExists(t, entries[1].Key+".next")
+
+	assert.Equal(t, 0, r.pass(entries))
+	assert.Equal(t, 1, fa.requests, "no new sign request until renewRetryDelay")
+}
+
+func TestRenewer_FailedInstallKeepsOldKey(t *testing.T) {
+	fa, ac := newFakeAuthority(t)
+	dir := t.TempDir()
+	entries, err := parseRenewFile([]byte(`
+certificates:
+  - domains: [api.internal]
+    cert: ` + filepath.Join(dir, "api.crt") + `
+    key: ` + filepath.Join(dir, "api.key") + `
+`))
+	require.NoError(t, err)
+	e := entries[0]
+	require.NoError(t, os.WriteFile(e.Key, []byte("old key"), 0600))
+
+	now := time.Now()
+	fa.notAfter = now.Add(90 * 24 * time.Hour)
+	r := &renewer{
+		ac:        ac,
+		state:     map[string]pendingRenewal{},
+		statePath: filepath.Join(dir, "state.json"),
+		now:       func() time.Time { return now },
+		runHook:   func(string) error { return nil },
+	}
+	assert.Equal(t, 0, r.pass(entries))
+
+	// A directory where the certificate goes makes its rename fail.
+	require.NoError(t, os.MkdirAll(filepath.Join(e.Cert, "busy"), 0700))
+	fa.set("signed")
+	assert.Equal(t, 1, r.pass(entries))
+	assert.Contains(t, r.state[e.Name].Error, "failed to replace the certificate")
+
+	data, err := os.ReadFile(e.Key)
+	require.NoError(t, err)
+	assert.Equal(t, "old key", string(data), "the old key is back in place")
+	assert.NoFileExists(t, e.Key+".prev")
+	assert.NoFileExists(t, e.Key+".next")
+}
+
+func TestCheckKeyPair_Mismatch(t *testing.T) {
+	fa, _ := newFakeAuthority(t)
+	dir := t.TempDir()
+	path := pkgcert.CertificatePath{PrivateKeyPath: filepath.Join(dir, "a.key"), CSRPath: filepath.Join(dir, "a.csr")}
+	csrPEM, err := pkgcert.CreateCSR(certApi.SignRequestResponse{CommonName: "a.com", DomainList: []string{"a.com"}}, path, pkgcert.KeyOptions{Type: pkgcert.KeyTypeECDSAP256})
+	require.NoError(t, err)
+	fa.csrText = string(csrPEM)
+	fa.notAfter = time.Now().Add(time.Hour)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.crt"), []byte(fa.sign()), 0644))
+	require.NoError(t, checkKeyPair(filepath.Join(dir, "a.crt"), path.PrivateKeyPath, nil))
+
+	other := filepath.Join(dir, "b.key")
+	_, err = pkgcert.CreateCSR(certApi.SignRequestResponse{CommonName: "b.com"}, pkgcert.CertificatePath{PrivateKeyPath: other, CSRPath: filepath.Join(dir, "b.csr")}, pkgcert.KeyOptions{Type: pkgcert.KeyTypeECDSAP256})
+	require.NoError(t, err)
+	assert.ErrorContains(t, checkKeyPair(filepath.Join(dir, "a.crt"), other, nil), "does not match the key")
+}
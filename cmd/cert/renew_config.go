@@ -0,0 +1,119 @@
+package cert
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/alpacax/alpacon-cli/pkg/cert"
+	"github.com/alpacax/alpacon-cli/utils"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultRenewBefore = "30d"
+	defaultRenewDays   = 365
+)
+
+// renewFile is the --watch file: the certificates the renewer keeps fresh.
+type renewFile struct {
+	RenewBefore  string       `yaml:"renew_before"`
+	Certificates []renewEntry `yaml:"certificates"`
+}
+
+type renewEntry struct {
+	Name             string   `yaml:"name"`
+	Domains          []string `yaml:"domains"`
+	IPs              []string `yaml:"ips"`
+	ValidDays        int      `yaml:"valid_days"`
+	Cert             string   `yaml:"cert"`
+	Key              string   `yaml:"key"`
+	KeyType          string   `yaml:"key_type"`
+	ReuseKey         bool     `yaml:"reuse_key"`
+	KeyPassphraseEnv string   `yaml:"key_passphrase_env"`
+	RenewBefore      string   `yaml:"renew_before"`
+	Reload           string   `yaml:"reload"`
+
+	keyType     cert.KeyType
+	renewBefore time.Duration
+}
+
+func readRenewFile(path string) ([]renewEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseRenewFile(data)
+}
+
+// parseRenewFile reads and checks the file, filling in defaults: names from
+// the first domain or IP, 365 valid days, RSA 2048 keys, and renewal 30 days
+// before expiry.
+func parseRenewFile(data []byte) ([]renewEntry, error) {
+	var file renewFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if len(file.Certificates) == 0 {
+		return nil, errors.New("no certificates defined")
+	}
+	if file.RenewBefore == "" {
+		file.RenewBefore = defaultRenewBefore
+	}
+
+	seen := map[string]bool{}
+	for i := range file.Certificates {
+		e := &file.Certificates[i]
+		if len(e.Domains) == 0 && len(e.IPs) == 0 {
+			return nil, fmt.Errorf("certificate %d: at least one domain or IP is required", i+1)
+		}
+		if e.Name == "" {
+			e.Name = firstName(e.Domains, e.IPs)
+		}
+		if seen[e.Name] {
+			return nil, fmt.Errorf("certificate %q is defined twice; give one a distinct name", e.Name)
+		}
+		seen[e.Name] = true
+		if e.Cert == "" || e.Key == "" {
+			return nil, fmt.Errorf("certificate %q: cert and key paths are required", e.Name)
+		}
+		if e.ValidDays == 0 {
+			e.ValidDays = defaultRenewDays
+		}
+		if e.ValidDays < 0 {
+			return nil, fmt.Errorf("certificate %q: valid_days must be positive", e.Name)
+		}
+		if e.KeyType == "" {
+			e.KeyType = string(cert.KeyTypeRSA2048)
+		}
+		keyType, err := cert.ParseKeyType(e.KeyType)
+		if err != nil {
+			return nil, fmt.Errorf("certificate %q: %w", e.Name, err)
+		}
+		e.keyType = keyType
+		if e.RenewBefore == "" {
+			e.RenewBefore = file.RenewBefore
+		}
+		if e.renewBefore, err = utils.ParseDayDuration("renew_before", e.RenewBefore); err != nil {
+			return nil, fmt.Errorf("certificate %q: %w", e.Name, err)
+		}
+		// Otherwise every fresh certificate would be due at once, and the
+		// renewer would request one on every pass.
+		if e.renewBefore >= time.Duration(e.ValidDays)*24*time.Hour {
+			return nil, fmt.Errorf("certificate %q: renew_before %s is not shorter than valid_days %d", e.Name, e.RenewBefore, e.ValidDays)
+		}
+	}
+	return file.Certificates, nil
+}
+
+func firstName(domains, ips []string) string {
+	if len(domains) > 0 {
+		return domains[0]
+	}
+	return ips[0]
+}
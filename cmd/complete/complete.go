@@ -0,0 +1,295 @@
+// Package complete provides the dynamic shell completions commands register:
+// server names, work-session and websh session IDs, token, user, group, and
+// authority names, approval IDs, and remote paths. Lookups go to the API once
+// and are cached in ~/.alpacon so repeated tab presses answer without it.
+package complete
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	approvalapi "github.com/alpacax/alpacon-cli/api/approval"
+	"github.com/alpacax/alpacon-cli/api/auth"
+	certapi "github.com/alpacax/alpacon-cli/api/cert"
+	"github.com/alpacax/alpacon-cli/api/iam"
+	"github.com/alpacax/alpacon-cli/api/server"
+	"github.com/alpacax/alpacon-cli/api/websh"
+	wsapi "github.com/alpacax/alpacon-cli/api/worksession"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/config"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+const cacheFileName = "completion-cache.json"
+
+// cacheTTL is how long a looked-up list answers completions before it is
+// fetched again.
+var cacheTTL = time.Minute
+
+// websh session completion offers the newest connectable sessions only.
+const webshSessionTail = 50
+
+// newClient is a var so tests can answer lookups without an API.
+var newClient = client.NewAlpaconAPIClient
+
+// cacheEntry is one cached list of candidates, each "value\tdescription" as
+// cobra takes them.
+type cacheEntry struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Values    []string  `json:"values"`
+}
+
+func cachePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, config.ConfigFileDir, cacheFileName), nil
+}
+
+func loadCache() map[string]cacheEntry {
+	entries := map[string]cacheEntry{}
+	path, err := cachePath()
+	if err != nil {
+		return entries
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return entries
+	}
+	_ = json.Unmarshal(data, &entries)
+	return entries
+}
+
+// storeCache writes entry under key, dropping entries that expired long ago so
+// the file does not grow with every remote directory listed.
+func storeCache(key string, entry cacheEntry) {
+	path, err := cachePath()
+	if err != nil {
+		return
+	}
+	entries := loadCache()
+	for k, e := range entries {
+		if time.Since(e.FetchedAt) > 10*cacheTTL {
+			delete(entries, k)
+		}
+	}
+	entries[key] = entry
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return
+	}
+	_, _ = utils.SaveStreamAtomic(path, bytes.NewReader(data), 0600)
+}
+
+// cacheKey scopes a list to the workspace it came from, so switching
+// workspaces never completes the other one's names.
+func cacheKey(kind string) string {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return kind
+	}
+	return cfg.WorkspaceURL + " " + kind
+}
+
+// cached returns the candidates of kind, fetching them when the cache has none
+// fresher than ttl. A failed fetch falls back to a stale list, if any.
+func cached(kind string, ttl time.Duration, fetch func(ac *client.AlpaconClient) ([]string, error)) []string {
+	key := cacheKey(kind)
+	entry, ok := loadCache()[key]
+	if ok && time.Since(entry.FetchedAt) < ttl {
+		return entry.Values
+	}
+
+	ac, err := newClient()
+	if err != nil {
+		return entry.Values
+	}
+	values, err := fetch(ac)
+	if err != nil {
+		return entry.Values
+	}
+	storeCache(key, cacheEntry{FetchedAt: time.Now(), Values: values})
+	return values
+}
+
+// filter keeps the candidates whose value starts with toComplete.
+func filter(candidates []string, toComplete string) []string {
+	var matched []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, toComplete) {
+			matched = append(matched, c)
+		}
+	}
+	return matched
+}
+
+func candidate(value string, description ...string) string {
+	desc := strings.Join(strings.Fields(strings.Join(description, " ")), " ")
+	if desc == "" {
+		return value
+	}
+	return value + "\t" + desc
+}
+
+func lookup(kind string, fetch func(ac *client.AlpaconClient) ([]string, error)) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		return filter(cached(kind, cacheTTL, fetch), toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// FirstArg completes the first positional argument with fn and nothing after
+// it, for commands that take a single name or ID.
+func FirstArg(fn cobra.CompletionFunc) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return fn(cmd, args, toComplete)
+	}
+}
+
+// CommaSeparated completes the last item of a comma-separated flag value with
+// fn, keeping the items before it.
+func CommaSeparated(fn cobra.CompletionFunc) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		i := strings.LastIndex(toComplete, ",")
+		if i < 0 {
+			return fn(cmd, args, toComplete)
+		}
+		values, directive := fn(cmd, args, toComplete[i+1:])
+		for j := range values {
+			values[j] = toComplete[:i+1] + values[j]
+		}
+		return values, directive
+	}
+}
+
+var serverNames = lookup("servers", func(ac *client.AlpaconClient) ([]string, error) {
+	servers, err := server.GetServerList(ac)
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	for _, s := range servers {
+		state := "disconnected"
+		if s.Connected {
+			state = "connected"
+		}
+		values = append(values, candidate(s.Name, state, s.OS))
+	}
+	return values, nil
+})
+
+// Servers completes server names, keeping a USER@ prefix as typed.
+func Servers(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	user, host, found := strings.Cut(toComplete, "@")
+	if !found {
+		return serverNames(cmd, args, toComplete)
+	}
+	names, directive := serverNames(cmd, args, host)
+	for i := range names {
+		names[i] = user + "@" + names[i]
+	}
+	return names, directive
+}
+
+// WorkSessions completes the IDs of the current user's work sessions.
+var WorkSessions = lookup("work-sessions", func(ac *client.AlpaconClient) ([]string, error) {
+	user, err := iam.GetCurrentUser(ac)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := wsapi.GetWorkSessionList(ac, "", "", user.ID)
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	for _, s := range sessions {
+		values = append(values, candidate(s.ID, s.Status, s.Description))
+	}
+	return values, nil
+})
+
+// WebshSessions completes the IDs of the newest connectable websh sessions.
+var WebshSessions = lookup("websh-sessions", func(ac *client.AlpaconClient) ([]string, error) {
+	sessions, err := websh.GetSessionList(ac, webshSessionTail)
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	for _, s := range sessions {
+		values = append(values, candidate(s.ID, s.Server, s.Username))
+	}
+	return values, nil
+})
+
+// Tokens completes API token names.
+var Tokens = lookup("tokens", func(ac *client.AlpaconClient) ([]string, error) {
+	tokens, err := auth.GetAPITokenList(ac)
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	for _, t := range tokens {
+		values = append(values, candidate(t.Name, "expires", t.ExpiresAt))
+	}
+	return values, nil
+})
+
+// Users completes usernames.
+var Users = lookup("users", func(ac *client.AlpaconClient) ([]string, error) {
+	users, err := iam.GetUserList(ac)
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	for _, u := range users {
+		values = append(values, candidate(u.Username, u.Name))
+	}
+	return values, nil
+})
+
+// Groups completes group names.
+var Groups = lookup("groups", func(ac *client.AlpaconClient) ([]string, error) {
+	groups, err := iam.GetGroupList(ac)
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	for _, g := range groups {
+		values = append(values, candidate(g.Name, g.DisplayName))
+	}
+	return values, nil
+})
+
+// Authorities completes certificate authority names.
+var Authorities = lookup("authorities", func(ac *client.AlpaconClient) ([]string, error) {
+	authorities, err := certapi.GetAuthorityList(ac)
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	for _, a := range authorities {
+		values = append(values, candidate(a.Name, a.Domain))
+	}
+	return values, nil
+})
+
+// Approvals completes the IDs of pending approval requests.
+var Approvals = lookup("approvals", func(ac *client.AlpaconClient) ([]string, error) {
+	requests, err := approvalapi.ListApprovalRequests(ac, "pending", "")
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	for _, r := range requests {
+		values = append(values, candidate(r.ID, r.Type, "by", r.RequestedBy))
+	}
+	return values, nil
+})
@@ -0,0 +1,129 @@
+package complete
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/config"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubAPI points the cache at a temp home and answers every client request
+// without an API.
+func stubAPI(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	orig := newClient
+	newClient = func() (*client.AlpaconClient, error) { return &client.AlpaconClient{}, nil }
+	t.Cleanup(func() { newClient = orig })
+}
+
+func TestCached(t *testing.T) {
+	stubAPI(t)
+
+	calls := 0
+	fetch := func(*client.AlpaconClient) ([]string, error) {
+		calls++
+		return []string{"web-1", "web-2"}, nil
+	}
+	assert.Equal(t, []string{"web-1", "web-2"}, cached("servers", time.Minute, fetch))
+	assert.Equal(t, []string{"web-1", "web-2"}, cached("servers", time.Minute, fetch))
+	assert.Equal(t, 1, calls, "a fresh entry answers without fetching")
+
+	cached("servers", 0, fetch)
+	assert.Equal(t, 2, calls, "an expired entry is fetched again")
+
+	failing := func(*client.AlpaconClient) ([]string, error) { return nil, errors.New("offline") }
+	assert.Equal(t, []string{"web-1", "web-2"}, cached("servers", 0, failing), "a failed fetch falls back to the stale list")
+	assert.Nil(t, cached("groups", 0, failing))
+}
+
+func TestLookupFiltersByPrefix(t *testing.T) {
+	stubAPI(t)
+
+	fn := lookup("servers", func(*client.AlpaconClient) ([]string, error) {
+		return []string{candidate("web-1", "connected"), candidate("db-1", "")}, nil
+	})
+	values, directive := fn(&cobra.Command{}, nil, "we")
+	assert.Equal(t, []string{"web-1\tconnected"}, values)
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, directive)
+}
+
+func TestFirstArgAndCommaSeparated(t *testing.T) {
+	fn := func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		return filter([]string{"web-1", "web-2", "db-1"}, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+
+	values, _ := FirstArg(fn)(&cobra.Command{}, nil, "db")
+	assert.Equal(t, []string{"db-1"}, values)
+	values, _ = FirstArg(fn)(&cobra.Command{}, []string{"db-1"}, "")
+	assert.Empty(t, values)
+
+	values, _ = CommaSeparated(fn)(&cobra.Command{}, nil, "db-1,web")
+	assert.Equal(t, []string{"db-1,web-1", "db-1,web-2"}, values)
+}
+
+func TestRemotePaths(t *testing.T) {
+	stubAPI(t)
+	storeCache(cacheKey("servers"), cacheEntry{FetchedAt: time.Now(), Values: []string{"web-1\tconnected"}})
+
+	var listed []string
+	orig := listRemoteDir
+	listRemoteDir = func(_ *client.AlpaconClient, serverName, username, groupname, dir, workSessionID string) (string, error) {
+		listed = append(listed, serverName+"|"+username+"|"+dir)
+		return "nginx/\nhosts\nhostname\n", nil
+	}
+	t.Cleanup(func() { listRemoteDir = orig })
+
+	cmd := &cobra.Command{}
+	cmd.Flags().StringP("username", "u", "", "")
+
+	values, directive := RemotePaths(cmd, nil, "admin@we")
+	assert.Equal(t, []string{"admin@web-1:\tconnected"}, values)
+	assert.Equal(t, cobra.ShellCompDirectiveNoSpace|cobra.ShellCompDirectiveNoFileComp, directive)
+
+	values, directive = RemotePaths(cmd, nil, "./local")
+	assert.Empty(t, values)
+	assert.Equal(t, cobra.ShellCompDirectiveDefault, directive)
+
+	values, _ = RemotePaths(cmd, nil, "admin@web-1:/etc/host")
+	assert.Equal(t, []string{"admin@web-1:/etc/hosts", "admin@web-1:/etc/hostname"}, values)
+
+	require.NoError(t, cmd.Flags().Set("username", "root"))
+	values, _ = RemotePaths(cmd, nil, "web-1:")
+	assert.Equal(t, []string{"web-1:nginx/", "web-1:hosts", "web-1:hostname"}, values)
+
+	assert.Equal(t, []string{"web-1|admin|/etc/", "web-1|root|"}, listed)
+}
+
+func TestRemotePaths_WorkSession(t *testing.T) {
+	stubAPI(t)
+	t.Setenv(config.WorkSessionEnvVar, "ws-pinned")
+
+	var sessions []string
+	orig := listRemoteDir
+	listRemoteDir = func(_ *client.AlpaconClient, serverName, username, groupname, dir, workSessionID string) (string, error) {
+		sessions = append(sessions, workSessionID)
+		return "", nil
+	}
+	t.Cleanup(func() { listRemoteDir = orig })
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("work-session", "", "")
+	RemotePaths(cmd, nil, "web-1:/etc/")
+	require.NoError(t, cmd.Flags().Set("work-session", "ws-flag"))
+	RemotePaths(cmd, nil, "web-1:/var/")
+
+	assert.Equal(t, []string{"ws-pinned", "ws-flag"}, sessions, "a work-session shell's pin, unless --work-session is given")
+}
+
+func TestQuoteRemoteDir(t *testing.T) {
+	assert.Equal(t, "'/var/log/'", quoteRemoteDir("/var/log/"))
+	assert.Equal(t, "~/'my dir/'", quoteRemoteDir("~/my dir/"))
+	assert.Equal(t, "~/", quoteRemoteDir("~/"))
+	assert.Equal(t, `'/tmp/it'\''s/'`, quoteRemoteDir("/tmp/it's/"))
+}
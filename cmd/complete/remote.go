@@ -0,0 +1,123 @@
+package complete
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/alpacax/alpacon-cli/api/event"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/config"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+// remotePathTTL is shorter than cacheTTL: directories change more often than
+// the server list, and a listing is cheap to redo.
+var remotePathTTL = 15 * time.Second
+
+// remoteListTimeout bounds the ls behind a completion; a server that is slow
+// to answer completes nothing rather than stall the shell.
+var remoteListTimeout = 5 * time.Second
+
+// listRemoteDir runs ls for dir on the server; a var so tests can answer it.
+var listRemoteDir = func(ac *client.AlpaconClient, serverName, username, groupname, dir, workSessionID string) (string, error) {
+	command := "ls -1Ap"
+	if dir != "" {
+		command += " -- " + quoteRemoteDir(dir)
+	}
+	var out bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		done <- event.RunCommandStreaming(ac, serverName, command, username, groupname, nil, workSessionID, &out)
+	}()
+	select {
+	case err := <-done:
+		return out.String(), err
+	case <-time.After(remoteListTimeout):
+		return "", errors.New("listing timed out")
+	}
+}
+
+// quoteRemoteDir single-quotes dir for the remote shell, leaving a leading ~/
+// outside the quotes so it still expands to the home directory.
+func quoteRemoteDir(dir string) string {
+	prefix := ""
+	if strings.HasPrefix(dir, "~/") {
+		prefix, dir = "~/", dir[2:]
+		if dir == "" {
+			return prefix
+		}
+	}
+	return prefix + "'" + strings.ReplaceAll(dir, "'", `'\''`) + "'"
+}
+
+// RemotePaths completes [USER@]SERVER:PATH. Before the colon it offers server
+// names followed by a colon; after it, the entries of the remote directory,
+// listed with ls under the command's -u, -g, and --work-session flags or the
+// active work session. An argument that starts like a local path completes
+// as a local file.
+func RemotePaths(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if !utils.IsRemoteTarget(toComplete) {
+		if strings.HasPrefix(toComplete, "/") || strings.HasPrefix(toComplete, ".") || strings.HasPrefix(toComplete, "~") {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		names, _ := Servers(cmd, args, toComplete)
+		if len(names) == 0 {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		for i, name := range names {
+			value, desc, _ := strings.Cut(name, "\t")
+			names[i] = candidate(value+":", desc)
+		}
+		return names, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	}
+
+	target := utils.ParseSSHTarget(toComplete)
+	username := target.User
+	if username == "" {
+		username = flagValue(cmd, "username")
+	}
+	groupname := flagValue(cmd, "groupname")
+	// The listing runs, and is audited, under the session the command itself
+	// would use, which a work-session shell pins through the environment.
+	workSessionID, _ := config.ResolveWorkSession(flagValue(cmd, "work-session"))
+
+	prefix := strings.TrimSuffix(toComplete, target.Path)
+	dir := target.Path[:strings.LastIndex(target.Path, "/")+1]
+	kind := "remote " + target.Host + " " + username + " " + groupname + " " + dir
+	entries := cached(kind, remotePathTTL, func(ac *client.AlpaconClient) ([]string, error) {
+		out, err := listRemoteDir(ac, target.Host, username, groupname, dir, workSessionID)
+		if err != nil {
+			return nil, err
+		}
+		return remoteEntries(out), nil
+	})
+
+	var values []string
+	for _, e := range entries {
+		values = append(values, prefix+dir+e)
+	}
+	return filter(values, toComplete), cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+}
+
+// remoteEntries splits ls -1Ap output into entries, directories ending in /.
+func remoteEntries(out string) []string {
+	var entries []string
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line != "" {
+			entries = append(entries, line)
+		}
+	}
+	return entries
+}
+
+func flagValue(cmd *cobra.Command, name string) string {
+	if cmd.Flags().Lookup(name) == nil {
+		return ""
+	}
+	value, _ := cmd.Flags().GetString(name)
+	return value
+}
@@ -0,0 +1,141 @@
+package csr
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	certApi "github.com/alpacax/alpacon-cli/api/cert"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/pkg/cert"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+// Formats a signed certificate can be downloaded in.
+const (
+	FormatPEM       = "pem"
+	FormatFullchain = "fullchain"
+	FormatP12       = "p12"
+	FormatJKSP12    = "jks-compatible-p12"
+)
+
+// BundleOptions are the flags of a certificate download.
+type BundleOptions struct {
+	Format           string
+	KeyPath          string
+	KeyPassphraseEnv string
+	PasswordEnv      string
+	Alias            string
+}
+
+// AddBundleFlags registers the download format flags on cmd.
+func AddBundleFlags(cmd *cobra.Command, opts *BundleOptions) {
+	cmd.Flags().StringVar(&opts.Format, "format", FormatPEM, "Output format: pem (the certificate), fullchain (with the authority root), p12, or jks-compatible-p12")
+	cmd.Flags().StringVarP(&opts.KeyPath, "key", "k", "", "Private key for p12 output (default: where csr create writes it)")
+	cmd.Flags().StringVar(&opts.KeyPassphraseEnv, "key-passphrase-env", "", "Environment variable holding the private key passphrase, if the key is encrypted")
+	cmd.Flags().StringVar(&opts.PasswordEnv, "password-env", "", "Environment variable holding the p12 password (default: prompt)")
+	cmd.Flags().StringVar(&opts.Alias, "alias", "", "Name of the p12 key entry, which keytool takes as the alias (default: the common name)")
+}
+
+// Validate checks the flags before anything is downloaded.
+func (o BundleOptions) Validate() error {
+	switch o.Format {
+	case FormatPEM, FormatFullchain:
+		if o.KeyPath != "" || o.PasswordEnv != "" || o.Alias != "" || o.KeyPassphraseEnv != "" {
+			return fmt.Errorf("--key, --key-passphrase-env, --password-env, and --alias apply to p12 output only")
+		}
+		return nil
+	case FormatP12, FormatJKSP12:
+		return nil
+	}
+	return fmt.Errorf("invalid format %q: must be pem, fullchain, p12, or jks-compatible-p12", o.Format)
+}
+
+// WriteBundle writes a signed certificate to filePath in the chosen format.
+// fullchain and p12 add the root of the authority that signed it, and p12
+// the private key, which must belong to the certificate.
+func WriteBundle(ac *client.AlpaconClient, crtText, authorityID, commonName, filePath string, opts BundleOptions) error {
+	if opts.Format == FormatPEM {
+		return utils.SaveFile(filePath, []byte(crtText))
+	}
+
+	rootText, err := certApi.GetRootCertificate(ac, authorityID)
+	if err != nil {
+		return fmt.Errorf("failed to get the authority's root certificate: %w", err)
+	}
+	if opts.Format == FormatFullchain {
+		return utils.SaveFile(filePath, []byte(joinPEM(crtText, rootText)))
+	}
+
+	certs, err := parsePEMCertificates(joinPEM(crtText, rootText))
+	if err != nil {
+		return err
+	}
+	keyPath := opts.KeyPath
+	if keyPath == "" {
+		keyPath = DefaultKeyPath(commonName)
+	}
+	key, err := cert.ReadPrivateKey(keyPath, KeyPassphrase(opts.KeyPassphraseEnv, false))
+	if err != nil {
+		return fmt.Errorf("failed to read the private key %s: %w", keyPath, err)
+	}
+	password, err := secretSource(opts.PasswordEnv, "--password-env", "PKCS#12 password", true)()
+	if err != nil {
+		return err
+	}
+	alias := opts.Alias
+	if alias == "" {
+		alias = commonName
+	}
+	p12, err := cert.EncodePKCS12(key, certs, password, cert.PKCS12Options{
+		Legacy:       opts.Format == FormatJKSP12,
+		FriendlyName: alias,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = utils.SaveStreamAtomic(filePath, bytes.NewReader(p12), 0600)
+	return err
+}
+
+// joinPEM concatenates PEM texts, each ending in a newline.
+func joinPEM(texts ...string) string {
+	var b strings.Builder
+	for _, t := range texts {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		b.WriteString(t)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func parsePEMCertificates(text string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	data := []byte(text)
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse a certificate: %w", err)
+		}
+		certs = append(certs, c)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("the server returned no PEM certificate")
+	}
+	return certs, nil
+}
@@ -2,8 +2,10 @@ package csr
 
 import (
 	"fmt"
+	"os"
 	"os/user"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	certApi "github.com/alpacax/alpacon-cli/api/cert"
@@ -26,11 +28,15 @@ const (
 )
 
 var csrFlags struct {
-	domains   string
-	ips       string
-	validDays int
-	keyPath   string
-	csrPath   string
+	domains       string
+	ips           string
+	validDays     int
+	keyPath       string
+	csrPath       string
+	keyType       string
+	csrFile       string
+	encryptKey    bool
+	passphraseEnv string
 }
 
 var csrCreateCmd = &cobra.Command{
@@ -39,13 +45,33 @@ var csrCreateCmd = &cobra.Command{
 	Long: `
 	Generates a new Certificate Signing Request based on provided information,
 	which can then be submitted for signing to a certificate authority.
+
+	Every prompt has a flag; with --domain or --ip, nothing is asked. The key
+	at --key is reused if it exists, otherwise a new one of --key-type is
+	generated. With --csr, a request made elsewhere (e.g. by an HSM or
+	openssl) is submitted as it is, and its SANs are the domains and IPs.
+
+	--encrypt-key writes a new key as encrypted PKCS#8 (PBKDF2, AES-256). The
+	passphrase, also used to read an encrypted existing key, comes from the
+	variable named by --key-passphrase-env, or is asked for.
 	`,
 	Example: `
 	alpacon csr create                                   # interactive
 	alpacon csr create --domain test-cli.alpacax.lab     # non-interactive
 	alpacon csr create -d "a.com,b.com" --valid-days 90
+	alpacon csr create -d api.internal --key-type ecdsa-p256 --key /etc/ssl/private/api.key
+	KEY_PASS=... alpacon csr create -d api.internal --encrypt-key --key-passphrase-env KEY_PASS
+	alpacon csr create --csr ./api.csr --valid-days 90
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
+		keyType, err := cert.ParseKeyType(csrFlags.keyType)
+		if err != nil {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+		}
+		external := cmd.Flags().Changed("csr")
+		if external && (cmd.Flags().Changed("key") || cmd.Flags().Changed("out") || cmd.Flags().Changed("key-type") || csrFlags.encryptKey) {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "--csr submits a request made elsewhere; --key, --out, --key-type, and --encrypt-key do not apply.")
+		}
 
 		alpaconClient, err := client.NewAlpaconAPIClient()
 		if err != nil {
@@ -54,10 +80,11 @@ var csrCreateCmd = &cobra.Command{
 
 		var signRequest certApi.SignRequest
 		var certPath cert.CertificatePath
+		var externalCSR []byte
 
-		nonInteractive := cmd.Flags().Changed("domain") || cmd.Flags().Changed("ip")
+		nonInteractive := cmd.Flags().Changed("domain") || cmd.Flags().Changed("ip") || external
 		if !nonInteractive && (cmd.Flags().Changed("valid-days") || cmd.Flags().Changed("key") || cmd.Flags().Changed("out")) {
-			utils.CliErrorWithExit("--valid-days, --key, and --out require --domain or --ip to be specified.")
+			utils.CliErrorWithExit("--valid-days, --key, and --out require --domain, --ip, or --csr to be specified.")
 		}
 		if nonInteractive {
 			signRequest.DomainList = []string{}
@@ -68,6 +95,12 @@ var csrCreateCmd = &cobra.Command{
 			if csrFlags.ips != "" {
 				signRequest.IpList = splitAndTrim(csrFlags.ips)
 			}
+			if external {
+				signRequest, externalCSR, err = externalSignRequest(csrFlags.csrFile, signRequest)
+				if err != nil {
+					utils.CliErrorWithExit("Invalid CSR file %s: %s.", csrFlags.csrFile, err)
+				}
+			}
 			if len(signRequest.DomainList) == 0 && len(signRequest.IpList) == 0 {
 				utils.CliErrorWithExit("You must provide at least one valid domain or IP address.")
 			}
@@ -76,7 +109,7 @@ var csrCreateCmd = &cobra.Command{
 			commonName := firstOf(signRequest.DomainList, signRequest.IpList)
 			certPath.PrivateKeyPath = csrFlags.keyPath
 			if certPath.PrivateKeyPath == "" {
-				certPath.PrivateKeyPath = filepath.Join(defaultPrivateKeyDir, commonName+".key")
+				certPath.PrivateKeyPath = DefaultKeyPath(commonName)
 			}
 			certPath.CSRPath = csrFlags.csrPath
 			if certPath.CSRPath == "" {
@@ -86,6 +119,23 @@ var csrCreateCmd = &cobra.Command{
 			signRequest, certPath = promptForCert()
 		}
 
+		_, statErr := os.Stat(certPath.PrivateKeyPath)
+		keyOptions := cert.KeyOptions{
+			Type:        keyType,
+			RequireType: cmd.Flags().Changed("key-type"),
+			Encrypt:     csrFlags.encryptKey,
+			// A new key's passphrase is asked twice, so a typo does not lock it.
+			Passphrase: KeyPassphrase(csrFlags.passphraseEnv, csrFlags.encryptKey && statErr != nil),
+		}
+
+		// Checked before the sign request, so a missing passphrase or a key of
+		// the wrong type does not leave a request on the server.
+		if externalCSR == nil {
+			if err = cert.CheckKey(certPath.PrivateKeyPath, keyOptions); err != nil {
+				utils.CliErrorWithExit("Failed to prepare the private key %s: %s.", certPath.PrivateKeyPath, err)
+			}
+		}
+
 		EnsureSecureConnection(alpaconClient)
 
 		response, err := certApi.CreateSignRequest(alpaconClient, signRequest)
@@ -93,9 +143,12 @@ var csrCreateCmd = &cobra.Command{
 			utils.CliErrorWithExit("Failed to send sign request to server: %s.", err)
 		}
 
-		csr, err := cert.CreateCSR(response, certPath)
-		if err != nil {
-			utils.CliErrorWithExit("Failed to create CSR file: %s.", err)
+		csr := externalCSR
+		if csr == nil {
+			csr, err = cert.CreateCSR(response, certPath, keyOptions)
+			if err != nil {
+				utils.CliErrorWithExit("Failed to create CSR file: %s.", err)
+			}
 		}
 
 		err = certApi.SubmitCSR(alpaconClient, csr, response.SubmitURL)
@@ -119,8 +172,91 @@ func init() {
 	csrCreateCmd.Flags().StringVarP(&csrFlags.domains, "domain", "d", "", "Comma-separated domain list (e.g., a.com,b.com)")
 	csrCreateCmd.Flags().StringVarP(&csrFlags.ips, "ip", "i", "", "Comma-separated IP list (e.g., 192.168.1.1)")
 	csrCreateCmd.Flags().IntVar(&csrFlags.validDays, "valid-days", 365, "Certificate validity in days")
-	csrCreateCmd.Flags().StringVarP(&csrFlags.keyPath, "key", "k", "", "Path for the private key file")
+	csrCreateCmd.Flags().StringVarP(&csrFlags.keyPath, "key", "k", "", "Path for the private key file; an existing key there is reused")
 	csrCreateCmd.Flags().StringVarP(&csrFlags.csrPath, "out", "o", "", "Path for the CSR output file")
+	csrCreateCmd.Flags().StringVar(&csrFlags.keyType, "key-type", string(cert.KeyTypeRSA2048), "Type of a new private key: rsa2048, rsa4096, ecdsa-p256, ecdsa-p384, or ed25519")
+	csrCreateCmd.Flags().StringVar(&csrFlags.csrFile, "csr", "", "Submit this existing PEM CSR instead of generating one")
+	csrCreateCmd.Flags().BoolVar(&csrFlags.encryptKey, "encrypt-key", false, "Encrypt a new private key with a passphrase (PKCS#8)")
+	csrCreateCmd.Flags().StringVar(&csrFlags.passphraseEnv, "key-passphrase-env", "", "Environment variable holding the private key passphrase (default: prompt)")
+}
+
+// externalSignRequest reads a CSR made elsewhere. Its SANs become the
+// request's domains and IPs; any given with --domain or --ip must match them,
+// since the authority signs what the CSR names.
+func externalSignRequest(path string, given certApi.SignRequest) (certApi.SignRequest, []byte, error) {
+	request, csrPEM, err := cert.ReadCSR(path)
+	if err != nil {
+		return given, nil, err
+	}
+	fromCSR := certApi.SignRequest{DomainList: request.DNSNames, IpList: []string{}}
+	if fromCSR.DomainList == nil {
+		fromCSR.DomainList = []string{}
+	}
+	for _, ip := range request.IPAddresses {
+		fromCSR.IpList = append(fromCSR.IpList, ip.String())
+	}
+	if len(given.DomainList) > 0 && !sameSet(given.DomainList, fromCSR.DomainList) {
+		return given, nil, fmt.Errorf("its DNS names %v do not match --domain %v", fromCSR.DomainList, given.DomainList)
+	}
+	if len(given.IpList) > 0 && !sameSet(given.IpList, fromCSR.IpList) {
+		return given, nil, fmt.Errorf("its IP addresses %v do not match --ip %v", fromCSR.IpList, given.IpList)
+	}
+	return fromCSR, csrPEM, nil
+}
+
+func sameSet(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(slices.Compact(a), slices.Compact(b))
+}
+
+// KeyPassphrase returns a source for a private key passphrase: the named
+// environment variable, or else a prompt, asked twice when confirm is set.
+// The answer is remembered, so a key read and written in one run asks once.
+func KeyPassphrase(envName string, confirm bool) func() ([]byte, error) {
+	return secretSource(envName, "--key-passphrase-env", "Private key passphrase", confirm)
+}
+
+// DefaultKeyPath is where csr create writes the key of a request for
+// commonName unless told otherwise.
+func DefaultKeyPath(commonName string) string {
+	return filepath.Join(defaultPrivateKeyDir, commonName+".key")
+}
+
+// secretSource reads a secret from the environment variable envName, or
+// prompts for it; envFlag names the flag that sets envName.
+func secretSource(envName, envFlag, label string, confirm bool) func() ([]byte, error) {
+	var cached []byte
+	return func() ([]byte, error) {
+		if cached != nil {
+			return cached, nil
+		}
+		if envName != "" {
+			value, ok := os.LookupEnv(envName)
+			if !ok || value == "" {
+				return nil, fmt.Errorf("environment variable %s is not set", envName)
+			}
+			cached = []byte(value)
+			return cached, nil
+		}
+		if !utils.IsInteractiveShell() {
+			return nil, fmt.Errorf("a %s is needed; set %s in a non-interactive environment", strings.ToLower(label), envFlag)
+		}
+		for {
+			pass := utils.PromptForPassword(label + ": ")
+			if pass == "" {
+				utils.CliWarning("The %s cannot be empty.", strings.ToLower(label))
+				continue
+			}
+			if confirm && utils.PromptForPassword("Confirm "+strings.ToLower(label)+": ") != pass {
+				utils.CliWarning("The entries do not match. Please try again.")
+				continue
+			}
+			cached = []byte(pass)
+			return cached, nil
+		}
+	}
 }
 
 func promptForCert() (certApi.SignRequest, cert.CertificatePath) {
@@ -7,18 +7,31 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var downloadBundle BundleOptions
+
 var csrDownloadCrtCmd = &cobra.Command{
 	Use:   "download-crt CSR_ID",
 	Short: "Download the certificate for a CSR",
 	Long: `
 	Download the signed certificate associated with a CSR.
 	The CSR must be in 'signed' status for the certificate to be available.
-	Use 'alpacon csr ls' to check the status of your CSRs.`,
+	Use 'alpacon csr ls' to check the status of your CSRs.
+
+	--format fullchain appends the authority's root certificate. --format p12
+	bundles the certificate, the root, and the private key csr create wrote
+	into a password-protected PKCS#12 file; jks-compatible-p12 uses the older
+	encryption every Java version reads. The password comes from the variable
+	named by --password-env, or is asked for.`,
 	Example: `
-	alpacon csr download-crt 550e8400-e29b-41d4-a716-446655440000 --out=/path/to/certificate.crt`,
+	alpacon csr download-crt 550e8400-e29b-41d4-a716-446655440000 --out=/path/to/certificate.crt
+	alpacon csr download-crt 550e8400-e29b-41d4-a716-446655440000 --format fullchain --out=/etc/nginx/tls/api.pem
+	P12_PASS=... alpacon csr download-crt 550e8400-e29b-41d4-a716-446655440000 --format p12 --password-env P12_PASS --out=api.p12`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		csrId := args[0]
+		if err := downloadBundle.Validate(); err != nil {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+		}
 		filePath, _ := cmd.Flags().GetString("out")
 		if filePath == "" {
 			filePath = promptForCrtPath()
@@ -29,11 +42,16 @@ var csrDownloadCrtCmd = &cobra.Command{
 			utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
 		}
 
-		err = certApi.DownloadCertificateByCSR(alpaconClient, csrId, filePath)
+		detail, err := certApi.GetSignedCSR(alpaconClient, csrId)
 		if err != nil {
 			utils.CliErrorWithExit("Failed to download the certificate: %s.", err)
 		}
 
+		err = WriteBundle(alpaconClient, detail.CrtText, detail.Authority.ID, detail.CommonName, filePath, downloadBundle)
+		if err != nil {
+			utils.CliErrorWithExit("Failed to write the certificate: %s.", err)
+		}
+
 		utils.CliSuccess("Certificate downloaded: %s", filePath)
 	},
 }
@@ -41,6 +59,7 @@ var csrDownloadCrtCmd = &cobra.Command{
 func init() {
 	var filePath string
 	csrDownloadCrtCmd.Flags().StringVarP(&filePath, "out", "o", "", "path where certificate should be stored")
+	AddBundleFlags(csrDownloadCrtCmd, &downloadBundle)
 }
 
 func promptForCrtPath() string {
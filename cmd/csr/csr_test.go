@@ -1,11 +1,26 @@
 package csr
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	certApi "github.com/alpacax/alpacon-cli/api/cert"
 	"github.com/alpacax/alpacon-cli/client"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCsrCommandStructure(t *testing.T) {
@@ -196,3 +211,132 @@ func TestEnsureSecureConnection_HTTPS(t *testing.T) {
 		EnsureSecureConnection(ac)
 	})
 }
+
+func TestCreateSubcommandKeyFlags(t *testing.T) {
+	cmd, _, err := CsrCmd.Find([]string{"create"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "rsa2048", cmd.Flags().Lookup("key-type").DefValue)
+	for _, name := range []string{"csr", "encrypt-key", "key-passphrase-env"} {
+		assert.NotNil(t, cmd.Flags().Lookup(name), "--%s flag should exist", name)
+	}
+}
+
+func writeTestCSR(t *testing.T, dns []string, ips []net.IP) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:     pkix.Name{CommonName: "ext"},
+		DNSNames:    dns,
+		IPAddresses: ips,
+	}, key)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "ext.csr")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), 0600))
+	return path
+}
+
+func TestExternalSignRequest(t *testing.T) {
+	path := writeTestCSR(t, []string{"a.com", "b.com"}, []net.IP{net.ParseIP("10.0.0.1")})
+
+	req, csrPEM, err := externalSignRequest(path, certApi.SignRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.com", "b.com"}, req.DomainList)
+	assert.Equal(t, []string{"10.0.0.1"}, req.IpList)
+	assert.Contains(t, string(csrPEM), "BEGIN CERTIFICATE REQUEST")
+
+	// Given SANs must name the same set, in any order.
+	_, _, err = externalSignRequest(path, certApi.SignRequest{DomainList: []string{"b.com", "a.com"}, IpList: []string{"10.0.0.1"}})
+	assert.NoError(t, err)
+
+	_, _, err = externalSignRequest(path, certApi.SignRequest{DomainList: []string{"a.com"}})
+	assert.ErrorContains(t, err, "do not match --domain")
+
+	_, _, err = externalSignRequest(path, certApi.SignRequest{IpList: []string{"10.0.0.2"}})
+	assert.ErrorContains(t, err, "do not match --ip")
+}
+
+func TestKeyPassphrase_FromEnv(t *testing.T) {
+	t.Setenv("TEST_KEY_PASS", "s3cret")
+	pass, err := KeyPassphrase("TEST_KEY_PASS", true)()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("s3cret"), pass)
+
+	_, err = KeyPassphrase("TEST_KEY_PASS_UNSET", false)()
+	assert.ErrorContains(t, err, "TEST_KEY_PASS_UNSET is not set")
+}
+
+func TestBundleOptionsValidate(t *testing.T) {
+	assert.NoError(t, BundleOptions{Format: FormatPEM}.Validate())
+	assert.NoError(t, BundleOptions{Format: FormatJKSP12, KeyPath: "a.key", PasswordEnv: "P"}.Validate())
+	assert.ErrorContains(t, BundleOptions{Format: "der"}.Validate(), "invalid format")
+	assert.ErrorContains(t, BundleOptions{Format: FormatFullchain, PasswordEnv: "P"}.Validate(), "p12 output only")
+}
+
+func TestJoinPEM(t *testing.T) {
+	assert.Equal(t, "A\nB\n", joinPEM("A", "\n", "B\n\n"))
+	assert.Equal(t, "", joinPEM())
+}
+
+func TestWriteBundle(t *testing.T) {
+	issue := func(parent *x509.Certificate, parentKey *ecdsa.PrivateKey, name string, isCA bool) (*x509.Certificate, *ecdsa.PrivateKey) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber:          big.NewInt(time.Now().UnixNano()),
+			Subject:               pkix.Name{CommonName: name},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(time.Hour),
+			IsCA:                  isCA,
+			BasicConstraintsValid: true,
+		}
+		if parent == nil {
+			parent, parentKey = template, key
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+		require.NoError(t, err)
+		c, err := x509.ParseCertificate(der)
+		require.NoError(t, err)
+		return c, key
+	}
+	root, rootKey := issue(nil, nil, "Test CA", true)
+	leaf, leafKey := issue(root, rootKey, "api.internal", false)
+	encode := func(c *x509.Certificate) string {
+		return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw}))
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/cert/authorities/ca-1/", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(certApi.AuthorityDetails{ID: "ca-1", CrtText: encode(root)})
+	}))
+	defer ts.Close()
+	ac := &client.AlpaconClient{HTTPClient: ts.Client(), BaseURL: ts.URL}
+	dir := t.TempDir()
+
+	fullchain := filepath.Join(dir, "api.pem")
+	require.NoError(t, WriteBundle(ac, encode(leaf), "ca-1", "api.internal", fullchain, BundleOptions{Format: FormatFullchain}))
+	data, err := os.ReadFile(fullchain)
+	require.NoError(t, err)
+	assert.Equal(t, encode(leaf)+encode(root), string(data))
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(leafKey)
+	require.NoError(t, err)
+	keyPath := filepath.Join(dir, "api.key")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600))
+	t.Setenv("TEST_P12_PASS", "changeit")
+
+	p12 := filepath.Join(dir, "api.p12")
+	opts := BundleOptions{Format: FormatP12, KeyPath: keyPath, PasswordEnv: "TEST_P12_PASS"}
+	require.NoError(t, WriteBundle(ac, encode(leaf), "ca-1", "api.internal", p12, opts))
+	info, err := os.Stat(p12)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	_, otherKey := issue(root, rootKey, "other", false)
+	otherDER, err := x509.MarshalPKCS8PrivateKey(otherKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: otherDER}), 0600))
+	assert.ErrorContains(t, WriteBundle(ac, encode(leaf), "ca-1", "api.internal", p12, opts), "does not match")
+}
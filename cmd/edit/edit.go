@@ -14,6 +14,7 @@ import (
 	"github.com/alpacax/alpacon-cli/api/iam"
 	"github.com/alpacax/alpacon-cli/api/mfa"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/cmd/worksession"
 	"github.com/alpacax/alpacon-cli/config"
 	"github.com/alpacax/alpacon-cli/utils"
@@ -58,7 +59,8 @@ redirections, or '&&' will not work.`,
 	Example: `  alpacon edit my-server:/etc/nginx/nginx.conf
   alpacon edit my-server:/etc/nginx/nginx.conf --editor "code --wait"
   alpacon edit my-server:/var/log/large.txt --force`,
-	Args: cobra.ExactArgs(1),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.RemotePaths),
 	Run: func(cmd *cobra.Command, args []string) {
 		editorFlag, _ := cmd.Flags().GetString("editor")
 		usernameFlag, _ := cmd.Flags().GetString("username")
@@ -133,6 +135,7 @@ func init() {
 	EditCmd.Flags().StringP("username", "u", "", "Specify username")
 	EditCmd.Flags().StringP("groupname", "g", "", "Specify groupname")
 	EditCmd.Flags().String("work-session", "", "Attach this edit to a work-session (overrides 'work-session use')")
+	_ = EditCmd.RegisterFlagCompletionFunc("work-session", complete.WorkSessions)
 }
 
 func realEditDeps(ac *client.AlpaconClient, groupname string) editDeps {
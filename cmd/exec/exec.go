@@ -13,6 +13,7 @@ import (
 	"github.com/alpacax/alpacon-cli/api/iam"
 	"github.com/alpacax/alpacon-cli/api/mfa"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/cmd/worksession"
 	"github.com/alpacax/alpacon-cli/config"
 	"github.com/alpacax/alpacon-cli/utils"
@@ -102,6 +103,7 @@ Requires an active WorkSession when using Browser login (Auth0); Token auth (API
 	// would otherwise be consumed by Cobra's flag parser.
 	// All flags are parsed manually in the Run function.
 	DisableFlagParsing: true,
+	ValidArgsFunction:  completeExecArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		parsed := ParseRemoteExecArgs(args)
 
@@ -255,3 +257,12 @@ func reRunHint(parsed RemoteExecArgs) utils.NextAction {
 	}
 	return action
 }
+
+// completeExecArgs completes the server name when the argument being typed is
+// where ParseRemoteExecArgs expects it; the remote command completes nothing.
+func completeExecArgs(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if ParseRemoteExecArgs(append(args[:len(args):len(args)], "SERVER")).Server != "SERVER" || ParseRemoteExecArgs(args).Server != "" {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return complete.Servers(cmd, args, toComplete)
+}
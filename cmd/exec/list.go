@@ -1,8 +1,14 @@
 package exec
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/alpacax/alpacon-cli/api/event"
+	"github.com/alpacax/alpacon-cli/api/logquery"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
+	logcmd "github.com/alpacax/alpacon-cli/cmd/log"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -14,10 +20,15 @@ var listCmd = &cobra.Command{
 	Long: `List recent remote command executions, most recent first.
 
 Use --tail to limit the number of entries, --server to scope to one server,
-and --user to filter by the requesting user.`,
+and --user to filter by the requesting user. Narrow further with --since/--until
+(a duration such as 15m, an RFC3339 time, or a date) and --match (a regular
+expression over the command and its result), or use -f/--follow to keep
+printing new commands as they are requested.`,
 	Example: `  alpacon exec ls
   alpacon exec ls --tail 10
-  alpacon exec ls --tail 10 --server my-server --user admin`,
+  alpacon exec ls --tail 10 --server my-server --user admin
+  alpacon exec ls --since 1h --match 'systemctl'
+  alpacon exec ls --server my-server -f`,
 	Run: func(cmd *cobra.Command, _ []string) {
 		RunListFromFlags(cmd)
 	},
@@ -35,6 +46,9 @@ func AddListFlags(cmd *cobra.Command) {
 	cmd.Flags().IntP("tail", "t", 25, "Number of command entries to show, newest first")
 	cmd.Flags().StringP("server", "s", "", "Filter by server name")
 	cmd.Flags().StringP("user", "u", "", "Filter by requesting user")
+	_ = cmd.RegisterFlagCompletionFunc("server", complete.Servers)
+	_ = cmd.RegisterFlagCompletionFunc("user", complete.Users)
+	logcmd.AddFilterFlags(cmd)
 }
 
 // RunListFromFlags requires the flag set registered by AddListFlags.
@@ -42,11 +56,12 @@ func RunListFromFlags(cmd *cobra.Command) {
 	tail, _ := cmd.Flags().GetInt("tail")
 	serverName, _ := cmd.Flags().GetString("server")
 	userName, _ := cmd.Flags().GetString("user")
+	filter, interval := logcmd.FilterFromFlags(cmd)
 
-	runList(tail, serverName, userName)
+	runList(tail, serverName, userName, filter, interval)
 }
 
-func runList(tail int, serverName, userName string) {
+func runList(tail int, serverName, userName string, filter logquery.Filter, interval time.Duration) {
 	utils.RequirePositiveInt("tail", tail)
 
 	alpaconClient, err := client.NewAlpaconAPIClient()
@@ -54,10 +69,23 @@ func runList(tail int, serverName, userName string) {
 		utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
 	}
 
-	eventList, err := event.GetEventList(alpaconClient, tail, serverName, userName)
+	endpoint, err := event.EventListEndpoint(alpaconClient, serverName, userName)
 	if err != nil {
 		utils.CliErrorWithExit("Failed to retrieve the commands: %s.", err)
 	}
 
-	utils.PrintTable(eventList)
+	events, err := event.GetEvents(alpaconClient, endpoint, tail, filter)
+	if err = logcmd.WarnIfTruncated(err); err != nil {
+		utils.CliErrorWithExit("Failed to retrieve the commands: %s.", err)
+	}
+
+	if interval > 0 {
+		logcmd.Follow(event.FollowEvents(alpaconClient, endpoint, filter, interval), events, eventLine)
+		return
+	}
+	utils.PrintTable(event.ToEventAttributes(events))
+}
+
+func eventLine(e event.EventDetails) string {
+	return fmt.Sprintf("%s  %s  %s  %s  %s", logcmd.FollowTime(e.AddedAt), e.RequestedBy.Name, e.Server.Name, e.Line, utils.BoolPointerToString(e.Success))
 }
@@ -2,7 +2,9 @@ package exec
 
 import (
 	"testing"
+	"time"
 
+	logcmd "github.com/alpacax/alpacon-cli/cmd/log"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -69,3 +71,15 @@ func TestExecLsRoutesAndParsesFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestExecLsParsesFilterFlags(t *testing.T) {
+	cmd, flags, err := ExecCmd.Find([]string{"ls", "--since", "1h", "--match", "systemctl", "-f", "--interval", "5s"})
+	require.NoError(t, err)
+	require.Equal(t, "ls", cmd.Name())
+	require.NoError(t, cmd.ParseFlags(flags))
+
+	filter, interval := logcmd.FilterFromFlags(cmd)
+	assert.False(t, filter.Since.IsZero())
+	assert.True(t, filter.Match.MatchString("sudo systemctl restart nginx"))
+	assert.Equal(t, 5*time.Second, interval)
+}
@@ -0,0 +1,449 @@
+package iam
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alpacax/alpacon-cli/api/audit"
+	"github.com/alpacax/alpacon-cli/api/auth"
+	"github.com/alpacax/alpacon-cli/api/iam"
+	"github.com/alpacax/alpacon-cli/api/logquery"
+	"github.com/alpacax/alpacon-cli/api/security"
+	"github.com/alpacax/alpacon-cli/api/worksession"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+var iamAccessReviewCmd = &cobra.Command{
+	Use:   "access-review",
+	Short: "Report who can reach which servers, and how, for an access review",
+	Long: `Assemble an access review: for every user, their group memberships with the
+servers each group reaches, the API tokens they own with scopes and ACL rules,
+their recent work sessions with sudo policies, and their last activity in the
+audit log.
+
+Active accounts with no activity for longer than --inactive-days (counted from
+when they joined if they were never active) are flagged as dormant.
+
+Formats (--format):
+  csv    one row per user, lists joined with "; " (the default)
+  html   a self-contained page to attach to a sign-off
+  json   the full report
+
+API tokens are reported under the owner the server gives. When the token list
+names no owners, it holds only the reviewer's own tokens: other users' tokens
+are then marked as not collected (tokens_visible: false in JSON), not as none,
+and a warning is printed.`,
+	Example: `  alpacon iam access-review -f access-review-2026q3.csv
+  alpacon iam access-review --format html -f review.html --inactive-days 60
+  alpacon iam access-review --user alice --user bob --format json`,
+	Args: cobra.NoArgs,
+	Run:  runAccessReview,
+}
+
+func init() {
+	iamAccessReviewCmd.Flags().StringP("file", "f", "", "File to write (default: standard output)")
+	iamAccessReviewCmd.Flags().String("format", "", "Report format: csv, html, or json (default: from the file extension, else csv)")
+	iamAccessReviewCmd.Flags().Int("inactive-days", 90, "Flag active accounts with no activity for longer than this many days (0 to turn off)")
+	iamAccessReviewCmd.Flags().String("sessions-since", "90d", "Include work sessions created since: a duration back from now, an RFC3339 time, or a date")
+	iamAccessReviewCmd.Flags().StringSlice("user", nil, "Review only these users (repeatable)")
+	_ = iamAccessReviewCmd.RegisterFlagCompletionFunc("user", complete.Users)
+}
+
+const (
+	reviewFormatCSV  = "csv"
+	reviewFormatHTML = "html"
+	reviewFormatJSON = "json"
+)
+
+var reviewFormats = []string{reviewFormatCSV, reviewFormatHTML, reviewFormatJSON}
+
+func runAccessReview(cmd *cobra.Command, args []string) {
+	path, _ := cmd.Flags().GetString("file")
+	formatFlag, _ := cmd.Flags().GetString("format")
+	inactiveDays, _ := cmd.Flags().GetInt("inactive-days")
+	sessionsSince, _ := cmd.Flags().GetString("sessions-since")
+	only, _ := cmd.Flags().GetStringSlice("user")
+
+	format, err := reviewFormatFor(path, formatFlag)
+	if err != nil {
+		utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+	}
+	if inactiveDays < 0 {
+		utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "--inactive-days cannot be negative.")
+	}
+	now := time.Now()
+	since, err := logquery.ParseTime("--sessions-since", sessionsSince, now)
+	if err != nil {
+		utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+	}
+
+	alpaconClient, err := client.NewAlpaconAPIClient()
+	if err != nil {
+		utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
+	}
+
+	if err := alpaconClient.LoadCurrentUser(); err != nil {
+		utils.CliErrorWithExit("Failed to load current user: %s", err)
+	}
+	if alpaconClient.Privileges == "general" {
+		utils.CliErrorWithExit("Insufficient permissions to review access. This action requires staff or superuser privileges. Please contact your administrator to request elevated permissions")
+	}
+
+	review, err := collectAccessReview(alpaconClient, reviewOptions{
+		only:          only,
+		inactiveDays:  inactiveDays,
+		sessionsSince: since,
+	}, now)
+	if err != nil {
+		utils.CliErrorWithExit("Failed to assemble the access review: %s.", err)
+	}
+
+	var buf bytes.Buffer
+	if err = writeAccessReview(&buf, review, format); err != nil {
+		utils.CliErrorWithExit("Failed to render the access review: %s.", err)
+	}
+	if path == "" || path == "-" {
+		_, _ = os.Stdout.Write(buf.Bytes())
+	} else if _, err = utils.SaveStreamAtomic(path, &buf, 0o600); err != nil {
+		utils.CliErrorWithExit("Failed to write %s: %s.", path, err)
+	}
+
+	if !review.TokenOwnersKnown {
+		utils.CliWarning("The API token list does not name owners, so only %s's tokens are reported; other users' tokens are marked as not collected.", review.Reviewer)
+	}
+	if dormant := review.dormantCount(); dormant > 0 {
+		utils.CliWarning("%d of %d accounts have been inactive for more than %d days.", dormant, len(review.Users), inactiveDays)
+	}
+	if path != "" && path != "-" {
+		utils.CliSuccess("Access review of %d users written to %s.", len(review.Users), path)
+	}
+}
+
+func reviewFormatFor(path, flag string) (string, error) {
+	if flag != "" {
+		if !slices.Contains(reviewFormats, flag) {
+			return "", fmt.Errorf("invalid --format %q: must be one of %s", flag, strings.Join(reviewFormats, ", "))
+		}
+		return flag, nil
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		return reviewFormatHTML, nil
+	case ".json":
+		return reviewFormatJSON, nil
+	}
+	return reviewFormatCSV, nil
+}
+
+type reviewOptions struct {
+	only          []string
+	inactiveDays  int
+	sessionsSince time.Time
+}
+
+// accessReview is the report. Times are UTC, as in the work-session evidence
+// report: whoever signs off may sit in another zone.
+type accessReview struct {
+	GeneratedAt   time.Time `json:"generated_at"`
+	GeneratedBy   string    `json:"generated_by"`
+	Reviewer      string    `json:"reviewer"`
+	InactiveDays  int       `json:"inactive_days"`
+	SessionsSince time.Time `json:"sessions_since"`
+	// TokenOwnersKnown is false when the token list named no owners, so
+	// only the reviewer's tokens could be attributed.
+	TokenOwnersKnown bool         `json:"token_owners_known"`
+	Users            []userAccess `json:"users"`
+}
+
+type userAccess struct {
+	Username     string        `json:"username"`
+	Name         string        `json:"name"`
+	Email        string        `json:"email"`
+	Status       string        `json:"status"`
+	LDAP         bool          `json:"ldap"`
+	DateJoined   time.Time     `json:"date_joined"`
+	LastActivity *time.Time    `json:"last_activity"`
+	Dormant      bool          `json:"dormant"`
+	Groups       []groupAccess `json:"groups"`
+	Servers      []string      `json:"servers"`
+	Tokens       []tokenAccess `json:"tokens"`
+	// TokensVisible is false when this user's tokens could not be collected;
+	// Tokens is then empty for want of data, not for want of tokens.
+	TokensVisible bool            `json:"tokens_visible"`
+	WorkSessions  []sessionAccess `json:"work_sessions"`
+}
+
+type groupAccess struct {
+	Group   string   `json:"group"`
+	Role    string   `json:"role"`
+	Servers []string `json:"servers"`
+}
+
+type tokenAccess struct {
+	Name       string     `json:"name"`
+	Enabled    bool       `json:"enabled"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	Scopes     []string   `json:"scopes"`
+	CommandACL []string   `json:"command_acl"`
+	ServerACL  []string   `json:"server_acl"`
+	FileACL    []string   `json:"file_acl"`
+}
+
+type sessionAccess struct {
+	ID           string                         `json:"id"`
+	Status       string                         `json:"status"`
+	AddedAt      time.Time                      `json:"added_at"`
+	ExpiresAt    time.Time                      `json:"expires_at"`
+	Scopes       []string                       `json:"scopes"`
+	Servers      []string                       `json:"servers"`
+	SudoPolicies []worksession.SudoPolicyInline `json:"sudo_policies"`
+}
+
+func (r *accessReview) dormantCount() int {
+	n := 0
+	for _, u := range r.Users {
+		if u.Dormant {
+			n++
+		}
+	}
+	return n
+}
+
+// collectAccessReview gathers the report. Workspace-wide lists are fetched
+// once; memberships and last activity take a request per user.
+func collectAccessReview(ac *client.AlpaconClient, opts reviewOptions, now time.Time) (*accessReview, error) {
+	users, err := iam.GetUsers(ac)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	if len(opts.only) > 0 {
+		users = slices.DeleteFunc(users, func(u iam.UserResponse) bool { return !slices.Contains(opts.only, u.Username) })
+		for _, name := range opts.only {
+			if !slices.ContainsFunc(users, func(u iam.UserResponse) bool { return u.Username == name }) {
+				return nil, fmt.Errorf("no user found with the name %s", name)
+			}
+		}
+	}
+	groups, err := iam.GetGroups(ac)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+	groupServers := map[string][]string{}
+	for _, g := range groups {
+		for _, s := range g.Servers {
+			groupServers[g.Name] = append(groupServers[g.Name], s.Name)
+		}
+		sort.Strings(groupServers[g.Name])
+	}
+	tokens, ownersKnown, err := collectTokens(ac)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := collectSessions(ac, opts.sessionsSince)
+	if err != nil {
+		return nil, err
+	}
+
+	review := &accessReview{
+		GeneratedAt:      now.UTC(),
+		GeneratedBy:      fmt.Sprintf("alpacon-cli/%s", utils.GetCLIVersion()),
+		Reviewer:         ac.Username,
+		InactiveDays:     opts.inactiveDays,
+		SessionsSince:    opts.sessionsSince.UTC(),
+		TokenOwnersKnown: ownersKnown,
+		Users:            []userAccess{},
+	}
+	for _, u := range users {
+		entry := userAccess{
+			Username:     u.Username,
+			Name:         strings.TrimSpace(u.FirstName + " " + u.LastName),
+			Email:        u.Email,
+			Status:       u.Status(),
+			LDAP:         u.IsLDAPUser,
+			DateJoined:   u.DateJoined.UTC(),
+			Groups:       []groupAccess{},
+			Servers:      []string{},
+			Tokens:       []tokenAccess{},
+			WorkSessions: []sessionAccess{},
+		}
+
+		memberships, err := iam.GetUserMemberships(ac, u.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the groups of %s: %w", u.Username, err)
+		}
+		servers := map[string]bool{}
+		for _, m := range memberships {
+			entry.Groups = append(entry.Groups, groupAccess{Group: m.Name, Role: m.Role, Servers: groupServers[m.Name]})
+			for _, s := range groupServers[m.Name] {
+				servers[s] = true
+			}
+		}
+		sort.Slice(entry.Groups, func(i, j int) bool { return entry.Groups[i].Group < entry.Groups[j].Group })
+		for s := range servers {
+			entry.Servers = append(entry.Servers, s)
+		}
+		sort.Strings(entry.Servers)
+
+		owner := u.Username
+		entry.TokensVisible = ownersKnown || owner == ac.Username
+		if tokens[owner] != nil {
+			entry.Tokens = tokens[owner]
+		}
+		if sessions[owner] != nil {
+			entry.WorkSessions = sessions[owner]
+		}
+
+		latest, err := audit.GetAuditLogs(ac, map[string]string{"user": u.ID}, 1, logquery.Filter{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the audit log of %s: %w", u.Username, err)
+		}
+		if len(latest) > 0 {
+			at := latest[0].AddedAt.UTC()
+			entry.LastActivity = &at
+		}
+		entry.Dormant = isDormant(u, entry.LastActivity, opts.inactiveDays, now)
+
+		review.Users = append(review.Users, entry)
+	}
+	sort.Slice(review.Users, func(i, j int) bool { return review.Users[i].Username < review.Users[j].Username })
+	return review, nil
+}
+
+// isDormant reports whether an active account has gone unused for longer
+// than days. An account never used counts from when it joined.
+func isDormant(u iam.UserResponse, lastActivity *time.Time, days int, now time.Time) bool {
+	if days == 0 || !u.IsActive {
+		return false
+	}
+	ref := u.DateJoined
+	if lastActivity != nil {
+		ref = *lastActivity
+	}
+	return ref.Before(now.AddDate(0, 0, -days))
+}
+
+// collectTokens returns each owner's tokens with their ACL rules, and whether
+// the list named the owners. A list without them is the reviewer's own, so the
+// tokens of everyone else are unknown rather than absent.
+func collectTokens(ac *client.AlpaconClient) (map[string][]tokenAccess, bool, error) {
+	tokens, err := auth.GetAPITokens(ac)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list API tokens: %w", err)
+	}
+	ownersKnown := len(tokens) > 0
+	byOwner := map[string][]tokenAccess{}
+	for _, t := range tokens {
+		entry := tokenAccess{
+			Name:       t.Name,
+			Enabled:    t.Enabled,
+			Scopes:     t.Scopes,
+			CommandACL: []string{},
+			ServerACL:  []string{},
+			FileACL:    []string{},
+		}
+		if entry.Scopes == nil {
+			entry.Scopes = []string{}
+		}
+		if !t.ExpiresAt.IsZero() {
+			at := t.ExpiresAt.UTC()
+			entry.ExpiresAt = &at
+		}
+
+		commands, err := security.GetCommandAclList(ac, t.ID)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to list the command ACL of token %s: %w", t.Name, err)
+		}
+		for _, c := range commands {
+			entry.CommandACL = append(entry.CommandACL, aclRule(c.Command, c.Username, c.Groupname))
+		}
+		servers, err := security.GetServerAclList(ac, t.ID)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to list the server ACL of token %s: %w", t.Name, err)
+		}
+		for _, s := range servers {
+			entry.ServerACL = append(entry.ServerACL, s.ServerName)
+		}
+		files, err := security.GetFileAclList(ac, t.ID)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to list the file ACL of token %s: %w", t.Name, err)
+		}
+		for _, f := range files {
+			entry.FileACL = append(entry.FileACL, aclRule(f.Action+" "+f.Path, f.Username, f.Groupname))
+		}
+
+		owner := ac.Username
+		if t.User != nil && t.User.Name != "" {
+			owner = t.User.Name
+		} else {
+			ownersKnown = false
+		}
+		byOwner[owner] = append(byOwner[owner], entry)
+	}
+	return byOwner, ownersKnown, nil
+}
+
+// aclRule renders a rule with the account it runs as, like "docker * as root".
+func aclRule(rule, username, groupname string) string {
+	switch {
+	case username != "" && groupname != "":
+		return fmt.Sprintf("%s as %s:%s", rule, username, groupname)
+	case username != "":
+		return fmt.Sprintf("%s as %s", rule, username)
+	case groupname != "":
+		return fmt.Sprintf("%s as :%s", rule, groupname)
+	}
+	return rule
+}
+
+// collectSessions returns each assignee's work sessions created since since,
+// newest first.
+func collectSessions(ac *client.AlpaconClient, since time.Time) (map[string][]sessionAccess, error) {
+	sessions, err := worksession.GetWorkSessions(ac, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list work sessions: %w", err)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].AddedAt.After(sessions[j].AddedAt) })
+
+	byUser := map[string][]sessionAccess{}
+	for _, s := range sessions {
+		if s.AddedAt.Before(since) {
+			continue
+		}
+		user := s.AssignedUser
+		if user == nil {
+			user = s.CreatedBy
+		}
+		if user == nil {
+			continue
+		}
+		entry := sessionAccess{
+			ID:           s.ID,
+			Status:       s.Status,
+			AddedAt:      s.AddedAt.UTC(),
+			ExpiresAt:    s.ExpiresAt.UTC(),
+			Scopes:       s.Scopes,
+			Servers:      []string{},
+			SudoPolicies: s.SudoPolicies,
+		}
+		if entry.Scopes == nil {
+			entry.Scopes = []string{}
+		}
+		if entry.SudoPolicies == nil {
+			entry.SudoPolicies = []worksession.SudoPolicyInline{}
+		}
+		for _, srv := range s.Servers {
+			entry.Servers = append(entry.Servers, srv.Name)
+		}
+		byUser[user.Name] = append(byUser[user.Name], entry)
+	}
+	return byUser, nil
+}
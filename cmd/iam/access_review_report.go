@@ -0,0 +1,195 @@
+package iam
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var reviewCSVColumns = []string{
+	"username", "name", "email", "status", "ldap", "date_joined", "last_activity", "dormant",
+	"groups", "servers", "tokens", "token_acl", "work_sessions", "sudo_policies",
+}
+
+func writeAccessReview(w io.Writer, r *accessReview, format string) error {
+	switch format {
+	case reviewFormatHTML:
+		return accessReviewHTMLTemplate.Execute(w, r)
+	case reviewFormatJSON:
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	default:
+		return writeAccessReviewCSV(w, r)
+	}
+}
+
+// writeAccessReviewCSV writes a row per user, so the file sorts and filters
+// in a spreadsheet; cells holding several entries join them with "; ".
+func writeAccessReviewCSV(w io.Writer, r *accessReview) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write(reviewCSVColumns)
+	for _, u := range r.Users {
+		_ = cw.Write([]string{
+			u.Username,
+			u.Name,
+			u.Email,
+			u.Status,
+			strconv.FormatBool(u.LDAP),
+			reviewTime(u.DateJoined),
+			u.LastActivityText(),
+			strconv.FormatBool(u.Dormant),
+			strings.Join(u.GroupLines(), "; "),
+			strings.Join(u.Servers, "; "),
+			strings.Join(u.TokenLines(), "; "),
+			strings.Join(u.TokenACLLines(), "; "),
+			strings.Join(u.SessionLines(), "; "),
+			strings.Join(u.SudoLines(), "; "),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func reviewTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// The views below are exported so the HTML template can call them.
+
+func (u userAccess) LastActivityText() string {
+	if u.LastActivity == nil {
+		return "never"
+	}
+	return reviewTime(*u.LastActivity)
+}
+
+func (u userAccess) GroupLines() []string {
+	lines := make([]string, 0, len(u.Groups))
+	for _, g := range u.Groups {
+		lines = append(lines, fmt.Sprintf("%s (%s)", g.Group, g.Role))
+	}
+	return lines
+}
+
+// tokensNotCollected stands in for the tokens of a user the token list did
+// not cover, so no report reads it as "owns no tokens".
+const tokensNotCollected = "not collected: the token list names no owners"
+
+func (u userAccess) TokenLines() []string {
+	if !u.TokensVisible {
+		return []string{tokensNotCollected}
+	}
+	lines := make([]string, 0, len(u.Tokens))
+	for _, t := range u.Tokens {
+		line := t.Name
+		if !t.Enabled {
+			line += " [disabled]"
+		}
+		if t.ExpiresAt != nil {
+			line += " expires " + reviewTime(*t.ExpiresAt)
+		}
+		if len(t.Scopes) > 0 {
+			line += " scopes: " + strings.Join(t.Scopes, ", ")
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (u userAccess) TokenACLLines() []string {
+	var lines []string
+	for _, t := range u.Tokens {
+		for _, rule := range t.CommandACL {
+			lines = append(lines, fmt.Sprintf("%s command: %s", t.Name, rule))
+		}
+		for _, server := range t.ServerACL {
+			lines = append(lines, fmt.Sprintf("%s server: %s", t.Name, server))
+		}
+		for _, rule := range t.FileACL {
+			lines = append(lines, fmt.Sprintf("%s file: %s", t.Name, rule))
+		}
+	}
+	return lines
+}
+
+func (u userAccess) SessionLines() []string {
+	lines := make([]string, 0, len(u.WorkSessions))
+	for _, s := range u.WorkSessions {
+		lines = append(lines, fmt.Sprintf("%s %s %s on %s (%s)",
+			s.ID, s.Status, reviewTime(s.AddedAt), strings.Join(s.Servers, ", "), strings.Join(s.Scopes, ", ")))
+	}
+	return lines
+}
+
+func (u userAccess) SudoLines() []string {
+	var lines []string
+	for _, s := range u.WorkSessions {
+		for _, p := range s.SudoPolicies {
+			line := fmt.Sprintf("%s: %s", s.ID, strings.Join(p.Commands, ", "))
+			if p.AllowBypassMFA {
+				line += " [MFA bypass]"
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func (r *accessReview) DormantCount() int { return r.dormantCount() }
+
+// The HTML report is self-contained, like the work-session evidence report,
+// so it renders the same when opened from a sign-off record later.
+var accessReviewHTMLTemplate = template.Must(template.New("review").Funcs(template.FuncMap{
+	"time": reviewTime,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Access review {{time .GeneratedAt}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #1f2328; }
+table { border-collapse: collapse; margin-bottom: 1.5em; width: 100%; }
+th, td { border: 1px solid #d0d7de; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+ul { margin: 0; padding-left: 1.2em; }
+tr.dormant td { background: #fff8c5; }
+.meta { color: #59636e; }
+</style>
+</head>
+<body>
+<h1>Access review</h1>
+<p class="meta">Generated {{time .GeneratedAt}} by {{.GeneratedBy}}{{with .Reviewer}} for {{.}}{{end}}.
+Work sessions since {{time .SessionsSince}}.
+{{- if .InactiveDays}} {{.DormantCount}} of {{len .Users}} accounts, highlighted, have been inactive for more than {{.InactiveDays}} days.{{end}}
+{{- if not .TokenOwnersKnown}} The API token list named no owners, so only the reviewer's tokens were collected; other users' tokens are not shown.{{end}}</p>
+<table>
+<tr><th>User</th><th>Status</th><th>Last activity</th><th>Groups</th><th>Servers</th><th>API tokens</th><th>Work sessions</th><th>Sudo policies</th></tr>
+{{- range .Users}}
+<tr{{if .Dormant}} class="dormant"{{end}}>
+<td><strong>{{.Username}}</strong>{{with .Name}}<br>{{.}}{{end}}{{with .Email}}<br>{{.}}{{end}}</td>
+<td>{{.Status}}{{if .LDAP}} (LDAP){{end}}{{if .Dormant}}<br><strong>dormant</strong>{{end}}</td>
+<td>{{.LastActivityText}}</td>
+<td><ul>{{range .GroupLines}}<li>{{.}}</li>{{end}}</ul></td>
+<td><ul>{{range .Servers}}<li>{{.}}</li>{{end}}</ul></td>
+<td><ul>{{range .TokenLines}}<li>{{.}}</li>{{end}}{{range .TokenACLLines}}<li>{{.}}</li>{{end}}</ul></td>
+<td><ul>{{range .SessionLines}}<li>{{.}}</li>{{end}}</ul></td>
+<td><ul>{{range .SudoLines}}<li>{{.}}</li>{{end}}</ul></td>
+</tr>
+{{- end}}
+</table>
+<p>Reviewed by: ______________________ &nbsp; Date: ____________</p>
+</body>
+</html>
+`))
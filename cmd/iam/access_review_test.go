@@ -0,0 +1,173 @@
+package iam
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alpacax/alpacon-cli/api"
+	"github.com/alpacax/alpacon-cli/api/audit"
+	"github.com/alpacax/alpacon-cli/api/auth"
+	"github.com/alpacax/alpacon-cli/api/iam"
+	"github.com/alpacax/alpacon-cli/api/security"
+	"github.com/alpacax/alpacon-cli/api/types"
+	"github.com/alpacax/alpacon-cli/api/worksession"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var reviewNow = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+// ownerlessTokens is a token list as /api/auth/tokens/ gives it to a reviewer:
+// their own tokens, with no owner named.
+var ownerlessTokens = []auth.APITokenResponse{{ID: "t-1", Name: "deploy", Enabled: true, Scopes: []string{"exec"}}}
+
+func reviewServer(t *testing.T, tokens []auth.APITokenResponse) *httptest.Server {
+	list := func(w http.ResponseWriter, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch r.URL.Path {
+		case "/api/iam/users/":
+			list(w, api.ListResponse[iam.UserResponse]{Count: 2, Results: []iam.UserResponse{
+				{ID: "u-bob", Username: "bob", IsActive: true, DateJoined: reviewNow.AddDate(-1, 0, 0)},
+				{ID: "u-alice", Username: "alice", FirstName: "Alice", LastName: "Kim", IsActive: true, IsStaff: true, DateJoined: reviewNow.AddDate(-1, 0, 0)},
+			}})
+		case "/api/iam/groups/":
+			list(w, api.ListResponse[iam.GroupResponse]{Count: 1, Results: []iam.GroupResponse{
+				{ID: "g-ops", Name: "ops", Servers: []types.ServerSummary{{Name: "web-02"}, {Name: "web-01"}}},
+			}})
+		case "/api/iam/memberships/":
+			var results []iam.MemberDetailResponse
+			if q.Get("user") == "u-alice" {
+				results = []iam.MemberDetailResponse{{GroupName: "ops", Role: roleManager}}
+			}
+			list(w, api.ListResponse[iam.MemberDetailResponse]{Count: len(results), Results: results})
+		case "/api/auth/tokens/":
+			list(w, api.ListResponse[auth.APITokenResponse]{Count: len(tokens), Results: tokens})
+		case "/api/security/command-acl/":
+			list(w, api.ListResponse[security.CommandAclResponse]{Count: 1, Results: []security.CommandAclResponse{{Command: "docker *", Username: "root"}}})
+		case "/api/security/server-acl/", "/api/security/file-acl/":
+			list(w, api.ListResponse[security.FileAclResponse]{})
+		case "/api/work-sessions/sessions/":
+			list(w, api.ListResponse[worksession.WorkSession]{Count: 2, Results: []worksession.WorkSession{
+				{ID: "ws-old", AssignedUser: &types.UserSummary{Name: "alice"}, AddedAt: reviewNow.AddDate(0, -6, 0)},
+				{ID: "ws-1", Status: "active", AssignedUser: &types.UserSummary{Name: "alice"}, AddedAt: reviewNow.AddDate(0, 0, -3),
+					Servers:      []types.ServerSummary{{Name: "web-01"}},
+					SudoPolicies: []worksession.SudoPolicyInline{{Commands: []string{"systemctl restart nginx"}, AllowBypassMFA: true}}},
+			}})
+		case "/api/audit/activity/":
+			var results []audit.AuditLogEntry
+			if q.Get("user") == "u-alice" {
+				results = []audit.AuditLogEntry{{ID: "a-1", AddedAt: reviewNow.AddDate(0, 0, -1)}}
+			}
+			list(w, api.CursorListResponse[audit.AuditLogEntry]{Results: results})
+		default:
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+	}))
+}
+
+func TestCollectAccessReview(t *testing.T) {
+	ts := reviewServer(t, ownerlessTokens)
+	defer ts.Close()
+	ac := &client.AlpaconClient{HTTPClient: ts.Client(), BaseURL: ts.URL, Username: "alice"}
+
+	review, err := collectAccessReview(ac, reviewOptions{inactiveDays: 90, sessionsSince: reviewNow.AddDate(0, 0, -90)}, reviewNow)
+	require.NoError(t, err)
+	require.Len(t, review.Users, 2)
+
+	alice, bob := review.Users[0], review.Users[1]
+	assert.Equal(t, "alice", alice.Username)
+	assert.Equal(t, "staff", alice.Status)
+	assert.Equal(t, []groupAccess{{Group: "ops", Role: roleManager, Servers: []string{"web-01", "web-02"}}}, alice.Groups)
+	assert.Equal(t, []string{"web-01", "web-02"}, alice.Servers)
+	require.Len(t, alice.Tokens, 1, "a token listed without an owner is the reviewer's")
+	assert.Equal(t, []string{"docker * as root"}, alice.Tokens[0].CommandACL)
+	require.Len(t, alice.WorkSessions, 1, "sessions before --sessions-since are left out")
+	assert.Equal(t, "ws-1", alice.WorkSessions[0].ID)
+	assert.False(t, alice.Dormant)
+
+	assert.Nil(t, bob.LastActivity)
+	assert.True(t, bob.Dormant, "never active since joining a year ago")
+	assert.Empty(t, bob.Tokens)
+	assert.Equal(t, 1, review.dormantCount())
+
+	assert.False(t, review.TokenOwnersKnown)
+	assert.True(t, alice.TokensVisible)
+	assert.False(t, bob.TokensVisible, "another user's tokens are unknown, not none")
+	assert.Equal(t, []string{tokensNotCollected}, bob.TokenLines())
+}
+
+func TestCollectAccessReview_TokenOwnersNamed(t *testing.T) {
+	ts := reviewServer(t, []auth.APITokenResponse{
+		{ID: "t-1", Name: "deploy", Enabled: true, User: &types.UserSummary{Name: "bob"}},
+	})
+	defer ts.Close()
+	ac := &client.AlpaconClient{HTTPClient: ts.Client(), BaseURL: ts.URL, Username: "alice"}
+
+	review, err := collectAccessReview(ac, reviewOptions{sessionsSince: reviewNow}, reviewNow)
+	require.NoError(t, err)
+	assert.True(t, review.TokenOwnersKnown)
+	alice, bob := review.Users[0], review.Users[1]
+	assert.True(t, alice.TokensVisible)
+	assert.Empty(t, alice.Tokens)
+	assert.True(t, bob.TokensVisible)
+	require.Len(t, bob.Tokens, 1)
+}
+
+func TestCollectAccessReview_UnknownUser(t *testing.T) {
+	ts := reviewServer(t, ownerlessTokens)
+	defer ts.Close()
+	ac := &client.AlpaconClient{HTTPClient: ts.Client(), BaseURL: ts.URL}
+
+	_, err := collectAccessReview(ac, reviewOptions{only: []string{"carol"}}, reviewNow)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "carol")
+}
+
+func TestWriteAccessReview(t *testing.T) {
+	last := reviewNow.AddDate(0, 0, -1)
+	review := &accessReview{GeneratedAt: reviewNow, InactiveDays: 90, Users: []userAccess{{
+		Username:      "alice",
+		Status:        "active",
+		LastActivity:  &last,
+		Groups:        []groupAccess{{Group: "ops", Role: roleOwner}},
+		Tokens:        []tokenAccess{{Name: "deploy", Scopes: []string{"exec"}, ServerACL: []string{"web-01"}}},
+		TokensVisible: true,
+		WorkSessions:  []sessionAccess{{ID: "ws-1", SudoPolicies: []worksession.SudoPolicyInline{{Commands: []string{"<script>"}, AllowBypassMFA: true}}}},
+	}, {
+		Username: "bob",
+		Status:   "active",
+	}}}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeAccessReview(&buf, review, reviewFormatCSV))
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	row := map[string]string{}
+	for i, column := range reviewCSVColumns {
+		row[column] = rows[1][i]
+	}
+	assert.Equal(t, "2026-10-17T12:00:00Z", row["last_activity"])
+	assert.Equal(t, "ops (owner)", row["groups"])
+	assert.Equal(t, "deploy [disabled] scopes: exec", row["tokens"])
+	assert.Equal(t, "deploy server: web-01", row["token_acl"])
+	assert.Equal(t, "ws-1: <script> [MFA bypass]", row["sudo_policies"])
+	assert.Equal(t, tokensNotCollected, rows[2][slices.Index(reviewCSVColumns, "tokens")])
+
+	buf.Reset()
+	require.NoError(t, writeAccessReview(&buf, review, reviewFormatHTML))
+	assert.Contains(t, buf.String(), "ws-1: &lt;script&gt; [MFA bypass]")
+	assert.NotContains(t, buf.String(), "<script>")
+	assert.Contains(t, buf.String(), "only the reviewer's tokens were collected")
+}
@@ -3,6 +3,7 @@ package iam
 import (
 	"github.com/alpacax/alpacon-cli/api/iam"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -19,7 +20,8 @@ var groupDetailCmd = &cobra.Command{
 	alpacon group describe developers
 	alpacon group desc developers
 	`,
-	Args: cobra.ExactArgs(1),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Groups),
 	Run: func(cmd *cobra.Command, args []string) {
 		groupName := args[0]
 
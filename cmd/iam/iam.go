@@ -0,0 +1,26 @@
+package iam
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+)
+
+var IAMCmd = &cobra.Command{
+	Use:   "iam",
+	Short: "Import, export, and review users, groups, and their access in bulk",
+	Long:  "Provision users, groups, and group memberships from a YAML or CSV file, export them in the same format to version access, and report who can reach what for an access review.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := cmd.Help()
+		if err != nil {
+			return err
+		}
+		return errors.New("a subcommand is required. Use 'alpacon iam import', 'alpacon iam export', or 'alpacon iam access-review'. Run 'alpacon iam --help' for more information")
+	},
+}
+
+func init() {
+	IAMCmd.AddCommand(iamImportCmd)
+	IAMCmd.AddCommand(iamExportCmd)
+	IAMCmd.AddCommand(iamAccessReviewCmd)
+}
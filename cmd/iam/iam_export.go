@@ -0,0 +1,64 @@
+package iam
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+var iamExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export users, groups, and memberships in the import format",
+	Long: `Write every user, group, and group membership in the format 'alpacon iam
+import' reads, sorted by name so successive exports diff cleanly. Commit the
+file to review access changes like code, and import it to apply them.
+
+CSV carries users and their memberships only; groups without members and
+group details such as descriptions are kept in YAML alone.`,
+	Example: `  alpacon iam export > people.yaml
+  alpacon iam export --format csv -f people.csv`,
+	Args: cobra.NoArgs,
+	Run:  runIAMExport,
+}
+
+func init() {
+	iamExportCmd.Flags().StringP("file", "f", "", "File to write (default: standard output)")
+	iamExportCmd.Flags().String("format", "", "File format: yaml or csv (default: from the file extension, else yaml)")
+}
+
+func runIAMExport(cmd *cobra.Command, args []string) {
+	path, _ := cmd.Flags().GetString("file")
+	formatFlag, _ := cmd.Flags().GetString("format")
+
+	format, err := peopleFormatFor(path, formatFlag)
+	if err != nil {
+		utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+	}
+
+	alpaconClient, err := client.NewAlpaconAPIClient()
+	if err != nil {
+		utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
+	}
+
+	state, err := loadWorkspaceState(alpaconClient)
+	if err != nil {
+		utils.CliErrorWithExit("Failed to read the workspace: %s.", err)
+	}
+	data, err := encodePeople(state.peopleFile(), format)
+	if err != nil {
+		utils.CliErrorWithExit("Failed to encode the export: %s.", err)
+	}
+
+	if path == "" || path == "-" {
+		_, _ = os.Stdout.Write(data)
+		return
+	}
+	// Owner-only: the file lists every user's email and phone number.
+	if _, err = utils.SaveStreamAtomic(path, bytes.NewReader(data), 0o600); err != nil {
+		utils.CliErrorWithExit("Failed to write %s: %s.", path, err)
+	}
+	utils.CliSuccess("Exported %d users and %d groups to %s.", len(state.users), len(state.groups), path)
+}
@@ -0,0 +1,245 @@
+package iam
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/config"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+var iamImportCmd = &cobra.Command{
+	Use:   "import -f FILE",
+	Short: "Create or update users, groups, and memberships from a file",
+	Long: `Bring users, groups, and group memberships in line with a YAML or CSV file.
+
+Import is idempotent: it compares the file with the workspace and only makes
+the changes needed, so running it again changes nothing. Fields left empty in
+the file are left as they are. Users and groups are never deleted; with
+--prune, memberships of the listed users in groups the file does not list for
+them are removed.
+
+The plan is shown before anything changes; --dry-run stops there. After
+applying, a report gives the outcome of each change. A failed change does not
+stop the others, but the changes that depend on it are skipped.
+
+YAML:
+  users:
+    - username: alice
+      first_name: Alice
+      last_name: Kim
+      email: alice@example.com
+      groups:
+        - group: developers
+          role: manager        # owner, manager, or member (default)
+  groups:
+    - name: developers
+      display_name: Developers
+      tags: "#dev"
+
+CSV has one row per membership, with a header naming the columns:
+  username,first_name,last_name,email,phone,tags,group,role
+
+Groups a user is put in that do not exist yet are created, named after
+themselves unless the YAML describes them. New users get a random password;
+use --password-file to save them for handing out. The import stops before
+changing anything if that file cannot be written. On Alpacon Cloud, new users
+are invited by email instead, and their memberships are added by an import
+run after they accept.`,
+	Example: `  alpacon iam import -f people.yaml --dry-run
+  alpacon iam import -f people.csv --yes --password-file new-passwords.csv
+  alpacon iam export > people.yaml && vi people.yaml && alpacon iam import -f people.yaml --prune`,
+	Args: cobra.NoArgs,
+	Run:  runIAMImport,
+}
+
+func init() {
+	iamImportCmd.Flags().StringP("file", "f", "", "YAML or CSV file to import, or - for standard input (required)")
+	iamImportCmd.Flags().String("format", "", "File format: yaml or csv (default: from the file extension, else yaml)")
+	iamImportCmd.Flags().Bool("dry-run", false, "Show the plan without changing anything")
+	iamImportCmd.Flags().Bool("prune", false, "Remove memberships of the listed users that the file does not list")
+	iamImportCmd.Flags().String("password-file", "", "Save the generated passwords of new users to this CSV file")
+	iamImportCmd.Flags().BoolP("yes", "y", false, "Skip confirmation prompt")
+	_ = iamImportCmd.MarkFlagRequired("file")
+}
+
+func runIAMImport(cmd *cobra.Command, args []string) {
+	path, _ := cmd.Flags().GetString("file")
+	formatFlag, _ := cmd.Flags().GetString("format")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	prune, _ := cmd.Flags().GetBool("prune")
+	passwordFile, _ := cmd.Flags().GetString("password-file")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	format, err := peopleFormatFor(path, formatFlag)
+	if err != nil {
+		utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+	}
+	var data []byte
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		utils.CliErrorWithExit("Failed to read %s: %s.", path, err)
+	}
+	file, err := parsePeople(data, format)
+	if err != nil {
+		utils.CliErrorWithExit("Invalid %s: %s.", path, err)
+	}
+
+	isSaaS, err := config.IsSaaS()
+	if err != nil {
+		utils.CliErrorWithExit("Not logged in. Run 'alpacon login' first.")
+	}
+
+	alpaconClient, err := client.NewAlpaconAPIClient()
+	if err != nil {
+		utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
+	}
+
+	if err := alpaconClient.LoadCurrentUser(); err != nil {
+		utils.CliErrorWithExit("Failed to load current user: %s", err)
+	}
+	if alpaconClient.Privileges == "general" {
+		utils.CliErrorWithExit("Insufficient permissions to import users and groups. This action requires staff or superuser privileges. Please contact your administrator to request elevated permissions")
+	}
+
+	state, err := loadWorkspaceState(alpaconClient)
+	if err != nil {
+		utils.CliErrorWithExit("Failed to read the workspace: %s.", err)
+	}
+	plan, err := buildImportPlan(file, state, planOptions{invite: isSaaS, prune: prune})
+	if err != nil {
+		utils.CliErrorWithExit("Failed to plan the import: %s.", err)
+	}
+
+	pending := plan.pending()
+	output := iamImportOutput{File: path, DryRun: dryRun}
+	if utils.OutputFormat != utils.OutputFormatJSON && len(plan.changes) > 0 {
+		utils.PrintTable(plan.rows())
+	}
+	if pending == 0 || dryRun {
+		if utils.OutputFormat == utils.OutputFormatJSON {
+			output.Changes = plan.rows()
+			printIAMImportJSON(output)
+		}
+		if pending == 0 {
+			utils.CliSuccess("Nothing to change: the workspace already matches %s.", path)
+		} else {
+			utils.CliInfo("%d changes planned. Run without --dry-run to apply them.", pending)
+		}
+		return
+	}
+	// The password file is opened before anything changes: passwords of
+	// users created with nowhere to save them would be lost.
+	var passwords *passwordSink
+	if passwordFile != "" {
+		if passwords, err = openPasswordSink(passwordFile); err != nil {
+			utils.CliErrorWithExit("Cannot write the password file %s: %s. Nothing was changed.", passwordFile, err)
+		}
+		defer passwords.discard()
+	}
+	if !yes {
+		utils.ConfirmAction("Apply %d changes?", pending)
+	}
+
+	failed := plan.apply(alpaconClient)
+	output.Applied, output.Changes = true, plan.rows()
+	if utils.OutputFormat == utils.OutputFormatJSON {
+		printIAMImportJSON(output)
+	} else {
+		utils.PrintHeader("Result")
+		utils.PrintTable(output.Changes)
+	}
+
+	if created := plan.createdPasswords(); len(created) > 0 {
+		if passwords == nil {
+			utils.CliWarning("%d users were created with random passwords nobody knows. Reset them in the console, or import new users with --password-file.", len(created))
+		} else if err := passwords.save(created); err != nil {
+			utils.CliWarning("Failed to save the passwords of new users to %s: %s. Reset them in the console.", passwordFile, err)
+		} else {
+			utils.CliInfo("Passwords of %d new users saved to %s.", len(created), passwordFile)
+		}
+	}
+	if failed > 0 {
+		utils.CliErrorWithExit("%d of %d changes failed. Fix the cause and run the import again; what succeeded is not repeated.", failed, pending)
+	}
+	utils.CliSuccess("Applied %d changes from %s.", pending, path)
+}
+
+type iamImportOutput struct {
+	File    string      `json:"file"`
+	DryRun  bool        `json:"dry_run"`
+	Applied bool        `json:"applied"`
+	Changes []changeRow `json:"changes"`
+}
+
+func printIAMImportJSON(output iamImportOutput) {
+	if err := utils.PrintJSONValue(os.Stdout, output); err != nil {
+		utils.CliErrorWithExit("Failed to marshal the import plan: %v.", err)
+	}
+}
+
+// passwordSink is --password-file, held as a temporary file beside it from
+// before the import applies until the passwords are saved.
+type passwordSink struct {
+	path string
+	tmp  *os.File
+}
+
+// openPasswordSink creates the temporary file, readable only by the owner, so
+// an unwritable path is found before any user is created.
+func openPasswordSink(path string) (*passwordSink, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return nil, err
+	}
+	return &passwordSink{path: path, tmp: tmp}, nil
+}
+
+// save writes username,password rows and renames them into place. Should the
+// rename fail, the rows are kept in the temporary file the error names.
+func (s *passwordSink) save(passwords map[string]string) error {
+	names := make([]string, 0, len(passwords))
+	for name := range passwords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := csv.NewWriter(s.tmp)
+	_ = w.Write([]string{"username", "password"})
+	for _, name := range names {
+		_ = w.Write([]string{name, passwords[name]})
+	}
+	w.Flush()
+	err := w.Error()
+	tmp := s.tmp
+	s.tmp = nil // from here on the file holds passwords and is kept
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err != nil {
+		return fmt.Errorf("%w; the passwords written so far are in %s", err, tmp.Name())
+	}
+	return nil
+}
+
+// discard removes the temporary file unless save moved it into place.
+func (s *passwordSink) discard() {
+	if s.tmp == nil {
+		return
+	}
+	_ = s.tmp.Close()
+	_ = os.Remove(s.tmp.Name())
+}
@@ -0,0 +1,384 @@
+package iam
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alpacax/alpacon-cli/api/iam"
+	"github.com/alpacax/alpacon-cli/client"
+)
+
+const (
+	statusPlanned  = "planned"
+	statusDone     = "done"
+	statusFailed   = "failed"
+	statusSkipped  = "skipped"
+	statusDeferred = "deferred"
+)
+
+// change is one step of an import: what the plan shows and, once applied,
+// how it went.
+type change struct {
+	Action string
+	Target string
+	Detail string
+	Status string
+	Error  string
+
+	apply func(ac *client.AlpaconClient) error
+	// needs are the steps this one depends on: a membership waits for its
+	// user and group to exist.
+	needs []*change
+}
+
+// changeRow is a change as the plan and report tables print it.
+type changeRow struct {
+	Action string `json:"action"`
+	Target string `json:"target"`
+	Detail string `json:"detail"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// workspaceState is what the workspace holds now, keyed by name.
+type workspaceState struct {
+	users   map[string]iam.UserResponse
+	groups  map[string]iam.GroupResponse
+	members map[memberKey]iam.MemberDetailResponse
+}
+
+type memberKey struct {
+	group, user string
+}
+
+func loadWorkspaceState(ac *client.AlpaconClient) (*workspaceState, error) {
+	users, err := iam.GetUsers(ac)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	groups, err := iam.GetGroups(ac)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+	members, err := iam.GetMemberships(ac)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memberships: %w", err)
+	}
+	return newWorkspaceState(users, groups, members), nil
+}
+
+func newWorkspaceState(users []iam.UserResponse, groups []iam.GroupResponse, members []iam.MemberDetailResponse) *workspaceState {
+	state := &workspaceState{
+		users:   map[string]iam.UserResponse{},
+		groups:  map[string]iam.GroupResponse{},
+		members: map[memberKey]iam.MemberDetailResponse{},
+	}
+	for _, u := range users {
+		state.users[u.Username] = u
+	}
+	for _, g := range groups {
+		state.groups[g.Name] = g
+	}
+	for _, m := range members {
+		state.members[memberKey{group: m.GroupName, user: m.User.Name}] = m
+	}
+	return state
+}
+
+// planOptions are the import flags that shape the plan.
+type planOptions struct {
+	// invite sends workspace invitations instead of creating users, as
+	// Alpacon Cloud requires. Memberships of an invited user wait until the
+	// invitation is accepted and the import is run again.
+	invite bool
+	// prune removes memberships of the listed users in groups the file does
+	// not list for them.
+	prune bool
+}
+
+// importPlan is the ordered list of changes that brings the workspace in line
+// with the file, and the passwords generated for users it creates.
+type importPlan struct {
+	changes   []*change
+	passwords map[string]string
+}
+
+// buildImportPlan diffs the file against the workspace. Groups come first,
+// then users, then memberships, so every step finds what it needs.
+func buildImportPlan(file *peopleFile, state *workspaceState, opts planOptions) (*importPlan, error) {
+	plan := &importPlan{passwords: map[string]string{}}
+	groupSteps := map[string]*change{}
+	userSteps := map[string]*change{}
+
+	listed := map[string]bool{}
+	for _, g := range file.Groups {
+		listed[g.Name] = true
+		if existing, ok := state.groups[g.Name]; ok {
+			fields, detail := diffFields([][3]string{
+				{"display_name", existing.DisplayName, g.DisplayName},
+				{"description", existing.Description, g.Description},
+				{"tags", existing.Tags, g.Tags},
+			})
+			if len(fields) > 0 {
+				id := existing.ID
+				plan.add(&change{Action: "update group", Target: g.Name, Detail: detail,
+					apply: func(ac *client.AlpaconClient) error { return iam.PatchGroup(ac, id, fields) }})
+			}
+			continue
+		}
+		groupSteps[g.Name] = plan.add(newGroupChange(g))
+	}
+	// Groups the users are put in but the file does not describe.
+	for _, u := range file.Users {
+		for _, m := range u.Groups {
+			if _, ok := state.groups[m.Group]; ok || listed[m.Group] {
+				continue
+			}
+			listed[m.Group] = true
+			groupSteps[m.Group] = plan.add(newGroupChange(groupEntry{Name: m.Group}))
+		}
+	}
+
+	for _, u := range file.Users {
+		if existing, ok := state.users[u.Username]; ok {
+			fields, detail := diffFields([][3]string{
+				{"first_name", existing.FirstName, u.FirstName},
+				{"last_name", existing.LastName, u.LastName},
+				{"email", existing.Email, u.Email},
+				{"phone", existing.Phone, u.Phone},
+				{"tags", existing.Tags, u.Tags},
+			})
+			if len(fields) > 0 {
+				id := existing.ID
+				plan.add(&change{Action: "update user", Target: u.Username, Detail: detail,
+					apply: func(ac *client.AlpaconClient) error { return iam.PatchUser(ac, id, fields) }})
+			}
+			continue
+		}
+		if opts.invite {
+			if u.Email == "" {
+				return nil, fmt.Errorf("user %s does not exist and has no email to invite", u.Username)
+			}
+			email := u.Email
+			userSteps[u.Username] = plan.add(&change{Action: "invite user", Target: u.Username, Detail: email,
+				apply: func(ac *client.AlpaconClient) error {
+					return iam.InviteUser(ac, iam.UserInviteRequest{Email: email})
+				}})
+			continue
+		}
+		password, err := randomPassword()
+		if err != nil {
+			return nil, err
+		}
+		plan.passwords[u.Username] = password
+		request := iam.UserCreateRequest{
+			Username:   u.Username,
+			Password:   password,
+			FirstName:  u.FirstName,
+			LastName:   u.LastName,
+			Email:      u.Email,
+			Phone:      u.Phone,
+			Tags:       u.Tags,
+			IsLdapUser: u.LDAP,
+		}
+		userSteps[u.Username] = plan.add(&change{Action: "create user", Target: u.Username, Detail: u.Email,
+			apply: func(ac *client.AlpaconClient) error { return iam.CreateUser(ac, request) }})
+	}
+
+	for _, u := range file.Users {
+		wanted := map[string]bool{}
+		for _, m := range u.Groups {
+			wanted[m.Group] = true
+			target := u.Username + " in " + m.Group
+			existing, ok := state.members[memberKey{group: m.Group, user: u.Username}]
+			switch {
+			case ok && existing.Role == m.Role:
+			case ok:
+				id, role := existing.ID, m.Role
+				plan.add(&change{Action: "change role", Target: target, Detail: existing.Role + " -> " + role,
+					apply: func(ac *client.AlpaconClient) error { return iam.SetMemberRole(ac, id, role) }})
+			case opts.invite && userSteps[u.Username] != nil:
+				plan.add(&change{Action: "add member", Target: target, Detail: m.Role + " (after the invitation is accepted)", Status: statusDeferred})
+			default:
+				request := iam.MemberAddRequest{Group: m.Group, User: u.Username, Role: m.Role}
+				c := &change{Action: "add member", Target: target, Detail: m.Role,
+					apply: func(ac *client.AlpaconClient) error { return iam.AddMember(ac, request) }}
+				for _, dep := range []*change{userSteps[u.Username], groupSteps[m.Group]} {
+					if dep != nil {
+						c.needs = append(c.needs, dep)
+					}
+				}
+				plan.add(c)
+			}
+		}
+		if !opts.prune {
+			continue
+		}
+		var stale []iam.MemberDetailResponse
+		for key, m := range state.members {
+			if key.user == u.Username && !wanted[key.group] {
+				stale = append(stale, m)
+			}
+		}
+		sort.Slice(stale, func(i, j int) bool { return stale[i].GroupName < stale[j].GroupName })
+		for _, m := range stale {
+			id := m.ID
+			plan.add(&change{Action: "remove member", Target: u.Username + " in " + m.GroupName, Detail: m.Role,
+				apply: func(ac *client.AlpaconClient) error { return iam.DeleteMembership(ac, id) }})
+		}
+	}
+	return plan, nil
+}
+
+func newGroupChange(g groupEntry) *change {
+	request := iam.GroupCreateRequest{
+		Name:        g.Name,
+		DisplayName: g.DisplayName,
+		Tags:        g.Tags,
+		Description: g.Description,
+		IsLdapGroup: g.LDAP,
+		Servers:     []string{},
+	}
+	if request.DisplayName == "" {
+		request.DisplayName = g.Name
+	}
+	return &change{Action: "create group", Target: g.Name, Detail: request.DisplayName,
+		apply: func(ac *client.AlpaconClient) error { return iam.CreateGroup(ac, request) }}
+}
+
+// diffFields returns the fields whose wanted value is set and differs from
+// the current one, as a PATCH body and as a line for the plan. Each entry is
+// {field, current, wanted}.
+func diffFields(entries [][3]string) (map[string]string, string) {
+	fields := map[string]string{}
+	var detail []string
+	for _, e := range entries {
+		if e[2] == "" || e[2] == e[1] {
+			continue
+		}
+		fields[e[0]] = e[2]
+		detail = append(detail, fmt.Sprintf("%s: %q -> %q", e[0], e[1], e[2]))
+	}
+	return fields, strings.Join(detail, ", ")
+}
+
+func (p *importPlan) add(c *change) *change {
+	if c.Status == "" {
+		c.Status = statusPlanned
+	}
+	p.changes = append(p.changes, c)
+	return c
+}
+
+func (p *importPlan) rows() []changeRow {
+	rows := make([]changeRow, len(p.changes))
+	for i, c := range p.changes {
+		rows[i] = changeRow{Action: c.Action, Target: c.Target, Detail: c.Detail, Status: c.Status, Error: c.Error}
+	}
+	return rows
+}
+
+// pending counts the changes an apply would make.
+func (p *importPlan) pending() int {
+	n := 0
+	for _, c := range p.changes {
+		if c.Status == statusPlanned {
+			n++
+		}
+	}
+	return n
+}
+
+// apply runs the planned changes in order. A failed step does not stop the
+// rest; the steps that depend on it are skipped. It returns how many failed.
+func (p *importPlan) apply(ac *client.AlpaconClient) int {
+	failed := 0
+	for _, c := range p.changes {
+		if c.Status != statusPlanned {
+			continue
+		}
+		if blocker := c.blockedBy(); blocker != nil {
+			c.Status = statusSkipped
+			c.Error = fmt.Sprintf("%s %s did not succeed", blocker.Action, blocker.Target)
+			continue
+		}
+		if err := c.apply(ac); err != nil {
+			c.Status, c.Error = statusFailed, err.Error()
+			failed++
+			continue
+		}
+		c.Status = statusDone
+	}
+	return failed
+}
+
+func (c *change) blockedBy() *change {
+	for _, dep := range c.needs {
+		if dep.Status != statusDone {
+			return dep
+		}
+	}
+	return nil
+}
+
+// createdPasswords returns the generated passwords of the users that were
+// actually created, by username.
+func (p *importPlan) createdPasswords() map[string]string {
+	out := map[string]string{}
+	for _, c := range p.changes {
+		if c.Action == "create user" && c.Status == statusDone {
+			out[c.Target] = p.passwords[c.Target]
+		}
+	}
+	return out
+}
+
+// randomPassword returns a password nobody knows yet, for a user created
+// without one: 24 characters from 144 random bits.
+func randomPassword() (string, error) {
+	buf := make([]byte, 18)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// peopleFile renders the workspace in the import format.
+func (s *workspaceState) peopleFile() *peopleFile {
+	file := &peopleFile{}
+	byUser := map[string][]groupMember{}
+	for key, m := range s.members {
+		byUser[key.user] = append(byUser[key.user], groupMember{Group: key.group, Role: m.Role})
+	}
+	for _, u := range s.users {
+		file.Users = append(file.Users, personEntry{
+			Username:  u.Username,
+			FirstName: u.FirstName,
+			LastName:  u.LastName,
+			Email:     u.Email,
+			Phone:     u.Phone,
+			Tags:      u.Tags,
+			LDAP:      u.IsLDAPUser,
+			Groups:    byUser[u.Username],
+		})
+	}
+	for _, g := range s.groups {
+		entry := groupEntry{
+			Name:        g.Name,
+			DisplayName: g.DisplayName,
+			Description: g.Description,
+			Tags:        g.Tags,
+			LDAP:        g.IsLDAPGroup,
+		}
+		// Import defaults the display name to the name; leave it out then.
+		if entry.DisplayName == entry.Name {
+			entry.DisplayName = ""
+		}
+		file.Groups = append(file.Groups, entry)
+	}
+	file.sort()
+	return file
+}
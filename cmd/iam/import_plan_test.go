@@ -0,0 +1,168 @@
+package iam
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alpacax/alpacon-cli/api"
+	"github.com/alpacax/alpacon-cli/api/iam"
+	"github.com/alpacax/alpacon-cli/api/types"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testState() *workspaceState {
+	return newWorkspaceState(
+		[]iam.UserResponse{
+			{ID: "u-alice", Username: "alice", FirstName: "Alice", Email: "alice@old.example.com"},
+		},
+		[]iam.GroupResponse{
+			{ID: "g-dev", Name: "developers", DisplayName: "Developers"},
+			{ID: "g-ops", Name: "ops", DisplayName: "Ops"},
+		},
+		[]iam.MemberDetailResponse{
+			{ID: "m-1", GroupName: "developers", User: types.UserSummary{Name: "alice"}, Role: roleMember},
+			{ID: "m-2", GroupName: "ops", User: types.UserSummary{Name: "alice"}, Role: roleMember},
+		},
+	)
+}
+
+func testPeople(t *testing.T) *peopleFile {
+	file, err := parsePeople([]byte(`
+users:
+  - username: alice
+    first_name: Alice
+    email: alice@example.com
+    groups:
+      - group: developers
+        role: manager
+  - username: bob
+    email: bob@example.com
+    groups:
+      - group: developers
+      - group: sre
+groups:
+  - name: developers
+    display_name: Developers
+`), peopleFormatYAML)
+	require.NoError(t, err)
+	return file
+}
+
+func planSummary(plan *importPlan) []string {
+	var out []string
+	for _, c := range plan.changes {
+		out = append(out, c.Status+" "+c.Action+" "+c.Target)
+	}
+	return out
+}
+
+func TestBuildImportPlan(t *testing.T) {
+	plan, err := buildImportPlan(testPeople(t), testState(), planOptions{prune: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"planned create group sre",
+		"planned update user alice",
+		"planned create user bob",
+		"planned change role alice in developers",
+		"planned remove member alice in ops",
+		"planned add member bob in developers",
+		"planned add member bob in sre",
+	}, planSummary(plan))
+	assert.Equal(t, `email: "alice@old.example.com" -> "alice@example.com"`, plan.changes[1].Detail, "unchanged and empty fields are left out")
+	assert.Len(t, plan.changes[6].needs, 2, "waits for both the user and the group")
+	assert.Len(t, plan.passwords["bob"], 24)
+}
+
+func TestBuildImportPlan_InviteDefersMemberships(t *testing.T) {
+	plan, err := buildImportPlan(testPeople(t), testState(), planOptions{invite: true})
+	require.NoError(t, err)
+	assert.Contains(t, planSummary(plan), "planned invite user bob")
+	assert.Contains(t, planSummary(plan), "deferred add member bob in developers")
+	assert.NotContains(t, planSummary(plan), "planned remove member alice in ops", "no pruning without --prune")
+	assert.Empty(t, plan.passwords)
+}
+
+func TestBuildImportPlan_NoChangesWhenInSync(t *testing.T) {
+	state := testState()
+	file := state.peopleFile()
+	plan, err := buildImportPlan(file, state, planOptions{prune: true})
+	require.NoError(t, err)
+	assert.Empty(t, plan.changes, "importing an export changes nothing")
+}
+
+func TestImportPlanApply_SkipsDependentsOfFailures(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/iam/users/":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"email": ["Enter a valid email address."]}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/iam/groups/":
+			_ = json.NewEncoder(w).Encode(api.ListResponse[iam.GroupResponse]{Count: 1, Results: []iam.GroupResponse{{ID: "g-sre", Name: "sre"}}})
+		default:
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer ts.Close()
+	ac := &client.AlpaconClient{HTTPClient: ts.Client(), BaseURL: ts.URL}
+
+	plan, err := buildImportPlan(testPeople(t), testState(), planOptions{})
+	require.NoError(t, err)
+	failed := plan.apply(ac)
+
+	assert.Equal(t, 1, failed)
+	assert.Equal(t, []string{
+		"done create group sre",
+		"done update user alice",
+		"failed create user bob",
+		"done change role alice in developers",
+		"skipped add member bob in developers",
+		"skipped add member bob in sre",
+	}, planSummary(plan))
+	assert.Equal(t, "create user bob did not succeed", plan.changes[4].Error)
+	assert.Empty(t, plan.createdPasswords())
+	for _, call := range calls {
+		assert.False(t, strings.HasPrefix(call, "POST /api/iam/memberships/"), "no membership for a user that was not created")
+	}
+}
+
+func TestPasswordSink(t *testing.T) {
+	dir := t.TempDir()
+	_, err := openPasswordSink(filepath.Join(dir, "missing", "passwords.csv"))
+	assert.Error(t, err, "an unwritable path is found before anything changes")
+
+	path := filepath.Join(dir, "passwords.csv")
+	sink, err := openPasswordSink(path)
+	require.NoError(t, err)
+	require.NoError(t, sink.save(map[string]string{"bob": "b-pass", "alice": "a-pass"}))
+	sink.discard()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "username,password\nalice,a-pass\nbob,b-pass\n", string(data))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	sink, err = openPasswordSink(filepath.Join(dir, "unused.csv"))
+	require.NoError(t, err)
+	sink.discard()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "an unused sink leaves nothing behind")
+}
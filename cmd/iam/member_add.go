@@ -5,6 +5,7 @@ import (
 
 	"github.com/alpacax/alpacon-cli/api/iam"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -41,6 +42,8 @@ func init() {
 	memberAddCmd.Flags().StringVarP(&memberRequest.Group, "group", "g", "", "Group")
 	memberAddCmd.Flags().StringVarP(&memberRequest.User, "user", "u", "", "User")
 	memberAddCmd.Flags().StringVarP(&memberRequest.Role, "role", "r", "", "Role of member (owner, manager, member)")
+	_ = memberAddCmd.RegisterFlagCompletionFunc("group", complete.Groups)
+	_ = memberAddCmd.RegisterFlagCompletionFunc("user", complete.Users)
 }
 
 func promptForMembers() {
@@ -3,6 +3,7 @@ package iam
 import (
 	"github.com/alpacax/alpacon-cli/api/iam"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -45,6 +46,8 @@ func init() {
 	memberDeleteCmd.Flags().StringVarP(&memberDeleteRequest.Group, "group", "g", "", "Group")
 	memberDeleteCmd.Flags().StringVarP(&memberDeleteRequest.User, "user", "u", "", "User")
 	memberDeleteCmd.Flags().BoolP("yes", "y", false, "Skip confirmation prompt")
+	_ = memberDeleteCmd.RegisterFlagCompletionFunc("group", complete.Groups)
+	_ = memberDeleteCmd.RegisterFlagCompletionFunc("user", complete.Users)
 }
 
 func promptForDeleteMembers() {
@@ -0,0 +1,294 @@
+package iam
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	peopleFormatYAML = "yaml"
+	peopleFormatCSV  = "csv"
+
+	roleOwner   = "owner"
+	roleManager = "manager"
+	roleMember  = "member"
+)
+
+var (
+	peopleFormats = []string{peopleFormatYAML, peopleFormatCSV}
+	memberRoles   = []string{roleOwner, roleManager, roleMember}
+
+	// peopleCSVColumns is the CSV layout: one row per membership, and one row
+	// with empty group and role for a user in no group.
+	peopleCSVColumns = []string{"username", "first_name", "last_name", "email", "phone", "tags", "group", "role"}
+)
+
+// peopleFile is what 'iam import' reads and 'iam export' writes. Memberships
+// live under the users, so each one is stated once.
+type peopleFile struct {
+	Users  []personEntry `yaml:"users,omitempty"`
+	Groups []groupEntry  `yaml:"groups,omitempty"`
+}
+
+// personEntry is a user. An empty field is left as it is in the workspace,
+// so a file can carry only what it means to manage.
+type personEntry struct {
+	Username  string        `yaml:"username"`
+	FirstName string        `yaml:"first_name,omitempty"`
+	LastName  string        `yaml:"last_name,omitempty"`
+	Email     string        `yaml:"email,omitempty"`
+	Phone     string        `yaml:"phone,omitempty"`
+	Tags      string        `yaml:"tags,omitempty"`
+	LDAP      bool          `yaml:"ldap,omitempty"`
+	Groups    []groupMember `yaml:"groups,omitempty"`
+}
+
+type groupMember struct {
+	Group string `yaml:"group"`
+	Role  string `yaml:"role,omitempty"`
+}
+
+// groupEntry is a group. Groups a user belongs to need not be listed; one
+// that does not exist yet is created with its name as the display name.
+type groupEntry struct {
+	Name        string `yaml:"name"`
+	DisplayName string `yaml:"display_name,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	Tags        string `yaml:"tags,omitempty"`
+	LDAP        bool   `yaml:"ldap,omitempty"`
+}
+
+// peopleFormatFor picks the file format: the --format flag if given, else the
+// file extension, else YAML.
+func peopleFormatFor(path, flag string) (string, error) {
+	if flag != "" {
+		if !slices.Contains(peopleFormats, flag) {
+			return "", fmt.Errorf("invalid --format %q: must be one of %s", flag, strings.Join(peopleFormats, ", "))
+		}
+		return flag, nil
+	}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return peopleFormatCSV, nil
+	}
+	return peopleFormatYAML, nil
+}
+
+func parsePeople(data []byte, format string) (*peopleFile, error) {
+	var file *peopleFile
+	var err error
+	if format == peopleFormatCSV {
+		file, err = parsePeopleCSV(data)
+	} else {
+		file, err = parsePeopleYAML(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return file, file.normalize()
+}
+
+func parsePeopleYAML(data []byte) (*peopleFile, error) {
+	var file peopleFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// parsePeopleCSV reads rows by their header, so columns may come in any
+// order and unused ones may be left out. Rows of one user are merged.
+func parsePeopleCSV(data []byte) (*peopleFile, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return &peopleFile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	index := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(peopleCSVColumns, name) {
+			return nil, fmt.Errorf("unknown column %q: expected %s", name, strings.Join(peopleCSVColumns, ", "))
+		}
+		index[name] = i
+	}
+	if _, ok := index["username"]; !ok {
+		return nil, errors.New("the header has no username column")
+	}
+
+	var file peopleFile
+	byName := map[string]int{}
+	for {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := r.FieldPos(0)
+		get := func(column string) string {
+			if i, ok := index[column]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		person := personEntry{
+			Username:  get("username"),
+			FirstName: get("first_name"),
+			LastName:  get("last_name"),
+			Email:     get("email"),
+			Phone:     get("phone"),
+			Tags:      get("tags"),
+		}
+		if person.Username == "" {
+			return nil, fmt.Errorf("line %d: username is empty", line)
+		}
+		if group := get("group"); group != "" {
+			person.Groups = []groupMember{{Group: group, Role: get("role")}}
+		} else if get("role") != "" {
+			return nil, fmt.Errorf("line %d: role without a group", line)
+		}
+
+		i, seen := byName[person.Username]
+		if !seen {
+			byName[person.Username] = len(file.Users)
+			file.Users = append(file.Users, person)
+			continue
+		}
+		if err := file.Users[i].merge(person); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return &file, nil
+}
+
+// merge folds another CSV row of the same user in. Rows may leave fields
+// empty, but two rows must not disagree.
+func (p *personEntry) merge(row personEntry) error {
+	fields := []struct {
+		name string
+		dst  *string
+		src  string
+	}{
+		{"first_name", &p.FirstName, row.FirstName},
+		{"last_name", &p.LastName, row.LastName},
+		{"email", &p.Email, row.Email},
+		{"phone", &p.Phone, row.Phone},
+		{"tags", &p.Tags, row.Tags},
+	}
+	for _, f := range fields {
+		switch {
+		case f.src == "":
+		case *f.dst == "":
+			*f.dst = f.src
+		case *f.dst != f.src:
+			return fmt.Errorf("%s of %s is %q here but %q on an earlier row", f.name, p.Username, f.src, *f.dst)
+		}
+	}
+	p.Groups = append(p.Groups, row.Groups...)
+	return nil
+}
+
+// normalize fills in default roles and rejects what import cannot apply
+// unambiguously: missing or repeated names and unknown roles.
+func (f *peopleFile) normalize() error {
+	users := map[string]bool{}
+	for i := range f.Users {
+		u := &f.Users[i]
+		u.Username = strings.TrimSpace(u.Username)
+		if u.Username == "" {
+			return fmt.Errorf("users[%d]: username is required", i)
+		}
+		if users[u.Username] {
+			return fmt.Errorf("user %s is listed twice", u.Username)
+		}
+		users[u.Username] = true
+
+		groups := map[string]bool{}
+		for j := range u.Groups {
+			m := &u.Groups[j]
+			m.Group = strings.TrimSpace(m.Group)
+			m.Role = strings.ToLower(strings.TrimSpace(m.Role))
+			if m.Group == "" {
+				return fmt.Errorf("user %s: a group entry has no group name", u.Username)
+			}
+			if groups[m.Group] {
+				return fmt.Errorf("user %s is in group %s twice", u.Username, m.Group)
+			}
+			groups[m.Group] = true
+			if m.Role == "" {
+				m.Role = roleMember
+			}
+			if !slices.Contains(memberRoles, m.Role) {
+				return fmt.Errorf("user %s: invalid role %q in group %s: must be one of %s", u.Username, m.Role, m.Group, strings.Join(memberRoles, ", "))
+			}
+		}
+	}
+
+	groups := map[string]bool{}
+	for i := range f.Groups {
+		g := &f.Groups[i]
+		g.Name = strings.TrimSpace(g.Name)
+		if g.Name == "" {
+			return fmt.Errorf("groups[%d]: name is required", i)
+		}
+		if groups[g.Name] {
+			return fmt.Errorf("group %s is listed twice", g.Name)
+		}
+		groups[g.Name] = true
+	}
+	return nil
+}
+
+// sort orders users, groups, and each user's memberships by name, so an
+// export diffs cleanly from one run to the next.
+func (f *peopleFile) sort() {
+	sort.Slice(f.Users, func(i, j int) bool { return f.Users[i].Username < f.Users[j].Username })
+	for _, u := range f.Users {
+		sort.Slice(u.Groups, func(i, j int) bool { return u.Groups[i].Group < u.Groups[j].Group })
+	}
+	sort.Slice(f.Groups, func(i, j int) bool { return f.Groups[i].Name < f.Groups[j].Name })
+}
+
+func encodePeople(f *peopleFile, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	if format == peopleFormatCSV {
+		w := csv.NewWriter(&buf)
+		_ = w.Write(peopleCSVColumns)
+		for _, u := range f.Users {
+			row := []string{u.Username, u.FirstName, u.LastName, u.Email, u.Phone, u.Tags}
+			if len(u.Groups) == 0 {
+				_ = w.Write(append(row, "", ""))
+			}
+			for _, m := range u.Groups {
+				_ = w.Write(append(slices.Clip(row), m.Group, m.Role))
+			}
+		}
+		w.Flush()
+		return buf.Bytes(), w.Error()
+	}
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(f); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
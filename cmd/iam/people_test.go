@@ -0,0 +1,82 @@
+package iam
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePeopleCSV_MergesRowsOfOneUser(t *testing.T) {
+	data := []byte("username,email,group,role\n" +
+		"alice,alice@example.com,developers,Manager\n" +
+		"alice,,ops,\n" +
+		"bob,bob@example.com,,\n")
+
+	file, err := parsePeople(data, peopleFormatCSV)
+	require.NoError(t, err)
+	require.Len(t, file.Users, 2)
+	assert.Equal(t, "alice@example.com", file.Users[0].Email)
+	assert.Equal(t, []groupMember{{Group: "developers", Role: roleManager}, {Group: "ops", Role: roleMember}}, file.Users[0].Groups)
+	assert.Empty(t, file.Users[1].Groups)
+}
+
+func TestParsePeople_Rejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		data    string
+		wantErr string
+	}{
+		{"unknown column", peopleFormatCSV, "username,password\nalice,x\n", `unknown column "password"`},
+		{"conflicting rows", peopleFormatCSV, "username,email\nalice,a@x\nalice,b@x\n", "line 3: email of alice"},
+		{"role without group", peopleFormatCSV, "username,group,role\nalice,,owner\n", "role without a group"},
+		{"unknown field", peopleFormatYAML, "users:\n  - username: alice\n    password: x\n", "field password not found"},
+		{"bad role", peopleFormatYAML, "users:\n  - username: alice\n    groups:\n      - group: ops\n        role: admin\n", `invalid role "admin"`},
+		{"duplicate user", peopleFormatYAML, "users:\n  - username: alice\n  - username: alice\n", "listed twice"},
+		{"duplicate membership", peopleFormatYAML, "users:\n  - username: alice\n    groups:\n      - group: ops\n      - group: ops\n", "in group ops twice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parsePeople([]byte(tt.data), tt.format)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestEncodePeople_RoundTrips(t *testing.T) {
+	file := &peopleFile{
+		Users: []personEntry{
+			{Username: "alice", FirstName: "Alice", Email: "alice@example.com", Groups: []groupMember{{Group: "ops", Role: roleOwner}, {Group: "dev", Role: roleMember}}},
+			{Username: "bob", Tags: "#contractor, #dev"},
+		},
+		Groups: []groupEntry{{Name: "ops", DisplayName: "Operations"}},
+	}
+
+	for _, format := range peopleFormats {
+		t.Run(format, func(t *testing.T) {
+			data, err := encodePeople(file, format)
+			require.NoError(t, err)
+			got, err := parsePeople(data, format)
+			require.NoError(t, err)
+			assert.Equal(t, file.Users, got.Users)
+			if format == peopleFormatYAML {
+				assert.Equal(t, file.Groups, got.Groups)
+			}
+		})
+	}
+}
+
+func TestPeopleFormatFor(t *testing.T) {
+	format, err := peopleFormatFor("people.CSV", "")
+	require.NoError(t, err)
+	assert.Equal(t, peopleFormatCSV, format)
+
+	format, err = peopleFormatFor("-", "")
+	require.NoError(t, err)
+	assert.Equal(t, peopleFormatYAML, format)
+
+	_, err = peopleFormatFor("people.yaml", "json")
+	assert.Error(t, err)
+}
@@ -0,0 +1,119 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/alpacax/alpacon-cli/api"
+	"github.com/alpacax/alpacon-cli/api/logquery"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+// AddFilterFlags registers the time range, --match, and follow flags. They are
+// shared by 'alpacon log', 'audit', 'webftp-log', and 'exec ls', so the names
+// exist in one place.
+func AddFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().String("since", "", "Only entries at or after this time: a duration back from now (15m, 2h, 7d), an RFC3339 time, or a date")
+	cmd.Flags().String("until", "", "Only entries at or before this time, in the same forms as --since")
+	cmd.Flags().String("match", "", "Only entries whose text matches this regular expression (use (?i) to ignore case)")
+	cmd.Flags().BoolP("follow", "f", false, "Keep polling and print new entries as they arrive")
+	cmd.Flags().String("interval", "2s", "How often --follow polls")
+}
+
+// FilterFromFlags reads the flags AddFilterFlags registered. A bad value exits
+// with a usage error. interval is zero unless --follow is set.
+func FilterFromFlags(cmd *cobra.Command) (filter logquery.Filter, interval time.Duration) {
+	since, _ := cmd.Flags().GetString("since")
+	until, _ := cmd.Flags().GetString("until")
+	match, _ := cmd.Flags().GetString("match")
+	follow, _ := cmd.Flags().GetBool("follow")
+	intervalRaw, _ := cmd.Flags().GetString("interval")
+
+	var err error
+	now := time.Now()
+	if since != "" {
+		if filter.Since, err = logquery.ParseTime("--since", since, now); err != nil {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+		}
+	}
+	if until != "" {
+		if follow {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "--until cannot be used with --follow.")
+		}
+		if filter.Until, err = logquery.ParseTime("--until", until, now); err != nil {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+		}
+		if !filter.Since.IsZero() && filter.Until.Before(filter.Since) {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "--until is before --since.")
+		}
+	}
+	if filter.Match, err = logquery.ParseMatch(match); err != nil {
+		utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+	}
+	if follow {
+		if interval, err = utils.ParsePositiveDuration("--interval", intervalRaw); err != nil {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+		}
+	}
+	return filter, interval
+}
+
+// WarnIfTruncated turns api.ErrScanLimit into a warning: the entries found
+// are shown, but older ones that match may exist. Other errors are returned.
+func WarnIfTruncated(err error) error {
+	if !errors.Is(err, api.ErrScanLimit) {
+		return err
+	}
+	utils.CliWarning("Searched only the newest %d entries; older matches may be missing. Narrow --since, or filter by server or user, to search further back.", api.FilterScanLimit)
+	return nil
+}
+
+// Follow prints seed oldest first, then every new entry the follower finds,
+// until interrupted. Each entry is one line: line's rendering, or the entry
+// itself as JSON with --output json.
+func Follow[T any](f *logquery.Follower[T], seed []T, line func(T) string) {
+	emit := func(e T) {
+		if utils.OutputFormat == utils.OutputFormatJSON {
+			out, err := utils.FormatJSONLine(e)
+			if err != nil {
+				utils.CliWarning("Failed to encode an entry: %s", err)
+				return
+			}
+			_, _ = fmt.Fprintln(os.Stdout, out)
+			return
+		}
+		_, _ = fmt.Fprintln(os.Stdout, utils.SanitizeTerminalText(line(e)))
+	}
+	f.Emit = emit
+	f.Warn = func(err error) {
+		if err = WarnIfTruncated(err); err != nil {
+			utils.CliWarning("Polling failed, retrying: %s", err)
+		}
+	}
+
+	for i := len(seed) - 1; i >= 0; i-- {
+		emit(seed[i])
+	}
+	f.Seed(seed, time.Now())
+
+	stop := make(chan struct{})
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+	go func() {
+		<-sigChan
+		close(stop)
+	}()
+	f.Run(stop)
+}
+
+// FollowTime renders an entry's time for a follow line: absolute and local,
+// since a relative time goes stale as the stream scrolls.
+func FollowTime(t time.Time) string {
+	return t.Local().Format(time.DateTime)
+}
@@ -1,8 +1,12 @@
 package log
 
 import (
+	"fmt"
+
 	"github.com/alpacax/alpacon-cli/api/log"
+	"github.com/alpacax/alpacon-cli/api/server"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -14,30 +18,56 @@ var LogCmd = &cobra.Command{
 	Long: `Retrieve and display logs for a specified server. This command allows you 
 	to view logs of different levels and types associated with a server. Use the '--tail' flag 
 	to show the newest N log entries. Suitable for debugging and monitoring 
-	server activities.`,
+	server activities.
+
+	Narrow the entries with --level (a level, or warn+ for warn and above), --program,
+	--match (a regular expression over the message), and --since/--until (a duration
+	such as 15m, an RFC3339 time, or a date). Use -f/--follow to keep printing new
+	entries as they arrive; press Ctrl+C to stop.`,
 	Example: `
 	alpacon log my-server
 	alpacon logs my-server
 	alpacon log my-server --tail=10
 	alpacon logs my-server --tail=10
+	alpacon log my-server --level warn+ --since 1h
+	alpacon log my-server --program sshd --match 'Failed password' -f
 	`,
-	Args: cobra.ExactArgs(1),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Servers),
 	Run: func(cmd *cobra.Command, args []string) {
 		serverName := args[0]
 		tail, _ := cmd.Flags().GetInt("tail")
 		utils.RequirePositiveInt("tail", tail)
+		levelRaw, _ := cmd.Flags().GetString("level")
+		program, _ := cmd.Flags().GetString("program")
+
+		level, err := log.ParseLevel(levelRaw)
+		if err != nil {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+		}
+		filter, interval := FilterFromFlags(cmd)
+		query := log.Query{Filter: filter, Level: level, Program: program}
 
 		alpaconClient, err := client.NewAlpaconAPIClient()
 		if err != nil {
 			utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
 		}
 
-		logList, err := log.GetSystemLogList(alpaconClient, serverName, tail)
+		serverID, err := server.GetServerIDByName(alpaconClient, serverName)
 		if err != nil {
 			utils.CliErrorWithExit("Failed to get logs: %s.", err)
 		}
 
-		utils.PrintTable(logList)
+		entries, err := log.GetSystemLogs(alpaconClient, serverID, tail, query)
+		if err = WarnIfTruncated(err); err != nil {
+			utils.CliErrorWithExit("Failed to get logs: %s.", err)
+		}
+
+		if interval > 0 {
+			Follow(log.FollowSystemLogs(alpaconClient, serverID, query, interval), entries, systemLogLine)
+			return
+		}
+		utils.PrintTable(log.ToLogAttributes(entries))
 	},
 }
 
@@ -45,4 +75,11 @@ func init() {
 	var tail int
 
 	LogCmd.Flags().IntVarP(&tail, "tail", "t", 25, "Number of log entries to show, newest first")
+	LogCmd.Flags().String("level", "", "Only entries at this level (debug, info, warn, error, critical), or at or above it with a trailing + (warn+)")
+	LogCmd.Flags().String("program", "", "Only entries from this program (e.g. sshd)")
+	AddFilterFlags(LogCmd)
+}
+
+func systemLogLine(e log.LogEntry) string {
+	return fmt.Sprintf("%s  %-8s  %s  [%s] %s", FollowTime(e.Date), log.LevelName(e.Level), e.Program, e.Process, e.Msg)
 }
@@ -3,6 +3,7 @@ package note
 import (
 	"github.com/alpacax/alpacon-cli/api/note"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -52,4 +53,5 @@ func init() {
 	noteCreateCmd.Flags().StringVarP(&noteRequest.Server, "server", "s", "", "Specify the server name where the note will be created.")
 	noteCreateCmd.Flags().StringVarP(&noteRequest.Content, "content", "c", "", "Enter the note content (up to 512 characters).")
 	noteCreateCmd.Flags().BoolVarP(&noteRequest.Private, "private", "p", false, "Set this flag to mark the note as private.")
+	_ = noteCreateCmd.RegisterFlagCompletionFunc("server", complete.Servers)
 }
@@ -3,6 +3,7 @@ package note
 import (
 	"github.com/alpacax/alpacon-cli/api/note"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -32,6 +33,7 @@ func init() {
 	noteListCmd.Flags().IntP("tail", "t", 25, "Number of notes to show, newest first")
 	noteListCmd.Flags().StringP("server", "s", "", "Specify server for notes")
 	noteListCmd.Flags().Bool("pinned", false, "Show only pinned notes")
+	_ = noteListCmd.RegisterFlagCompletionFunc("server", complete.Servers)
 }
 
 func runNoteList(tail int, serverName string, pinnedOnly bool) {
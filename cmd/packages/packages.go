@@ -15,11 +15,12 @@ var PackagesCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		return errors.New("a subcommand is required. Use 'alpacon package system' or 'alpacon package python' to manage packages. Run 'alpacon package --help' for more information")
+		return errors.New("a subcommand is required. Use 'alpacon package system', 'alpacon package python', or 'alpacon package sync' to manage packages. Run 'alpacon package --help' for more information")
 	},
 }
 
 func init() {
 	PackagesCmd.AddCommand(systemCmd)
 	PackagesCmd.AddCommand(pythonCmd)
+	PackagesCmd.AddCommand(packageSyncCmd)
 }
@@ -0,0 +1,401 @@
+package packages
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/alpacax/alpacon-cli/api/packages"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/pkg/pkgmeta"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	validSyncTypes = []string{"python", "system"}
+
+	// syncExtensions are the files a sync of each type picks up.
+	syncExtensions = map[string][]string{
+		"python": {".whl"},
+		"system": {".deb", ".rpm"},
+	}
+)
+
+var packageSyncCmd = &cobra.Command{
+	Use:   "sync DIR --type python|system",
+	Short: "Upload the packages in a local directory that Alpacon does not have",
+	Long: `
+	Mirror a directory of packages into Alpacon. The directory is walked
+	recursively for wheels (--type python) or deb and rpm packages
+	(--type system), and each is identified by its metadata: name, version,
+	and architecture, or for wheels name, version, Python tag, ABI, and
+	platform. Packages Alpacon already lists are skipped; the rest are
+	uploaded in parallel.
+
+	A deb whose control archive is compressed with xz or zstd is identified
+	by its file name, {name}_{version}_{arch}.deb, which leaves out the
+	version's epoch; it matches Alpacon's copy with any epoch.
+
+	With --prune, packages of the type that are in Alpacon but not in the
+	directory are deleted. Prune refuses to run while a package file cannot
+	be read, and never deletes a package of the same name and arch as a deb
+	identified by its file name. The plan is shown before anything changes;
+	--dry-run stops there.
+	`,
+	Example: `
+	alpacon package sync ./dist --type python
+	alpacon package sync /srv/repo --type system --dry-run
+	alpacon package sync /srv/repo --type system --prune -y
+	`,
+	Args: cobra.ExactArgs(1),
+	Run:  runPackageSync,
+}
+
+func init() {
+	packageSyncCmd.Flags().String("type", "", "Package type: python or system (required)")
+	packageSyncCmd.Flags().Bool("prune", false, "Delete packages of the type that the directory does not have")
+	packageSyncCmd.Flags().Bool("dry-run", false, "Show the plan without changing anything")
+	packageSyncCmd.Flags().Int("parallel", 4, "Number of uploads and deletions to run at once")
+	packageSyncCmd.Flags().BoolP("yes", "y", false, "Skip confirmation prompt")
+	_ = packageSyncCmd.MarkFlagRequired("type")
+	_ = packageSyncCmd.RegisterFlagCompletionFunc("type", cobra.FixedCompletions(validSyncTypes, cobra.ShellCompDirectiveNoFileComp))
+}
+
+func runPackageSync(cmd *cobra.Command, args []string) {
+	dir := args[0]
+	packageType, _ := cmd.Flags().GetString("type")
+	prune, _ := cmd.Flags().GetBool("prune")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	parallel, _ := cmd.Flags().GetInt("parallel")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	if !slices.Contains(validSyncTypes, packageType) {
+		utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "Invalid --type %q. Valid values: %s.", packageType, strings.Join(validSyncTypes, ", "))
+	}
+	if parallel < 1 {
+		utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "--parallel must be at least 1.")
+	}
+
+	local, skipped, unreadable, err := scanPackages(dir, packageType)
+	if err != nil {
+		utils.CliErrorWithExit("Failed to read %s: %s.", dir, err)
+	}
+	if prune && unreadable > 0 {
+		utils.PrintTable(skipped)
+		utils.CliErrorWithExit("%d package files in %s could not be read. Refusing to --prune, which would delete their copies in Alpacon; fix or remove them, or run without --prune.", unreadable, dir)
+	}
+	if prune && len(local) == 0 {
+		utils.CliErrorWithExit("No %s packages found in %s. Refusing to --prune every %s package in Alpacon.", packageType, dir, packageType)
+	}
+
+	alpaconClient, err := client.NewAlpaconAPIClient()
+	if err != nil {
+		utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
+	}
+
+	remote, err := remotePackages(alpaconClient, packageType)
+	if err != nil {
+		utils.CliErrorWithExit("Failed to retrieve the %s packages: %s.", packageType, err)
+	}
+
+	plan := planSync(packageType, local, remote, prune)
+	plan.skipped = append(skipped, plan.skipped...)
+	pending := len(plan.uploads) + len(plan.deletes)
+	output := packageSyncOutput{Dir: dir, Type: packageType, DryRun: dryRun, Unchanged: plan.unchanged}
+
+	rows := plan.rows(packageType)
+	if utils.OutputFormat != utils.OutputFormatJSON && len(rows) > 0 {
+		utils.PrintTable(rows)
+	}
+	if pending == 0 || dryRun {
+		if utils.OutputFormat == utils.OutputFormatJSON {
+			output.Changes = rows
+			printPackageSyncJSON(output)
+		}
+		if pending == 0 {
+			utils.CliSuccess("Nothing to sync: Alpacon already has the %d %s packages in %s.", plan.unchanged, packageType, dir)
+		} else {
+			utils.CliInfo("%d uploads and %d deletions planned. Run without --dry-run to apply them.", len(plan.uploads), len(plan.deletes))
+		}
+		return
+	}
+	if len(plan.deletes) > 0 && !yes {
+		utils.ConfirmAction("Upload %d packages and delete %d from Alpacon?", len(plan.uploads), len(plan.deletes))
+	}
+
+	failed := plan.apply(syncDeps{
+		upload: func(path string) error {
+			return packages.UploadPackage(alpaconClient, path, packageType)
+		},
+		remove: func(id string) error {
+			return packages.DeletePackage(alpaconClient, id, packageType)
+		},
+	}, parallel)
+	output.Applied, output.Changes = true, plan.rows(packageType)
+	if utils.OutputFormat == utils.OutputFormatJSON {
+		printPackageSyncJSON(output)
+	} else {
+		utils.PrintHeader("Result")
+		utils.PrintTable(output.Changes)
+	}
+
+	if failed > 0 {
+		utils.CliErrorWithExit("%d of %d changes failed. Run the sync again to retry them; what succeeded is not repeated.", failed, pending)
+	}
+	utils.CliSuccess("Synced %s: %d uploaded, %d deleted, %d already present.", dir, len(plan.uploads), len(plan.deletes), plan.unchanged)
+}
+
+type packageSyncOutput struct {
+	Dir       string    `json:"dir"`
+	Type      string    `json:"type"`
+	DryRun    bool      `json:"dry_run"`
+	Applied   bool      `json:"applied"`
+	Unchanged int       `json:"unchanged"`
+	Changes   []syncRow `json:"changes"`
+}
+
+func printPackageSyncJSON(output packageSyncOutput) {
+	if err := utils.PrintJSONValue(os.Stdout, output); err != nil {
+		utils.CliErrorWithExit("Failed to marshal the sync plan: %v.", err)
+	}
+}
+
+// localPackage is a package file found in the directory.
+type localPackage struct {
+	Path string
+	Meta pkgmeta.Metadata
+}
+
+// remotePackage is a package Alpacon lists.
+type remotePackage struct {
+	ID   string
+	Meta pkgmeta.Metadata
+}
+
+// packageKey identifies a package regardless of its file name, so a file
+// matches what Alpacon lists for it.
+func packageKey(packageType string, m pkgmeta.Metadata) string {
+	if packageType == "python" {
+		return strings.Join([]string{pkgmeta.NormalizeName(m.Name), m.Version, m.PythonTarget, m.ABI, m.Platform}, "|")
+	}
+	return strings.Join([]string{m.Name, m.Version, m.Arch}, "|")
+}
+
+// scanPackages walks dir for the files of packageType and reads their
+// metadata. A file that cannot be read, or that repeats a package found
+// earlier, comes back as a skip row instead; unreadable counts the former.
+func scanPackages(dir, packageType string) (local []localPackage, skipped []syncRow, unreadable int, err error) {
+	seen := map[string]string{}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !slices.Contains(syncExtensions[packageType], strings.ToLower(filepath.Ext(path))) {
+			return nil
+		}
+		meta, err := pkgmeta.Read(path)
+		if err != nil {
+			skipped = append(skipped, syncRow{Action: "skip", File: path, Status: err.Error()})
+			unreadable++
+			return nil
+		}
+		key := packageKey(packageType, meta)
+		if first, ok := seen[key]; ok {
+			skipped = append(skipped, syncRow{Action: "skip", Package: meta.Name, Version: meta.Version, File: path, Status: "same package as " + first})
+			return nil
+		}
+		seen[key] = path
+		local = append(local, localPackage{Path: path, Meta: meta})
+		return nil
+	})
+	return local, skipped, unreadable, err
+}
+
+func remotePackages(ac *client.AlpaconClient, packageType string) ([]remotePackage, error) {
+	var remote []remotePackage
+	if packageType == "python" {
+		details, err := packages.GetPythonPackageDetails(ac)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range details {
+			remote = append(remote, remotePackage{ID: d.ID, Meta: pkgmeta.Metadata{
+				Name: d.Name, Version: d.Version, PythonTarget: d.Target, ABI: d.ABI, Platform: d.Platform,
+			}})
+		}
+		return remote, nil
+	}
+
+	details, err := packages.GetSystemPackageDetails(ac)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range details {
+		remote = append(remote, remotePackage{ID: d.ID, Meta: pkgmeta.Metadata{Name: d.Name, Version: d.Version, Arch: d.Arch}})
+	}
+	return remote, nil
+}
+
+type syncPlan struct {
+	uploads   []localPackage
+	deletes   []remotePackage
+	skipped   []syncRow
+	unchanged int
+	// results holds the outcome of each upload, then each delete, once applied.
+	results []string
+}
+
+// planSync uploads the local packages Alpacon does not list and, with prune,
+// deletes the listed ones the directory does not have. A deb whose metadata
+// came from its file name has no epoch, so it matches Alpacon's copy without
+// one, and prune keeps every package of its name and arch.
+func planSync(packageType string, local []localPackage, remote []remotePackage, prune bool) syncPlan {
+	var plan syncPlan
+	remoteKeys := map[string]bool{}
+	remoteEpochless := map[string]bool{}
+	for _, r := range remote {
+		remoteKeys[packageKey(packageType, r.Meta)] = true
+		remoteEpochless[epochlessKey(r.Meta)] = true
+	}
+	localKeys := map[string]bool{}
+	kept := map[string]bool{}
+	for _, l := range local {
+		key := packageKey(packageType, l.Meta)
+		present := remoteKeys[key]
+		if l.Meta.FromFileName {
+			present = remoteEpochless[epochlessKey(l.Meta)]
+			kept[l.Meta.Name+"|"+l.Meta.Arch] = true
+		}
+		localKeys[key] = true
+		if present {
+			plan.unchanged++
+		} else {
+			plan.uploads = append(plan.uploads, l)
+		}
+	}
+	if prune {
+		for _, r := range remote {
+			switch {
+			case localKeys[packageKey(packageType, r.Meta)]:
+			case kept[r.Meta.Name+"|"+r.Meta.Arch]:
+				if !localEpochless(local, r.Meta) {
+					plan.skipped = append(plan.skipped, syncRow{
+						Action: "keep", Package: r.Meta.Name, Version: r.Meta.Version, Platform: r.Meta.Arch,
+						Status: "not pruned: a file of this package has its version only in the file name",
+					})
+				}
+			default:
+				plan.deletes = append(plan.deletes, r)
+			}
+		}
+	}
+	return plan
+}
+
+// epochlessKey identifies a system package with the epoch left out of its
+// version, as deb file names write it.
+func epochlessKey(m pkgmeta.Metadata) string {
+	version := m.Version
+	if _, rest, ok := strings.Cut(version, ":"); ok {
+		version = rest
+	}
+	return strings.Join([]string{m.Name, version, m.Arch}, "|")
+}
+
+func localEpochless(local []localPackage, m pkgmeta.Metadata) bool {
+	for _, l := range local {
+		if l.Meta.FromFileName && epochlessKey(l.Meta) == epochlessKey(m) {
+			return true
+		}
+	}
+	return false
+}
+
+type syncRow struct {
+	Action   string `json:"action"`
+	Package  string `json:"package"`
+	Version  string `json:"version"`
+	Platform string `json:"platform"`
+	File     string `json:"file"`
+	Status   string `json:"status"`
+}
+
+func platformLabel(packageType string, m pkgmeta.Metadata) string {
+	if packageType == "python" {
+		return strings.Join([]string{m.PythonTarget, m.ABI, m.Platform}, "-")
+	}
+	return m.Arch
+}
+
+func (p syncPlan) rows(packageType string) []syncRow {
+	var rows []syncRow
+	for i, u := range p.uploads {
+		rows = append(rows, syncRow{
+			Action: "upload", Package: u.Meta.Name, Version: u.Meta.Version,
+			Platform: platformLabel(packageType, u.Meta), File: u.Path, Status: p.result(i),
+		})
+	}
+	for i, d := range p.deletes {
+		rows = append(rows, syncRow{
+			Action: "delete", Package: d.Meta.Name, Version: d.Meta.Version,
+			Platform: platformLabel(packageType, d.Meta), Status: p.result(len(p.uploads) + i),
+		})
+	}
+	return append(rows, p.skipped...)
+}
+
+func (p syncPlan) result(i int) string {
+	if i < len(p.results) {
+		return p.results[i]
+	}
+	return ""
+}
+
+type syncDeps struct {
+	upload func(path string) error
+	remove func(id string) error
+}
+
+// apply runs the uploads and deletions, parallel at a time, records each
+// outcome for rows, and returns how many failed. A failure does not stop
+// the others.
+func (p *syncPlan) apply(deps syncDeps, parallel int) int {
+	total := len(p.uploads) + len(p.deletes)
+	p.results = make([]string, total)
+
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := 0
+	for i := range total {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			var err error
+			status := "uploaded"
+			if i < len(p.uploads) {
+				err = deps.upload(p.uploads[i].Path)
+			} else {
+				status = "deleted"
+				err = deps.remove(p.deletes[i-len(p.uploads)].ID)
+			}
+			if err != nil {
+				status = fmt.Sprintf("failed: %s", err)
+				mu.Lock()
+				failed++
+				mu.Unlock()
+			}
+			p.results[i] = status
+		}(i)
+	}
+	wg.Wait()
+	return failed
+}
@@ -0,0 +1,120 @@
+package packages
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/alpacax/alpacon-cli/pkg/pkgmeta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanPackages(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"alpamon-1.1.0-py3-none-any.whl",
+		"nested/Alpamon-1.1.0-py3-none-any.whl",
+		"nested/tool-2.0-py3-none-any.whl",
+		"broken.whl",
+		"README.md",
+		"osquery_5.8.2-1_amd64.deb",
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, nil, 0644))
+	}
+
+	local, skipped, unreadable, err := scanPackages(dir, "python")
+	require.NoError(t, err)
+	assert.Equal(t, 1, unreadable)
+	require.Len(t, local, 2)
+	assert.Equal(t, "alpamon", local[0].Meta.Name)
+	assert.Equal(t, "tool", local[1].Meta.Name)
+
+	require.Len(t, skipped, 2)
+	assert.Equal(t, filepath.Join(dir, "broken.whl"), skipped[0].File)
+	assert.Contains(t, skipped[1].Status, "same package as")
+}
+
+func TestPlanSync(t *testing.T) {
+	local := []localPackage{
+		{Path: "a.whl", Meta: pkgmeta.Metadata{Name: "My_Pkg", Version: "1.0", PythonTarget: "py3", ABI: "none", Platform: "any"}},
+		{Path: "b.whl", Meta: pkgmeta.Metadata{Name: "my-pkg", Version: "1.1", PythonTarget: "py3", ABI: "none", Platform: "any"}},
+	}
+	remote := []remotePackage{
+		{ID: "1", Meta: pkgmeta.Metadata{Name: "my-pkg", Version: "1.0", PythonTarget: "py3", ABI: "none", Platform: "any"}},
+		{ID: "2", Meta: pkgmeta.Metadata{Name: "old", Version: "0.1", PythonTarget: "py3", ABI: "none", Platform: "any"}},
+	}
+
+	plan := planSync("python", local, remote, false)
+	assert.Equal(t, 1, plan.unchanged)
+	require.Len(t, plan.uploads, 1)
+	assert.Equal(t, "b.whl", plan.uploads[0].Path)
+	assert.Empty(t, plan.deletes)
+
+	plan = planSync("python", local, remote, true)
+	require.Len(t, plan.deletes, 1)
+	assert.Equal(t, "2", plan.deletes[0].ID)
+}
+
+func TestPlanSync_VersionFromFileName(t *testing.T) {
+	local := []localPackage{
+		{Path: "osquery_5.8.2-1_amd64.deb", Meta: pkgmeta.Metadata{Name: "osquery", Version: "5.8.2-1", Arch: "amd64", FromFileName: true}},
+		{Path: "tool_1.0_amd64.deb", Meta: pkgmeta.Metadata{Name: "tool", Version: "1.0", Arch: "amd64", FromFileName: true}},
+	}
+	remote := []remotePackage{
+		{ID: "1", Meta: pkgmeta.Metadata{Name: "osquery", Version: "1:5.8.2-1", Arch: "amd64"}},
+		{ID: "2", Meta: pkgmeta.Metadata{Name: "tool", Version: "1:0.9", Arch: "amd64"}},
+		{ID: "3", Meta: pkgmeta.Metadata{Name: "gone", Version: "1.0", Arch: "amd64"}},
+	}
+
+	plan := planSync("system", local, remote, true)
+	assert.Equal(t, 1, plan.unchanged, "the epoch is ignored for a version from the file name")
+	require.Len(t, plan.uploads, 1)
+	assert.Equal(t, "tool_1.0_amd64.deb", plan.uploads[0].Path)
+	require.Len(t, plan.deletes, 1, "prune keeps packages a file-name-only deb may be")
+	assert.Equal(t, "3", plan.deletes[0].ID)
+	require.Len(t, plan.skipped, 1)
+	assert.Equal(t, "keep", plan.skipped[0].Action)
+	assert.Equal(t, "1:0.9", plan.skipped[0].Version)
+}
+
+func TestSyncPlanApply(t *testing.T) {
+	plan := syncPlan{
+		uploads: []localPackage{
+			{Path: "a.deb", Meta: pkgmeta.Metadata{Name: "a", Version: "1", Arch: "amd64"}},
+			{Path: "b.deb", Meta: pkgmeta.Metadata{Name: "b", Version: "1", Arch: "amd64"}},
+		},
+		deletes: []remotePackage{{ID: "9", Meta: pkgmeta.Metadata{Name: "c", Version: "1", Arch: "amd64"}}},
+		skipped: []syncRow{{Action: "skip", File: "d.deb", Status: "not a deb package"}},
+	}
+
+	var mu sync.Mutex
+	var removed []string
+	failed := plan.apply(syncDeps{
+		upload: func(path string) error {
+			if path == "b.deb" {
+				return errors.New("quota exceeded")
+			}
+			return nil
+		},
+		remove: func(id string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			removed = append(removed, id)
+			return nil
+		},
+	}, 2)
+
+	assert.Equal(t, 1, failed)
+	assert.Equal(t, []string{"9"}, removed)
+	rows := plan.rows("system")
+	require.Len(t, rows, 4)
+	assert.Equal(t, syncRow{Action: "upload", Package: "a", Version: "1", Platform: "amd64", File: "a.deb", Status: "uploaded"}, rows[0])
+	assert.Equal(t, "failed: quota exceeded", rows[1].Status)
+	assert.Equal(t, syncRow{Action: "delete", Package: "c", Version: "1", Platform: "amd64", Status: "deleted"}, rows[2])
+	assert.Equal(t, "skip", rows[3].Action)
+}
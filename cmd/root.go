@@ -94,6 +94,7 @@ func init() {
 	// iam
 	RootCmd.AddCommand(iam.UserCmd)
 	RootCmd.AddCommand(iam.GroupCmd)
+	RootCmd.AddCommand(iam.IAMCmd)
 
 	// server
 	RootCmd.AddCommand(server.ServerCmd)
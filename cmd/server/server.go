@@ -15,7 +15,7 @@ var ServerCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		return errors.New("a subcommand is required. Use 'alpacon server list', 'alpacon server create', 'alpacon server describe', 'alpacon server update', 'alpacon server delete', 'alpacon server reboot', 'alpacon server shutdown', 'alpacon server upgrade', 'alpacon server refresh', or 'alpacon server token'. Run 'alpacon server --help' for more information")
+		return errors.New("a subcommand is required. Use 'alpacon server list', 'alpacon server create', 'alpacon server describe', 'alpacon server update', 'alpacon server delete', 'alpacon server reboot', 'alpacon server shutdown', 'alpacon server upgrade', 'alpacon server refresh', 'alpacon server export', 'alpacon server wait', or 'alpacon server token'. Run 'alpacon server --help' for more information")
 	},
 }
 
@@ -30,4 +30,6 @@ func init() {
 	ServerCmd.AddCommand(serverShutdownCmd)
 	ServerCmd.AddCommand(serverUpgradeCmd)
 	ServerCmd.AddCommand(serverRefreshCmd)
+	ServerCmd.AddCommand(serverExportCmd)
+	ServerCmd.AddCommand(serverWaitCmd)
 }
@@ -3,6 +3,7 @@ package server
 import (
 	"github.com/alpacax/alpacon-cli/api/server"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -21,7 +22,8 @@ var serverDeleteCmd = &cobra.Command{
 	alpacon server rm my-server
 	alpacon server delete my-server -y
 	`,
-	Args: cobra.ExactArgs(1),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Servers),
 	Run: func(cmd *cobra.Command, args []string) {
 		serverName := args[0]
 
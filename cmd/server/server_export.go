@@ -0,0 +1,259 @@
+package server
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/alpacax/alpacon-cli/api/server"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	validExportFormats     = []string{"ansible-ini", "ansible-yaml", "ssh-config", "csv"}
+	validExportFormatsList = strings.Join(validExportFormats, ", ")
+)
+
+var (
+	hostAliasUnsafe  = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+	groupNameUnsafe  = regexp.MustCompile(`[^a-z0-9_]+`)
+	iniValueUnquoted = regexp.MustCompile(`^[A-Za-z0-9._@:/-]*$`)
+)
+
+var serverExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the servers as an Ansible inventory, SSH config, or CSV",
+	Long: `
+	Write every registered server to stdout in a format other tools read, so
+	existing automation can target Alpacon-managed hosts.
+
+	ansible-ini and ansible-yaml produce an inventory whose hosts carry
+	ansible_host (the server's IP) and ansible_user (its owner), grouped by
+	OS (os_ubuntu), Alpacon group (group_web), and connection state
+	(connected, disconnected). Server names that are not valid host names are
+	rewritten, with the original kept in alpacon_server.
+
+	ssh-config produces one Host block per server with its IP and owner.
+	csv produces one row per server.
+	`,
+	Example: `
+	alpacon server export > inventory.ini
+	alpacon server export --format ansible-yaml > inventory.yml
+	alpacon server export --format ssh-config >> ~/.ssh/config.d/alpacon
+	alpacon server export --format csv --connected-only
+	`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		connectedOnly, _ := cmd.Flags().GetBool("connected-only")
+		if !slices.Contains(validExportFormats, format) {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "Invalid --format %q. Valid values: %s.", format, validExportFormatsList)
+		}
+
+		alpaconClient, err := client.NewAlpaconAPIClient()
+		if err != nil {
+			utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
+		}
+
+		hosts, err := server.GetServerInventory(alpaconClient)
+		if err != nil {
+			utils.CliErrorWithExit("Failed to retrieve the servers: %s.", err)
+		}
+		if connectedOnly {
+			hosts = slices.DeleteFunc(hosts, func(h server.InventoryHost) bool { return !h.Connected })
+		}
+
+		if err := writeInventory(os.Stdout, format, hosts); err != nil {
+			utils.CliErrorWithExit("Failed to write the inventory: %s.", err)
+		}
+	},
+}
+
+func init() {
+	serverExportCmd.Flags().String("format", "ansible-ini", fmt.Sprintf("output format: %s", validExportFormatsList))
+	serverExportCmd.Flags().Bool("connected-only", false, "export only servers that are connected")
+}
+
+func writeInventory(w io.Writer, format string, hosts []server.InventoryHost) error {
+	slices.SortFunc(hosts, func(a, b server.InventoryHost) int { return strings.Compare(a.Name, b.Name) })
+	switch format {
+	case "ansible-ini":
+		return writeAnsibleINI(w, buildInventory(hosts))
+	case "ansible-yaml":
+		return writeAnsibleYAML(w, buildInventory(hosts))
+	case "ssh-config":
+		return writeSSHConfig(w, hosts)
+	case "csv":
+		return writeCSV(w, hosts)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// inventoryHost is a server as an Ansible inventory names it.
+type inventoryHost struct {
+	Alias string
+	Vars  [][2]string
+}
+
+type inventory struct {
+	Hosts  []inventoryHost
+	Groups map[string][]string
+}
+
+func buildInventory(hosts []server.InventoryHost) inventory {
+	inv := inventory{Groups: map[string][]string{}}
+	for _, h := range hosts {
+		alias := hostAliasUnsafe.ReplaceAllString(h.Name, "_")
+		var vars [][2]string
+		if h.IP != "" {
+			vars = append(vars, [2]string{"ansible_host", h.IP})
+		}
+		if h.Owner != "" {
+			vars = append(vars, [2]string{"ansible_user", h.Owner})
+		}
+		if alias != h.Name {
+			vars = append(vars, [2]string{"alpacon_server", h.Name})
+		}
+		if osLabel := strings.TrimSpace(h.OSName + " " + h.OSVersion); osLabel != "" {
+			vars = append(vars, [2]string{"alpacon_os", osLabel})
+		}
+		inv.Hosts = append(inv.Hosts, inventoryHost{Alias: alias, Vars: vars})
+
+		state := "disconnected"
+		if h.Connected {
+			state = "connected"
+		}
+		groups := []string{state, ansibleGroupName("os", h.OSName)}
+		for _, g := range h.Groups {
+			groups = append(groups, ansibleGroupName("group", g))
+		}
+		for _, g := range groups {
+			if g != "" && !slices.Contains(inv.Groups[g], alias) {
+				inv.Groups[g] = append(inv.Groups[g], alias)
+			}
+		}
+	}
+	return inv
+}
+
+// ansibleGroupName turns a name into a valid Ansible group: lowercase letters,
+// digits, and underscores, behind a prefix so it never starts with a digit.
+// It returns "" when nothing of the name is left.
+func ansibleGroupName(prefix, name string) string {
+	name = strings.Trim(groupNameUnsafe.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return ""
+	}
+	return prefix + "_" + name
+}
+
+func (inv inventory) groupNames() []string {
+	names := make([]string, 0, len(inv.Groups))
+	for name := range inv.Groups {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func writeAnsibleINI(w io.Writer, inv inventory) error {
+	var b strings.Builder
+	b.WriteString("# Generated by alpacon server export\n[all]\n")
+	for _, h := range inv.Hosts {
+		b.WriteString(h.Alias)
+		for _, v := range h.Vars {
+			value := v[1]
+			if !iniValueUnquoted.MatchString(value) {
+				value = strconv.Quote(value)
+			}
+			fmt.Fprintf(&b, " %s=%s", v[0], value)
+		}
+		b.WriteString("\n")
+	}
+	for _, name := range inv.groupNames() {
+		fmt.Fprintf(&b, "\n[%s]\n", name)
+		for _, alias := range inv.Groups[name] {
+			b.WriteString(alias + "\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeAnsibleYAML(w io.Writer, inv inventory) error {
+	hosts := map[string]map[string]string{}
+	for _, h := range inv.Hosts {
+		vars := map[string]string{}
+		for _, v := range h.Vars {
+			vars[v[0]] = v[1]
+		}
+		hosts[h.Alias] = vars
+	}
+	children := map[string]any{}
+	for name, members := range inv.Groups {
+		groupHosts := map[string]map[string]string{}
+		for _, alias := range members {
+			groupHosts[alias] = map[string]string{}
+		}
+		children[name] = map[string]any{"hosts": groupHosts}
+	}
+	all := map[string]any{"hosts": hosts}
+	if len(children) > 0 {
+		all["children"] = children
+	}
+
+	if _, err := io.WriteString(w, "# Generated by alpacon server export\n"); err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(map[string]any{"all": all}); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// writeSSHConfig writes one Host block per server. A server with no known IP
+// is left out, with a comment saying so.
+func writeSSHConfig(w io.Writer, hosts []server.InventoryHost) error {
+	var b strings.Builder
+	b.WriteString("# Generated by alpacon server export\n")
+	for _, h := range hosts {
+		alias := hostAliasUnsafe.ReplaceAllString(h.Name, "_")
+		if h.IP == "" {
+			fmt.Fprintf(&b, "\n# %s: no IP address known\n", alias)
+			continue
+		}
+		fmt.Fprintf(&b, "\nHost %s\n    HostName %s\n", alias, h.IP)
+		if h.Owner != "" {
+			fmt.Fprintf(&b, "    User %s\n", h.Owner)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeCSV(w io.Writer, hosts []server.InventoryHost) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"name", "ip", "os", "connected", "owner", "groups"})
+	for _, h := range hosts {
+		_ = cw.Write([]string{
+			h.Name,
+			h.IP,
+			strings.TrimSpace(h.OSName + " " + h.OSVersion),
+			strconv.FormatBool(h.Connected),
+			h.Owner,
+			strings.Join(h.Groups, ";"),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alpacax/alpacon-cli/api/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func exportTestHosts() []server.InventoryHost {
+	return []server.InventoryHost{
+		{Name: "web-2", IP: "10.0.0.2", OSName: "Ubuntu", OSVersion: "22.04", Connected: false, Owner: "alice", Groups: []string{"Web Tier"}},
+		{Name: "db 1", IP: "10.0.0.9", OSName: "Rocky Linux", OSVersion: "9", Connected: true, Owner: "bob"},
+		{Name: "web-1", OSName: "Ubuntu", OSVersion: "22.04", Connected: true, Owner: "alice", Groups: []string{"Web Tier"}},
+	}
+}
+
+func TestWriteInventory_AnsibleINI(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, writeInventory(&b, "ansible-ini", exportTestHosts()))
+	assert.Equal(t, `# Generated by alpacon server export
+[all]
+db_1 ansible_host=10.0.0.9 ansible_user=bob alpacon_server="db 1" alpacon_os="Rocky Linux 9"
+web-1 ansible_user=alice alpacon_os="Ubuntu 22.04"
+web-2 ansible_host=10.0.0.2 ansible_user=alice alpacon_os="Ubuntu 22.04"
+
+[connected]
+db_1
+web-1
+
+[disconnected]
+web-2
+
+[group_web_tier]
+web-1
+web-2
+
+[os_rocky_linux]
+db_1
+
+[os_ubuntu]
+web-1
+web-2
+`, b.String())
+}
+
+func TestWriteInventory_AnsibleYAML(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, writeInventory(&b, "ansible-yaml", exportTestHosts()))
+
+	var parsed struct {
+		All struct {
+			Hosts    map[string]map[string]string `yaml:"hosts"`
+			Children map[string]struct {
+				Hosts map[string]map[string]string `yaml:"hosts"`
+			} `yaml:"children"`
+		} `yaml:"all"`
+	}
+	require.NoError(t, yaml.Unmarshal([]byte(b.String()), &parsed))
+	assert.Equal(t, map[string]string{"ansible_host": "10.0.0.2", "ansible_user": "alice", "alpacon_os": "Ubuntu 22.04"}, parsed.All.Hosts["web-2"])
+	assert.Equal(t, "db 1", parsed.All.Hosts["db_1"]["alpacon_server"])
+	assert.Len(t, parsed.All.Children["os_ubuntu"].Hosts, 2)
+	assert.Contains(t, parsed.All.Children["disconnected"].Hosts, "web-2")
+}
+
+func TestWriteInventory_SSHConfig(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, writeInventory(&b, "ssh-config", exportTestHosts()))
+	assert.Equal(t, `# Generated by alpacon server export
+
+Host db_1
+    HostName 10.0.0.9
+    User bob
+
+# web-1: no IP address known
+
+Host web-2
+    HostName 10.0.0.2
+    User alice
+`, b.String())
+}
+
+func TestWriteInventory_CSV(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, writeInventory(&b, "csv", exportTestHosts()))
+	assert.Equal(t, `name,ip,os,connected,owner,groups
+db 1,10.0.0.9,Rocky Linux 9,true,bob,
+web-1,,Ubuntu 22.04,true,alice,Web Tier
+web-2,10.0.0.2,Ubuntu 22.04,false,alice,Web Tier
+`, b.String())
+}
+
+func TestAnsibleGroupName(t *testing.T) {
+	assert.Equal(t, "os_red_hat_enterprise_linux", ansibleGroupName("os", "Red Hat Enterprise Linux"))
+	assert.Equal(t, "group_2024_ops", ansibleGroupName("group", "-2024 ops!"))
+	assert.Equal(t, "", ansibleGroupName("os", "  "))
+}
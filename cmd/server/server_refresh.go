@@ -3,6 +3,7 @@ package server
 import (
 	"github.com/alpacax/alpacon-cli/api/server"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -14,8 +15,9 @@ var serverRefreshCmd = &cobra.Command{
 	This command requests a refresh of a specified server's system information through its agent.
 	It is a non-disruptive action and does not require confirmation.
 	`,
-	Example: `alpacon server refresh my-server`,
-	Args:    cobra.ExactArgs(1),
+	Example:           `alpacon server refresh my-server`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Servers),
 	Run: func(cmd *cobra.Command, args []string) {
 		serverName := args[0]
 
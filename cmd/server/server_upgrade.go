@@ -2,6 +2,7 @@ package server
 
 import (
 	"github.com/alpacax/alpacon-cli/api/server"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/spf13/cobra"
 )
 
@@ -18,7 +19,8 @@ var serverUpgradeCmd = &cobra.Command{
 	alpacon server upgrade my-server -y
 	alpacon server upgrade my-server --force
 	`,
-	Args: cobra.ExactArgs(1),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Servers),
 	Run: func(cmd *cobra.Command, args []string) {
 		yes, _ := cmd.Flags().GetBool("yes")
 		force, _ := cmd.Flags().GetBool("force")
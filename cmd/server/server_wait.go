@@ -0,0 +1,257 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	eventapi "github.com/alpacax/alpacon-cli/api/event"
+	"github.com/alpacax/alpacon-cli/api/server"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+const (
+	opServerWait = "wait"
+
+	// eventTypeServer is the event channel type for server state changes. Its sub
+	// types are the --for states, plus serverDeletedSubType.
+	eventTypeServer      = "server"
+	serverDeletedSubType = "deleted"
+)
+
+var (
+	validWaitStates     = []string{"connected", "disconnected", "commissioned"}
+	validWaitStatesList = strings.Join(validWaitStates, ", ")
+)
+
+// serverWaitPollInterval is how often the server is read over REST, which
+// decides the wait when the event channel is unavailable or quiet; a var so
+// tests can shorten it.
+var serverWaitPollInterval = 10 * time.Second
+
+var serverWaitCmd = &cobra.Command{
+	Use:   "wait SERVER --for STATE",
+	Short: "Wait until a server is connected, disconnected, or commissioned",
+	Long: `
+	Block until a server reaches a state, then exit. Use it after server create,
+	server reboot, or agent restart instead of sleeping.
+
+	The wait listens on the event channel and also reads the server over REST
+	every 10 seconds, so it ends promptly when events arrive and still ends when
+	they do not. A server that is not registered yet is waited for.
+
+	The outcome is carried by the exit code, as in 'alpacon event wait':
+
+	  0  the server reached the state
+	  1  the wait could not run
+	  2  a flag or argument was rejected
+	  4  timed out or interrupted—the outcome is still open
+	  6  the server was deleted while waiting
+	`,
+	Example: `
+	alpacon server wait my-server --for connected
+	alpacon server reboot my-server -y && alpacon server wait my-server --for disconnected --timeout 2m && alpacon server wait my-server --for connected
+	alpacon server wait new-server --for commissioned --timeout 30m && alpacon exec new-server -- uptime
+	`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Servers),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverName := args[0]
+		state, _ := cmd.Flags().GetString("for")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		if !slices.Contains(validWaitStates, state) {
+			utils.CliUsageErrorEnvelopeWithExit(opServerWait, "Invalid --for %q. Valid values: %s.", state, validWaitStatesList)
+		}
+		if timeout <= 0 {
+			utils.CliUsageErrorEnvelopeWithExit(opServerWait, "--timeout must be positive.")
+		}
+
+		alpaconClient, err := client.NewAlpaconAPIClient()
+		if err != nil {
+			utils.CliErrorEnvelopeWithExit(opServerWait, err, "Connection to Alpacon API failed: %s. Consider re-logging.", err)
+		}
+
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sigChan)
+
+		w := &serverWaiter{
+			name:  serverName,
+			state: state,
+			read: func() (server.ServerDetails, error) {
+				return readServerDetails(alpaconClient, serverName)
+			},
+			events: func(serverID string, timeout time.Duration) <-chan serverEvent {
+				return watchServerEvents(alpaconClient, serverID, state, timeout, func() (server.ServerDetails, error) {
+					return readServerDetails(alpaconClient, serverName)
+				})
+			},
+			after:     time.After,
+			interrupt: sigChan,
+		}
+
+		utils.CliInfo("Waiting for %s to be %s (timeout %s). Press Ctrl+C to stop.", serverName, state, timeout)
+		outcome, err := w.wait(timeout)
+		if err != nil {
+			utils.CliErrorEnvelopeWithExit(opServerWait, err, "Failed to read the server: %s.", err)
+		}
+
+		switch outcome {
+		case eventapi.OutcomeMatched:
+			if utils.OutputFormat == utils.OutputFormatJSON {
+				if err := utils.PrintJSONValue(os.Stdout, map[string]any{"ok": true, "server": serverName, "state": state}); err != nil {
+					utils.CliErrorEnvelopeWithExit(opServerWait, err, "Failed to encode the result: %s.", err)
+				}
+				return
+			}
+			utils.CliSuccess("Server %s is %s.", serverName, state)
+		case eventapi.OutcomeFailed:
+			utils.CliErrorEnvelopeWithExitCode(utils.ExitCodeNotApproved, opServerWait, nil, "Server %s was deleted before it was %s.", serverName, state)
+		case eventapi.OutcomeTimeout:
+			utils.PrintPendingApproval(
+				fmt.Sprintf("Timed out after %s before %s was %s. The outcome is still open.", timeout, serverName, state),
+				"", utils.NextAction{})
+			os.Exit(utils.ExitCodePendingApproval)
+		default:
+			utils.PrintPendingApproval(
+				fmt.Sprintf("Interrupted before %s was %s. The outcome is still open.", serverName, state),
+				"", utils.NextAction{})
+			os.Exit(utils.ExitCodePendingApproval)
+		}
+	},
+}
+
+func init() {
+	serverWaitCmd.Flags().String("for", "connected", fmt.Sprintf("state to wait for: %s", validWaitStatesList))
+	serverWaitCmd.Flags().Duration("timeout", 10*time.Minute, "how long to wait before giving up")
+}
+
+func readServerDetails(ac *client.AlpaconClient, serverName string) (server.ServerDetails, error) {
+	body, err := server.GetServerDetail(ac, serverName)
+	if err != nil {
+		return server.ServerDetails{}, err
+	}
+	var details server.ServerDetails
+	err = json.Unmarshal(body, &details)
+	return details, err
+}
+
+// serverInState reports whether a server read over REST is in state.
+func serverInState(d server.ServerDetails, state string) bool {
+	switch state {
+	case "connected":
+		return d.IsConnected
+	case "disconnected":
+		return !d.IsConnected
+	case "commissioned":
+		return d.Commissioned
+	}
+	return false
+}
+
+// serverEvent is how the event channel decided a wait, or why it could not.
+type serverEvent struct {
+	outcome eventapi.Outcome
+	err     error
+}
+
+// watchServerEvents waits on the event channel in the background. Its catch-up
+// read answers in the channel's vocabulary: the state when the server is in
+// it, deleted when it is gone.
+func watchServerEvents(ac *client.AlpaconClient, serverID, state string, timeout time.Duration, read func() (server.ServerDetails, error)) <-chan serverEvent {
+	waiter := eventapi.NewWaiter(ac, eventTypeServer, serverID, eventapi.WaitOptions{
+		OK:      []string{state},
+		Fail:    []string{serverDeletedSubType},
+		Timeout: timeout,
+		CatchUp: func() (string, error) {
+			d, err := read()
+			switch {
+			case errors.Is(err, server.ErrServerNotFound):
+				return serverDeletedSubType, nil
+			case err != nil:
+				return "", err
+			case serverInState(d, state):
+				return state, nil
+			}
+			return "", nil
+		},
+	})
+	result := make(chan serverEvent, 1)
+	go func() {
+		_, outcome, err := waiter.Wait()
+		result <- serverEvent{outcome: outcome, err: err}
+	}()
+	return result
+}
+
+type serverWaiter struct {
+	name  string
+	state string
+	read  func() (server.ServerDetails, error)
+	// events starts the event channel wait for a server; nil polls only.
+	events    func(serverID string, timeout time.Duration) <-chan serverEvent
+	after     func(time.Duration) <-chan time.Time
+	interrupt <-chan os.Signal
+}
+
+// wait polls the server and, once it exists, also listens for its events,
+// until either decides the wait. The error is only for a first read that
+// fails for a reason other than the server not existing yet.
+func (w *serverWaiter) wait(timeout time.Duration) (eventapi.Outcome, error) {
+	deadline := w.after(timeout)
+	var events <-chan serverEvent
+	seen := false
+	first := true
+	for {
+		d, err := w.read()
+		switch {
+		case errors.Is(err, server.ErrServerNotFound):
+			if seen {
+				return eventapi.OutcomeFailed, nil
+			}
+			if first {
+				utils.CliInfo("%s is not registered yet; waiting for it.", w.name)
+			}
+		case err != nil:
+			if first {
+				return eventapi.OutcomeError, err
+			}
+			utils.CliWarning("Failed to read %s: %s. Still waiting.", w.name, err)
+		default:
+			if serverInState(d, w.state) {
+				return eventapi.OutcomeMatched, nil
+			}
+			if !seen && w.events != nil {
+				events = w.events(d.ID, timeout)
+			}
+			seen = true
+		}
+		first = false
+
+		select {
+		case <-deadline:
+			return eventapi.OutcomeTimeout, nil
+		case <-w.interrupt:
+			return eventapi.OutcomeCanceled, nil
+		case e := <-events:
+			events = nil
+			switch {
+			case e.err != nil:
+				utils.CliDebug("Event channel unavailable, polling only: %s", e.err)
+			case e.outcome == eventapi.OutcomeMatched, e.outcome == eventapi.OutcomeFailed:
+				return e.outcome, nil
+			}
+		case <-w.after(serverWaitPollInterval):
+		}
+	}
+}
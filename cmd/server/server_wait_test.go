@@ -0,0 +1,105 @@
+package server
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	eventapi "github.com/alpacax/alpacon-cli/api/event"
+	"github.com/alpacax/alpacon-cli/api/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedWaiter reads the server from a script, one entry per poll; once the
+// script runs out, the deadline fires and the poll does not.
+func scriptedWaiter(state string, reads []func() (server.ServerDetails, error)) *serverWaiter {
+	deadline := make(chan time.Time, 1)
+	i := 0
+	return &serverWaiter{
+		name:  "web-1",
+		state: state,
+		read: func() (server.ServerDetails, error) {
+			r := reads[i]
+			i++
+			if i == len(reads) {
+				deadline <- time.Now()
+			}
+			return r()
+		},
+		after: func(d time.Duration) <-chan time.Time {
+			if d != serverWaitPollInterval {
+				return deadline
+			}
+			c := make(chan time.Time, 1)
+			if i < len(reads) {
+				c <- time.Now()
+			}
+			return c
+		},
+		interrupt: make(chan os.Signal),
+	}
+}
+
+func details(connected, commissioned bool) func() (server.ServerDetails, error) {
+	return func() (server.ServerDetails, error) {
+		return server.ServerDetails{ID: "id-1", IsConnected: connected, Commissioned: commissioned}, nil
+	}
+}
+
+func readErr(err error) func() (server.ServerDetails, error) {
+	return func() (server.ServerDetails, error) { return server.ServerDetails{}, err }
+}
+
+func TestServerWaiter_Polling(t *testing.T) {
+	w := scriptedWaiter("connected", []func() (server.ServerDetails, error){
+		readErr(server.ErrServerNotFound),
+		details(false, false),
+		readErr(errors.New("bad gateway")),
+		details(true, false),
+		details(true, false),
+	})
+	outcome, err := w.wait(time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, eventapi.OutcomeMatched, outcome)
+
+	w = scriptedWaiter("commissioned", []func() (server.ServerDetails, error){details(true, false), details(true, false)})
+	outcome, err = w.wait(time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, eventapi.OutcomeTimeout, outcome)
+
+	w = scriptedWaiter("disconnected", []func() (server.ServerDetails, error){details(true, true), readErr(server.ErrServerNotFound), details(true, true)})
+	outcome, err = w.wait(time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, eventapi.OutcomeFailed, outcome)
+
+	w = scriptedWaiter("connected", []func() (server.ServerDetails, error){readErr(errors.New("forbidden"))})
+	_, err = w.wait(time.Minute)
+	assert.ErrorContains(t, err, "forbidden")
+}
+
+func TestServerWaiter_Events(t *testing.T) {
+	w := scriptedWaiter("connected", []func() (server.ServerDetails, error){details(false, true), details(false, true), details(false, true)})
+	events := make(chan serverEvent, 1)
+	var watched string
+	w.events = func(serverID string, _ time.Duration) <-chan serverEvent {
+		watched = serverID
+		events <- serverEvent{outcome: eventapi.OutcomeMatched}
+		return events
+	}
+	// The poll fires too; block it so the event decides.
+	w.after = func(d time.Duration) <-chan time.Time { return make(chan time.Time) }
+
+	outcome, err := w.wait(time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, eventapi.OutcomeMatched, outcome)
+	assert.Equal(t, "id-1", watched)
+}
+
+func TestServerInState(t *testing.T) {
+	d := server.ServerDetails{IsConnected: true}
+	assert.True(t, serverInState(d, "connected"))
+	assert.False(t, serverInState(d, "disconnected"))
+	assert.False(t, serverInState(d, "commissioned"))
+}
@@ -16,7 +16,11 @@ Three independent ACL types enforce deny-by-default:
   server   — which servers the token can access
   file     — which file paths the token can read/write via cp
 
-If no ACL rule exists for a given type, that access is denied entirely.`,
+If no ACL rule exists for a given type, that access is denied entirely.
+
+Use 'export' and 'apply' to keep a token's rules in a YAML file under version
+control instead of changing them one rule at a time, and 'test' to see which
+rule allows or denies a request without sending it.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		_ = cmd.Help()
 		return errors.New("a subcommand is required. Run 'alpacon token acl --help' for more information")
@@ -27,6 +31,9 @@ func init() {
 	AclCmd.AddCommand(aclCommandCmd)
 	AclCmd.AddCommand(aclServerCmd)
 	AclCmd.AddCommand(aclFileCmd)
+	AclCmd.AddCommand(aclApplyCmd)
+	AclCmd.AddCommand(aclExportCmd)
+	AclCmd.AddCommand(aclTestCmd)
 
 	// Legacy top-level aliases (deprecated, hidden)
 	AclCmd.AddCommand(aclAddCmd)
@@ -4,6 +4,7 @@ import (
 	"github.com/alpacax/alpacon-cli/api/auth"
 	"github.com/alpacax/alpacon-cli/api/security"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -22,6 +23,7 @@ func init() {
 	aclAddCmd.Flags().StringP("command", "c", "", "Server-side shell command (supports * wildcard)")
 	aclAddCmd.Flags().String("username", "", "Username restriction")
 	aclAddCmd.Flags().String("groupname", "", "Groupname restriction")
+	_ = aclAddCmd.RegisterFlagCompletionFunc("token", complete.Tokens)
 }
 
 func runLegacyAclAdd(cmd *cobra.Command, args []string) {
@@ -0,0 +1,250 @@
+package token
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/alpacax/alpacon-cli/api/auth"
+	"github.com/alpacax/alpacon-cli/api/security"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+var aclApplyCmd = &cobra.Command{
+	Use:   "apply [TOKEN] -f FILE",
+	Short: "Make a token's ACL rules match a file",
+	Long: `Compare a YAML ACL file against the token's command, server, and file ACL
+rules, print the plan, and apply it: rules missing from the token are added and
+rules the file does not list are deleted.
+
+The token is taken from the TOKEN argument, or from the file's "token" field.
+A section left out of the file is not managed and its rules are kept as they
+are; an explicit empty list ("servers: []") deletes every rule of that type.
+Use 'alpacon token acl export' to produce a starting file.
+
+Deletions run before additions, so the token never holds more than the old
+rules or the new ones at any moment.`,
+	Example: `  alpacon token acl apply -f acl.yaml --dry-run
+  alpacon token acl apply my-api-token -f acl.yaml
+  alpacon token acl apply -f acl.yaml -y
+
+  # acl.yaml
+  token: my-api-token
+  commands:
+    - command: "systemctl status *"
+      username: root
+  servers:
+    - web-01
+    - web-02
+  files:
+    - path: /var/log/*
+      action: download`,
+	Args:              cobra.RangeArgs(0, 1),
+	ValidArgsFunction: complete.FirstArg(complete.Tokens),
+	Run:               runAclApply,
+}
+
+func init() {
+	aclApplyCmd.Flags().StringP("file", "f", "", `ACL file to apply ("-" reads stdin)`)
+	aclApplyCmd.Flags().Bool("dry-run", false, "Print the plan without changing anything")
+	aclApplyCmd.Flags().BoolP("yes", "y", false, "Skip confirmation prompt")
+	_ = aclApplyCmd.MarkFlagRequired("file")
+}
+
+type aclApplyOutput struct {
+	Token   string  `json:"token"`
+	DryRun  bool    `json:"dry_run"`
+	Applied bool    `json:"applied"`
+	Plan    aclPlan `json:"plan"`
+}
+
+func runAclApply(cmd *cobra.Command, args []string) {
+	file, _ := cmd.Flags().GetString("file")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	spec, err := loadAclSpec(file)
+	if err != nil {
+		utils.CliErrorWithExit("Failed to read ACL file %s: %v.", file, err)
+	}
+
+	tokenArg := spec.Token
+	if len(args) > 0 {
+		tokenArg = args[0]
+	}
+	if tokenArg == "" {
+		utils.CliErrorWithExit("A token is required: pass TOKEN or set \"token\" in the ACL file.")
+	}
+
+	alpaconClient, err := client.NewAlpaconAPIClient()
+	if err != nil {
+		utils.CliErrorWithExit("Connection to Alpacon API failed: %v. Consider re-logging.", err)
+	}
+
+	tokenID, err := auth.ResolveTokenID(alpaconClient, tokenArg)
+	if err != nil {
+		utils.CliErrorWithExit("Failed to resolve token: %v.", err)
+	}
+
+	state, err := fetchTokenAcl(alpaconClient, tokenID)
+	if err != nil {
+		utils.CliErrorWithExit("%v.", err)
+	}
+
+	plan := planAcl(spec, state)
+	output := aclApplyOutput{Token: tokenArg, DryRun: dryRun, Plan: plan}
+
+	if utils.OutputFormat != utils.OutputFormatJSON {
+		printAclPlan(os.Stdout, tokenArg, plan)
+	}
+
+	if plan.empty() || dryRun {
+		if utils.OutputFormat == utils.OutputFormatJSON {
+			printAclApplyJSON(output)
+		}
+		return
+	}
+
+	if !yes {
+		utils.ConfirmAction("Apply %d addition(s) and %d deletion(s) to token '%s'?", plan.additions(), plan.deletions(), tokenArg)
+	}
+
+	if err = applyAclPlan(alpaconClient, tokenID, spec, plan); err != nil {
+		utils.CliErrorWithExit("Failed to apply the ACL plan: %v. Rules changed before the failure stay changed; re-run apply to converge.", err)
+	}
+
+	output.Applied = true
+	if utils.OutputFormat == utils.OutputFormatJSON {
+		printAclApplyJSON(output)
+		return
+	}
+	utils.CliSuccess("ACL rules applied to token %s: %d added, %d deleted.", tokenArg, plan.additions(), plan.deletions())
+}
+
+func printAclApplyJSON(output aclApplyOutput) {
+	if err := utils.PrintJSONValue(os.Stdout, output); err != nil {
+		utils.CliErrorWithExit("Failed to marshal the ACL plan: %v.", err)
+	}
+}
+
+// printAclPlan lists the changes one rule per line. Rule text comes from the
+// API and from a file someone else may have written, so it is sanitized.
+func printAclPlan(w io.Writer, token string, plan aclPlan) {
+	_, _ = fmt.Fprintf(w, "ACL plan for token %s:\n", utils.SanitizeTerminalText(token))
+	for _, c := range plan.DeleteCommands {
+		_, _ = fmt.Fprintf(w, "  - command  %s\n", formatCommandRule(c.Command, c.Username, c.Groupname))
+	}
+	for _, c := range plan.AddCommands {
+		_, _ = fmt.Fprintf(w, "  + command  %s\n", formatCommandRule(c.Command, c.Username, c.Groupname))
+	}
+	for _, s := range plan.DeleteServers {
+		_, _ = fmt.Fprintf(w, "  - server   %s\n", utils.SanitizeTerminalText(s.ServerName))
+	}
+	for _, name := range plan.AddServers {
+		_, _ = fmt.Fprintf(w, "  + server   %s\n", utils.SanitizeTerminalText(name))
+	}
+	for _, f := range plan.DeleteFiles {
+		_, _ = fmt.Fprintf(w, "  - file     %s\n", formatFileRule(f.Path, f.Action, f.Username, f.Groupname))
+	}
+	for _, f := range plan.AddFiles {
+		_, _ = fmt.Fprintf(w, "  + file     %s\n", formatFileRule(f.Path, f.Action, f.Username, f.Groupname))
+	}
+	if plan.empty() {
+		_, _ = fmt.Fprintf(w, "  (no changes, %d rule(s) up to date)\n", plan.Unchanged)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "Plan: %d to add, %d to delete, %d unchanged.\n", plan.additions(), plan.deletions(), plan.Unchanged)
+}
+
+func formatCommandRule(command, username, groupname string) string {
+	return fmt.Sprintf("%q%s", utils.SanitizeTerminalText(command), formatRestrictions(username, groupname))
+}
+
+func formatFileRule(path, action, username, groupname string) string {
+	return fmt.Sprintf("%q [%s]%s", utils.SanitizeTerminalText(path), utils.SanitizeTerminalText(action), formatRestrictions(username, groupname))
+}
+
+func formatRestrictions(username, groupname string) string {
+	var out string
+	if username != "" {
+		out += " username=" + utils.SanitizeTerminalText(username)
+	}
+	if groupname != "" {
+		out += " groupname=" + utils.SanitizeTerminalText(groupname)
+	}
+	return out
+}
+
+func applyAclPlan(ac *client.AlpaconClient, tokenID string, spec *aclSpec, plan aclPlan) error {
+	for _, c := range plan.DeleteCommands {
+		if err := security.DeleteCommandAcl(ac, c.ID); err != nil {
+			return fmt.Errorf("deleting command rule %q: %w", c.Command, err)
+		}
+	}
+
+	// A server the file still lists is only here as a server-side duplicate;
+	// the bulk endpoint goes by server, so it would take the kept rule too.
+	var bulkDelete []string
+	for _, s := range plan.DeleteServers {
+		if slices.Contains(spec.Servers, s.ServerName) {
+			if err := security.DeleteServerAcl(ac, s.ID); err != nil {
+				return fmt.Errorf("deleting duplicate server rule for %q: %w", s.ServerName, err)
+			}
+			continue
+		}
+		bulkDelete = append(bulkDelete, s.ServerName)
+	}
+	if len(bulkDelete) > 0 {
+		serverIDs, err := resolveServerIDs(ac, bulkDelete)
+		if err != nil {
+			return err
+		}
+		if err = security.BulkDeleteServerAcl(ac, security.ServerAclBulkRequest{Token: tokenID, Servers: serverIDs}); err != nil {
+			return fmt.Errorf("deleting server rules: %w", err)
+		}
+	}
+
+	for _, f := range plan.DeleteFiles {
+		if err := security.DeleteFileAcl(ac, f.ID); err != nil {
+			return fmt.Errorf("deleting file rule %q: %w", f.Path, err)
+		}
+	}
+
+	for _, c := range plan.AddCommands {
+		if err := security.AddCommandAcl(ac, security.CommandAclRequest{
+			Token:     tokenID,
+			Command:   c.Command,
+			Username:  c.Username,
+			Groupname: c.Groupname,
+		}); err != nil {
+			return fmt.Errorf("adding command rule %q: %w", c.Command, err)
+		}
+	}
+
+	if len(plan.AddServers) > 0 {
+		serverIDs, err := resolveServerIDs(ac, plan.AddServers)
+		if err != nil {
+			return err
+		}
+		if err = security.BulkAddServerAcl(ac, security.ServerAclBulkRequest{Token: tokenID, Servers: serverIDs}); err != nil {
+			return fmt.Errorf("adding server rules: %w", err)
+		}
+	}
+
+	for _, f := range plan.AddFiles {
+		if err := security.AddFileAcl(ac, security.FileAclRequest{
+			Token:     tokenID,
+			Path:      f.Path,
+			Action:    f.Action,
+			Username:  f.Username,
+			Groupname: f.Groupname,
+		}); err != nil {
+			return fmt.Errorf("adding file rule %q: %w", f.Path, err)
+		}
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/alpacax/alpacon-cli/api/auth"
 	"github.com/alpacax/alpacon-cli/api/security"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -22,8 +23,9 @@ Groupname semantics: "" = no group restriction, "*" = any group, exact name = ma
 	Example: `  alpacon token acl command add my-api-token --command="whoami"
   alpacon token acl command add my-api-token --command="docker *" --username=root
   alpacon token acl command add my-api-token --command="systemctl status *" --username="*" --groupname="*"`,
-	Args: cobra.ExactArgs(1),
-	Run:  runCommandAclAdd,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Tokens),
+	Run:               runCommandAclAdd,
 }
 
 func init() {
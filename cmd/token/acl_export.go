@@ -0,0 +1,70 @@
+package token
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/alpacax/alpacon-cli/api/auth"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var aclExportCmd = &cobra.Command{
+	Use:   "export TOKEN",
+	Short: "Export a token's ACL rules as a YAML file",
+	Long: `Write every command, server, and file ACL rule of a token in the format
+'alpacon token acl apply' reads. Rules are sorted, so exporting unchanged rules
+twice produces the same file.`,
+	Example: `  alpacon token acl export my-api-token > acl.yaml
+  alpacon token acl export my-api-token -f acl.yaml`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Tokens),
+	Run:               runAclExport,
+}
+
+func init() {
+	aclExportCmd.Flags().StringP("file", "f", "", "Write to this file instead of stdout")
+}
+
+func runAclExport(cmd *cobra.Command, args []string) {
+	tokenArg := args[0]
+	file, _ := cmd.Flags().GetString("file")
+
+	alpaconClient, err := client.NewAlpaconAPIClient()
+	if err != nil {
+		utils.CliErrorWithExit("Connection to Alpacon API failed: %v. Consider re-logging.", err)
+	}
+
+	tokenID, err := auth.ResolveTokenID(alpaconClient, tokenArg)
+	if err != nil {
+		utils.CliErrorWithExit("Failed to resolve token: %v.", err)
+	}
+
+	state, err := fetchTokenAcl(alpaconClient, tokenID)
+	if err != nil {
+		utils.CliErrorWithExit("%v.", err)
+	}
+
+	spec := specFromState(tokenArg, state)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err = enc.Encode(spec); err != nil {
+		utils.CliErrorWithExit("Failed to encode the ACL file: %v.", err)
+	}
+	_ = enc.Close()
+
+	if file == "" {
+		_, _ = os.Stdout.Write(buf.Bytes())
+		return
+	}
+	if _, err = utils.SaveStreamAtomic(file, &buf, 0644); err != nil {
+		utils.CliErrorWithExit("Failed to write %s: %v.", file, err)
+	}
+	utils.CliSuccess("Exported %d command, %d server, and %d file rule(s) of token %s to %s.",
+		len(spec.Commands), len(spec.Servers), len(spec.Files), tokenArg, file)
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/alpacax/alpacon-cli/api/auth"
 	"github.com/alpacax/alpacon-cli/api/security"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -21,8 +22,9 @@ Groupname semantics: "" = no group restriction, "*" = any group, exact name = ma
 	Example: `  alpacon token acl file add my-api-token --path "/home/deploy/*" --action upload
   alpacon token acl file add my-api-token --path "/var/log/*" --action download --username root
   alpacon token acl file add my-api-token --path "*" --action "*" --username "*" --groupname "*"`,
-	Args: cobra.ExactArgs(1),
-	Run:  runFileAclAdd,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Tokens),
+	Run:               runFileAclAdd,
 }
 
 func init() {
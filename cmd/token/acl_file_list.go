@@ -4,6 +4,7 @@ import (
 	"github.com/alpacax/alpacon-cli/api/auth"
 	"github.com/alpacax/alpacon-cli/api/security"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -14,8 +15,9 @@ var aclFileListCmd = &cobra.Command{
 	Short:   "List file ACL rules for a token",
 	Example: `  alpacon token acl file ls my-api-token
   alpacon token acl file list my-api-token`,
-	Args: cobra.ExactArgs(1),
-	Run:  runFileAclList,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Tokens),
+	Run:               runFileAclList,
 }
 
 func runFileAclList(_ *cobra.Command, args []string) {
@@ -1,13 +1,17 @@
 package token
 
-import "github.com/spf13/cobra"
+import (
+	"github.com/alpacax/alpacon-cli/cmd/complete"
+	"github.com/spf13/cobra"
+)
 
 var aclListCmd = &cobra.Command{
-	Use:        "ls TOKEN",
-	Aliases:    []string{"list"},
-	Short:      "List command ACL rules for a token (deprecated: use 'acl command ls')",
-	Deprecated: "use 'alpacon token acl command ls' instead",
-	Hidden:     true,
-	Args:       cobra.ExactArgs(1),
-	Run:        runCommandAclList,
+	Use:               "ls TOKEN",
+	Aliases:           []string{"list"},
+	Short:             "List command ACL rules for a token (deprecated: use 'acl command ls')",
+	Deprecated:        "use 'alpacon token acl command ls' instead",
+	Hidden:            true,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Tokens),
+	Run:               runCommandAclList,
 }
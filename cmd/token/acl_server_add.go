@@ -7,6 +7,7 @@ import (
 	"github.com/alpacax/alpacon-cli/api/security"
 	serverapi "github.com/alpacax/alpacon-cli/api/server"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -20,13 +21,16 @@ the token is denied access to all servers (deny-by-default).
 Use --server for a single server or --servers for bulk operations.`,
 	Example: `  alpacon token acl server add my-api-token --server my-server
   alpacon token acl server add my-api-token --servers web-01,web-02,web-03`,
-	Args: cobra.ExactArgs(1),
-	Run:  runServerAclAdd,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Tokens),
+	Run:               runServerAclAdd,
 }
 
 func init() {
 	aclServerAddCmd.Flags().String("server", "", "Server name (single)")
 	aclServerAddCmd.Flags().String("servers", "", "Comma-separated server names (bulk)")
+	_ = aclServerAddCmd.RegisterFlagCompletionFunc("server", complete.Servers)
+	_ = aclServerAddCmd.RegisterFlagCompletionFunc("servers", complete.CommaSeparated(complete.Servers))
 }
 
 func runServerAclAdd(cmd *cobra.Command, args []string) {
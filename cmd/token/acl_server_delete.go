@@ -4,6 +4,7 @@ import (
 	"github.com/alpacax/alpacon-cli/api/auth"
 	"github.com/alpacax/alpacon-cli/api/security"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -26,6 +27,7 @@ to multiple servers at once using --servers (bulk delete).`,
 func init() {
 	aclServerDeleteCmd.Flags().String("servers", "", "Comma-separated server names (bulk delete)")
 	aclServerDeleteCmd.Flags().BoolP("yes", "y", false, "Skip confirmation prompt")
+	_ = aclServerDeleteCmd.RegisterFlagCompletionFunc("servers", complete.CommaSeparated(complete.Servers))
 }
 
 func runServerAclDelete(cmd *cobra.Command, args []string) {
@@ -4,6 +4,7 @@ import (
 	"github.com/alpacax/alpacon-cli/api/auth"
 	"github.com/alpacax/alpacon-cli/api/security"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -14,8 +15,9 @@ var aclServerListCmd = &cobra.Command{
 	Short:   "List server ACL rules for a token",
 	Example: `  alpacon token acl server ls my-api-token
   alpacon token acl server list my-api-token`,
-	Args: cobra.ExactArgs(1),
-	Run:  runServerAclList,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Tokens),
+	Run:               runServerAclList,
 }
 
 func runServerAclList(_ *cobra.Command, args []string) {
@@ -0,0 +1,287 @@
+package token
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alpacax/alpacon-cli/api/auth"
+	"github.com/alpacax/alpacon-cli/api/security"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
+	"github.com/alpacax/alpacon-cli/cmd/exec"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+var aclTestCmd = &cobra.Command{
+	Use:   "test TOKEN --server SERVER [--] COMMAND...",
+	Short: "Check whether a token's ACLs allow a request",
+	Long: `Fetch a token's ACL rules and evaluate a request against them locally,
+without sending the request itself. Use it to find out why a job hit
+api_token_acl_not_allowed, or to check a rule change before applying it.
+
+A command request needs a server ACL for --server and a command ACL matching
+the command. A file request (--file-path with --action) needs a server ACL and
+a file ACL matching the path and action.
+
+Matching follows the documented rule semantics:
+  command, path   * matches any run of characters; without *, the whole string
+                  must match exactly
+  username        "" = token owner only, "*" = any user, exact name = match only
+  groupname       "" = no group restriction, "*" = any group, exact name = match only
+
+Leave --user empty to test a request running as the token owner, as exec does
+without -u. Token scopes are not evaluated; only the ACL rules are.
+
+Exits 0 when the request would be allowed and 1 when it would be denied.`,
+	Example: `  alpacon token acl test my-api-token --server web-1 --user root -- systemctl restart nginx
+  alpacon token acl test my-api-token --server web-1 --file-path /var/log/syslog --action download
+  alpacon token acl test my-api-token --server web-1 --output json -- whoami`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Tokens),
+	Run:               runAclTest,
+}
+
+func init() {
+	aclTestCmd.Flags().String("server", "", "Server the request targets")
+	aclTestCmd.Flags().StringP("user", "u", "", "User the request runs as (empty = token owner)")
+	aclTestCmd.Flags().StringP("group", "g", "", "Group the request runs as")
+	aclTestCmd.Flags().String("file-path", "", "Remote file path, to test a file transfer instead of a command")
+	aclTestCmd.Flags().String("action", "", "File action with --file-path: upload or download")
+	_ = aclTestCmd.MarkFlagRequired("server")
+	_ = aclTestCmd.RegisterFlagCompletionFunc("server", complete.Servers)
+}
+
+// aclRequest is the request being simulated. Exactly one of Command and
+// FilePath is set.
+type aclRequest struct {
+	Server    string `json:"server"`
+	Command   string `json:"command,omitempty"`
+	FilePath  string `json:"file_path,omitempty"`
+	Action    string `json:"action,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Groupname string `json:"groupname,omitempty"`
+}
+
+// aclRuleResult is one rule whose command or path pattern matched the request,
+// with the restriction that turned it down, if any.
+type aclRuleResult struct {
+	ID      string `json:"id"`
+	Rule    string `json:"rule"`
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+type aclDecision struct {
+	Allowed       bool            `json:"allowed"`
+	Request       aclRequest      `json:"request"`
+	ServerAllowed bool            `json:"server_allowed"`
+	ServerRuleID  string          `json:"server_rule_id,omitempty"`
+	RuleType      string          `json:"rule_type"`
+	Rules         []aclRuleResult `json:"rules"`
+	Reason        string          `json:"reason,omitempty"`
+}
+
+func runAclTest(cmd *cobra.Command, args []string) {
+	tokenArg := args[0]
+	server, _ := cmd.Flags().GetString("server")
+	username, _ := cmd.Flags().GetString("user")
+	groupname, _ := cmd.Flags().GetString("group")
+	filePath, _ := cmd.Flags().GetString("file-path")
+	action, _ := cmd.Flags().GetString("action")
+
+	commandArgs := args[1:]
+	req := aclRequest{Server: server, Username: username, Groupname: groupname}
+	switch {
+	case filePath != "" && len(commandArgs) > 0:
+		utils.CliErrorWithExit("Pass either a command or --file-path, not both.")
+	case filePath != "":
+		if action != security.FileAclActionUpload && action != security.FileAclActionDownload {
+			utils.CliErrorWithExit("--action must be upload or download with --file-path.")
+		}
+		req.FilePath = filePath
+		req.Action = action
+	case len(commandArgs) > 0:
+		if action != "" {
+			utils.CliErrorWithExit("--action only applies with --file-path.")
+		}
+		// Joined the way exec joins it, so the string matches what the server sees.
+		req.Command = exec.ShellJoin(commandArgs)
+	default:
+		utils.CliErrorWithExit("A command (after --) or --file-path is required.")
+	}
+
+	alpaconClient, err := client.NewAlpaconAPIClient()
+	if err != nil {
+		utils.CliErrorWithExit("Connection to Alpacon API failed: %v. Consider re-logging.", err)
+	}
+
+	tokenID, err := auth.ResolveTokenID(alpaconClient, tokenArg)
+	if err != nil {
+		utils.CliErrorWithExit("Failed to resolve token: %v.", err)
+	}
+
+	state, err := fetchTokenAcl(alpaconClient, tokenID)
+	if err != nil {
+		utils.CliErrorWithExit("%v.", err)
+	}
+
+	decision := evaluateAcl(state, req)
+
+	if utils.OutputFormat == utils.OutputFormatJSON {
+		if err = utils.PrintJSONValue(os.Stdout, decision); err != nil {
+			utils.CliErrorWithExit("Failed to marshal the ACL decision: %v.", err)
+		}
+	} else {
+		printAclDecision(os.Stdout, tokenArg, decision)
+	}
+
+	if !decision.Allowed {
+		os.Exit(utils.ExitCodeGeneralError)
+	}
+}
+
+// evaluateAcl decides the request the way the server documents it: the server
+// ACL and one matching rule of the request's type must both allow it.
+func evaluateAcl(state *tokenAclState, req aclRequest) aclDecision {
+	decision := aclDecision{Request: req, Rules: []aclRuleResult{}}
+
+	for _, s := range state.Servers {
+		if s.ServerName == req.Server {
+			decision.ServerAllowed = true
+			decision.ServerRuleID = s.ID
+			break
+		}
+	}
+
+	ruleAllowed := false
+	if req.FilePath != "" {
+		decision.RuleType = "file"
+		for _, f := range state.Files {
+			if !aclPatternMatch(f.Path, req.FilePath) {
+				continue
+			}
+			result := aclRuleResult{ID: f.ID, Rule: formatFileRule(f.Path, f.Action, f.Username, f.Groupname)}
+			if f.Action != security.FileAclActionAll && f.Action != req.Action {
+				result.Reason = fmt.Sprintf("action %q does not cover %q", f.Action, req.Action)
+			} else {
+				result.Reason = restrictionFailure(f.Username, f.Groupname, req)
+			}
+			result.Allowed = result.Reason == ""
+			ruleAllowed = ruleAllowed || result.Allowed
+			decision.Rules = append(decision.Rules, result)
+		}
+	} else {
+		decision.RuleType = "command"
+		for _, c := range state.Commands {
+			if !aclPatternMatch(c.Command, req.Command) {
+				continue
+			}
+			result := aclRuleResult{ID: c.ID, Rule: formatCommandRule(c.Command, c.Username, c.Groupname)}
+			result.Reason = restrictionFailure(c.Username, c.Groupname, req)
+			result.Allowed = result.Reason == ""
+			ruleAllowed = ruleAllowed || result.Allowed
+			decision.Rules = append(decision.Rules, result)
+		}
+	}
+
+	decision.Allowed = decision.ServerAllowed && ruleAllowed
+	switch {
+	case !decision.ServerAllowed:
+		decision.Reason = fmt.Sprintf("no server ACL for %q", req.Server)
+	case len(decision.Rules) == 0 && req.FilePath != "":
+		decision.Reason = fmt.Sprintf("no file ACL matches path %q", req.FilePath)
+	case len(decision.Rules) == 0:
+		decision.Reason = fmt.Sprintf("no command ACL matches %q", req.Command)
+	case !ruleAllowed:
+		decision.Reason = fmt.Sprintf("every matching %s rule restricts the user or group", decision.RuleType)
+	}
+	return decision
+}
+
+// restrictionFailure explains why a rule's username or groupname turns the
+// request down, or returns "" when both allow it.
+func restrictionFailure(ruleUser, ruleGroup string, req aclRequest) string {
+	var reasons []string
+	switch {
+	case ruleUser == "*":
+	case ruleUser == "":
+		if req.Username != "" {
+			reasons = append(reasons, fmt.Sprintf("username allows only the token owner, not %q", req.Username))
+		}
+	case ruleUser != req.Username:
+		if req.Username == "" {
+			reasons = append(reasons, fmt.Sprintf("username requires %q, request runs as the token owner", ruleUser))
+		} else {
+			reasons = append(reasons, fmt.Sprintf("username requires %q, not %q", ruleUser, req.Username))
+		}
+	}
+	if ruleGroup != "" && ruleGroup != "*" && ruleGroup != req.Groupname {
+		if req.Groupname == "" {
+			reasons = append(reasons, fmt.Sprintf("groupname requires %q, request has no group", ruleGroup))
+		} else {
+			reasons = append(reasons, fmt.Sprintf("groupname requires %q, not %q", ruleGroup, req.Groupname))
+		}
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// aclPatternMatch reports whether value matches pattern, where * matches any
+// run of characters (including none, spaces, and slashes) and every other
+// character matches only itself.
+func aclPatternMatch(pattern, value string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == value
+	}
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		idx := strings.Index(value, part)
+		if idx < 0 {
+			return false
+		}
+		value = value[idx+len(part):]
+	}
+	return strings.HasSuffix(value, last)
+}
+
+func printAclDecision(w io.Writer, token string, d aclDecision) {
+	target := d.Request.Command
+	if d.Request.FilePath != "" {
+		target = fmt.Sprintf("%s %s", d.Request.Action, d.Request.FilePath)
+	}
+	_, _ = fmt.Fprintf(w, "Token:    %s\n", utils.SanitizeTerminalText(token))
+	_, _ = fmt.Fprintf(w, "Request:  %s on %s%s\n", utils.SanitizeTerminalText(target),
+		utils.SanitizeTerminalText(d.Request.Server), formatRestrictions(d.Request.Username, d.Request.Groupname))
+
+	if d.ServerAllowed {
+		_, _ = fmt.Fprintf(w, "Server:   allowed by server ACL %s\n", d.ServerRuleID)
+	} else {
+		_, _ = fmt.Fprintf(w, "Server:   denied—no server ACL for %s\n", utils.SanitizeTerminalText(d.Request.Server))
+	}
+
+	if len(d.Rules) == 0 {
+		_, _ = fmt.Fprintf(w, "Rules:    no %s ACL matches\n", d.RuleType)
+	} else {
+		_, _ = fmt.Fprintf(w, "Rules:    %d matching %s ACL(s)\n", len(d.Rules), d.RuleType)
+		for _, r := range d.Rules {
+			verdict := "allows"
+			if !r.Allowed {
+				verdict = "denies: " + utils.SanitizeTerminalText(r.Reason)
+			}
+			_, _ = fmt.Fprintf(w, "  %s %s—%s\n", r.ID, r.Rule, verdict)
+		}
+	}
+
+	if d.Allowed {
+		_, _ = fmt.Fprintln(w, "Result:   ALLOWED")
+		return
+	}
+	_, _ = fmt.Fprintf(w, "Result:   DENIED (%s)\n", utils.SanitizeTerminalText(d.Reason))
+}
@@ -0,0 +1,86 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/alpacax/alpacon-cli/api/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAclPatternMatch(t *testing.T) {
+	cases := []struct {
+		pattern, value string
+		want           bool
+	}{
+		{"whoami", "whoami", true},
+		{"whoami", "whoami -a", false},
+		{"systemctl status *", "systemctl status nginx", true},
+		{"systemctl status *", "systemctl status ", true},
+		{"systemctl status *", "systemctl restart nginx", false},
+		{"*", "anything at all", true},
+		{"docker * ps", "docker compose ps", true},
+		{"docker * ps", "docker compose ps -a", false},
+		{"/home/deploy/*", "/home/deploy/app/releases/1.tar", true},
+		{"/var/*/*.log", "/var/log/syslog", false},
+		{"a*a", "a", false},
+		{"*.log", "app.log", true},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, aclPatternMatch(tc.pattern, tc.value), "pattern %q value %q", tc.pattern, tc.value)
+	}
+}
+
+func TestEvaluateAcl_Command(t *testing.T) {
+	state := &tokenAclState{
+		Servers: []security.ServerAclAttributes{{ID: "s1", ServerName: "web-1"}},
+		Commands: []security.CommandAclResponse{
+			{ID: "c1", Command: "systemctl *"},
+			{ID: "c2", Command: "systemctl restart *", Username: "root", Groupname: "ops"},
+			{ID: "c3", Command: "whoami", Username: "*"},
+		},
+	}
+
+	d := evaluateAcl(state, aclRequest{Server: "web-1", Command: "systemctl restart nginx", Username: "root"})
+	assert.False(t, d.Allowed)
+	require.Len(t, d.Rules, 2)
+	assert.Contains(t, d.Rules[0].Reason, "only the token owner")
+	assert.Contains(t, d.Rules[1].Reason, `groupname requires "ops"`)
+
+	d = evaluateAcl(state, aclRequest{Server: "web-1", Command: "systemctl restart nginx", Username: "root", Groupname: "ops"})
+	assert.True(t, d.Allowed)
+
+	d = evaluateAcl(state, aclRequest{Server: "web-1", Command: "systemctl restart nginx"})
+	assert.True(t, d.Allowed, "an empty username rule allows the token owner")
+
+	d = evaluateAcl(state, aclRequest{Server: "web-2", Command: "whoami"})
+	assert.False(t, d.Allowed)
+	assert.False(t, d.ServerAllowed)
+	assert.Contains(t, d.Reason, "no server ACL")
+
+	d = evaluateAcl(state, aclRequest{Server: "web-1", Command: "rm -rf /"})
+	assert.False(t, d.Allowed)
+	assert.Empty(t, d.Rules)
+	assert.Contains(t, d.Reason, "no command ACL matches")
+}
+
+func TestEvaluateAcl_File(t *testing.T) {
+	state := &tokenAclState{
+		Servers: []security.ServerAclAttributes{{ID: "s1", ServerName: "web-1"}},
+		Files: []security.FileAclResponse{
+			{ID: "f1", Path: "/var/log/*", Action: "download", Username: "*"},
+			{ID: "f2", Path: "/home/deploy/*", Action: "*"},
+		},
+	}
+
+	d := evaluateAcl(state, aclRequest{Server: "web-1", FilePath: "/var/log/syslog", Action: "download", Username: "root"})
+	assert.True(t, d.Allowed)
+
+	d = evaluateAcl(state, aclRequest{Server: "web-1", FilePath: "/var/log/syslog", Action: "upload"})
+	assert.False(t, d.Allowed)
+	require.Len(t, d.Rules, 1)
+	assert.Contains(t, d.Rules[0].Reason, "action")
+
+	d = evaluateAcl(state, aclRequest{Server: "web-1", FilePath: "/home/deploy/app.tar", Action: "upload"})
+	assert.True(t, d.Allowed)
+}
@@ -0,0 +1,269 @@
+package token
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/alpacax/alpacon-cli/api/security"
+	"github.com/alpacax/alpacon-cli/client"
+	"gopkg.in/yaml.v3"
+)
+
+// aclSpec is the file format shared by 'acl apply' and 'acl export'.
+//
+// A section left out of the file is not managed: apply leaves those rules as
+// they are. An explicit empty list ("commands: []") is managed and empty, so
+// apply deletes every rule of that type. Export always writes all three
+// sections, so a round-tripped file manages everything.
+type aclSpec struct {
+	Token    string           `yaml:"token,omitempty" json:"token,omitempty"`
+	Commands []commandAclRule `yaml:"commands" json:"commands"`
+	Servers  []string         `yaml:"servers" json:"servers"`
+	Files    []fileAclRule    `yaml:"files" json:"files"`
+}
+
+type commandAclRule struct {
+	Command   string `yaml:"command" json:"command"`
+	Username  string `yaml:"username,omitempty" json:"username,omitempty"`
+	Groupname string `yaml:"groupname,omitempty" json:"groupname,omitempty"`
+}
+
+type fileAclRule struct {
+	Path      string `yaml:"path" json:"path"`
+	Action    string `yaml:"action" json:"action"`
+	Username  string `yaml:"username,omitempty" json:"username,omitempty"`
+	Groupname string `yaml:"groupname,omitempty" json:"groupname,omitempty"`
+}
+
+func (r commandAclRule) key() string {
+	return strings.Join([]string{r.Command, r.Username, r.Groupname}, "\x00")
+}
+
+func (r fileAclRule) key() string {
+	return strings.Join([]string{r.Path, r.Action, r.Username, r.Groupname}, "\x00")
+}
+
+// tokenAclState is every rule a token holds, as the API lists them.
+type tokenAclState struct {
+	Commands []security.CommandAclResponse
+	Servers  []security.ServerAclAttributes
+	Files    []security.FileAclResponse
+}
+
+// aclPlan is what apply has to change to make the token match the file. The
+// delete side keeps the API records because deletion goes by rule ID.
+type aclPlan struct {
+	AddCommands    []commandAclRule               `json:"add_commands"`
+	DeleteCommands []security.CommandAclResponse  `json:"delete_commands"`
+	AddServers     []string                       `json:"add_servers"`
+	DeleteServers  []security.ServerAclAttributes `json:"delete_servers"`
+	AddFiles       []fileAclRule                  `json:"add_files"`
+	DeleteFiles    []security.FileAclResponse     `json:"delete_files"`
+	Unchanged      int                            `json:"unchanged"`
+}
+
+func (p aclPlan) additions() int {
+	return len(p.AddCommands) + len(p.AddServers) + len(p.AddFiles)
+}
+
+func (p aclPlan) deletions() int {
+	return len(p.DeleteCommands) + len(p.DeleteServers) + len(p.DeleteFiles)
+}
+
+func (p aclPlan) empty() bool {
+	return p.additions() == 0 && p.deletions() == 0
+}
+
+func loadAclSpec(path string) (*aclSpec, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseAclSpec(data)
+}
+
+func parseAclSpec(data []byte) (*aclSpec, error) {
+	var spec aclSpec
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&spec); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("the ACL file is empty")
+		}
+		return nil, err
+	}
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// validate rejects what the API would reject, and duplicates: a rule listed
+// twice makes the file disagree with itself about how many copies to keep.
+func (s *aclSpec) validate() error {
+	seenCommands := map[string]bool{}
+	for i, r := range s.Commands {
+		if strings.TrimSpace(r.Command) == "" {
+			return fmt.Errorf("commands[%d]: command is required", i)
+		}
+		if seenCommands[r.key()] {
+			return fmt.Errorf("commands[%d]: duplicate rule for %q", i, r.Command)
+		}
+		seenCommands[r.key()] = true
+	}
+
+	seenServers := map[string]bool{}
+	for i, name := range s.Servers {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("servers[%d]: server name is required", i)
+		}
+		if seenServers[name] {
+			return fmt.Errorf("servers[%d]: duplicate server %q", i, name)
+		}
+		seenServers[name] = true
+	}
+
+	seenFiles := map[string]bool{}
+	for i, r := range s.Files {
+		if strings.TrimSpace(r.Path) == "" {
+			return fmt.Errorf("files[%d]: path is required", i)
+		}
+		switch r.Action {
+		case security.FileAclActionUpload, security.FileAclActionDownload, security.FileAclActionAll:
+		default:
+			return fmt.Errorf("files[%d]: action must be one of: upload, download, * (got %q)", i, r.Action)
+		}
+		if seenFiles[r.key()] {
+			return fmt.Errorf("files[%d]: duplicate rule for %q", i, r.Path)
+		}
+		seenFiles[r.key()] = true
+	}
+	return nil
+}
+
+func fetchTokenAcl(ac *client.AlpaconClient, tokenID string) (*tokenAclState, error) {
+	commands, err := security.GetCommandAclList(ac, tokenID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve command ACLs: %w", err)
+	}
+	servers, err := security.GetServerAclList(ac, tokenID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve server ACLs: %w", err)
+	}
+	files, err := security.GetFileAclList(ac, tokenID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve file ACLs: %w", err)
+	}
+	return &tokenAclState{Commands: commands, Servers: servers, Files: files}, nil
+}
+
+// specFromState renders the token's rules in file form, sorted so a re-export
+// of unchanged rules produces the same bytes.
+func specFromState(token string, state *tokenAclState) aclSpec {
+	spec := aclSpec{
+		Token:    token,
+		Commands: []commandAclRule{},
+		Servers:  []string{},
+		Files:    []fileAclRule{},
+	}
+	for _, c := range state.Commands {
+		spec.Commands = append(spec.Commands, commandAclRule{Command: c.Command, Username: c.Username, Groupname: c.Groupname})
+	}
+	for _, s := range state.Servers {
+		spec.Servers = append(spec.Servers, s.ServerName)
+	}
+	for _, f := range state.Files {
+		spec.Files = append(spec.Files, fileAclRule{Path: f.Path, Action: f.Action, Username: f.Username, Groupname: f.Groupname})
+	}
+
+	slices.SortFunc(spec.Commands, func(a, b commandAclRule) int { return strings.Compare(a.key(), b.key()) })
+	slices.Sort(spec.Servers)
+	spec.Servers = slices.Compact(spec.Servers)
+	slices.SortFunc(spec.Files, func(a, b fileAclRule) int { return strings.Compare(a.key(), b.key()) })
+	return spec
+}
+
+// planAcl diffs the file against the token. Each desired rule claims one
+// matching remote rule; remote rules nobody claims are deleted, which also
+// removes server-side duplicates of a rule the file lists once.
+func planAcl(spec *aclSpec, state *tokenAclState) aclPlan {
+	var plan aclPlan
+
+	if spec.Commands != nil {
+		wanted := map[string]bool{}
+		for _, r := range spec.Commands {
+			wanted[r.key()] = true
+		}
+		present := map[string]bool{}
+		for _, c := range state.Commands {
+			key := commandAclRule{Command: c.Command, Username: c.Username, Groupname: c.Groupname}.key()
+			if wanted[key] && !present[key] {
+				present[key] = true
+				plan.Unchanged++
+				continue
+			}
+			plan.DeleteCommands = append(plan.DeleteCommands, c)
+		}
+		for _, r := range spec.Commands {
+			if !present[r.key()] {
+				plan.AddCommands = append(plan.AddCommands, r)
+			}
+		}
+	}
+
+	if spec.Servers != nil {
+		wanted := map[string]bool{}
+		for _, name := range spec.Servers {
+			wanted[name] = true
+		}
+		present := map[string]bool{}
+		for _, s := range state.Servers {
+			if wanted[s.ServerName] && !present[s.ServerName] {
+				present[s.ServerName] = true
+				plan.Unchanged++
+				continue
+			}
+			plan.DeleteServers = append(plan.DeleteServers, s)
+		}
+		for _, name := range spec.Servers {
+			if !present[name] {
+				plan.AddServers = append(plan.AddServers, name)
+			}
+		}
+	}
+
+	if spec.Files != nil {
+		wanted := map[string]bool{}
+		for _, r := range spec.Files {
+			wanted[r.key()] = true
+		}
+		present := map[string]bool{}
+		for _, f := range state.Files {
+			key := fileAclRule{Path: f.Path, Action: f.Action, Username: f.Username, Groupname: f.Groupname}.key()
+			if wanted[key] && !present[key] {
+				present[key] = true
+				plan.Unchanged++
+				continue
+			}
+			plan.DeleteFiles = append(plan.DeleteFiles, f)
+		}
+		for _, r := range spec.Files {
+			if !present[r.key()] {
+				plan.AddFiles = append(plan.AddFiles, r)
+			}
+		}
+	}
+
+	return plan
+}
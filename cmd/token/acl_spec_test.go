@@ -0,0 +1,142 @@
+package token
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/alpacax/alpacon-cli/api/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseAclSpec(t *testing.T) {
+	spec, err := parseAclSpec([]byte(`
+token: ci-token
+commands:
+  - command: "systemctl status *"
+    username: root
+servers: [web-01, web-02]
+files:
+  - path: /var/log/*
+    action: download
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "ci-token", spec.Token)
+	assert.Equal(t, []commandAclRule{{Command: "systemctl status *", Username: "root"}}, spec.Commands)
+	assert.Equal(t, []string{"web-01", "web-02"}, spec.Servers)
+	assert.Equal(t, []fileAclRule{{Path: "/var/log/*", Action: "download"}}, spec.Files)
+}
+
+func TestParseAclSpec_OmittedSectionIsUnmanaged(t *testing.T) {
+	spec, err := parseAclSpec([]byte("servers: []\n"))
+	require.NoError(t, err)
+	assert.Nil(t, spec.Commands)
+	assert.NotNil(t, spec.Servers)
+	assert.Nil(t, spec.Files)
+}
+
+func TestParseAclSpec_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"empty file":        "",
+		"unknown field":     "comands: []\n",
+		"missing command":   "commands:\n  - username: root\n",
+		"duplicate command": "commands:\n  - command: ls\n  - command: ls\n",
+		"duplicate server":  "servers: [web-01, web-01]\n",
+		"bad file action":   "files:\n  - path: /tmp/*\n    action: read\n",
+		"missing path":      "files:\n  - action: upload\n",
+	}
+	for name, input := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseAclSpec([]byte(input))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPlanAcl(t *testing.T) {
+	spec := &aclSpec{
+		Commands: []commandAclRule{
+			{Command: "whoami"},
+			{Command: "docker *", Username: "root"},
+		},
+		Servers: []string{"web-01", "web-03"},
+		Files: []fileAclRule{
+			{Path: "/tmp/*", Action: "*"},
+		},
+	}
+	state := &tokenAclState{
+		Commands: []security.CommandAclResponse{
+			{ID: "c1", Command: "whoami"},
+			{ID: "c2", Command: "whoami"},
+			{ID: "c3", Command: "docker *"},
+		},
+		Servers: []security.ServerAclAttributes{
+			{ID: "s1", ServerName: "web-01"},
+			{ID: "s2", ServerName: "web-02"},
+		},
+		Files: []security.FileAclResponse{
+			{ID: "f1", Path: "/tmp/*", Action: "*"},
+		},
+	}
+
+	plan := planAcl(spec, state)
+
+	assert.Equal(t, []commandAclRule{{Command: "docker *", Username: "root"}}, plan.AddCommands)
+	assert.Equal(t, []string{"c2", "c3"}, commandIDs(plan.DeleteCommands), "the duplicate and the rule with a different username go")
+	assert.Equal(t, []string{"web-03"}, plan.AddServers)
+	assert.Len(t, plan.DeleteServers, 1)
+	assert.Equal(t, "s2", plan.DeleteServers[0].ID)
+	assert.Empty(t, plan.AddFiles)
+	assert.Empty(t, plan.DeleteFiles)
+	assert.Equal(t, 3, plan.Unchanged)
+	assert.Equal(t, 2, plan.additions())
+	assert.Equal(t, 3, plan.deletions())
+}
+
+func TestPlanAcl_UnmanagedSectionsAreKept(t *testing.T) {
+	spec := &aclSpec{Servers: []string{}}
+	state := &tokenAclState{
+		Commands: []security.CommandAclResponse{{ID: "c1", Command: "whoami"}},
+		Servers:  []security.ServerAclAttributes{{ID: "s1", ServerName: "web-01"}},
+	}
+
+	plan := planAcl(spec, state)
+
+	assert.Empty(t, plan.DeleteCommands)
+	assert.Len(t, plan.DeleteServers, 1)
+}
+
+func TestSpecFromState_RoundTrips(t *testing.T) {
+	state := &tokenAclState{
+		Commands: []security.CommandAclResponse{
+			{ID: "c2", Command: "whoami"},
+			{ID: "c1", Command: "docker *", Username: "root"},
+		},
+		Servers: []security.ServerAclAttributes{
+			{ID: "s2", ServerName: "web-02"},
+			{ID: "s1", ServerName: "web-01"},
+		},
+	}
+
+	spec := specFromState("ci-token", state)
+	assert.Equal(t, "docker *", spec.Commands[0].Command, "rules are sorted")
+	assert.Equal(t, []string{"web-01", "web-02"}, spec.Servers)
+
+	var buf bytes.Buffer
+	require.NoError(t, yaml.NewEncoder(&buf).Encode(spec))
+	assert.True(t, strings.Contains(buf.String(), "files: []"), "an empty section is written so it stays managed")
+
+	parsed, err := parseAclSpec(buf.Bytes())
+	require.NoError(t, err)
+	assert.True(t, planAcl(parsed, state).empty(), "applying an export changes nothing")
+}
+
+func commandIDs(acls []security.CommandAclResponse) []string {
+	ids := make([]string, len(acls))
+	for i, a := range acls {
+		ids[i] = a.ID
+	}
+	return ids
+}
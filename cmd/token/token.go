@@ -23,6 +23,7 @@ func init() {
 	TokenCmd.AddCommand(tokenListCmd)
 	TokenCmd.AddCommand(tokenDeleteCmd)
 	TokenCmd.AddCommand(tokenDuplicateCmd)
+	TokenCmd.AddCommand(tokenRotateCmd)
 	TokenCmd.AddCommand(tokenScopesCmd)
 
 	// ACL
@@ -3,6 +3,7 @@ package token
 import (
 	"github.com/alpacax/alpacon-cli/api/auth"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -20,7 +21,8 @@ var tokenDeleteCmd = &cobra.Command{
 	alpacon token rm my-api-token
 	alpacon token delete my-api-token -y
 	`,
-	Args: cobra.ExactArgs(1),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Tokens),
 	Run: func(cmd *cobra.Command, args []string) {
 		tokenId := args[0]
 
@@ -3,6 +3,7 @@ package token
 import (
 	"github.com/alpacax/alpacon-cli/api/auth"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -19,7 +20,8 @@ var tokenDuplicateCmd = &cobra.Command{
 	alpacon token duplicate my-api-token --name "my-api-token-copy"
 	alpacon token duplicate 550e8400-e29b-41d4-a716-446655440000
 	`,
-	Args: cobra.ExactArgs(1),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Tokens),
 	Run: func(cmd *cobra.Command, args []string) {
 		tokenID := args[0]
 		name, _ := cmd.Flags().GetString("name")
@@ -36,12 +38,12 @@ var tokenDuplicateCmd = &cobra.Command{
 			}
 		}
 
-		key, err := auth.DuplicateAPIToken(alpaconClient, tokenID, name)
+		token, err := auth.DuplicateAPIToken(alpaconClient, tokenID, name)
 		if err != nil {
 			utils.CliErrorWithExit("Failed to duplicate the API token: %s.", err)
 		}
 
-		utils.CliSuccess("API token duplicated: %s", key)
+		utils.CliSuccess("API token duplicated: %s", token.Key)
 		utils.CliWarning("This token cannot be retrieved again after you exit.")
 	},
 }
@@ -1,6 +1,8 @@
 package token
 
 import (
+	"time"
+
 	"github.com/alpacax/alpacon-cli/api/auth"
 	"github.com/alpacax/alpacon-cli/client"
 	"github.com/alpacax/alpacon-cli/utils"
@@ -14,22 +16,52 @@ var tokenListCmd = &cobra.Command{
 	Long: `
 	Displays a list of all API tokens issued. 
 	This command provides an overview of token names, creation dates, and expiration dates, helping you manage access effectively.
+
+	With --expiring, only tokens that expire within the given window (e.g. 14d, 36h)
+	are listed, soonest first, including tokens that have already expired.
+	Rotate them with 'alpacon token rotate'.
 	`,
 	Example: `
 	alpacon token ls
 	alpacon token list
+	alpacon token ls --expiring 14d
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
+		expiring, _ := cmd.Flags().GetString("expiring")
+
+		var within time.Duration
+		if expiring != "" {
+			var err error
+			within, err = utils.ParseDayDuration("--expiring", expiring)
+			if err != nil {
+				utils.CliErrorWithExit("%s.", err)
+			}
+		}
+
 		alpaconClient, err := client.NewAlpaconAPIClient()
 		if err != nil {
 			utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
 		}
 
-		tokenList, err := auth.GetAPITokenList(alpaconClient)
+		var tokenList []auth.APITokenAttributes
+		if within > 0 {
+			tokenList, err = auth.GetExpiringAPITokenList(alpaconClient, within)
+		} else {
+			tokenList, err = auth.GetAPITokenList(alpaconClient)
+		}
 		if err != nil {
 			utils.CliErrorWithExit("Failed to retrieve the api token list: %s.", err)
 		}
 
+		if within > 0 && len(tokenList) == 0 && utils.OutputFormat != utils.OutputFormatJSON {
+			utils.CliInfo("No API tokens expire within %s.", expiring)
+			return
+		}
+
 		utils.PrintTable(tokenList)
 	},
 }
+
+func init() {
+	tokenListCmd.Flags().String("expiring", "", "Only list tokens expiring within this window (e.g. 14d, 36h)")
+}
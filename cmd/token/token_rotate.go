@@ -0,0 +1,208 @@
+package token
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/alpacax/alpacon-cli/api/auth"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+var tokenRotateCmd = &cobra.Command{
+	Use:   "rotate TOKEN",
+	Short: "Replace an api token with a fresh copy",
+	Long: `
+	Rotates an API token: creates a copy with the same scopes and ACL rules
+	(Command, Server, File), prints the new key once, and then retires the old token.
+
+	The old token is deleted only after the copy's ACL rules are confirmed to match.
+	By default you are asked before it is deleted; in a non-interactive shell it is
+	kept and the delete command is printed. Pass --delete-old to delete it without
+	asking, and --grace to wait first while jobs holding the old key switch over.
+	Interrupting the grace period keeps the old token. --grace is not available
+	with --output json, which prints the new key only once the rotation is done.
+	`,
+	Example: `
+	alpacon token rotate ci-token
+	alpacon token rotate ci-token --name ci-token-2026q4
+	alpacon token rotate ci-token --delete-old --grace 30m
+	alpacon token rotate ci-token --keep-old --output json
+	`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.Tokens),
+	Run:               runTokenRotate,
+}
+
+func init() {
+	tokenRotateCmd.Flags().StringP("name", "n", "", "Name for the new token (optional)")
+	tokenRotateCmd.Flags().Bool("delete-old", false, "Delete the old token without asking once the copy is verified")
+	tokenRotateCmd.Flags().String("grace", "", "Wait this long before deleting the old token (e.g. 30m); implies --delete-old")
+	tokenRotateCmd.Flags().Bool("keep-old", false, "Keep the old token; delete it later with 'alpacon token delete'")
+	tokenRotateCmd.MarkFlagsMutuallyExclusive("keep-old", "delete-old")
+	tokenRotateCmd.MarkFlagsMutuallyExclusive("keep-old", "grace")
+}
+
+type tokenRotateOutput struct {
+	OldTokenID      string `json:"old_token_id"`
+	OldTokenDeleted bool   `json:"old_token_deleted"`
+	TokenID         string `json:"token_id"`
+	Name            string `json:"name"`
+	Key             string `json:"key"`
+}
+
+func runTokenRotate(cmd *cobra.Command, args []string) {
+	tokenArg := args[0]
+	name, _ := cmd.Flags().GetString("name")
+	deleteOld, _ := cmd.Flags().GetBool("delete-old")
+	keepOld, _ := cmd.Flags().GetBool("keep-old")
+	graceRaw, _ := cmd.Flags().GetString("grace")
+
+	var grace time.Duration
+	if graceRaw != "" {
+		var err error
+		grace, err = utils.ParseDayDuration("--grace", graceRaw)
+		if err != nil {
+			utils.CliErrorWithExit("%s.", err)
+		}
+		// The JSON result, key included, is printed whole at the end, so a
+		// grace period would hold the only copy of the new key in memory.
+		if utils.OutputFormat == utils.OutputFormatJSON {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "--grace cannot be combined with --output json. Rotate with --keep-old, then run 'alpacon token delete' once jobs have switched.")
+		}
+		deleteOld = true
+	}
+
+	alpaconClient, err := client.NewAlpaconAPIClient()
+	if err != nil {
+		utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
+	}
+
+	oldID, err := auth.ResolveTokenID(alpaconClient, tokenArg)
+	if err != nil {
+		utils.CliErrorWithExit("Failed to resolve token: %s.", err)
+	}
+
+	oldAcl, err := fetchTokenAcl(alpaconClient, oldID)
+	if err != nil {
+		utils.CliErrorWithExit("%s.", err)
+	}
+
+	token, err := auth.DuplicateAPIToken(alpaconClient, oldID, name)
+	if err != nil {
+		utils.CliErrorWithExit("Failed to create the replacement token: %s.", err)
+	}
+
+	output := tokenRotateOutput{OldTokenID: oldID, TokenID: token.ID, Name: token.Name, Key: token.Key}
+
+	// In table output the key goes out before anything that can wait or
+	// fail, so a grace period is spent with the new key already in hand.
+	if utils.OutputFormat != utils.OutputFormatJSON {
+		utils.CliSuccess("API token rotated: %s (%s)", token.Name, token.ID)
+		fmt.Println(token.Key)
+		utils.CliWarning("This token cannot be retrieved again after you exit.")
+	}
+
+	deleteHint := fmt.Sprintf("alpacon token delete %s", oldID)
+	var aclErr error
+	if !keepOld {
+		if aclErr = verifyRotatedAcl(alpaconClient, oldAcl, token.ID); aclErr != nil {
+			utils.CliWarning("Keeping the old token: %s. Compare 'alpacon token acl export' of both tokens, then run '%s'.", aclErr, deleteHint)
+		}
+	}
+
+	var ask func() bool
+	if utils.IsInteractiveShell() && utils.OutputFormat != utils.OutputFormatJSON {
+		ask = func() bool { return utils.PromptForBool(fmt.Sprintf("Delete the old token '%s' now?", tokenArg)) }
+	}
+	deleteNow := decideDeleteOld(keepOld, deleteOld, aclErr, ask)
+	if !deleteNow && !keepOld && aclErr == nil {
+		utils.CliInfo("The old token is still active. Delete it once nothing uses it: %s", deleteHint)
+	}
+
+	if deleteNow && grace > 0 && !waitForGrace(grace) {
+		utils.CliInfo("Interrupted; the old token is still active. Delete it once nothing uses it: %s", deleteHint)
+		deleteNow = false
+	}
+	if deleteNow {
+		if err = auth.DeleteAPIToken(alpaconClient, oldID); err != nil {
+			// In JSON mode the key has not been shown yet.
+			printRotateOutput(output)
+			utils.CliErrorWithExit("The new token is active, but deleting the old token failed: %s. Run '%s' to finish.", err, deleteHint)
+		}
+		output.OldTokenDeleted = true
+		if utils.OutputFormat != utils.OutputFormatJSON {
+			utils.CliSuccess("Old API token deleted: %s", tokenArg)
+		}
+	}
+
+	printRotateOutput(output)
+}
+
+// printRotateOutput prints the result in JSON mode; otherwise the key was
+// printed as soon as the token was created.
+func printRotateOutput(output tokenRotateOutput) {
+	if utils.OutputFormat != utils.OutputFormatJSON {
+		return
+	}
+	if err := utils.PrintJSONValue(os.Stdout, output); err != nil {
+		utils.CliErrorWithExit("Failed to marshal the rotation result: %s.", err)
+	}
+}
+
+// decideDeleteOld reports whether the old token goes now. --keep-old and a
+// copy whose rules differ keep it; --delete-old deletes it; otherwise ask
+// decides, and without a way to ask it is kept.
+func decideDeleteOld(keepOld, deleteOld bool, aclErr error, ask func() bool) bool {
+	switch {
+	case keepOld || aclErr != nil:
+		return false
+	case deleteOld:
+		return true
+	case ask != nil:
+		return ask()
+	}
+	return false
+}
+
+// verifyRotatedAcl confirms the copy holds the same rules as the original
+// before the original may go: a copy that dropped a rule would break the job
+// that switches to it, with the old key already gone.
+func verifyRotatedAcl(ac *client.AlpaconClient, oldAcl *tokenAclState, newID string) error {
+	newAcl, err := fetchTokenAcl(ac, newID)
+	if err != nil {
+		return err
+	}
+	want := specFromState("", oldAcl)
+	if plan := planAcl(&want, newAcl); !plan.empty() {
+		return fmt.Errorf("the new token's ACL rules differ from the old token's (%d missing, %d extra)", plan.additions(), plan.deletions())
+	}
+	return nil
+}
+
+// waitForGrace waits out the grace period. It returns false if interrupted,
+// so the old token is kept and the new key still reaches the output.
+func waitForGrace(grace time.Duration) bool {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	deadline := time.Now().Add(grace).Truncate(time.Second)
+	spinner := utils.NewSpinner(fmt.Sprintf("Deleting the old token at %s (Ctrl+C keeps it)...", deadline.Format(time.Kitchen)))
+	spinner.Start()
+	defer spinner.Stop()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-sigChan:
+		return false
+	}
+}
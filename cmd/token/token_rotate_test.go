@@ -0,0 +1,68 @@
+package token
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alpacax/alpacon-cli/api"
+	"github.com/alpacax/alpacon-cli/api/security"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecideDeleteOld(t *testing.T) {
+	yes := func() bool { return true }
+	no := func() bool { return false }
+	aclErr := errors.New("rules differ")
+
+	tests := []struct {
+		name      string
+		keepOld   bool
+		deleteOld bool
+		aclErr    error
+		ask       func() bool
+		want      bool
+	}{
+		{"--keep-old keeps", true, false, nil, yes, false},
+		{"--delete-old deletes", false, true, nil, nil, true},
+		{"differing rules keep despite --delete-old", false, true, aclErr, yes, false},
+		{"asks when neither flag is given", false, false, nil, yes, true},
+		{"a declined prompt keeps", false, false, nil, no, false},
+		{"no way to ask keeps", false, false, nil, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, decideDeleteOld(tt.keepOld, tt.deleteOld, tt.aclErr, tt.ask))
+		})
+	}
+}
+
+func TestVerifyRotatedAcl(t *testing.T) {
+	commands := map[string][]security.CommandAclResponse{
+		"old":     {{ID: "c-1", Command: "uptime", Username: "root"}},
+		"same":    {{ID: "c-2", Command: "uptime", Username: "root"}},
+		"missing": {},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/security/command-acl/":
+			_ = json.NewEncoder(w).Encode(api.ListResponse[security.CommandAclResponse]{Results: commands[r.URL.Query().Get("token")]})
+		case "/api/security/server-acl/", "/api/security/file-acl/":
+			_ = json.NewEncoder(w).Encode(api.ListResponse[security.FileAclResponse]{Results: []security.FileAclResponse{}})
+		default:
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+	}))
+	defer ts.Close()
+	ac := &client.AlpaconClient{HTTPClient: ts.Client(), BaseURL: ts.URL}
+
+	oldAcl, err := fetchTokenAcl(ac, "old")
+	require.NoError(t, err)
+	assert.NoError(t, verifyRotatedAcl(ac, oldAcl, "same"), "rule IDs differ between copies")
+	assert.ErrorContains(t, verifyRotatedAcl(ac, oldAcl, "missing"), "1 missing, 0 extra")
+}
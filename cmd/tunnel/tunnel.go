@@ -10,6 +10,7 @@ import (
 	"github.com/alpacax/alpacon-cli/api/iam"
 	"github.com/alpacax/alpacon-cli/api/mfa"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/cmd/worksession"
 	tunnelruntime "github.com/alpacax/alpacon-cli/pkg/tunnel/runtime"
 	"github.com/alpacax/alpacon-cli/utils"
@@ -75,8 +76,9 @@ var TunnelCmd = &cobra.Command{
 	# Run kubectl with attached tunnel session
 	alpacon tunnel prod-k8s -l 6443 -r 6443 -- kubectl --server=https://127.0.0.1:6443 get pods
 	`,
-	Args: validateTunnelArgs,
-	Run:  runTunnel,
+	Args:              validateTunnelArgs,
+	ValidArgsFunction: complete.FirstArg(complete.Servers),
+	Run:               runTunnel,
 }
 
 func init() {
@@ -90,6 +92,7 @@ func bindTunnelFlags(cmd *cobra.Command, flags *tunnelFlagValues) {
 	cmd.Flags().StringVarP(&flags.groupname, "groupname", "g", "", "Groupname for the tunnel")
 	cmd.Flags().BoolVarP(&flags.verbose, "verbose", "v", false, "Show connection logs")
 	cmd.Flags().StringVar(&flags.workSessionID, "work-session", "", "Attach this tunnel to a work-session (overrides 'work-session use')")
+	_ = cmd.RegisterFlagCompletionFunc("work-session", complete.WorkSessions)
 
 	if err := cmd.MarkFlagRequired("local"); err != nil {
 		panic(err)
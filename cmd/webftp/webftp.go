@@ -1,8 +1,12 @@
 package webftp
 
 import (
+	"fmt"
+
 	"github.com/alpacax/alpacon-cli/api/webftp"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
+	logcmd "github.com/alpacax/alpacon-cli/cmd/log"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -14,12 +18,18 @@ var WebFTPCmd = &cobra.Command{
 	Long: `
 	Retrieve and display WebFTP file transfer logs from the Alpacon, with options to filter by
 	server, user, and action. Use the '--tail' flag to show the newest N entries.
+
+	Narrow the entries with --since/--until (a duration such as 15m, an RFC3339 time,
+	or a date) and --match (a regular expression over the file name and message).
+	Use -f/--follow to keep printing new entries; press Ctrl+C to stop.
 	`,
 	Example: `
 	alpacon webftp-log
 	alpacon webftp-logs
 	alpacon webftp-log --tail 10 --server my-server
 	alpacon webftp-log --tail=50 --user=admin --action=upload
+	alpacon webftp-log --server my-server --since 2026-10-01 --match '\.tar\.gz$'
+	alpacon webftp-log --server my-server -f
 	`,
 	Run: runWebFTP,
 }
@@ -34,6 +44,9 @@ func init() {
 	WebFTPCmd.Flags().StringVarP(&serverName, "server", "s", "", "Filter by server name")
 	WebFTPCmd.Flags().StringVarP(&userName, "user", "u", "", "Filter by username")
 	WebFTPCmd.Flags().StringVarP(&action, "action", "a", "", "Filter by action (e.g., upload, download)")
+	_ = WebFTPCmd.RegisterFlagCompletionFunc("server", complete.Servers)
+	_ = WebFTPCmd.RegisterFlagCompletionFunc("user", complete.Users)
+	logcmd.AddFilterFlags(WebFTPCmd)
 }
 
 func runWebFTP(cmd *cobra.Command, args []string) {
@@ -42,16 +55,35 @@ func runWebFTP(cmd *cobra.Command, args []string) {
 	serverName, _ := cmd.Flags().GetString("server")
 	userName, _ := cmd.Flags().GetString("user")
 	action, _ := cmd.Flags().GetString("action")
+	filter, interval := logcmd.FilterFromFlags(cmd)
 
 	alpaconClient, err := client.NewAlpaconAPIClient()
 	if err != nil {
 		utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
 	}
 
-	logList, err := webftp.GetWebFTPLogList(alpaconClient, tail, serverName, userName, action)
+	params, err := webftp.WebFTPLogParams(alpaconClient, serverName, userName, action)
 	if err != nil {
 		utils.CliErrorWithExit("Failed to get WebFTP logs: %s.", err)
 	}
 
-	utils.PrintTable(logList)
+	entries, err := webftp.GetWebFTPLogs(alpaconClient, params, tail, filter)
+	if err = logcmd.WarnIfTruncated(err); err != nil {
+		utils.CliErrorWithExit("Failed to get WebFTP logs: %s.", err)
+	}
+
+	if interval > 0 {
+		logcmd.Follow(webftp.FollowWebFTPLogs(alpaconClient, params, filter, interval), entries, webftpLine)
+		return
+	}
+	utils.PrintTable(webftp.ToWebFTPLogAttributes(entries))
+}
+
+func webftpLine(e webftp.WebFTPLogEntry) string {
+	row := webftp.ToWebFTPLogAttributes([]webftp.WebFTPLogEntry{e})[0]
+	status := "ok"
+	if !e.Success {
+		status = "failed"
+	}
+	return fmt.Sprintf("%s  %s  %s  %s  %s  %d bytes  %s  %s", logcmd.FollowTime(e.AddedAt), row.User, row.Server, e.Action, e.FileName, e.Size, status, e.RemoteIP)
 }
@@ -3,6 +3,7 @@ package websh
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/alpacax/alpacon-cli/api/mfa"
 	"github.com/alpacax/alpacon-cli/api/websh"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	execCmd "github.com/alpacax/alpacon-cli/cmd/exec"
 	"github.com/alpacax/alpacon-cli/cmd/worksession"
 	"github.com/alpacax/alpacon-cli/config"
@@ -32,6 +34,10 @@ type WebshArgs struct {
 	WorkSessionID string
 	OutputFormat  string
 	Env           map[string]string
+	LogFile       string
+	LogFormat     string
+	LogInput      bool
+	EscapeChar    rune
 }
 
 // ParseWebshArgs parses raw CLI args for `alpacon websh` (DisableFlagParsing mode).
@@ -40,7 +46,7 @@ type WebshArgs struct {
 // NOTE: --read-only must be checked before generic -r prefixes, and
 // --work-session before the default fallthrough.
 func ParseWebshArgs(args []string) (WebshArgs, error) {
-	res := WebshArgs{Env: map[string]string{}}
+	res := WebshArgs{Env: map[string]string{}, EscapeChar: websh.DefaultEscapeChar}
 	for i := 0; i < len(args); i++ {
 		switch {
 		case args[i] == "-s" || args[i] == "--share":
@@ -89,6 +95,30 @@ func ParseWebshArgs(args []string) (WebshArgs, error) {
 			}
 			res.WorkSessionID = ws
 			i = newI
+		case args[i] == "--log" || strings.HasPrefix(args[i], "--log="):
+			file, newI := extractValue(args, i)
+			if file == "" {
+				return res, fmt.Errorf("--log requires a file name")
+			}
+			res.LogFile = file
+			i = newI
+		case args[i] == "--log-format" || strings.HasPrefix(args[i], "--log-format="):
+			format, newI := extractValue(args, i)
+			if format != websh.LogFormatPlain && format != websh.LogFormatAsciicast {
+				return res, fmt.Errorf("the --log-format value must be either 'plain' or 'asciicast'")
+			}
+			res.LogFormat = format
+			i = newI
+		case args[i] == "--log-input":
+			res.LogInput = true
+		case args[i] == "-e" || args[i] == "--escape-char" || strings.HasPrefix(args[i], "--escape-char="):
+			raw, newI := extractValue(args, i)
+			escapeChar, err := parseEscapeChar(raw)
+			if err != nil {
+				return res, err
+			}
+			res.EscapeChar = escapeChar
+			i = newI
 		case args[i] == "--output" || strings.HasPrefix(args[i], "--output="):
 			val, newI := extractValue(args, i)
 			if val == "" {
@@ -175,6 +205,15 @@ Requires an active WorkSession when using Browser login (Auth0); Token auth (API
   alpacon websh --share my-server
   alpacon websh --share --read-only=true my-server
 
+  # Keep your own log of the session (plain text, or asciicast for a .cast file)
+  alpacon websh --log incident-1234.log my-server
+  alpacon websh --log incident-1234.cast --log-input my-server
+
+  # Inside a session, press Enter then ~? for escapes: ~. disconnects a wedged
+  # session, ~C opens a prompt for a file transfer or tunnel to the same server
+  alpacon websh -e '^]' my-server           # use Ctrl+] as the escape character
+  alpacon websh -e none my-server           # turn escapes off
+
   # Join an existing shared session
   alpacon websh join --url https://myws.us1.alpacon.io/websh/shared/abcd1234?channel=default --password my-session-pass
 
@@ -183,6 +222,7 @@ Requires an active WorkSession when using Browser login (Auth0); Token auth (API
   alpacon websh describe SESSION_ID         # Show session details
   alpacon websh watch SESSION_ID            # Watch a session (read-only, staff/superuser only)
   alpacon websh invite SESSION_ID --email user@example.com
+  alpacon websh replay SESSION_ID           # Play back a session's recording
   alpacon websh close SESSION_ID            # Close a session
   alpacon websh force-close SESSION_ID      # Force close (admin only)
 
@@ -202,6 +242,16 @@ Flags:
   --work-session [UUID]              Attach this session to a work-session.
                                      Overrides the workspace's active session
                                      set via 'alpacon work-session use'.
+  --log [FILE]                       Write what the session shows to FILE
+                                     (owner-only permissions), with timestamps.
+  --log-format [plain|asciicast]     Log format (default: asciicast for a .cast
+                                     file, plain otherwise). Play an asciicast
+                                     log with 'alpacon websh replay --file'.
+  --log-input                        Also log what you type. This includes input
+                                     that is not echoed, such as passwords.
+  -e, --escape-char [CHAR]           Escape character, recognized at the start of
+                                     a line (default: ~). Use ^X for a control
+                                     character, or 'none' to turn escapes off.
 
 Note: All flags must be placed before the server name.
       Everything after the server name is treated as the remote command.`,
@@ -210,6 +260,7 @@ Note: All flags must be placed before the server name.
 	// As a trade-off, we parse all flags manually in the Run function.
 	// Flags after the server name are intentionally treated as remote command args.
 	DisableFlagParsing: true,
+	ValidArgsFunction:  completeWebshArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		parsed, err := ParseWebshArgs(args)
 		if err != nil {
@@ -235,6 +286,12 @@ Note: All flags must be placed before the server name.
 		if share && len(commandArgs) > 0 {
 			utils.CliErrorWithExit("The --share flag cannot be used with remote commands. Use --share for interactive sessions only.")
 		}
+		if parsed.LogFile == "" && (parsed.LogFormat != "" || parsed.LogInput) {
+			utils.CliErrorWithExit("The --log-format and --log-input flags require --log.")
+		}
+		if parsed.LogFile != "" && len(commandArgs) > 0 {
+			utils.CliErrorWithExit("The --log flag cannot be used with remote commands. Use --log for interactive sessions only.")
+		}
 
 		// Parse SSH-like syntax for user@host
 		if strings.Contains(serverName, "@") && !strings.Contains(serverName, ":") {
@@ -275,6 +332,20 @@ Note: All flags must be placed before the server name.
 			utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
 		}
 
+		// The log opens before the session does, so a bad path fails here
+		// rather than after a session has been created for nothing.
+		var sessionLog *websh.SessionLog
+		if parsed.LogFile != "" {
+			title := "websh " + serverName
+			if username != "" {
+				title = fmt.Sprintf("websh %s@%s", username, serverName)
+			}
+			sessionLog, err = websh.OpenSessionLog(parsed.LogFile, sessionLogFormat(parsed), title, parsed.LogInput)
+			if err != nil {
+				utils.CliErrorWithExit("Failed to open the session log %s: %s.", parsed.LogFile, err)
+			}
+		}
+
 		session, err := websh.CreateWebshSession(alpaconClient, serverName, username, groupname, share, readOnly, workSessionID)
 
 		if err != nil {
@@ -297,6 +368,7 @@ Note: All flags must be placed before the server name.
 			})
 
 			if err != nil {
+				_ = sessionLog.Close()
 				utils.HandleWorkSessionError(err, "websh", serverName, authMethod, workSessionID)
 				utils.CliErrorWithExit("Failed to create websh session for '%s' server: %s.", serverName, err)
 			}
@@ -319,12 +391,34 @@ Note: All flags must be placed before the server name.
 			}
 		}()
 
-		if err = websh.OpenNewTerminal(alpaconClient, session); err != nil {
+		commands := newEscapeCommands(alpaconClient, serverName, username, groupname, workSessionID)
+		err = websh.OpenNewTerminal(alpaconClient, session, terminalOptions(session, parsed.EscapeChar, sessionLog, commands))
+		commands.closeAll()
+		if sessionLog != nil {
+			if logErr := sessionLog.Close(); logErr != nil {
+				utils.CliWarning("The session log %s may be incomplete: %s", parsed.LogFile, logErr)
+			} else {
+				utils.CliInfo("Session log written to %s.", parsed.LogFile)
+			}
+		}
+		if err != nil {
 			utils.CliErrorWithExitCode(utils.ExitCodeGeneralError, "Websh session ended with error: %s.", err)
 		}
 	},
 }
 
+// sessionLogFormat is --log-format when given, else asciicast for a .cast
+// file and plain for anything else.
+func sessionLogFormat(parsed WebshArgs) string {
+	if parsed.LogFormat != "" {
+		return parsed.LogFormat
+	}
+	if strings.EqualFold(filepath.Ext(parsed.LogFile), ".cast") {
+		return websh.LogFormatAsciicast
+	}
+	return websh.LogFormatPlain
+}
+
 func init() {
 	WebshCmd.AddCommand(webshJoinCmd)
 	WebshCmd.AddCommand(webshListCmd)
@@ -334,6 +428,7 @@ func init() {
 	WebshCmd.AddCommand(webshInviteCmd)
 	WebshCmd.AddCommand(webshWatchCmd)
 	WebshCmd.AddCommand(webshRecordsCmd)
+	WebshCmd.AddCommand(webshReplayCmd)
 }
 
 func extractValue(args []string, i int) (string, int) {
@@ -393,3 +488,14 @@ func isNotFoundError(err error) bool {
 	return msg == "not found" || msg == "not found." ||
 		strings.HasSuffix(msg, ": not found") || strings.HasSuffix(msg, ": not found.")
 }
+
+// completeWebshArgs completes the server name when the argument being typed is
+// where ParseWebshArgs expects it; the remote command completes nothing.
+func completeWebshArgs(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	before, _ := ParseWebshArgs(args)
+	at, err := ParseWebshArgs(append(args[:len(args):len(args)], "SERVER"))
+	if err != nil || at.ServerName != "SERVER" || before.ServerName != "" {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return complete.Servers(cmd, args, toComplete)
+}
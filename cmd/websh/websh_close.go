@@ -3,15 +3,17 @@ package websh
 import (
 	"github.com/alpacax/alpacon-cli/api/websh"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
 
 var webshCloseCmd = &cobra.Command{
-	Use:     "close SESSION_ID",
-	Short:   "Close a websh session",
-	Example: `  alpacon websh close abc123`,
-	Args:    cobra.ExactArgs(1),
+	Use:               "close SESSION_ID",
+	Short:             "Close a websh session",
+	Example:           `  alpacon websh close abc123`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.WebshSessions),
 	Run: func(cmd *cobra.Command, args []string) {
 		sessionID := args[0]
 
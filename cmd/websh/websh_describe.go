@@ -3,6 +3,7 @@ package websh
 import (
 	"github.com/alpacax/alpacon-cli/api/websh"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -13,7 +14,8 @@ var webshDescribeCmd = &cobra.Command{
 	Short:   "Display detailed information about a websh session",
 	Example: `  alpacon websh describe abc123
   alpacon websh desc abc123`,
-	Args: cobra.ExactArgs(1),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.WebshSessions),
 	Run: func(cmd *cobra.Command, args []string) {
 		sessionID := args[0]
 
@@ -0,0 +1,212 @@
+package websh
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/alpacax/alpacon-cli/api/ftp"
+	"github.com/alpacax/alpacon-cli/api/websh"
+	"github.com/alpacax/alpacon-cli/client"
+	tunnelruntime "github.com/alpacax/alpacon-cli/pkg/tunnel/runtime"
+	"github.com/alpacax/alpacon-cli/utils"
+)
+
+const escapeCommandHelp = `Commands:
+  -L LOCAL:REMOTE      forward local port LOCAL to port REMOTE on this server
+  -KL LOCAL            close the forward on local port LOCAL
+  -L                   list open forwards
+  get REMOTE [LOCAL]   download a file from this server (default: current directory)
+  put LOCAL REMOTE     upload a file to this server
+  help                 show this message`
+
+// parseEscapeChar reads the --escape-char value: a single character, ^X for
+// a control character, or "none" to turn escapes off.
+func parseEscapeChar(raw string) (rune, error) {
+	if raw == "none" {
+		return 0, nil
+	}
+	if len(raw) == 2 && raw[0] == '^' && raw[1] >= '@' && raw[1] <= '_' {
+		return rune(raw[1] - '@'), nil
+	}
+	if r, size := utf8.DecodeRuneInString(raw); size > 0 && size == len(raw) && r != utf8.RuneError {
+		return r, nil
+	}
+	return 0, fmt.Errorf("the --escape-char value must be a single character, ^X, or 'none'")
+}
+
+// escapeCommands runs what is typed at the ~C prompt against the server the
+// websh session is on, as the same user and under the same work session.
+// Forwards stay open until the websh session ends.
+type escapeCommands struct {
+	ac            *client.AlpaconClient
+	serverName    string
+	username      string
+	groupname     string
+	workSessionID string
+
+	mu      sync.Mutex
+	tunnels map[string]*tunnelruntime.Runtime // by local port as typed
+}
+
+func newEscapeCommands(ac *client.AlpaconClient, serverName, username, groupname, workSessionID string) *escapeCommands {
+	return &escapeCommands{
+		ac:            ac,
+		serverName:    serverName,
+		username:      username,
+		groupname:     groupname,
+		workSessionID: workSessionID,
+		tunnels:       map[string]*tunnelruntime.Runtime{},
+	}
+}
+
+func (e *escapeCommands) run(line string) (string, error) {
+	fields := strings.Fields(line)
+	switch {
+	case len(fields) == 0:
+		return "", nil
+	case fields[0] == "help" || fields[0] == "?":
+		return escapeCommandHelp, nil
+	case fields[0] == "-L" && len(fields) == 1:
+		return e.listTunnels(), nil
+	case strings.HasPrefix(fields[0], "-L"):
+		spec, err := flagValue(fields, "-L")
+		if err != nil {
+			return "", err
+		}
+		return e.openTunnel(spec)
+	case strings.HasPrefix(fields[0], "-KL"):
+		local, err := flagValue(fields, "-KL")
+		if err != nil {
+			return "", err
+		}
+		return e.closeTunnel(local)
+	case fields[0] == "get" && (len(fields) == 2 || len(fields) == 3):
+		local := "."
+		if len(fields) == 3 {
+			local = fields[2]
+		}
+		file, err := ftp.DownloadFileToPath(e.ac, e.serverName, fields[1], local, e.username, e.groupname, e.workSessionID)
+		if err != nil {
+			return "", fmt.Errorf("download failed: %w", err)
+		}
+		return fmt.Sprintf("Downloaded %s to %s (%d bytes).", fields[1], file.Path, file.Size), nil
+	case fields[0] == "put" && len(fields) == 3:
+		remote := fields[2]
+		if strings.HasSuffix(remote, "/") {
+			remote = path.Join(remote, filepath.Base(fields[1]))
+		}
+		if err := ftp.UploadLocalFileAs(e.ac, fields[1], e.serverName, remote, e.username, e.groupname, e.workSessionID); err != nil {
+			return "", fmt.Errorf("upload failed: %w", err)
+		}
+		return fmt.Sprintf("Uploaded %s to %s:%s.", fields[1], e.serverName, remote), nil
+	}
+	return "", fmt.Errorf("unknown command %q; type 'help' for the list", fields[0])
+}
+
+// flagValue takes the value of an ssh-style flag, written either joined to
+// it (-L9000:80) or as the next field (-L 9000:80).
+func flagValue(fields []string, flag string) (string, error) {
+	if v := strings.TrimPrefix(fields[0], flag); v != "" && len(fields) == 1 {
+		return v, nil
+	}
+	if fields[0] == flag && len(fields) == 2 {
+		return fields[1], nil
+	}
+	return "", fmt.Errorf("usage: %s VALUE", flag)
+}
+
+func (e *escapeCommands) openTunnel(spec string) (string, error) {
+	local, remote, ok := strings.Cut(spec, ":")
+	if !ok || local == "" || remote == "" {
+		return "", errors.New("usage: -L LOCAL:REMOTE, e.g. -L 9000:8080")
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, open := e.tunnels[local]; open {
+		return "", fmt.Errorf("local port %s is already forwarded", local)
+	}
+	rt, err := tunnelruntime.Start(tunnelruntime.StartOptions{
+		ServerName:    e.serverName,
+		LocalPort:     local,
+		RemotePort:    remote,
+		Username:      e.username,
+		Groupname:     e.groupname,
+		WorkSessionID: e.workSessionID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to start the tunnel: %w", err)
+	}
+	if err = rt.CheckReady(); err != nil {
+		rt.Close(nil)
+		return "", fmt.Errorf("failed to establish tunnel connection: %w", err)
+	}
+	e.tunnels[local] = rt
+	return fmt.Sprintf("Forwarding %s -> %s until this session ends.", rt.LocalAddress(), rt.RemoteAddress()), nil
+}
+
+func (e *escapeCommands) closeTunnel(local string) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	rt, ok := e.tunnels[local]
+	if !ok {
+		return "", fmt.Errorf("no forward on local port %s", local)
+	}
+	delete(e.tunnels, local)
+	rt.Close(nil)
+	return fmt.Sprintf("Closed the forward on %s.", rt.LocalAddress()), nil
+}
+
+func (e *escapeCommands) listTunnels() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.tunnels) == 0 {
+		return "No open forwards."
+	}
+	locals := make([]string, 0, len(e.tunnels))
+	for local := range e.tunnels {
+		locals = append(locals, local)
+	}
+	sort.Strings(locals)
+	lines := make([]string, 0, len(locals))
+	for _, local := range locals {
+		rt := e.tunnels[local]
+		state := ""
+		select {
+		case <-rt.Done():
+			state = " (closed)"
+		default:
+		}
+		lines = append(lines, fmt.Sprintf("%s -> %s%s", rt.LocalAddress(), rt.RemoteAddress(), state))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// closeAll ends every forward opened from the session.
+func (e *escapeCommands) closeAll() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for local, rt := range e.tunnels {
+		rt.Close(nil)
+		delete(e.tunnels, local)
+	}
+}
+
+// terminalOptions gathers what OpenNewTerminal needs for one session.
+func terminalOptions(session websh.SessionResponse, escapeChar rune, log *websh.SessionLog, commands *escapeCommands) websh.TerminalOptions {
+	opts := websh.TerminalOptions{
+		Log:        log,
+		EscapeChar: escapeChar,
+		SessionID:  session.ID,
+		ServerName: utils.SanitizeTerminalText(session.Server.Name),
+	}
+	if commands != nil {
+		opts.Command = commands.run
+	}
+	return opts
+}
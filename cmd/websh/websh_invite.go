@@ -3,6 +3,7 @@ package websh
 import (
 	"github.com/alpacax/alpacon-cli/api/websh"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -15,7 +16,8 @@ An invitation email will be sent to each specified address.`,
 	Example: `  alpacon websh invite abc123 --email user@example.com
   alpacon websh invite abc123 --email user1@example.com --email user2@example.com
   alpacon websh invite abc123 --email user@example.com --read-only`,
-	Args: cobra.ExactArgs(1),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.WebshSessions),
 	Run: func(cmd *cobra.Command, args []string) {
 		sessionID := args[0]
 		emails, _ := cmd.Flags().GetStringArray("email")
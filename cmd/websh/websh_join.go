@@ -10,13 +10,19 @@ import (
 var webshJoinCmd = &cobra.Command{
 	Use:   "join --url URL --password PASSWORD",
 	Short: "Join a shared websh session",
-	Long:  `Join an existing shared websh session using the provided URL and password.`,
+	Long: `Join an existing shared websh session using the provided URL and password.
+Press Enter then ~. to leave the session, or ~? for the other escapes.`,
 	Example: `  alpacon websh join --url https://myws.us1.alpacon.io/websh/shared/abcd1234?channel=default --password my-session-pass
   alpacon websh join --url https://myws.us1.alpacon.io/websh/shared/abcd1234?channel=default -p my-session-pass`,
 	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		url, _ := cmd.Flags().GetString("url")
 		password, _ := cmd.Flags().GetString("password")
+		escapeRaw, _ := cmd.Flags().GetString("escape-char")
+		escapeChar, err := parseEscapeChar(escapeRaw)
+		if err != nil {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+		}
 
 		alpaconClient, err := client.NewAlpaconAPIClient()
 		if err != nil {
@@ -28,7 +34,7 @@ var webshJoinCmd = &cobra.Command{
 			utils.CliErrorWithExit("Failed to join the session: %s.", err)
 		}
 
-		if err = websh.OpenNewTerminal(alpaconClient, session); err != nil {
+		if err = websh.OpenNewTerminal(alpaconClient, session, terminalOptions(session, escapeChar, nil, nil)); err != nil {
 			utils.CliErrorWithExitCode(utils.ExitCodeGeneralError, "Websh session ended with error: %s.", err)
 		}
 	},
@@ -37,6 +43,7 @@ var webshJoinCmd = &cobra.Command{
 func init() {
 	webshJoinCmd.Flags().String("url", "", "URL of the shared session to join (required)")
 	webshJoinCmd.Flags().StringP("password", "p", "", "Password for the shared session (required)")
+	webshJoinCmd.Flags().StringP("escape-char", "e", string(websh.DefaultEscapeChar), "Escape character at the start of a line (^X for a control character, 'none' to disable)")
 	_ = webshJoinCmd.MarkFlagRequired("url")
 	_ = webshJoinCmd.MarkFlagRequired("password")
 }
@@ -1,10 +1,12 @@
 package websh
 
 import (
+	"os"
 	"strings"
 
 	"github.com/alpacax/alpacon-cli/api/websh"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -16,22 +18,53 @@ var webshRecordsCmd = &cobra.Command{
 	Long: `Retrieve masked terminal records for a Websh session, optionally
 filtering by command text. Available on paid plans only.
 
-Use --query to search records by command text (fuzzy match).`,
+Use --query to search records by command text (fuzzy match).
+
+Use --format asciicast to write the session as an asciicast v2 recording, with
+its timing and terminal output intact, for 'alpacon websh replay --file' or any
+asciinema player. The whole session is fetched unless --limit is set, and
+--query does not apply.`,
 	Example: `  alpacon websh records abc123
   alpacon websh records abc123 --query docker
-  alpacon websh rec abc123 -q "sudo reboot" -n 50`,
-	Args: cobra.ExactArgs(1),
+  alpacon websh rec abc123 -q "sudo reboot" -n 50
+  alpacon websh records abc123 --format asciicast > session.cast`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.WebshSessions),
 	Run: func(cmd *cobra.Command, args []string) {
 		sessionID := args[0]
 		query, _ := cmd.Flags().GetString("query")
 		limit, _ := cmd.Flags().GetInt("limit")
+		format, _ := cmd.Flags().GetString("format")
 		utils.RequirePositiveInt("limit", limit)
+		switch format {
+		case "":
+		case formatAsciicast:
+			if query != "" {
+				utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "--query cannot be used with --format asciicast: a cast needs the whole session.")
+			}
+			if !cmd.Flags().Changed("limit") {
+				limit = websh.CastRecordLimit
+			}
+		default:
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "Invalid --format %q: must be asciicast.", format)
+		}
 
 		alpaconClient, err := client.NewAlpaconAPIClient()
 		if err != nil {
 			utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
 		}
 
+		if format == formatAsciicast {
+			cast, err := websh.GetSessionCast(alpaconClient, sessionID, limit)
+			if err != nil {
+				utils.CliErrorWithExit("Failed to retrieve Websh session records: %s.", err)
+			}
+			if err = cast.Encode(os.Stdout); err != nil {
+				utils.CliErrorWithExit("Failed to write the cast: %s.", err)
+			}
+			return
+		}
+
 		records, err := websh.GetSessionRecords(alpaconClient, sessionID, query, limit)
 		if err != nil {
 			utils.CliErrorWithExit("Failed to retrieve Websh session records: %s.", err)
@@ -74,4 +107,5 @@ func sanitizeRecord(record string, width int) string {
 func init() {
 	webshRecordsCmd.Flags().StringP("query", "q", "", "Search records by command text (fuzzy match)")
 	webshRecordsCmd.Flags().IntP("limit", "n", 100, "Maximum number of records to fetch")
+	webshRecordsCmd.Flags().String("format", "", "Write the records in another format: asciicast")
 }
@@ -0,0 +1,110 @@
+package websh
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/alpacax/alpacon-cli/api/websh"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
+	"github.com/alpacax/alpacon-cli/pkg/asciicast"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+const formatAsciicast = "asciicast"
+
+var webshReplayCmd = &cobra.Command{
+	Use:   "replay [SESSION_ID]",
+	Short: "Play back a Websh session in the terminal",
+	Long: `Play back a Websh session's masked records in your terminal at the pace they
+were recorded. Available on paid plans only.
+
+Use --speed to play faster or slower, and --idle-limit to cut long pauses short.
+Pass --file to play a cast file instead, such as one saved with
+'alpacon websh records SESSION_ID --format asciicast' or a session logged with
+'alpacon websh --log FILE.cast'.
+
+Escape sequences that draw (cursor movement, colour) are replayed; control
+strings that act on your terminal beyond drawing (OSC, DCS, APC) are dropped.
+Press Ctrl+C to stop.`,
+	Example: `  alpacon websh replay abc123
+  alpacon websh replay abc123 --speed 2 --idle-limit 1s
+  alpacon websh replay --file session.cast
+  alpacon websh replay --file session.cast --idle-limit 2s`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.WebshSessions),
+	Run: func(cmd *cobra.Command, args []string) {
+		file, _ := cmd.Flags().GetString("file")
+		speed, _ := cmd.Flags().GetFloat64("speed")
+		idleLimitRaw, _ := cmd.Flags().GetString("idle-limit")
+
+		if (file == "") == (len(args) == 0) {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "Pass either a SESSION_ID or --file.")
+		}
+		if speed <= 0 {
+			utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "--speed must be greater than 0.")
+		}
+		var idleLimit time.Duration
+		if idleLimitRaw != "" {
+			var err error
+			if idleLimit, err = utils.ParsePositiveDuration("--idle-limit", idleLimitRaw); err != nil {
+				utils.CliErrorWithExitCode(utils.ExitCodeUsageError, "%s.", err)
+			}
+		}
+
+		var cast *asciicast.Cast
+		if file != "" {
+			f, err := os.Open(file)
+			if err != nil {
+				utils.CliErrorWithExit("Failed to open %s: %s.", file, err)
+			}
+			cast, err = asciicast.Read(f)
+			_ = f.Close()
+			if err != nil {
+				utils.CliErrorWithExit("Failed to read %s: %s.", file, err)
+			}
+		} else {
+			alpaconClient, err := client.NewAlpaconAPIClient()
+			if err != nil {
+				utils.CliErrorWithExit("Connection to Alpacon API failed: %s. Consider re-logging.", err)
+			}
+			cast, err = websh.GetSessionCast(alpaconClient, args[0], websh.CastRecordLimit)
+			if err != nil {
+				utils.CliErrorWithExit("Failed to retrieve Websh session records: %s.", err)
+			}
+		}
+		if len(cast.Events) == 0 {
+			utils.CliInfoWithExit("The recording is empty.")
+		}
+
+		utils.CliInfo("Replaying %s recorded at %dx%d (press Ctrl+C to stop).",
+			cast.Duration().Round(time.Second), cast.Header.Width, cast.Header.Height)
+
+		stop := make(chan struct{})
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sigChan)
+		go func() {
+			<-sigChan
+			close(stop)
+		}()
+
+		err := asciicast.Play(asciicast.NewControlStringFilter(os.Stdout), cast, asciicast.PlayOptions{Speed: speed, IdleLimit: idleLimit}, stop)
+		// Reset attributes and show the cursor: a replay cut short can leave
+		// either the way the recording had them.
+		_, _ = fmt.Fprint(os.Stdout, "\x1b[0m\x1b[?25h\r\n")
+		if err != nil {
+			utils.CliErrorWithExit("Replay failed: %s.", err)
+		}
+	},
+}
+
+func init() {
+	webshReplayCmd.Flags().StringP("file", "f", "", "Play a local asciicast file instead of fetching a session")
+	webshReplayCmd.Flags().Float64("speed", 1, "Playback speed multiplier (e.g. 2 for twice as fast)")
+	webshReplayCmd.Flags().String("idle-limit", "", "Cap pauses at this duration (e.g. 2s)")
+}
@@ -375,6 +375,27 @@ func TestParseWebshArgs_CommandAfterServerNotConsumed(t *testing.T) {
 	assert.Equal(t, []string{"ls", "--work-session", "fake"}, got.CommandArgs)
 }
 
+func TestParseWebshArgs_LogFlags(t *testing.T) {
+	got, err := ParseWebshArgs([]string{"--log", "s.log", "--log-format=asciicast", "--log-input", "my-server"})
+	require.NoError(t, err)
+	assert.Equal(t, "s.log", got.LogFile)
+	assert.Equal(t, "asciicast", got.LogFormat)
+	assert.True(t, got.LogInput)
+	assert.Equal(t, "my-server", got.ServerName)
+
+	_, err = ParseWebshArgs([]string{"--log-format", "html", "--log", "s.log", "my-server"})
+	assert.ErrorContains(t, err, "--log-format")
+
+	_, err = ParseWebshArgs([]string{"--log"})
+	assert.ErrorContains(t, err, "--log requires a file name")
+}
+
+func TestSessionLogFormat(t *testing.T) {
+	assert.Equal(t, "asciicast", sessionLogFormat(WebshArgs{LogFile: "incident.CAST"}))
+	assert.Equal(t, "plain", sessionLogFormat(WebshArgs{LogFile: "incident.log"}))
+	assert.Equal(t, "plain", sessionLogFormat(WebshArgs{LogFile: "incident.cast", LogFormat: "plain"}))
+}
+
 func TestSanitizeRecord(t *testing.T) {
 	// ANSI color codes stripped, newlines collapsed to single spaces.
 	in := "\x1b[31mdocker\x1b[0m ps\n  -a\tfoo"
@@ -444,3 +465,45 @@ func TestBuildRemoteExecArgsPinsWebshInvocation(t *testing.T) {
 	assert.Equal(t, "psql -h localhost", args.Command)
 	assert.Equal(t, parsed.Env, args.Env)
 }
+
+func TestParseEscapeChar(t *testing.T) {
+	for raw, want := range map[string]rune{"~": '~', "^]": 0x1d, "none": 0, "§": '§'} {
+		got, err := parseEscapeChar(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, want, got, raw)
+	}
+	for _, raw := range []string{"", "ab", "^a"} {
+		_, err := parseEscapeChar(raw)
+		assert.Error(t, err, raw)
+	}
+
+	got, err := ParseWebshArgs([]string{"-e", "^]", "my-server"})
+	require.NoError(t, err)
+	assert.Equal(t, rune(0x1d), got.EscapeChar)
+	assert.Equal(t, "my-server", got.ServerName)
+
+	got, err = ParseWebshArgs([]string{"my-server"})
+	require.NoError(t, err)
+	assert.Equal(t, '~', got.EscapeChar, "escapes are on by default")
+}
+
+func TestEscapeCommands_Parsing(t *testing.T) {
+	e := newEscapeCommands(nil, "web-01", "", "", "")
+
+	out, err := e.run("help")
+	require.NoError(t, err)
+	assert.Contains(t, out, "-L LOCAL:REMOTE")
+
+	out, err = e.run("-L")
+	require.NoError(t, err)
+	assert.Equal(t, "No open forwards.", out)
+
+	_, err = e.run("-L 9000")
+	assert.ErrorContains(t, err, "usage: -L LOCAL:REMOTE")
+	_, err = e.run("-KL9000")
+	assert.ErrorContains(t, err, "no forward on local port 9000")
+	_, err = e.run("put only-one-arg")
+	assert.ErrorContains(t, err, "unknown command")
+	_, err = e.run("rm -rf /")
+	assert.ErrorContains(t, err, "unknown command \"rm\"")
+}
@@ -8,15 +8,17 @@ import (
 
 	"github.com/alpacax/alpacon-cli/api/websh"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
 
 var webshWatchCmd = &cobra.Command{
-	Use:     "watch SESSION_ID",
-	Short:   "Watch an active websh session (staff/superuser only)",
-	Example: `  alpacon websh watch abc123`,
-	Args:    cobra.ExactArgs(1),
+	Use:               "watch SESSION_ID",
+	Short:             "Watch an active websh session (staff/superuser only)",
+	Example:           `  alpacon websh watch abc123`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.WebshSessions),
 	Run: func(cmd *cobra.Command, args []string) {
 		sessionID := args[0]
 
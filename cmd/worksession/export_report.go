@@ -0,0 +1,355 @@
+package worksession
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	wsapi "github.com/alpacax/alpacon-cli/api/worksession"
+	"github.com/alpacax/alpacon-cli/utils"
+)
+
+const (
+	exportFormatHTML = "html"
+	exportFormatJSON = "json"
+	exportFormatMD   = "md"
+)
+
+// evidenceReport is what the report in an export bundle shows. Timestamps
+// stay as the API sent them (UTC, RFC 3339) rather than in the exporter's
+// local zone: the reader of a change ticket may sit anywhere.
+type evidenceReport struct {
+	GeneratedAt   string             `json:"generated_at"`
+	GeneratedBy   string             `json:"generated_by"`
+	Session       *wsapi.WorkSession `json:"session"`
+	Commands      []evidenceEntry    `json:"commands"`
+	Transfers     []evidenceEntry    `json:"transfers"`
+	Tunnels       []evidenceEntry    `json:"tunnels"`
+	SudoGrants    []evidenceEntry    `json:"sudo_grants"`
+	WebshSessions []evidenceEntry    `json:"websh_sessions"`
+	Recordings    []evidenceEntry    `json:"recordings"`
+	Other         []evidenceEntry    `json:"other,omitempty"`
+}
+
+// evidenceEntry is one timeline event. Details is written out in full—unlike
+// the timeline table, an audit record must not be truncated.
+type evidenceEntry struct {
+	Time    string `json:"time"`
+	Type    string `json:"type"`
+	Server  string `json:"server"`
+	User    string `json:"user"`
+	Details string `json:"details"`
+	File    string `json:"file,omitempty"`
+}
+
+func buildEvidenceReport(session *wsapi.WorkSession, items []wsapi.TimelineItem, recordingFiles map[int]string, now time.Time) *evidenceReport {
+	serverMap := map[string]string{}
+	for _, s := range session.Servers {
+		serverMap[s.ID] = s.Name
+	}
+
+	r := &evidenceReport{
+		GeneratedAt:   now.UTC().Format(time.RFC3339),
+		GeneratedBy:   fmt.Sprintf("alpacon-cli/%s", utils.GetCLIVersion()),
+		Session:       session,
+		Commands:      []evidenceEntry{},
+		Transfers:     []evidenceEntry{},
+		Tunnels:       []evidenceEntry{},
+		SudoGrants:    []evidenceEntry{},
+		WebshSessions: []evidenceEntry{},
+		Recordings:    []evidenceEntry{},
+	}
+	for i := range items {
+		item := &items[i]
+		entry := evidenceEntry{
+			Time:    derefString(item.Timestamp),
+			Type:    formatType(item.Type),
+			Server:  resolveServer(item.ServerID, serverMap),
+			User:    item.Username,
+			Details: evidenceDetails(item),
+		}
+		switch item.Type {
+		case "command":
+			r.Commands = append(r.Commands, entry)
+		case "file_upload", "file_download", "ftp_session":
+			r.Transfers = append(r.Transfers, entry)
+		case "tunnel_session":
+			r.Tunnels = append(r.Tunnels, entry)
+		case "sudo_grant":
+			r.SudoGrants = append(r.SudoGrants, entry)
+		case "websh_session":
+			r.WebshSessions = append(r.WebshSessions, entry)
+		case "websh_record":
+			entry.File = recordingFiles[i]
+			r.Recordings = append(r.Recordings, entry)
+		default:
+			r.Other = append(r.Other, entry)
+		}
+	}
+	return r
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func evidenceDetails(item *wsapi.TimelineItem) string {
+	switch item.Type {
+	case "command":
+		status := "unknown"
+		if item.Denied {
+			status = "denied"
+		} else if item.Success != nil {
+			status = map[bool]string{true: "ok", false: "failed"}[*item.Success]
+		}
+		return fmt.Sprintf("[%s] %s (%.2fs)", status, item.Line, item.ElapsedTime)
+	case "file_upload", "file_download":
+		return fmt.Sprintf("%s (%d bytes)", item.Name, item.Size)
+	case "tunnel_session":
+		detail := sessionState(item.ClosedAt)
+		if item.TargetPort != nil {
+			detail = fmt.Sprintf("port %d, %s", *item.TargetPort, detail)
+		}
+		return withClosedAt(detail, item.ClosedAt)
+	case "ftp_session":
+		return withClosedAt(sessionState(item.ClosedAt), item.ClosedAt)
+	case "sudo_grant":
+		detail := fmt.Sprintf("%s: %s", item.GrantType, item.Status)
+		if item.Command != nil && *item.Command != "" {
+			detail += fmt.Sprintf(", command %q", *item.Command)
+		}
+		if item.OneTime {
+			detail += ", one-time"
+		}
+		return detail
+	case "websh_session":
+		detail := sessionState(item.ClosedAt)
+		if item.ClientType != "" {
+			detail += fmt.Sprintf(", client %s", item.ClientType)
+		}
+		return withClosedAt(detail, item.ClosedAt)
+	case "websh_record":
+		return recordingPreview(item.MaskedRecord)
+	default:
+		return ""
+	}
+}
+
+func withClosedAt(detail string, closedAt *string) string {
+	if closedAt != nil {
+		return fmt.Sprintf("%s at %s", detail, *closedAt)
+	}
+	return detail
+}
+
+type evidenceSection struct {
+	Title   string
+	Entries []evidenceEntry
+}
+
+func (r *evidenceReport) sections() []evidenceSection {
+	sections := []evidenceSection{
+		{"Commands", r.Commands},
+		{"File transfers", r.Transfers},
+		{"Tunnels", r.Tunnels},
+		{"Sudo grants", r.SudoGrants},
+		{"Websh sessions", r.WebshSessions},
+		{"Websh recordings", r.Recordings},
+	}
+	if len(r.Other) > 0 {
+		sections = append(sections, evidenceSection{"Other events", r.Other})
+	}
+	return sections
+}
+
+// summaryRows is the session metadata in reading order, shared by the
+// Markdown and HTML reports.
+func (r *evidenceReport) summaryRows() [][2]string {
+	s := r.Session
+	servers := make([]string, len(s.Servers))
+	for i, srv := range s.Servers {
+		servers[i] = srv.Name
+	}
+	createdBy, assignedUser := "", ""
+	if s.CreatedBy != nil {
+		createdBy = s.CreatedBy.Name
+	}
+	if s.AssignedUser != nil {
+		assignedUser = s.AssignedUser.Name
+	}
+	rows := [][2]string{
+		{"ID", s.ID},
+		{"Purpose", s.Description},
+		{"Status", s.Status},
+		{"Requester type", s.RequesterType},
+		{"Created by", createdBy},
+		{"Assigned user", assignedUser},
+		{"Scopes", strings.Join(s.Scopes, ", ")},
+		{"Servers", strings.Join(servers, ", ")},
+		{"Approval request", s.ApprovalRequestID},
+		{"Requested at", formatEvidenceTime(&s.AddedAt)},
+		{"Starts at", formatEvidenceTime(s.StartsAt)},
+		{"Started at", formatEvidenceTime(s.StartedAt)},
+		{"Expires at", formatEvidenceTime(&s.ExpiresAt)},
+		{"Completed at", formatEvidenceTime(s.CompletedAt)},
+	}
+	for _, p := range s.SudoPolicies {
+		detail := strings.Join(p.Commands, ", ")
+		if p.AllowBypassMFA {
+			detail += " (MFA bypass)"
+		}
+		if p.Reason != "" {
+			detail += ": " + p.Reason
+		}
+		rows = append(rows, [2]string{"Sudo policy", detail})
+	}
+	return rows
+}
+
+func formatEvidenceTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func (r *evidenceReport) adjustmentLines() []string {
+	adj := r.Session.Adjustments
+	if adj == nil {
+		return nil
+	}
+	var lines []string
+	if adj.Scopes != nil {
+		lines = append(lines, fmt.Sprintf("Scopes: %s → %s", strings.Join(adj.Scopes.Old, ", "), strings.Join(adj.Scopes.New, ", ")))
+	}
+	if adj.Servers != nil {
+		lines = append(lines, fmt.Sprintf("Servers: %s → %s", joinServerNames(adj.Servers.Old), joinServerNames(adj.Servers.New)))
+	}
+	return lines
+}
+
+func writeEvidenceReport(w io.Writer, format string, r *evidenceReport) error {
+	switch format {
+	case exportFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case exportFormatMD:
+		return writeEvidenceMarkdown(w, r)
+	case exportFormatHTML:
+		return evidenceHTMLTemplate.Execute(w, r)
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// mdCell keeps a value on one table row: pipes would open a new column and
+// newlines a new row.
+func mdCell(s string) string {
+	s = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(s)
+	s = utils.StripControlChars(s)
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+func writeEvidenceMarkdown(w io.Writer, r *evidenceReport) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Work session %s\n\n", mdCell(r.Session.ID))
+	fmt.Fprintf(&b, "Generated %s by %s. The bundle's MANIFEST.sha256 lists the SHA-256 of every file.\n\n", r.GeneratedAt, r.GeneratedBy)
+
+	b.WriteString("| Field | Value |\n| --- | --- |\n")
+	for _, row := range r.summaryRows() {
+		fmt.Fprintf(&b, "| %s | %s |\n", row[0], mdCell(row[1]))
+	}
+
+	if lines := r.adjustmentLines(); len(lines) > 0 {
+		b.WriteString("\n## Approver adjustments\n\n")
+		for _, l := range lines {
+			fmt.Fprintf(&b, "- %s\n", mdCell(l))
+		}
+	}
+	if len(r.Session.Recommendations) > 0 {
+		b.WriteString("\n## Recommendations\n\n")
+		for _, rec := range r.Session.Recommendations {
+			fmt.Fprintf(&b, "- **%s** %s\n", mdCell(strings.ToUpper(rec.Severity)), mdCell(rec.Text))
+		}
+	}
+
+	for _, section := range r.sections() {
+		fmt.Fprintf(&b, "\n## %s (%d)\n\n", section.Title, len(section.Entries))
+		if len(section.Entries) == 0 {
+			b.WriteString("None.\n")
+			continue
+		}
+		b.WriteString("| Time | Server | User | Details |\n| --- | --- | --- | --- |\n")
+		for _, e := range section.Entries {
+			details := mdCell(e.Details)
+			if e.File != "" {
+				details = fmt.Sprintf("%s ([%s](%s))", details, e.File, e.File)
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", mdCell(e.Time), mdCell(e.Server), mdCell(e.User), details)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// The HTML report is self-contained—inline style, no scripts or remote
+// assets—so it renders the same from a ticket attachment years later.
+var evidenceHTMLTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Work session {{.Session.ID}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #1f2328; }
+table { border-collapse: collapse; margin-bottom: 1.5em; width: 100%; }
+th, td { border: 1px solid #d0d7de; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+td.mono { font-family: ui-monospace, Menlo, Consolas, monospace; white-space: pre-wrap; word-break: break-all; }
+.meta { color: #59636e; }
+</style>
+</head>
+<body>
+<h1>Work session {{.Session.ID}}</h1>
+<p class="meta">Generated {{.GeneratedAt}} by {{.GeneratedBy}}. The bundle's MANIFEST.sha256 lists the SHA-256 of every file.</p>
+<table>
+{{- range .SummaryRows}}
+<tr><th>{{index . 0}}</th><td>{{index . 1}}</td></tr>
+{{- end}}
+</table>
+{{- with .AdjustmentLines}}
+<h2>Approver adjustments</h2>
+<ul>{{range .}}<li>{{.}}</li>{{end}}</ul>
+{{- end}}
+{{- with .Session.Recommendations}}
+<h2>Recommendations</h2>
+<ul>{{range .}}<li><strong>{{.Severity}}</strong> {{.Text}}</li>{{end}}</ul>
+{{- end}}
+{{- range .Sections}}
+<h2>{{.Title}} ({{len .Entries}})</h2>
+{{- if .Entries}}
+<table>
+<tr><th>Time</th><th>Server</th><th>User</th><th>Details</th></tr>
+{{- range .Entries}}
+<tr><td>{{.Time}}</td><td>{{.Server}}</td><td>{{.User}}</td><td class="mono">{{.Details}}{{with .File}} (<a href="{{.}}">{{.}}</a>){{end}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>None.</p>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// Exported-name views for the HTML template, which cannot call unexported methods.
+
+func (r *evidenceReport) SummaryRows() [][2]string    { return r.summaryRows() }
+func (r *evidenceReport) AdjustmentLines() []string   { return r.adjustmentLines() }
+func (r *evidenceReport) Sections() []evidenceSection { return r.sections() }
@@ -0,0 +1,384 @@
+package worksession
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/alpacax/alpacon-cli/config"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateFileName is the work-session template file looked up in a
+// repository's .alpacon directory and in the user's ~/.alpacon.
+const TemplateFileName = "worksession-templates.yaml"
+
+// templateVarRE matches a {{name}} placeholder; whitespace inside the braces
+// is allowed so "{{ server }}" reads the same.
+var templateVarRE = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_-]*)\s*\}\}`)
+
+type templateFile struct {
+	Templates map[string]workSessionTemplate `yaml:"templates"`
+}
+
+// workSessionTemplate mirrors the create flags. Every string may carry
+// {{name}} placeholders; a scopes or servers entry that renders to a
+// comma-separated list expands into several entries, like the flags do.
+type workSessionTemplate struct {
+	Description   string                      `yaml:"description,omitempty" json:"description,omitempty"`
+	Purpose       string                      `yaml:"purpose" json:"purpose"`
+	Scopes        []string                    `yaml:"scopes" json:"scopes"`
+	Servers       []string                    `yaml:"servers" json:"servers"`
+	ExpiresIn     string                      `yaml:"expires_in,omitempty" json:"expires_in,omitempty"`
+	RequesterType string                      `yaml:"requester_type,omitempty" json:"requester_type,omitempty"`
+	Sudo          []string                    `yaml:"sudo,omitempty" json:"sudo,omitempty"`
+	SudoReason    string                      `yaml:"sudo_reason,omitempty" json:"sudo_reason,omitempty"`
+	Variables     map[string]templateVariable `yaml:"variables,omitempty" json:"variables,omitempty"`
+}
+
+// templateVariable declares a placeholder. A variable without a default must
+// be given with --var (or answered at the prompt in an interactive shell).
+type templateVariable struct {
+	Description string  `yaml:"description,omitempty" json:"description,omitempty"`
+	Default     *string `yaml:"default,omitempty" json:"default,omitempty"`
+}
+
+// namedTemplate is a template with the file it came from.
+type namedTemplate struct {
+	Name   string
+	Source string
+	workSessionTemplate
+}
+
+// renderedTemplate is a template with every placeholder filled in, ready to
+// stand in for the create flags.
+type renderedTemplate struct {
+	Purpose       string   `json:"purpose"`
+	Scopes        []string `json:"scopes"`
+	Servers       []string `json:"servers"`
+	ExpiresIn     string   `json:"expires_in,omitempty"`
+	RequesterType string   `json:"requester_type,omitempty"`
+	Sudo          []string `json:"sudo,omitempty"`
+	SudoReason    string   `json:"sudo_reason,omitempty"`
+}
+
+// templateFilePaths returns the template files in precedence order: the one
+// in the nearest .alpacon directory at or above the working directory, then
+// the user's. A repository file wins so a team's runbook templates apply to
+// everyone working in that checkout.
+func templateFilePaths() []string {
+	var paths []string
+	if wd, err := os.Getwd(); err == nil {
+		for dir := wd; ; dir = filepath.Dir(dir) {
+			candidate := filepath.Join(dir, config.ConfigFileDir, TemplateFileName)
+			if fileExists(candidate) {
+				paths = append(paths, candidate)
+				break
+			}
+			if filepath.Dir(dir) == dir {
+				break
+			}
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		candidate := filepath.Join(home, config.ConfigFileDir, TemplateFileName)
+		if fileExists(candidate) && !slices.Contains(paths, candidate) {
+			paths = append(paths, candidate)
+		}
+	}
+	return paths
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+func readTemplateFile(path string) (*templateFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseTemplateFile(data)
+}
+
+func parseTemplateFile(data []byte) (*templateFile, error) {
+	var file templateFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// loadTemplates reads every template file, keeping the first definition of a
+// name in precedence order.
+func loadTemplates() ([]namedTemplate, error) {
+	var out []namedTemplate
+	seen := map[string]bool{}
+	for _, path := range templateFilePaths() {
+		file, err := readTemplateFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		names := make([]string, 0, len(file.Templates))
+		for name := range file.Templates {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			out = append(out, namedTemplate{Name: name, Source: path, workSessionTemplate: file.Templates[name]})
+		}
+	}
+	return out, nil
+}
+
+func findTemplate(name string) (*namedTemplate, error) {
+	templates, err := loadTemplates()
+	if err != nil {
+		return nil, err
+	}
+	for i := range templates {
+		if templates[i].Name == name {
+			return &templates[i], nil
+		}
+	}
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("template %q not found: no %s in ./%s (or a parent directory) or ~/%s",
+			name, TemplateFileName, config.ConfigFileDir, config.ConfigFileDir)
+	}
+	return nil, fmt.Errorf("template %q not found. Run 'alpacon work-session template ls' to see the available templates", name)
+}
+
+// parseTemplateVars turns repeated --var key=value flags into a map.
+func parseTemplateVars(raw []string) (map[string]string, error) {
+	vars := make(map[string]string, len(raw))
+	for _, kv := range raw {
+		key, value, ok := strings.Cut(kv, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --var %q: expected KEY=VALUE", kv)
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+// referencedVars lists the placeholders the template uses, sorted.
+func (t workSessionTemplate) referencedVars() []string {
+	fields := []string{t.Purpose, t.ExpiresIn, t.RequesterType, t.SudoReason}
+	fields = append(fields, t.Scopes...)
+	fields = append(fields, t.Servers...)
+	fields = append(fields, t.Sudo...)
+
+	seen := map[string]bool{}
+	var names []string
+	for _, f := range fields {
+		for _, m := range templateVarRE.FindAllStringSubmatch(f, -1) {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				names = append(names, m[1])
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// missingVars lists the referenced variables that have neither a value in
+// vars nor a default, sorted.
+func (t workSessionTemplate) missingVars(vars map[string]string) []string {
+	var missing []string
+	for _, name := range t.referencedVars() {
+		if _, ok := vars[name]; ok {
+			continue
+		}
+		if v, ok := t.Variables[name]; ok && v.Default != nil {
+			continue
+		}
+		missing = append(missing, name)
+	}
+	return missing
+}
+
+// render fills every placeholder from vars, falling back to each variable's
+// default. A value for a variable the template does not declare is an error:
+// it is almost always a typo that would otherwise be silently ignored.
+func (t workSessionTemplate) render(vars map[string]string) (*renderedTemplate, error) {
+	for name := range vars {
+		if _, ok := t.Variables[name]; !ok {
+			return nil, fmt.Errorf("unknown variable %q (declared: %s)", name, strings.Join(t.declaredVars(), ", "))
+		}
+	}
+	if missing := t.missingVars(vars); len(missing) > 0 {
+		return nil, fmt.Errorf("missing value for variable(s): %s (pass --var NAME=VALUE)", strings.Join(missing, ", "))
+	}
+
+	values := map[string]string{}
+	for name, v := range t.Variables {
+		if v.Default != nil {
+			values[name] = *v.Default
+		}
+	}
+	for name, value := range vars {
+		values[name] = value
+	}
+	sub := func(s string) string {
+		return templateVarRE.ReplaceAllStringFunc(s, func(m string) string {
+			return values[templateVarRE.FindStringSubmatch(m)[1]]
+		})
+	}
+	expand := func(items []string) []string {
+		var out []string
+		for _, item := range items {
+			out = append(out, utils.SplitAndTrim(sub(item), ",")...)
+		}
+		return out
+	}
+
+	r := &renderedTemplate{
+		Purpose:       strings.TrimSpace(sub(t.Purpose)),
+		Scopes:        expand(t.Scopes),
+		Servers:       expand(t.Servers),
+		ExpiresIn:     strings.TrimSpace(sub(t.ExpiresIn)),
+		RequesterType: strings.TrimSpace(sub(t.RequesterType)),
+		SudoReason:    sub(t.SudoReason),
+	}
+	for _, s := range t.Sudo {
+		if rendered := strings.TrimSpace(sub(s)); rendered != "" {
+			r.Sudo = append(r.Sudo, rendered)
+		}
+	}
+	return r, nil
+}
+
+func (t workSessionTemplate) declaredVars() []string {
+	names := make([]string, 0, len(t.Variables))
+	for name := range t.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validate reports every problem in the template rather than the first, so one
+// 'template validate' run lists everything to fix. Fields still holding a
+// placeholder are checked once rendered, at create time.
+func (t workSessionTemplate) validate() []string {
+	var problems []string
+	if strings.TrimSpace(t.Purpose) == "" {
+		problems = append(problems, "purpose is required")
+	}
+	if len(t.Scopes) == 0 && len(t.Sudo) == 0 {
+		problems = append(problems, "scopes is required")
+	}
+	if len(t.Servers) == 0 {
+		problems = append(problems, "servers is required")
+	}
+
+	var literalScopes []string
+	for _, s := range t.Scopes {
+		if !templateVarRE.MatchString(s) {
+			literalScopes = append(literalScopes, utils.SplitAndTrim(s, ",")...)
+		}
+	}
+	if err := validateScopeEnum(literalScopes); err != nil {
+		problems = append(problems, fmt.Sprintf("invalid scopes: %s", err))
+	}
+
+	if t.ExpiresIn != "" && !templateVarRE.MatchString(t.ExpiresIn) {
+		if _, err := utils.ParsePositiveDuration("expires_in", t.ExpiresIn); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	switch t.RequesterType {
+	case "", "user":
+	case "agent":
+		if err := validateAgentScopes("agent", literalScopes); err != nil {
+			problems = append(problems, err.Error())
+		}
+	default:
+		if !templateVarRE.MatchString(t.RequesterType) {
+			problems = append(problems, fmt.Sprintf("requester_type %q must be \"user\" or \"agent\"", t.RequesterType))
+		}
+	}
+
+	referenced := t.referencedVars()
+	for _, name := range referenced {
+		if _, ok := t.Variables[name]; !ok {
+			problems = append(problems, fmt.Sprintf("variable %q is used but not declared under variables", name))
+		}
+	}
+	for _, name := range t.declaredVars() {
+		if !slices.Contains(referenced, name) {
+			problems = append(problems, fmt.Sprintf("variable %q is declared but never used", name))
+		}
+	}
+	return problems
+}
+
+// applyCreateTemplate fills the create flags the caller left unset from the
+// named template. Explicit flags win, so a template can be reused with a
+// one-off change such as --expires-in 4h. Variables without a value are asked
+// for in an interactive shell and are a usage error otherwise.
+func applyCreateTemplate(cmd *cobra.Command, name string, rawVars []string) error {
+	tmpl, err := findTemplate(name)
+	if err != nil {
+		return err
+	}
+	vars, err := parseTemplateVars(rawVars)
+	if err != nil {
+		return err
+	}
+	if missing := tmpl.missingVars(vars); len(missing) > 0 && utils.IsInteractiveShell() {
+		for _, v := range missing {
+			prompt := v
+			if desc := tmpl.Variables[v].Description; desc != "" {
+				prompt = fmt.Sprintf("%s (%s)", v, desc)
+			}
+			vars[v] = utils.PromptForRequiredInput(prompt + ": ")
+		}
+	}
+	rendered, err := tmpl.render(vars)
+	if err != nil {
+		return fmt.Errorf("template %q: %w", name, err)
+	}
+
+	flags := cmd.Flags()
+	if !flags.Changed("purpose") {
+		purpose = rendered.Purpose
+	}
+	if !flags.Changed("scope") {
+		createScopes = rendered.Scopes
+	}
+	if !flags.Changed("server") {
+		createServers = rendered.Servers
+	}
+	if !flags.Changed("expires-in") && !flags.Changed("expires-at") {
+		expiresIn = rendered.ExpiresIn
+	}
+	if !flags.Changed("requester-type") && rendered.RequesterType != "" {
+		requesterType = rendered.RequesterType
+	}
+	if !flags.Changed("sudo") {
+		createSudo = rendered.Sudo
+	}
+	if !flags.Changed("sudo-reason") {
+		createSudoReason = rendered.SudoReason
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package worksession
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTemplates = `
+templates:
+  db-maintenance:
+    description: Weekly vacuum
+    purpose: "Maintenance on {{server}}: {{ task }}"
+    scopes: [command, websh]
+    servers: ["{{server}}"]
+    expires_in: 2h
+    sudo:
+      - "systemctl restart postgresql"
+    variables:
+      server:
+        description: Database server
+      task:
+        default: vacuum
+`
+
+func TestParseTemplateFile(t *testing.T) {
+	file, err := parseTemplateFile([]byte(testTemplates))
+	require.NoError(t, err)
+	require.Contains(t, file.Templates, "db-maintenance")
+	tmpl := file.Templates["db-maintenance"]
+	assert.Equal(t, []string{"server", "task"}, tmpl.referencedVars())
+	assert.Empty(t, tmpl.validate())
+
+	_, err = parseTemplateFile([]byte("templates:\n  x:\n    purpse: typo\n"))
+	assert.Error(t, err, "unknown fields are rejected")
+}
+
+func TestTemplateRender(t *testing.T) {
+	file, err := parseTemplateFile([]byte(testTemplates))
+	require.NoError(t, err)
+	tmpl := file.Templates["db-maintenance"]
+
+	r, err := tmpl.render(map[string]string{"server": "db-3"})
+	require.NoError(t, err)
+	assert.Equal(t, "Maintenance on db-3: vacuum", r.Purpose)
+	assert.Equal(t, []string{"command", "websh"}, r.Scopes)
+	assert.Equal(t, []string{"db-3"}, r.Servers)
+	assert.Equal(t, "2h", r.ExpiresIn)
+	assert.Equal(t, []string{"systemctl restart postgresql"}, r.Sudo)
+
+	r, err = tmpl.render(map[string]string{"server": "db-3, db-4", "task": "reindex"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"db-3", "db-4"}, r.Servers, "a list value expands like the --server flag")
+	assert.Equal(t, "Maintenance on db-3, db-4: reindex", r.Purpose)
+
+	_, err = tmpl.render(map[string]string{})
+	assert.ErrorContains(t, err, "missing value for variable(s): server")
+
+	_, err = tmpl.render(map[string]string{"server": "db-3", "sever": "db-4"})
+	assert.ErrorContains(t, err, `unknown variable "sever"`)
+}
+
+func TestTemplateValidate(t *testing.T) {
+	tmpl := workSessionTemplate{
+		Scopes:        []string{"command", "shell", "{{extra}}"},
+		ExpiresIn:     "soon",
+		RequesterType: "robot",
+		Servers:       []string{"{{server}}"},
+		Variables:     map[string]templateVariable{"server": {}, "unused": {}},
+	}
+
+	problems := tmpl.validate()
+
+	assert.Contains(t, problems, "purpose is required")
+	assert.Contains(t, problems, `variable "extra" is used but not declared under variables`)
+	assert.Contains(t, problems, `variable "unused" is declared but never used`)
+	assert.Contains(t, problems, `requester_type "robot" must be "user" or "agent"`)
+	assert.Len(t, problems, 6, "the invalid scope and expiry are reported too: %v", problems)
+}
+
+func TestParseTemplateVars(t *testing.T) {
+	vars, err := parseTemplateVars([]string{"server=db-3", "query=a=b"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"server": "db-3", "query": "a=b"}, vars)
+
+	_, err = parseTemplateVars([]string{"server"})
+	assert.Error(t, err)
+}
+
+func TestLoadTemplates_RepositoryFileOverridesUserFile(t *testing.T) {
+	home := t.TempDir()
+	repo := t.TempDir()
+	t.Setenv("HOME", home)
+
+	writeTemplateFile(t, home, `
+templates:
+  shared:
+    description: from home
+    purpose: p
+    scopes: [command]
+    servers: [a]
+  personal:
+    purpose: p
+    scopes: [command]
+    servers: [a]
+`)
+	writeTemplateFile(t, repo, `
+templates:
+  shared:
+    description: from repo
+    purpose: p
+    scopes: [command]
+    servers: [a]
+`)
+
+	sub := filepath.Join(repo, "deploy", "scripts")
+	require.NoError(t, os.MkdirAll(sub, 0o755))
+	t.Chdir(sub)
+
+	templates, err := loadTemplates()
+	require.NoError(t, err)
+	require.Len(t, templates, 2)
+	assert.Equal(t, "shared", templates[0].Name)
+	assert.Equal(t, "from repo", templates[0].Description, "the file found above the working directory wins")
+	assert.Equal(t, "personal", templates[1].Name)
+
+	_, err = findTemplate("missing")
+	assert.ErrorContains(t, err, `template "missing" not found`)
+}
+
+func writeTemplateFile(t *testing.T, dir, content string) {
+	t.Helper()
+	path := filepath.Join(dir, ".alpacon", TemplateFileName)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
@@ -14,10 +14,13 @@ const (
 	opCreate    = "create"
 	opCurrent   = "current"
 	opDescribe  = "describe"
+	opExport    = "export"
 	opExtend    = "extend"
 	opList      = "list"
 	opRecording = "recording"
 	opRevoke    = "revoke"
+	opShell     = "shell"
+	opTemplate  = "template"
 	opTimeline  = "timeline"
 	opUnset     = "unset"
 	opUpdate    = "update"
@@ -63,7 +66,7 @@ Run 'alpacon whoami' to check your WorkSession requirement and active session.`,
 		if err := cmd.Help(); err != nil {
 			return err
 		}
-		return errors.New("a subcommand is required. Use 'alpacon work-session ls', 'alpacon work-session create', 'alpacon work-session describe', 'alpacon work-session use', 'alpacon work-session current', 'alpacon work-session activate', 'alpacon work-session complete', 'alpacon work-session extend', 'alpacon work-session update', 'alpacon work-session approve', 'alpacon work-session reject', 'alpacon work-session revoke', 'alpacon work-session cancel', 'alpacon work-session timeline', or 'alpacon work-session recording'. Run 'alpacon work-session --help' for more information")
+		return errors.New("a subcommand is required. Use 'alpacon work-session ls', 'alpacon work-session create', 'alpacon work-session describe', 'alpacon work-session use', 'alpacon work-session current', 'alpacon work-session activate', 'alpacon work-session complete', 'alpacon work-session extend', 'alpacon work-session update', 'alpacon work-session approve', 'alpacon work-session reject', 'alpacon work-session revoke', 'alpacon work-session cancel', 'alpacon work-session timeline', 'alpacon work-session recording', 'alpacon work-session export', 'alpacon work-session shell', or 'alpacon work-session template'. Run 'alpacon work-session --help' for more information")
 	},
 }
 
@@ -79,8 +82,11 @@ func init() {
 	WorkSessionCmd.AddCommand(workSessionCurrentCmd)
 	WorkSessionCmd.AddCommand(workSessionTimelineCmd)
 	WorkSessionCmd.AddCommand(workSessionRecordingCmd)
+	WorkSessionCmd.AddCommand(workSessionExportCmd)
 	WorkSessionCmd.AddCommand(workSessionApproveCmd)
 	WorkSessionCmd.AddCommand(workSessionRejectCmd)
 	WorkSessionCmd.AddCommand(workSessionRevokeCmd)
 	WorkSessionCmd.AddCommand(workSessionCancelCmd)
+	WorkSessionCmd.AddCommand(workSessionShellCmd)
+	WorkSessionCmd.AddCommand(workSessionTemplateCmd)
 }
@@ -3,16 +3,18 @@ package worksession
 import (
 	wsapi "github.com/alpacax/alpacon-cli/api/worksession"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
 
 var workSessionCancelCmd = &cobra.Command{
-	Use:     "cancel SESSION_ID",
-	Short:   "Withdraw your own pending work session request",
-	Long:    "Withdraw a pending work session you requested before it is reviewed. Restricted to the session's requester (or a superuser); only pending sessions can be cancelled.",
-	Args:    cobra.ExactArgs(1),
-	Example: `  alpacon work-session cancel ses-abc123`,
+	Use:               "cancel SESSION_ID",
+	Short:             "Withdraw your own pending work session request",
+	Long:              "Withdraw a pending work session you requested before it is reviewed. Restricted to the session's requester (or a superuser); only pending sessions can be cancelled.",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.WorkSessions),
+	Example:           `  alpacon work-session cancel ses-abc123`,
 	Run: func(cmd *cobra.Command, args []string) {
 		ac, err := client.NewAlpaconAPIClient()
 		if err != nil {
@@ -3,15 +3,17 @@ package worksession
 import (
 	wsapi "github.com/alpacax/alpacon-cli/api/worksession"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
 
 var workSessionCompleteCmd = &cobra.Command{
-	Use:     "complete SESSION_ID",
-	Short:   "Mark an active work session as completed",
-	Args:    cobra.ExactArgs(1),
-	Example: `  alpacon work-session complete ses-abc123`,
+	Use:               "complete SESSION_ID",
+	Short:             "Mark an active work session as completed",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.WorkSessions),
+	Example:           `  alpacon work-session complete ses-abc123`,
 	Run: func(cmd *cobra.Command, args []string) {
 		ac, err := client.NewAlpaconAPIClient()
 		if err != nil {
@@ -12,6 +12,7 @@ import (
 	"github.com/alpacax/alpacon-cli/api/server"
 	wsapi "github.com/alpacax/alpacon-cli/api/worksession"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/config"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
@@ -46,6 +47,8 @@ var (
 	useAfterCreate   bool
 	createSudo       []string
 	createSudoReason string
+	createTemplate   string
+	createVars       []string
 )
 
 var workSessionCreateCmd = &cobra.Command{
@@ -69,7 +72,13 @@ approval together with the session. If a sudo command is later denied, add it to
 session with 'alpacon work-session update <id> --sudo "<command>"'.
 
 When an AI agent (rather than a human) drives the session, pass --requester-type agent
-so it is recorded and scoped accordingly.`,
+so it is recorded and scoped accordingly.
+
+For requests you make repeatedly, pass --template NAME to fill the request from a
+template in .alpacon/worksession-templates.yaml (found in the current directory or
+a parent) or ~/.alpacon/worksession-templates.yaml. Fill the template's variables
+with --var NAME=VALUE; flags given explicitly override the template. See
+'alpacon work-session template --help'.`,
 	Example: `  alpacon work-session create --scope command,websh --server web-01 --expires-in 2h --purpose "restart nginx on web-01 to clear 502s"
   alpacon work-session create --scope command --server web-01,db-01 --expires-at 2027-01-15T10:00:00Z --purpose "deploy" --wait
   alpacon work-session create --scope command --server web-01 --expires-in 1h --purpose "hotfix" --use
@@ -77,8 +86,17 @@ so it is recorded and scoped accordingly.`,
   alpacon work-session create --scope command --server web-01 --expires-in 2h --purpose "deploy" --wait-approval 30m --use
   alpacon work-session create --scope command --server web-01 --expires-in 2h --purpose "auto-remediate disk-full alert on web-01: rotate logs, restart rsyslog" --requester-type agent
   alpacon work-session create --server web-01 --expires-in 2h --purpose "nginx hotfix" \
-    --sudo "systemctl restart nginx,systemctl reload nginx" --sudo "tail -f /var/log/nginx/*.log"`,
+    --sudo "systemctl restart nginx,systemctl reload nginx" --sudo "tail -f /var/log/nginx/*.log"
+  alpacon work-session create --template db-maintenance --var server=db-3 --wait --use`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if createTemplate != "" {
+			if err := applyCreateTemplate(cmd, createTemplate, createVars); err != nil {
+				utils.CliUsageErrorEnvelopeWithExit(opCreate, "%s.", err)
+			}
+		} else if len(createVars) > 0 {
+			utils.CliUsageErrorEnvelopeWithExit(opCreate, "--var requires --template.")
+		}
+
 		purpose = strings.TrimSpace(purpose)
 		if purpose == "" {
 			if !utils.IsInteractiveShell() {
@@ -518,5 +536,8 @@ func init() {
 	workSessionCreateCmd.Flags().BoolVar(&useAfterCreate, "use", false, "Set the created session as the workspace's active session (requires status to reach 'active'; combine with --wait when approval is needed)")
 	workSessionCreateCmd.Flags().StringArrayVar(&createSudo, "sudo", nil, "Pre-declare sudo command patterns to run without interactive MFA (repeatable; each value is a comma-separated pattern list forming one policy, wildcards allowed; literal commas inside a pattern are not supported — pass the flag again for each policy that needs them). Required for non-interactive sudo via 'exec' (e.g. AI agents). Implies the 'sudo' scope. Patterns are submitted for approval with the session.")
 	workSessionCreateCmd.Flags().StringVar(&createSudoReason, "sudo-reason", "", "Justification applied to the sudo policies created via --sudo")
+	workSessionCreateCmd.Flags().StringVar(&createTemplate, "template", "", "Fill the request from a work-session template (see 'alpacon work-session template ls')")
+	workSessionCreateCmd.Flags().StringArrayVar(&createVars, "var", nil, "Template variable as NAME=VALUE (repeatable; requires --template)")
 	workSessionCreateCmd.MarkFlagsMutuallyExclusive("expires-in", "expires-at")
+	_ = workSessionCreateCmd.RegisterFlagCompletionFunc("server", complete.CommaSeparated(complete.Servers))
 }
@@ -6,6 +6,7 @@ import (
 
 	wsapi "github.com/alpacax/alpacon-cli/api/worksession"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -16,10 +17,11 @@ type describeRow struct {
 }
 
 var workSessionDescribeCmd = &cobra.Command{
-	Use:     "describe SESSION_ID",
-	Aliases: []string{"desc"},
-	Short:   "Show details of a work session",
-	Args:    cobra.ExactArgs(1),
+	Use:               "describe SESSION_ID",
+	Aliases:           []string{"desc"},
+	Short:             "Show details of a work session",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.WorkSessions),
 	Example: `  alpacon work-session describe ses-abc123
   alpacon work-session desc ses-abc123`,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -0,0 +1,347 @@
+package worksession
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	wsapi "github.com/alpacax/alpacon-cli/api/worksession"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+const manifestFileName = "MANIFEST.sha256"
+
+var (
+	exportFormat string
+	exportFile   string
+	exportVerify string
+)
+
+var workSessionExportCmd = &cobra.Command{
+	Use:   "export SESSION_ID",
+	Short: "Export a work session as an audit evidence bundle",
+	Long: `Export a work session as a zip bundle to attach to a change ticket. The bundle
+holds:
+
+  report.html|json|md  session metadata, approver adjustments and
+                       recommendations, and every command, file transfer,
+                       tunnel, sudo grant, and websh session on the timeline
+  session.json         the work session as the API returns it
+  timeline.json        the full timeline as the API returns it
+  recordings/          each websh recording, one file per recording
+  MANIFEST.sha256      the SHA-256 of every other file, in sha256sum format
+
+The SHA-256 of the manifest itself is printed when the bundle is written.
+Record it in the ticket: it pins the manifest, and the manifest pins every file.
+Check a bundle later with --verify, or unzip it and run 'sha256sum -c MANIFEST.sha256'.`,
+	Example: `  alpacon work-session export ses-abc123
+  alpacon work-session export ses-abc123 --format md -f CHG-1234-evidence.zip
+  alpacon work-session export --verify CHG-1234-evidence.zip`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.WorkSessions),
+	Run: func(cmd *cobra.Command, args []string) {
+		if exportVerify != "" {
+			if len(args) > 0 {
+				utils.CliUsageErrorEnvelopeWithExit(opExport, "--verify takes a bundle file, not a SESSION_ID.")
+			}
+			runVerifyExport(exportVerify)
+			return
+		}
+		if len(args) != 1 {
+			utils.CliUsageErrorEnvelopeWithExit(opExport, "SESSION_ID argument is required (or pass --verify BUNDLE).")
+		}
+		switch exportFormat {
+		case exportFormatHTML, exportFormatJSON, exportFormatMD:
+		default:
+			utils.CliUsageErrorEnvelopeWithExit(opExport, "Invalid --format %q: must be html, json, or md.", exportFormat)
+		}
+
+		ac, err := client.NewAlpaconAPIClient()
+		if err != nil {
+			utils.CliErrorEnvelopeWithExit(opExport, err, "Connection to Alpacon API failed: %s. Consider re-logging.", err)
+		}
+
+		var (
+			rawSession  []byte
+			items       []wsapi.TimelineItem
+			sessionErr  error
+			timelineErr error
+			wg          sync.WaitGroup
+		)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			rawSession, sessionErr = wsapi.GetWorkSessionRaw(ac, args[0])
+		}()
+		go func() {
+			defer wg.Done()
+			items, timelineErr = wsapi.GetWorkSessionTimeline(ac, args[0], true)
+		}()
+		wg.Wait()
+		if sessionErr != nil {
+			utils.CliErrorEnvelopeWithExit(opExport, sessionErr, "Failed to retrieve work session: %s.", sessionErr)
+		}
+		if timelineErr != nil {
+			utils.CliErrorEnvelopeWithExit(opExport, timelineErr, "Failed to retrieve work session timeline: %s.", timelineErr)
+		}
+
+		bundle, err := buildEvidenceBundle(rawSession, items, exportFormat, time.Now())
+		if err != nil {
+			utils.CliErrorEnvelopeWithExit(opExport, nil, "Failed to build the evidence bundle: %s.", err)
+		}
+
+		fileName := exportFile
+		if fileName == "" {
+			fileName = fmt.Sprintf("work-session-%s-evidence.zip", safeFileComponent(bundle.SessionID))
+		}
+		if _, err = utils.SaveStreamAtomic(fileName, bytes.NewReader(bundle.Data), 0o600); err != nil {
+			utils.CliErrorEnvelopeWithExit(opExport, nil, "Failed to write %s: %s.", fileName, err)
+		}
+
+		output := exportOutput{
+			File:           fileName,
+			Format:         exportFormat,
+			SessionID:      bundle.SessionID,
+			Files:          bundle.Files,
+			ManifestSHA256: bundle.ManifestSHA256,
+		}
+		if utils.OutputFormat == utils.OutputFormatJSON {
+			if err = utils.PrintJSONValue(os.Stdout, output); err != nil {
+				utils.CliErrorEnvelopeWithExit(opExport, err, "Failed to serialize the export result: %s.", err)
+			}
+			return
+		}
+		utils.CliSuccess("Evidence bundle for work session %s written to %s (%d files).", bundle.SessionID, fileName, len(bundle.Files))
+		fmt.Printf("SHA256 (%s) = %s\n", manifestFileName, bundle.ManifestSHA256)
+	},
+}
+
+type exportOutput struct {
+	File           string   `json:"file"`
+	Format         string   `json:"format"`
+	SessionID      string   `json:"session_id"`
+	Files          []string `json:"files"`
+	ManifestSHA256 string   `json:"manifest_sha256"`
+}
+
+type evidenceBundle struct {
+	SessionID      string
+	Data           []byte
+	Files          []string
+	ManifestSHA256 string
+}
+
+// buildEvidenceBundle assembles the zip in memory. Entries are stamped with
+// the session's request time; when the bundle was made is recorded in the
+// report, where the manifest covers it.
+func buildEvidenceBundle(rawSession []byte, items []wsapi.TimelineItem, format string, now time.Time) (*evidenceBundle, error) {
+	var session wsapi.WorkSession
+	if err := json.Unmarshal(rawSession, &session); err != nil {
+		return nil, fmt.Errorf("invalid work session response: %w", err)
+	}
+
+	files := map[string][]byte{}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, rawSession, "", "  "); err != nil {
+		return nil, err
+	}
+	indented.WriteByte('\n')
+	files["session.json"] = indented.Bytes()
+
+	timeline, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	files["timeline.json"] = append(timeline, '\n')
+
+	recordingFiles := map[int]string{}
+	n := 0
+	for i, item := range items {
+		if item.Type != "websh_record" {
+			continue
+		}
+		n++
+		name := fmt.Sprintf("recordings/%03d-%s.txt", n, safeFileComponent(item.SessionID))
+		recordingFiles[i] = name
+		files[name] = []byte(item.MaskedRecord)
+	}
+
+	var report bytes.Buffer
+	if err = writeEvidenceReport(&report, format, buildEvidenceReport(&session, items, recordingFiles, now)); err != nil {
+		return nil, err
+	}
+	files["report."+format] = report.Bytes()
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var manifest strings.Builder
+	for _, name := range names {
+		sum := sha256.Sum256(files[name])
+		// sha256sum's text format: two spaces, so 'sha256sum -c' reads it as-is.
+		fmt.Fprintf(&manifest, "%s  %s\n", hex.EncodeToString(sum[:]), name)
+	}
+	files[manifestFileName] = []byte(manifest.String())
+	names = append(names, manifestFileName)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: session.AddedAt.UTC(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err = w.Write(files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err = zw.Close(); err != nil {
+		return nil, err
+	}
+
+	manifestSum := sha256.Sum256(files[manifestFileName])
+	return &evidenceBundle{
+		SessionID:      session.ID,
+		Data:           buf.Bytes(),
+		Files:          names,
+		ManifestSHA256: hex.EncodeToString(manifestSum[:]),
+	}, nil
+}
+
+// safeFileComponent keeps a server-supplied ID from steering a bundle path.
+func safeFileComponent(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+type verifyResult struct {
+	File           string   `json:"file"`
+	Valid          bool     `json:"valid"`
+	ManifestSHA256 string   `json:"manifest_sha256"`
+	Checked        int      `json:"checked"`
+	Problems       []string `json:"problems"`
+}
+
+func runVerifyExport(path string) {
+	result, err := verifyEvidenceBundle(path)
+	if err != nil {
+		utils.CliErrorEnvelopeWithExit(opExport, nil, "Failed to read %s: %s.", path, err)
+	}
+
+	if utils.OutputFormat == utils.OutputFormatJSON {
+		if err = utils.PrintJSONValue(os.Stdout, result); err != nil {
+			utils.CliErrorEnvelopeWithExit(opExport, err, "Failed to serialize the verification result: %s.", err)
+		}
+	} else {
+		fmt.Printf("SHA256 (%s) = %s\n", manifestFileName, result.ManifestSHA256)
+		for _, p := range result.Problems {
+			fmt.Printf("FAILED: %s\n", utils.SanitizeTerminalText(p))
+		}
+		if result.Valid {
+			utils.CliSuccess("All %d files match the manifest. Compare the manifest hash above with the one recorded at export.", result.Checked)
+		}
+	}
+	if !result.Valid {
+		if utils.OutputFormat != utils.OutputFormatJSON {
+			utils.CliErrorWithExit("%s does not match its manifest (%d problem(s)).", path, len(result.Problems))
+		}
+		os.Exit(utils.ExitCodeGeneralError)
+	}
+}
+
+// verifyEvidenceBundle checks every file against the manifest, and that the
+// bundle holds nothing the manifest does not list: a file slipped in after
+// export would otherwise ride along unverified.
+func verifyEvidenceBundle(path string) (*verifyResult, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = zr.Close() }()
+
+	contents := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		contents[f.Name] = data
+	}
+
+	manifest, ok := contents[manifestFileName]
+	if !ok {
+		return nil, fmt.Errorf("no %s in the bundle", manifestFileName)
+	}
+	sum := sha256.Sum256(manifest)
+	result := &verifyResult{File: path, ManifestSHA256: hex.EncodeToString(sum[:]), Problems: []string{}}
+
+	listed := map[string]bool{manifestFileName: true}
+	scanner := bufio.NewScanner(bytes.NewReader(manifest))
+	for scanner.Scan() {
+		line := scanner.Text()
+		want, name, ok := strings.Cut(line, "  ")
+		if !ok {
+			result.Problems = append(result.Problems, fmt.Sprintf("malformed manifest line %q", line))
+			continue
+		}
+		listed[name] = true
+		data, present := contents[name]
+		if !present {
+			result.Problems = append(result.Problems, fmt.Sprintf("%s: missing from the bundle", name))
+			continue
+		}
+		result.Checked++
+		got := sha256.Sum256(data)
+		if hex.EncodeToString(got[:]) != want {
+			result.Problems = append(result.Problems, fmt.Sprintf("%s: checksum does not match", name))
+		}
+	}
+	names := make([]string, 0, len(contents))
+	for name := range contents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !listed[name] {
+			result.Problems = append(result.Problems, fmt.Sprintf("%s: not listed in the manifest", name))
+		}
+	}
+	result.Valid = len(result.Problems) == 0
+	return result, nil
+}
+
+func init() {
+	workSessionExportCmd.Flags().StringVar(&exportFormat, "format", exportFormatHTML, "Report format: html, json, or md")
+	workSessionExportCmd.Flags().StringVarP(&exportFile, "file", "f", "", "Bundle file to write (default: work-session-<id>-evidence.zip)")
+	workSessionExportCmd.Flags().StringVar(&exportVerify, "verify", "", "Check an existing bundle against its manifest instead of exporting")
+}
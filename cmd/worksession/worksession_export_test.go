@@ -0,0 +1,137 @@
+package worksession
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	wsapi "github.com/alpacax/alpacon-cli/api/worksession"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const exportSessionJSON = `{
+  "id": "ses-1",
+  "description": "restart nginx | clear 502s",
+  "status": "completed",
+  "scopes": ["command", "websh"],
+  "servers": [{"id": "srv-1", "name": "web-01"}],
+  "expires_at": "2026-01-01T12:00:00Z",
+  "added_at": "2026-01-01T10:00:00Z",
+  "adjustments": {"scopes": {"old": ["command", "websh", "sudo"], "new": ["command", "websh"]}},
+  "recommendations": [{"id": "r1", "text": "Check <upstream> health first", "severity": "warning"}]
+}`
+
+func exportTimeline() []wsapi.TimelineItem {
+	ts := "2026-01-01T10:05:00Z"
+	server := "srv-1"
+	ok := true
+	return []wsapi.TimelineItem{
+		{Type: "command", Timestamp: &ts, ServerID: &server, Username: "alice", Line: "systemctl restart nginx && echo " + strings.Repeat("x", 80), Success: &ok},
+		{Type: "file_download", Timestamp: &ts, ServerID: &server, Username: "alice", Name: "/var/log/nginx/error.log", Size: 2048},
+		{Type: "websh_session", Timestamp: &ts, ID: "wsh-1", ServerID: &server, Username: "alice"},
+		{Type: "websh_record", Timestamp: &ts, SessionID: "../wsh-1", ServerID: &server, MaskedRecord: "$ uptime\r\n 10:05 up 3 days\r\n"},
+	}
+}
+
+func TestBuildEvidenceBundle(t *testing.T) {
+	bundle, err := buildEvidenceBundle([]byte(exportSessionJSON), exportTimeline(), exportFormatMD, time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	assert.Equal(t, "ses-1", bundle.SessionID)
+	assert.Equal(t, []string{
+		"recordings/001-___wsh-1.txt",
+		"report.md",
+		"session.json",
+		"timeline.json",
+		manifestFileName,
+	}, bundle.Files, "the recording name cannot escape the recordings directory")
+
+	files := readZip(t, bundle.Data)
+	report := files["report.md"]
+	assert.Contains(t, report, `restart nginx \| clear 502s`, "pipes are escaped in table cells")
+	assert.Contains(t, report, strings.Repeat("x", 80), "commands are not truncated")
+	assert.Contains(t, report, "Scopes: command, websh, sudo → command, websh")
+	assert.Contains(t, report, "**WARNING** Check <upstream> health first")
+	assert.Contains(t, report, "(recordings/001-___wsh-1.txt)")
+	assert.Equal(t, "$ uptime\r\n 10:05 up 3 days\r\n", files["recordings/001-___wsh-1.txt"], "recordings are kept byte for byte")
+
+	for _, line := range strings.Split(strings.TrimSpace(files[manifestFileName]), "\n") {
+		_, name, ok := strings.Cut(line, "  ")
+		require.True(t, ok, line)
+		assert.Contains(t, files, name)
+	}
+}
+
+func TestBuildEvidenceBundle_HTMLEscapes(t *testing.T) {
+	bundle, err := buildEvidenceBundle([]byte(exportSessionJSON), exportTimeline(), exportFormatHTML, time.Now())
+	require.NoError(t, err)
+
+	report := readZip(t, bundle.Data)["report.html"]
+	assert.Contains(t, report, "Check &lt;upstream&gt; health first")
+	assert.NotContains(t, report, "<upstream>")
+	assert.Contains(t, report, `<a href="recordings/001-___wsh-1.txt">`)
+}
+
+func TestVerifyEvidenceBundle(t *testing.T) {
+	bundle, err := buildEvidenceBundle([]byte(exportSessionJSON), exportTimeline(), exportFormatJSON, time.Now())
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.zip")
+	require.NoError(t, os.WriteFile(good, bundle.Data, 0o600))
+
+	result, err := verifyEvidenceBundle(good)
+	require.NoError(t, err)
+	assert.True(t, result.Valid, result.Problems)
+	assert.Equal(t, bundle.ManifestSHA256, result.ManifestSHA256)
+	assert.Equal(t, 4, result.Checked)
+
+	// Rewrite the bundle with one file changed and one file added.
+	files := readZip(t, bundle.Data)
+	files["timeline.json"] = "[]\n"
+	files["extra.txt"] = "slipped in"
+	tampered := filepath.Join(dir, "tampered.zip")
+	require.NoError(t, os.WriteFile(tampered, writeZip(t, files), 0o600))
+
+	result, err = verifyEvidenceBundle(tampered)
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Contains(t, result.Problems, "timeline.json: checksum does not match")
+	assert.Contains(t, result.Problems, "extra.txt: not listed in the manifest")
+}
+
+func readZip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		_ = rc.Close()
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+func writeZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
@@ -3,6 +3,7 @@ package worksession
 import (
 	wsapi "github.com/alpacax/alpacon-cli/api/worksession"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -13,9 +14,10 @@ var (
 )
 
 var workSessionExtendCmd = &cobra.Command{
-	Use:   "extend SESSION_ID",
-	Short: "Extend the expiry of an approved or active work session",
-	Args:  cobra.ExactArgs(1),
+	Use:               "extend SESSION_ID",
+	Short:             "Extend the expiry of an approved or active work session",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.WorkSessions),
 	Example: `  alpacon work-session extend ses-abc123 --expires-in 2h
   alpacon work-session extend ses-abc123 --expires-at 2026-05-09T10:00:00Z`,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -5,24 +5,41 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/alpacax/alpacon-cli/api/websh"
 	wsapi "github.com/alpacax/alpacon-cli/api/worksession"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
+	"github.com/alpacax/alpacon-cli/pkg/asciicast"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
 
-var recordingIndex int
+var (
+	recordingIndex  int
+	recordingFormat string
+)
 
 var workSessionRecordingCmd = &cobra.Command{
 	Use:     "recording SESSION_ID",
 	Aliases: []string{"rec"},
 	Short:   "Show a Websh session recording",
-	Args:    cobra.ExactArgs(1),
+	Long: `Show a Websh session recording from a work session's timeline.
+
+Use --format asciicast to write the recording as an asciicast v2 file, with its
+timing, for 'alpacon websh replay --file' or any asciinema player.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.WorkSessions),
 	Example: `  alpacon work-session recording ses-abc123
   alpacon work-session recording ses-abc123 --index 2
-  alpacon work-session rec ses-abc123`,
+  alpacon work-session rec ses-abc123
+  alpacon work-session recording ses-abc123 --format asciicast > session.cast`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if recordingFormat != "" && recordingFormat != formatAsciicast {
+			utils.CliUsageErrorEnvelopeWithExit(opRecording, "Invalid --format %q: must be asciicast.", recordingFormat)
+		}
+
 		ac, err := client.NewAlpaconAPIClient()
 		if err != nil {
 			utils.CliErrorEnvelopeWithExit(opRecording, err, "Connection to Alpacon API failed: %s. Consider re-logging.", err)
@@ -52,6 +69,13 @@ var workSessionRecordingCmd = &cobra.Command{
 			utils.CliUsageErrorEnvelopeWithExit(opRecording, "Recording index %d out of range (session has %d recording(s)).", recordingIndex, len(recordings))
 		}
 
+		if recordingFormat == formatAsciicast {
+			if err = recordingCast(ac, target).Encode(os.Stdout); err != nil {
+				utils.CliErrorEnvelopeWithExit(opRecording, nil, "Failed to write the cast: %s.", err)
+			}
+			return
+		}
+
 		printRecordingHeader(os.Stdout, target, idx, len(recordings))
 		printRecordingContent(os.Stdout, target.MaskedRecord)
 	},
@@ -59,6 +83,38 @@ var workSessionRecordingCmd = &cobra.Command{
 
 func init() {
 	workSessionRecordingCmd.Flags().IntVar(&recordingIndex, "index", 1, "Recording index to display (1-based)")
+	workSessionRecordingCmd.Flags().StringVar(&recordingFormat, "format", "", "Write the recording in another format: asciicast")
+}
+
+const formatAsciicast = "asciicast"
+
+// recordingCast prefers the websh session's own records, which keep the
+// timing of each write. The timeline holds only the whole masked record, so
+// when those cannot be fetched the cast falls back to it as a single event.
+func recordingCast(ac *client.AlpaconClient, target *wsapi.TimelineItem) *asciicast.Cast {
+	if target.SessionID != "" {
+		cast, err := websh.GetSessionCast(ac, target.SessionID, websh.CastRecordLimit)
+		if err == nil && len(cast.Events) > 0 {
+			return cast
+		}
+		if err != nil {
+			utils.CliWarning("Websh records for session %s are unavailable (%s); the cast will have no timing.", target.SessionID, err)
+		}
+	}
+	return timelineRecordCast(target)
+}
+
+func timelineRecordCast(target *wsapi.TimelineItem) *asciicast.Cast {
+	cast := &asciicast.Cast{Header: asciicast.Header{Width: asciicast.DefaultWidth, Height: asciicast.DefaultHeight}}
+	if target.Timestamp != nil {
+		if at, err := time.Parse(time.RFC3339Nano, *target.Timestamp); err == nil {
+			cast.Header.Timestamp = at.Unix()
+		}
+	}
+	if target.MaskedRecord != "" {
+		cast.Events = []asciicast.Event{{Type: asciicast.EventOutput, Data: target.MaskedRecord}}
+	}
+	return cast
 }
 
 func findRecording(recordings []wsapi.TimelineItem, index int) (*wsapi.TimelineItem, int) {
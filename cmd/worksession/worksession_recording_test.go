@@ -7,6 +7,7 @@ import (
 
 	wsapi "github.com/alpacax/alpacon-cli/api/worksession"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func makeRecording(id, sessionID string) wsapi.TimelineItem {
@@ -198,3 +199,12 @@ func TestPrintRecordingContent_KeepsControlBytes(t *testing.T) {
 	printRecordingContent(&buf, "a\rb")
 	assert.Equal(t, "a\rb\n", buf.String())
 }
+
+func TestTimelineRecordCast_SingleEvent(t *testing.T) {
+	ts := "2026-01-01T10:00:00Z"
+	cast := timelineRecordCast(&wsapi.TimelineItem{Timestamp: &ts, MaskedRecord: "$ uptime\r\n"})
+
+	assert.EqualValues(t, 1767261600, cast.Header.Timestamp)
+	require.Len(t, cast.Events, 1)
+	assert.Equal(t, "$ uptime\r\n", cast.Events[0].Data)
+}
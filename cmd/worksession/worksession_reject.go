@@ -1,6 +1,7 @@
 package worksession
 
 import (
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -13,7 +14,8 @@ var workSessionRejectCmd = &cobra.Command{
 The CLI is an execution and request surface only; a human approves or rejects
 out of band in the web console or Slack. The server rejects approve/reject from
 the CLI credential channel. Use 'alpacon work-session ls' to track status.`,
-	Args: cobra.ArbitraryArgs,
+	Args:              cobra.ArbitraryArgs,
+	ValidArgsFunction: complete.FirstArg(complete.WorkSessions),
 	Example: `  # Reject in the Alpacon console (web), then track status here:
   alpacon work-session ls --status rejected`,
 	Run: func(cmd *cobra.Command, args []string) {
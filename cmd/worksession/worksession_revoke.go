@@ -3,16 +3,18 @@ package worksession
 import (
 	wsapi "github.com/alpacax/alpacon-cli/api/worksession"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
 
 var workSessionRevokeCmd = &cobra.Command{
-	Use:     "revoke SESSION_ID",
-	Short:   "Force-terminate an active or approved work session",
-	Long:    "Force-terminate an active or approved work session. Superuser only.",
-	Args:    cobra.ExactArgs(1),
-	Example: `  alpacon work-session revoke ses-abc123`,
+	Use:               "revoke SESSION_ID",
+	Short:             "Force-terminate an active or approved work session",
+	Long:              "Force-terminate an active or approved work session. Superuser only.",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.WorkSessions),
+	Example:           `  alpacon work-session revoke ses-abc123`,
 	Run: func(cmd *cobra.Command, args []string) {
 		ac, err := client.NewAlpaconAPIClient()
 		if err != nil {
@@ -0,0 +1,385 @@
+package worksession
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	wsapi "github.com/alpacax/alpacon-cli/api/worksession"
+	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+// ShellEnvVar is set to the session ID inside 'work-session shell', so prompt
+// themes can show it and a nested 'work-session shell' can refuse to start.
+const ShellEnvVar = "ALPACON_WORK_SESSION_SHELL"
+
+const (
+	shellPromptEnvVar  = "ALPACON_SHELL_PROMPT"
+	shellZdotdirEnvVar = "ALPACON_SHELL_ZDOTDIR"
+)
+
+// The rc files source the user's own and then prefix the prompt, so aliases,
+// completion, and themes carry over. Values arrive through the environment
+// rather than being spliced into the script, which keeps quoting out of it.
+const (
+	bashShellRC = `[ -f "$HOME/.bashrc" ] && . "$HOME/.bashrc"
+PS1="${ALPACON_SHELL_PROMPT}${PS1}"
+`
+	zshShellEnv = `_alpacon_zdotdir="$ZDOTDIR"
+ZDOTDIR="$ALPACON_SHELL_ZDOTDIR"
+[ -f "$ZDOTDIR/.zshenv" ] && . "$ZDOTDIR/.zshenv"
+ZDOTDIR="$_alpacon_zdotdir"
+unset _alpacon_zdotdir
+`
+	zshShellRC = `ZDOTDIR="$ALPACON_SHELL_ZDOTDIR"
+[ -f "$ZDOTDIR/.zshrc" ] && . "$ZDOTDIR/.zshrc"
+PROMPT="${ALPACON_SHELL_PROMPT}${PROMPT}"
+`
+)
+
+var (
+	shellCompleteOnExit bool
+	shellWarnBefore     string
+	shellAutoExtend     string
+)
+
+var workSessionShellCmd = &cobra.Command{
+	Use:   "shell SESSION_ID",
+	Short: "Start a sub-shell bound to one work session",
+	Long: `Start your $SHELL with ALPACON_WORK_SESSION pinned to SESSION_ID, so every
+exec/websh/cp/tunnel command run inside it attaches to that session. The
+workspace's active session ('work-session use') is left alone, so two terminals
+can work on two tasks without switching each other's session.
+
+The prompt is prefixed with (ws:ID) for bash and zsh; other shells get PS1 and
+ALPACON_WORK_SESSION_SHELL to build their own marker from.
+
+A warning is printed --warn-before the session expires (default 10m), with the
+command to extend it; a shell started inside that window warns at once. The
+shell owns the terminal, so the CLI cannot ask in-line; pass --auto-extend
+DURATION to extend automatically instead.
+
+When the shell exits and the session is still active, you are asked whether to
+complete it; --complete-on-exit completes it without asking. The shell's exit
+status is passed through.`,
+	Example: `  alpacon work-session shell ses-abc123
+  alpacon work-session shell ses-abc123 --complete-on-exit
+  alpacon work-session shell ses-abc123 --warn-before 15m --auto-extend 1h`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.WorkSessions),
+	Run: func(cmd *cobra.Command, args []string) {
+		if current := os.Getenv(ShellEnvVar); current != "" {
+			utils.CliUsageErrorEnvelopeWithExit(opShell, "Already inside a work-session shell for %s. Exit it before starting another.", current)
+		}
+		warnBefore, err := utils.ParsePositiveDuration("--warn-before", shellWarnBefore)
+		if err != nil {
+			utils.CliUsageErrorEnvelopeWithExit(opShell, "%s.", err)
+		}
+		var autoExtend time.Duration
+		if shellAutoExtend != "" {
+			if autoExtend, err = utils.ParsePositiveDuration("--auto-extend", shellAutoExtend); err != nil {
+				utils.CliUsageErrorEnvelopeWithExit(opShell, "%s.", err)
+			}
+			// An extension that lands inside the warning window would be
+			// extended again at once.
+			if autoExtend <= warnBefore {
+				utils.CliUsageErrorEnvelopeWithExit(opShell, "--auto-extend (%s) must be longer than --warn-before (%s).", autoExtend, warnBefore)
+			}
+		}
+
+		ac, err := client.NewAlpaconAPIClient()
+		if err != nil {
+			utils.CliErrorEnvelopeWithExit(opShell, err, "Connection to Alpacon API failed: %s. Consider re-logging.", err)
+		}
+
+		session, err := wsapi.GetWorkSession(ac, args[0])
+		if err != nil {
+			utils.CliErrorEnvelopeWithExit(opShell, err, "Failed to retrieve work session: %s.", err)
+		}
+		// Same checks as 'use': gated commands refuse anything but an active,
+		// human session, so a shell bound to another would fail on first use.
+		if session.Status != activeWorkSessionStatus {
+			utils.CliErrorEnvelopeWithExit(opShell, nil, "Work session %s is in '%s' state and cannot be used.", session.ID, session.Status)
+		}
+		if session.RequesterType == "agent" {
+			utils.CliErrorEnvelopeWithExit(opShell, nil, "Work session %s is an agent session; agent sessions run non-interactively via their assigned token.", session.ID)
+		}
+
+		rcDir, err := os.MkdirTemp("", "alpacon-shell-")
+		if err != nil {
+			utils.CliErrorWithExit("Failed to prepare the shell: %s.", err)
+		}
+		defer func() { _ = os.RemoveAll(rcDir) }()
+
+		shell := userShell()
+		shellArgs, env, err := shellLaunch(shell, session.ID, rcDir, os.Environ())
+		if err != nil {
+			utils.CliErrorWithExit("Failed to prepare the shell: %s.", err)
+		}
+
+		utils.CliInfo("Starting %s bound to work session %s%s. Exit the shell to leave.",
+			filepath.Base(shell), session.ID, describeSessionSuffix(session))
+
+		done := make(chan struct{})
+		go watchExpiry(ac, session.ID, session.ExpiresAt, warnBefore, autoExtend, done)
+		exitCode := runShell(shell, shellArgs, env)
+		close(done)
+
+		finishShellSession(ac, session.ID, shellCompleteOnExit)
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	},
+}
+
+func describeSessionSuffix(session *wsapi.WorkSession) string {
+	if session.ExpiresAt.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf(" (expires %s)", session.ExpiresAt.Local().Format(time.Kitchen))
+}
+
+func userShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	if runtime.GOOS == "windows" {
+		if comspec := os.Getenv("COMSPEC"); comspec != "" {
+			return comspec
+		}
+		return "cmd.exe"
+	}
+	return "/bin/sh"
+}
+
+// shellPromptMarker is the prefix the shell's prompt gets. A short ID keeps
+// the prompt readable; it only has to tell two terminals apart.
+func shellPromptMarker(sessionID string) string {
+	short := sessionID
+	if len(short) > 8 {
+		short = short[:8]
+	}
+	return fmt.Sprintf("(ws:%s) ", short)
+}
+
+// shellLaunch returns the arguments and environment to start shell with. The
+// session is pinned through ALPACON_WORK_SESSION, which every gated command
+// already prefers over the workspace's active session.
+func shellLaunch(shell, sessionID, rcDir string, environ []string) ([]string, []string, error) {
+	marker := shellPromptMarker(sessionID)
+	env := append(withoutEnv(environ, WorkSessionEnvVar, ShellEnvVar, shellPromptEnvVar, shellZdotdirEnvVar),
+		WorkSessionEnvVar+"="+sessionID,
+		ShellEnvVar+"="+sessionID,
+		shellPromptEnvVar+"="+marker,
+	)
+
+	switch filepath.Base(shell) {
+	case "bash":
+		rc := filepath.Join(rcDir, "bashrc")
+		if err := os.WriteFile(rc, []byte(bashShellRC), 0o600); err != nil {
+			return nil, nil, err
+		}
+		return []string{"--rcfile", rc, "-i"}, env, nil
+	case "zsh":
+		// zsh has no --rcfile; pointing ZDOTDIR at our directory is the usual
+		// way in, and our files chain back to the user's.
+		zdotdir := envValue(environ, "ZDOTDIR")
+		if zdotdir == "" {
+			zdotdir, _ = os.UserHomeDir()
+		}
+		if err := os.WriteFile(filepath.Join(rcDir, ".zshenv"), []byte(zshShellEnv), 0o600); err != nil {
+			return nil, nil, err
+		}
+		if err := os.WriteFile(filepath.Join(rcDir, ".zshrc"), []byte(zshShellRC), 0o600); err != nil {
+			return nil, nil, err
+		}
+		env = append(withoutEnv(env, "ZDOTDIR"), "ZDOTDIR="+rcDir, shellZdotdirEnvVar+"="+zdotdir)
+		return []string{"-i"}, env, nil
+	default:
+		// sh and friends read PS1 from the environment; shells that don't can
+		// build a marker from ALPACON_WORK_SESSION_SHELL.
+		ps1 := envValue(environ, "PS1")
+		if ps1 == "" {
+			ps1 = "$ "
+		}
+		return nil, append(withoutEnv(env, "PS1"), "PS1="+marker+ps1), nil
+	}
+}
+
+func envValue(environ []string, name string) string {
+	for _, kv := range environ {
+		if value, ok := strings.CutPrefix(kv, name+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+func withoutEnv(environ []string, names ...string) []string {
+	out := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		keep := true
+		for _, name := range names {
+			if strings.HasPrefix(kv, name+"=") {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			out = append(out, kv)
+		}
+	}
+	return out
+}
+
+// runShell runs the shell in the foreground and returns its exit status.
+// Ctrl+C belongs to the shell: the terminal delivers it to the whole
+// foreground group, so this process swallows it rather than dying under the
+// shell. SIGTERM is passed on so a closing terminal ends the shell too.
+func runShell(shell string, args, env []string) int {
+	c := exec.Command(shell, args...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	c.Env = env
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	if err := c.Start(); err != nil {
+		utils.CliErrorWithExit("Failed to start %s: %s.", shell, err)
+	}
+	go func() {
+		for sig := range sigChan {
+			if sig == syscall.SIGTERM {
+				_ = c.Process.Signal(sig)
+			}
+		}
+	}()
+
+	err := c.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	if err != nil {
+		utils.CliWarning("%s exited abnormally: %s", filepath.Base(shell), err)
+		return utils.ExitCodeGeneralError
+	}
+	return 0
+}
+
+// nextExpiryCheck is how long to sleep before the next look at the session:
+// until the warning point, at once if that has passed without a warning, or
+// until expiry once warned.
+func nextExpiryCheck(now, expiresAt time.Time, warnBefore time.Duration, warned bool) time.Duration {
+	if warnAt := expiresAt.Add(-warnBefore); now.Before(warnAt) {
+		return warnAt.Sub(now)
+	}
+	if warned && now.Before(expiresAt) {
+		return expiresAt.Sub(now)
+	}
+	return 0
+}
+
+// watchExpiry warns (or extends) ahead of the session's expiry until done is
+// closed. The session is re-read before each warning, so an extension made
+// from inside the shell moves the warning instead of triggering a stale one.
+func watchExpiry(ac *client.AlpaconClient, sessionID string, expiresAt time.Time, warnBefore, autoExtend time.Duration, done <-chan struct{}) {
+	if expiresAt.IsZero() {
+		return
+	}
+	warned := false
+	for {
+		select {
+		case <-done:
+			return
+		case <-time.After(nextExpiryCheck(time.Now(), expiresAt, warnBefore, warned)):
+		}
+
+		session, err := wsapi.GetWorkSession(ac, sessionID)
+		if err != nil {
+			// A transient failure should not end the watch; try again shortly.
+			select {
+			case <-done:
+				return
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+		if session.Status != activeWorkSessionStatus {
+			shellNotice(utils.CliWarning, "Work session %s is now %s; gated commands in this shell will be refused.", sessionID, session.Status)
+			return
+		}
+		if session.ExpiresAt.After(expiresAt) {
+			expiresAt, warned = session.ExpiresAt, false
+			continue
+		}
+
+		remaining := time.Until(expiresAt).Round(time.Second)
+		if remaining <= 0 {
+			shellNotice(utils.CliWarning, "Work session %s has expired; gated commands in this shell will be refused.", sessionID)
+			return
+		}
+		if autoExtend > 0 {
+			newExpiry := time.Now().Add(autoExtend).UTC().Truncate(time.Second)
+			if err = wsapi.ExtendWorkSession(ac, sessionID, wsapi.WorkSessionExtendRequest{ExpiresAt: newExpiry.Format(time.RFC3339)}); err != nil {
+				shellNotice(utils.CliWarning, "Failed to extend work session %s: %s. It expires in %s.", sessionID, err, remaining)
+			} else {
+				shellNotice(utils.CliInfo, "Work session %s extended until %s.", sessionID, newExpiry.Local().Format(time.Kitchen))
+				expiresAt, warned = newExpiry, false
+				continue
+			}
+		}
+		if !warned {
+			shellNotice(utils.CliWarning, "Work session %s expires in %s. To extend it, run: alpacon work-session extend %s --expires-in 1h", sessionID, remaining, sessionID)
+			warned = true
+		}
+	}
+}
+
+// shellNotice prints a message over the running shell. The shell's prompt
+// leaves the cursor mid-line, so the message starts on a line of its own.
+func shellNotice(print func(string, ...any), msg string, args ...any) {
+	_, _ = fmt.Fprint(os.Stderr, "\r\n")
+	print(msg, args...)
+}
+
+// finishShellSession offers to complete the session once its shell is gone,
+// since leaving the shell usually means the task is done.
+func finishShellSession(ac *client.AlpaconClient, sessionID string, complete bool) {
+	session, err := wsapi.GetWorkSession(ac, sessionID)
+	if err != nil || session.Status != activeWorkSessionStatus {
+		return
+	}
+	if !complete {
+		if !utils.IsInteractiveShell() {
+			return
+		}
+		complete = utils.PromptForBool(fmt.Sprintf("Complete work session %s now?", sessionID))
+	}
+	if !complete {
+		utils.CliInfo("Work session %s is still active. Complete it later with 'alpacon work-session complete %s'.", sessionID, sessionID)
+		return
+	}
+	if err = wsapi.CompleteWorkSession(ac, sessionID); err != nil {
+		utils.CliWarning("Failed to complete work session %s: %s. Run 'alpacon work-session complete %s' to retry.", sessionID, err, sessionID)
+		return
+	}
+	utils.CliSuccess("Work session %s completed.", sessionID)
+}
+
+func init() {
+	workSessionShellCmd.Flags().BoolVar(&shellCompleteOnExit, "complete-on-exit", false, "Complete the session when the shell exits, without asking")
+	workSessionShellCmd.Flags().StringVar(&shellWarnBefore, "warn-before", "10m", "Warn this long before the session expires")
+	workSessionShellCmd.Flags().StringVar(&shellAutoExtend, "auto-extend", "", "At the warning, extend the session to now + this duration (e.g. 1h) instead of only warning")
+}
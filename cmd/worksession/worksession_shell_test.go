@@ -0,0 +1,60 @@
+package worksession
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShellLaunch_PinsSession(t *testing.T) {
+	environ := []string{"HOME=/home/u", WorkSessionEnvVar + "=ses-old", "PS1=> "}
+
+	args, env, err := shellLaunch("/bin/sh", "0123456789abcdef", t.TempDir(), environ)
+	require.NoError(t, err)
+
+	assert.Nil(t, args)
+	assert.Equal(t, "0123456789abcdef", envValue(env, WorkSessionEnvVar), "the pinned session replaces the inherited one")
+	assert.Equal(t, "0123456789abcdef", envValue(env, ShellEnvVar))
+	assert.Equal(t, "(ws:01234567) > ", envValue(env, "PS1"))
+	assert.Len(t, withoutEnv(env, WorkSessionEnvVar), len(env)-1, "the variable appears once")
+}
+
+func TestShellLaunch_Bash(t *testing.T) {
+	rcDir := t.TempDir()
+
+	args, env, err := shellLaunch("/usr/bin/bash", "ses-1", rcDir, []string{"HOME=/home/u"})
+	require.NoError(t, err)
+
+	rc := filepath.Join(rcDir, "bashrc")
+	assert.Equal(t, []string{"--rcfile", rc, "-i"}, args)
+	data, err := os.ReadFile(rc)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `. "$HOME/.bashrc"`, "the user's rc file still runs")
+	assert.Equal(t, "(ws:ses-1) ", envValue(env, shellPromptEnvVar))
+}
+
+func TestShellLaunch_Zsh(t *testing.T) {
+	rcDir := t.TempDir()
+
+	_, env, err := shellLaunch("/bin/zsh", "ses-1", rcDir, []string{"HOME=/home/u", "ZDOTDIR=/home/u/.config/zsh"})
+	require.NoError(t, err)
+
+	assert.Equal(t, rcDir, envValue(env, "ZDOTDIR"))
+	assert.Equal(t, "/home/u/.config/zsh", envValue(env, shellZdotdirEnvVar), "the user's ZDOTDIR is chained to")
+	assert.FileExists(t, filepath.Join(rcDir, ".zshrc"))
+	assert.FileExists(t, filepath.Join(rcDir, ".zshenv"))
+}
+
+func TestNextExpiryCheck(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	warn := 10 * time.Minute
+
+	assert.Equal(t, 50*time.Minute, nextExpiryCheck(now, now.Add(time.Hour), warn, false), "sleep until the warning point")
+	assert.Equal(t, time.Duration(0), nextExpiryCheck(now, now.Add(5*time.Minute), warn, false), "inside the window unwarned, check at once")
+	assert.Equal(t, 5*time.Minute, nextExpiryCheck(now, now.Add(5*time.Minute), warn, true), "inside the window once warned, sleep until expiry")
+	assert.Equal(t, time.Duration(0), nextExpiryCheck(now, now.Add(-time.Minute), warn, true))
+}
@@ -0,0 +1,264 @@
+package worksession
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/alpacax/alpacon-cli/utils"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var showTemplateVars []string
+
+var workSessionTemplateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "List, show, and validate work-session templates",
+	Long: `Work-session templates describe a request you make repeatedly, such as a
+weekly database maintenance window, so 'alpacon work-session create --template NAME'
+can fill it in.
+
+Templates are read from .alpacon/worksession-templates.yaml in the current
+directory or the nearest parent that has one (commit it with your runbooks), and
+from ~/.alpacon/worksession-templates.yaml. A template in the repository file
+overrides one of the same name in the user file.
+
+  templates:
+    db-maintenance:
+      description: Weekly vacuum and index rebuild
+      purpose: "Routine maintenance on {{server}}: {{task}}"
+      scopes: [command, websh]
+      servers: ["{{server}}"]
+      expires_in: 2h
+      sudo:
+        - "systemctl restart postgresql"
+      sudo_reason: Restart after maintenance
+      variables:
+        server:
+          description: Database server name
+        task:
+          default: vacuum and reindex
+
+Fields mirror the create flags (requester_type and sudo_reason included). Any
+string may use {{name}} placeholders, which must be declared under variables; a
+scopes or servers entry may render to a comma-separated list.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cmd.Help(); err != nil {
+			return err
+		}
+		return errors.New("a subcommand is required. Use 'alpacon work-session template ls', 'alpacon work-session template show', or 'alpacon work-session template validate'. Run 'alpacon work-session template --help' for more information")
+	},
+}
+
+type templateAttributes struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Scopes      string `json:"scopes"`
+	Servers     string `json:"servers"`
+	ExpiresIn   string `json:"expires_in" table:"Expires In"`
+	Variables   string `json:"variables"`
+	Source      string `json:"source"`
+}
+
+var workSessionTemplateListCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "List the available work-session templates",
+	Example: `  alpacon work-session template ls
+  alpacon work-session template ls --output json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		templates, err := loadTemplates()
+		if err != nil {
+			utils.CliErrorEnvelopeWithExit(opTemplate, nil, "%s.", err)
+		}
+		if len(templates) == 0 && utils.OutputFormat != utils.OutputFormatJSON {
+			utils.CliInfo("No work-session templates found. Create .alpacon/%s in your repository or ~/.alpacon/%s.", TemplateFileName, TemplateFileName)
+			return
+		}
+
+		rows := make([]templateAttributes, 0, len(templates))
+		for _, t := range templates {
+			rows = append(rows, templateAttributes{
+				Name:        t.Name,
+				Description: t.Description,
+				Scopes:      strings.Join(t.Scopes, ", "),
+				Servers:     strings.Join(t.Servers, ", "),
+				ExpiresIn:   t.ExpiresIn,
+				Variables:   strings.Join(t.declaredVars(), ", "),
+				Source:      t.Source,
+			})
+		}
+		utils.PrintTable(rows)
+	},
+}
+
+type templateShowOutput struct {
+	Name     string              `json:"name"`
+	Source   string              `json:"source"`
+	Template workSessionTemplate `json:"template"`
+	Rendered *renderedTemplate   `json:"rendered,omitempty"`
+}
+
+var workSessionTemplateShowCmd = &cobra.Command{
+	Use:   "show NAME",
+	Short: "Show a work-session template",
+	Long: `Print a template as it is defined. With --var, also render it: every
+placeholder is filled and the request 'create --template' would send is shown,
+without creating anything.`,
+	Example: `  alpacon work-session template show db-maintenance
+  alpacon work-session template show db-maintenance --var server=db-3`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		tmpl, err := findTemplate(args[0])
+		if err != nil {
+			utils.CliErrorEnvelopeWithExit(opTemplate, nil, "%s.", err)
+		}
+
+		output := templateShowOutput{Name: tmpl.Name, Source: tmpl.Source, Template: tmpl.workSessionTemplate}
+		if cmd.Flags().Changed("var") {
+			vars, err := parseTemplateVars(showTemplateVars)
+			if err != nil {
+				utils.CliUsageErrorEnvelopeWithExit(opTemplate, "%s.", err)
+			}
+			output.Rendered, err = tmpl.render(vars)
+			if err != nil {
+				utils.CliUsageErrorEnvelopeWithExit(opTemplate, "%s.", err)
+			}
+		}
+
+		if utils.OutputFormat == utils.OutputFormatJSON {
+			if err = utils.PrintJSONValue(os.Stdout, output); err != nil {
+				utils.CliErrorWithExit("Failed to marshal the template: %s.", err)
+			}
+			return
+		}
+		if err = printTemplate(os.Stdout, output); err != nil {
+			utils.CliErrorWithExit("Failed to print the template: %s.", err)
+		}
+	},
+}
+
+// printTemplate writes the template back in file form, so it can be copied
+// into another file as a starting point.
+func printTemplate(w io.Writer, out templateShowOutput) error {
+	_, _ = fmt.Fprintf(w, "# %s\n", utils.SanitizeTerminalText(out.Source))
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(map[string]workSessionTemplate{out.Name: out.Template}); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil || out.Rendered == nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(w, "# rendered")
+	enc = yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(out.Rendered); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+type templateValidation struct {
+	File     string   `json:"file"`
+	Template string   `json:"template,omitempty"`
+	Problems []string `json:"problems"`
+}
+
+var workSessionTemplateValidateCmd = &cobra.Command{
+	Use:   "validate [FILE...]",
+	Short: "Check work-session template files for mistakes",
+	Long: `Check template files without creating anything: unknown fields, missing
+purpose, scopes, or servers, invalid scopes, expiry, or requester type, and
+placeholders that are used but not declared (or declared but never used).
+
+Without arguments, the files 'template ls' reads are checked. Fields that hold a
+placeholder are checked when the template is rendered.
+
+Exits 0 when every template is valid and 1 otherwise.`,
+	Example: `  alpacon work-session template validate
+  alpacon work-session template validate .alpacon/worksession-templates.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		files := args
+		if len(files) == 0 {
+			files = templateFilePaths()
+		}
+		if len(files) == 0 {
+			utils.CliErrorEnvelopeWithExit(opTemplate, nil, "No work-session template files found. Pass a file to validate.")
+		}
+
+		results := validateTemplateFiles(files)
+		valid := true
+		for _, r := range results {
+			valid = valid && len(r.Problems) == 0
+		}
+
+		if utils.OutputFormat == utils.OutputFormatJSON {
+			if err := utils.PrintJSONValue(os.Stdout, results); err != nil {
+				utils.CliErrorWithExit("Failed to marshal the validation result: %s.", err)
+			}
+		} else {
+			for _, r := range results {
+				label := r.File
+				if r.Template != "" {
+					label = fmt.Sprintf("%s: %s", r.File, r.Template)
+				}
+				label = utils.SanitizeTerminalText(label)
+				if len(r.Problems) == 0 {
+					_, _ = fmt.Fprintf(os.Stdout, "%s: ok\n", label)
+					continue
+				}
+				for _, p := range r.Problems {
+					_, _ = fmt.Fprintf(os.Stdout, "%s: %s\n", label, utils.SanitizeTerminalText(p))
+				}
+			}
+		}
+
+		if !valid {
+			os.Exit(utils.ExitCodeGeneralError)
+		}
+	},
+}
+
+// validateTemplateFiles returns one result per template, or one per file that
+// cannot be read or parsed, or holds no templates.
+func validateTemplateFiles(files []string) []templateValidation {
+	results := []templateValidation{}
+	for _, path := range files {
+		file, err := readTemplateFile(path)
+		if err != nil {
+			results = append(results, templateValidation{File: path, Problems: []string{err.Error()}})
+			continue
+		}
+		if len(file.Templates) == 0 {
+			results = append(results, templateValidation{File: path, Problems: []string{"no templates defined under 'templates'"}})
+			continue
+		}
+		names := make([]string, 0, len(file.Templates))
+		for name := range file.Templates {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			problems := file.Templates[name].validate()
+			if problems == nil {
+				problems = []string{}
+			}
+			results = append(results, templateValidation{File: path, Template: name, Problems: problems})
+		}
+	}
+	return results
+}
+
+func init() {
+	workSessionTemplateShowCmd.Flags().StringArrayVar(&showTemplateVars, "var", nil, "Render the template with this variable as NAME=VALUE (repeatable)")
+
+	workSessionTemplateCmd.AddCommand(workSessionTemplateListCmd)
+	workSessionTemplateCmd.AddCommand(workSessionTemplateShowCmd)
+	workSessionTemplateCmd.AddCommand(workSessionTemplateValidateCmd)
+}
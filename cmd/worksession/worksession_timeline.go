@@ -12,6 +12,7 @@ import (
 
 	wsapi "github.com/alpacax/alpacon-cli/api/worksession"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -26,10 +27,11 @@ type recordingJSON struct {
 }
 
 var workSessionTimelineCmd = &cobra.Command{
-	Use:     "timeline SESSION_ID",
-	Aliases: []string{"tl"},
-	Short:   "Show the activity timeline for a work session",
-	Args:    cobra.ExactArgs(1),
+	Use:               "timeline SESSION_ID",
+	Aliases:           []string{"tl"},
+	Short:             "Show the activity timeline for a work session",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.WorkSessions),
 	Example: `  alpacon work-session timeline ses-abc123
   alpacon work-session timeline ses-abc123 --no-records
   alpacon work-session tl ses-abc123 --output json`,
@@ -9,6 +9,7 @@ import (
 	"github.com/alpacax/alpacon-cli/api/server"
 	wsapi "github.com/alpacax/alpacon-cli/api/worksession"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
 )
@@ -45,7 +46,8 @@ they take effect.
 If SESSION_ID is omitted, the effective work session is resolved from the
 ALPACON_WORK_SESSION environment variable, then the workspace's active session
 (set via 'alpacon work-session use').`,
-	Args: cobra.MaximumNArgs(1),
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: complete.FirstArg(complete.WorkSessions),
 	Example: `  alpacon work-session update ses-abc123 --title "deploy v2" --description "rollout"
   alpacon work-session update ses-abc123 --server web-01,db-01
   alpacon work-session update ses-abc123 --scope command,websh
@@ -218,4 +220,5 @@ func init() {
 	workSessionUpdateCmd.Flags().StringVar(&updateExpiresAt, "expires-at", "", "New absolute expiry time (RFC3339; pending sessions only — use 'extend' for approved/active sessions)")
 	workSessionUpdateCmd.Flags().StringArrayVar(&updateSudo, "sudo", nil, "Sudo command patterns to add as MFA-bypass policies (repeatable; each value is a comma-separated pattern list forming one policy, wildcards allowed)")
 	workSessionUpdateCmd.Flags().StringVar(&updateSudoReason, "sudo-reason", "", "Justification applied to the sudo policies added via --sudo")
+	_ = workSessionUpdateCmd.RegisterFlagCompletionFunc("server", complete.CommaSeparated(complete.Servers))
 }
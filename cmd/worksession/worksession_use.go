@@ -5,6 +5,7 @@ import (
 
 	wsapi "github.com/alpacax/alpacon-cli/api/worksession"
 	"github.com/alpacax/alpacon-cli/client"
+	"github.com/alpacax/alpacon-cli/cmd/complete"
 	"github.com/alpacax/alpacon-cli/config"
 	"github.com/alpacax/alpacon-cli/utils"
 	"github.com/spf13/cobra"
@@ -30,6 +31,7 @@ Subsequent exec/websh/cp/tunnel commands attach to this session unless overridde
 Pass --unset (with no SESSION_ID) to clear the active work-session.`,
 	Example: `  alpacon work-session use ses-abc123
   alpacon work-session use --unset`,
+	ValidArgsFunction: complete.FirstArg(complete.WorkSessions),
 	Run: func(cmd *cobra.Command, args []string) {
 		if unsetActiveWorkSession {
 			if len(args) > 0 {
@@ -182,6 +182,23 @@ func SetActiveWorkSession(uuid string) error {
 	return saveConfig(&cfg)
 }
 
+// WorkSessionEnvVar is the environment variable consulted as a fallback when
+// no --work-session flag is given. 'work-session shell' sets it to pin a
+// session. Resolution order: flag > env var > config.
+const WorkSessionEnvVar = "ALPACON_WORK_SESSION"
+
+// ResolveWorkSession returns the effective work-session UUID using flag > env
+// var > config priority.
+func ResolveWorkSession(flagValue string) (string, error) {
+	if flagValue != "" {
+		return flagValue, nil
+	}
+	if env := os.Getenv(WorkSessionEnvVar); env != "" {
+		return env, nil
+	}
+	return GetActiveWorkSession()
+}
+
 // GetActiveWorkSession returns the active work-session UUID for the current workspace.
 // Returns "" (no error) when no session is set, the config is missing the map, or no config file exists.
 func GetActiveWorkSession() (string, error) {
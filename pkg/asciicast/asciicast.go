@@ -0,0 +1,201 @@
+// Package asciicast reads, writes, and plays terminal recordings in the
+// asciicast v2 format (https://docs.asciinema.org/manual/asciicast/v2/), so
+// websh sessions can be replayed here or in any asciinema player.
+package asciicast
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	Version = 2
+
+	EventOutput = "o"
+	EventInput  = "i"
+
+	// DefaultWidth and DefaultHeight stand in when the terminal size of a
+	// recording is unknown.
+	DefaultWidth  = 80
+	DefaultHeight = 24
+)
+
+// Header is the first line of a cast file.
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Event is one line after the header: seconds since the start of the
+// recording, the event type, and the data written or typed.
+type Event struct {
+	Time float64
+	Type string
+	Data string
+}
+
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{e.Time, e.Type, e.Data})
+}
+
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 3 {
+		return fmt.Errorf("event has %d fields, want 3", len(raw))
+	}
+	if err := json.Unmarshal(raw[0], &e.Time); err != nil {
+		return fmt.Errorf("event time: %w", err)
+	}
+	if err := json.Unmarshal(raw[1], &e.Type); err != nil {
+		return fmt.Errorf("event type: %w", err)
+	}
+	if err := json.Unmarshal(raw[2], &e.Data); err != nil {
+		return fmt.Errorf("event data: %w", err)
+	}
+	return nil
+}
+
+// Cast is a whole recording.
+type Cast struct {
+	Header Header
+	Events []Event
+}
+
+// Encode writes the cast in asciicast v2 form: the header, then one event per line.
+func (c *Cast) Encode(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	// The data is terminal output; HTML escaping would only bloat it.
+	enc.SetEscapeHTML(false)
+	header := c.Header
+	header.Version = Version
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+	for _, e := range c.Events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Read parses an asciicast v2 stream.
+func Read(r io.Reader) (*Cast, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("empty cast file")
+	}
+	var c Cast
+	if err := json.Unmarshal(scanner.Bytes(), &c.Header); err != nil {
+		return nil, fmt.Errorf("invalid cast header: %w", err)
+	}
+	if c.Header.Version != Version {
+		return nil, fmt.Errorf("unsupported asciicast version %d (want %d)", c.Header.Version, Version)
+	}
+
+	line := 1
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		c.Events = append(c.Events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// PlayOptions controls playback. Speed multiplies the pace (2 plays twice as
+// fast); IdleLimit caps any pause, so a recording where the operator walked
+// away does not stall the replay. Zero values mean 1x and no cap.
+type PlayOptions struct {
+	Speed     float64
+	IdleLimit time.Duration
+}
+
+// Play writes the cast's output events to w at their recorded pace. Input
+// events are skipped: the terminal already echoed what was typed. It returns
+// early, with no error, when stop is closed.
+func Play(w io.Writer, c *Cast, opts PlayOptions, stop <-chan struct{}) error {
+	speed := opts.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+	prev := 0.0
+	for _, e := range c.Events {
+		if e.Type != EventOutput {
+			continue
+		}
+		wait := time.Duration((e.Time - prev) / speed * float64(time.Second))
+		prev = e.Time
+		if opts.IdleLimit > 0 && wait > opts.IdleLimit {
+			wait = opts.IdleLimit
+		}
+		if wait > 0 {
+			select {
+			case <-stop:
+				return nil
+			case <-time.After(wait):
+			}
+		}
+		if _, err := io.WriteString(w, e.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Duration is the time of the last event.
+func (c *Cast) Duration() time.Duration {
+	if len(c.Events) == 0 {
+		return 0
+	}
+	return time.Duration(c.Events[len(c.Events)-1].Time * float64(time.Second))
+}
+
+// Writer records a cast as it happens, one event per write, so a session
+// cut short still leaves a playable file behind.
+type Writer struct {
+	enc   *json.Encoder
+	start time.Time
+}
+
+// NewWriter writes the header, stamped with start, and returns a Writer that
+// times each event from start.
+func NewWriter(w io.Writer, header Header, start time.Time) (*Writer, error) {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	header.Version = Version
+	header.Timestamp = start.Unix()
+	if err := enc.Encode(header); err != nil {
+		return nil, err
+	}
+	return &Writer{enc: enc, start: start}, nil
+}
+
+// WriteEvent records data as an event of the given type that happened at at.
+func (w *Writer) WriteEvent(at time.Time, eventType, data string) error {
+	return w.enc.Encode(Event{Time: at.Sub(w.start).Seconds(), Type: eventType, Data: data})
+}
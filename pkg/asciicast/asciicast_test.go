@@ -0,0 +1,124 @@
+package asciicast
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeRead_RoundTrip(t *testing.T) {
+	in := &Cast{
+		Header: Header{Width: 100, Height: 30, Timestamp: 1767261600, Title: "a <b> & c"},
+		Events: []Event{
+			{Time: 0, Type: EventOutput, Data: "$ ls\r\n"},
+			{Time: 1.25, Type: EventInput, Data: "q"},
+			{Time: 2, Type: EventOutput, Data: "\x1b[31mred\x1b[0m"},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, in.Encode(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, `{"version":2,"width":100,"height":30,"timestamp":1767261600,"title":"a <b> & c"}`, lines[0])
+	assert.Equal(t, `[1.25,"i","q"]`, lines[2])
+
+	out, err := Read(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, Version, out.Header.Version)
+	assert.Equal(t, in.Events, out.Events)
+	assert.Equal(t, 2*time.Second, out.Duration())
+}
+
+func TestRead_RejectsOtherVersions(t *testing.T) {
+	_, err := Read(strings.NewReader(`{"version":1,"width":80,"height":24}` + "\n"))
+	assert.ErrorContains(t, err, "unsupported asciicast version 1")
+
+	_, err = Read(strings.NewReader(""))
+	assert.ErrorContains(t, err, "empty cast file")
+}
+
+func TestRead_ReportsBadEventLine(t *testing.T) {
+	_, err := Read(strings.NewReader(`{"version":2,"width":80,"height":24}` + "\n" + `[0,"o"]` + "\n"))
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestPlay_SpeedAndIdleLimit(t *testing.T) {
+	cast := &Cast{Events: []Event{
+		{Time: 0, Type: EventOutput, Data: "a"},
+		{Time: 0.2, Type: EventInput, Data: "typed"},
+		{Time: 0.4, Type: EventOutput, Data: "b"},
+		{Time: 3600, Type: EventOutput, Data: "c"},
+	}}
+
+	var buf bytes.Buffer
+	start := time.Now()
+	require.NoError(t, Play(&buf, cast, PlayOptions{Speed: 4, IdleLimit: 200 * time.Millisecond}, nil))
+	elapsed := time.Since(start)
+
+	assert.Equal(t, "abc", buf.String(), "input events are not replayed")
+	// 0.4s at 4x is 100ms; the hour-long pause is capped at 200ms.
+	assert.GreaterOrEqual(t, elapsed, 300*time.Millisecond)
+	assert.Less(t, elapsed, 2*time.Second)
+}
+
+func TestPlay_Stop(t *testing.T) {
+	cast := &Cast{Events: []Event{
+		{Time: 0, Type: EventOutput, Data: "a"},
+		{Time: 3600, Type: EventOutput, Data: "b"},
+	}}
+	stop := make(chan struct{})
+	close(stop)
+
+	var buf bytes.Buffer
+	require.NoError(t, Play(&buf, cast, PlayOptions{}, stop))
+	assert.Equal(t, "a", buf.String())
+}
+
+func TestControlStringFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{"keeps CSI", []string{"\x1b[1;31mred\x1b[0m\x1b[2J"}, "\x1b[1;31mred\x1b[0m\x1b[2J"},
+		{"drops OSC 52 ended by BEL", []string{"a\x1b]52;c;ZXZpbA==\x07b"}, "ab"},
+		{"drops OSC 8 ended by ST", []string{"a\x1b]8;;https://evil\x1b\\link\x1b]8;;\x1b\\b"}, "alinkb"},
+		{"drops DCS split across writes", []string{"a\x1bP+q", "544e\x1b", "\\b"}, "ab"},
+		{"drops C1 OSC", []string{"a\xc2\x9d0;title\x07b"}, "ab"},
+		{"keeps other C1-range text", []string{"caf\xc3\xa9 \xc2\xa9"}, "caf\xc3\xa9 \xc2\xa9"},
+		{"drops OSC after a stray 0xC2", []string{"a\xc2\x1b]52;c;aGVsbG8=\x07b"}, "a\xc2b"},
+		{"drops DCS after a stray 0xC2", []string{"a\xc2", "\x1bP+q544e\x1b\\b"}, "a\xc2b"},
+		{"drops C1 OSC after a stray 0xC2", []string{"a\xc2\xc2\x9d0;title\x07b"}, "a\xc2b"},
+		{"splits ESC from its final byte", []string{"a\x1b", "]2;t\x07b"}, "ab"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			f := NewControlStringFilter(&buf)
+			for _, w := range tt.writes {
+				n, err := f.Write([]byte(w))
+				require.NoError(t, err)
+				assert.Equal(t, len(w), n)
+			}
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Header{Width: 80, Height: 24}, start)
+	require.NoError(t, err)
+	require.NoError(t, w.WriteEvent(start.Add(1500*time.Millisecond), EventOutput, "hi"))
+
+	cast, err := Read(&buf)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1767261600, cast.Header.Timestamp)
+	assert.Equal(t, []Event{{Time: 1.5, Type: EventOutput, Data: "hi"}}, cast.Events)
+}
@@ -0,0 +1,89 @@
+package asciicast
+
+import "io"
+
+type filterState int
+
+const (
+	stateText      filterState = iota
+	stateEsc                   // saw ESC in text
+	stateC1                    // saw 0xC2, the lead byte of a UTF-8 C1 control
+	stateString                // inside a control string, dropping bytes
+	stateStringEsc             // saw ESC inside a control string
+)
+
+// ControlStringFilter drops terminal control strings—OSC, DCS, APC, PM, and
+// SOS—from what it writes through, and passes everything else, CSI
+// sequences included, unchanged.
+//
+// Replaying a recording has to pass cursor movement and colour through to
+// draw anything, but the control strings are where a terminal takes orders
+// beyond drawing: OSC 52 writes the clipboard, OSC 8 plants links, DCS talks
+// to the terminal itself. Output recorded on a server is untrusted, so those
+// never reach the viewer's terminal. The filter keeps its state across
+// writes, as a sequence may be split between two events.
+type ControlStringFilter struct {
+	w     io.Writer
+	state filterState
+}
+
+func NewControlStringFilter(w io.Writer) *ControlStringFilter {
+	return &ControlStringFilter{w: w}
+}
+
+func (f *ControlStringFilter) Write(p []byte) (int, error) {
+	out := make([]byte, 0, len(p))
+	for _, b := range p {
+		switch f.state {
+		case stateText:
+			switch b {
+			case 0x1b:
+				f.state = stateEsc
+			case 0xc2:
+				f.state = stateC1
+			default:
+				out = append(out, b)
+			}
+		case stateEsc:
+			switch b {
+			case ']', 'P', '_', '^', 'X':
+				f.state = stateString
+			case 0x1b:
+				out = append(out, 0x1b)
+			default:
+				out = append(out, 0x1b, b)
+				f.state = stateText
+			}
+		case stateC1:
+			switch b {
+			case 0x9d, 0x90, 0x9f, 0x9e, 0x98: // OSC, DCS, APC, PM, SOS
+				f.state = stateString
+			case 0x1b:
+				out = append(out, 0xc2)
+				f.state = stateEsc
+			case 0xc2:
+				out = append(out, 0xc2)
+			default:
+				out = append(out, 0xc2, b)
+				f.state = stateText
+			}
+		case stateString:
+			switch b {
+			case 0x07: // BEL ends an OSC in xterm's dialect
+				f.state = stateText
+			case 0x1b:
+				f.state = stateStringEsc
+			}
+		case stateStringEsc:
+			if b == '\\' { // ST
+				f.state = stateText
+			} else if b != 0x1b {
+				f.state = stateString
+			}
+		}
+	}
+	if _, err := f.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -1,6 +1,9 @@
 package cert
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -11,30 +14,87 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/alpacax/alpacon-cli/api/cert"
 	"github.com/alpacax/alpacon-cli/utils"
 )
 
-func generateKey(keyPath string) (*rsa.PrivateKey, error) {
+// ParseKeyType reads a --key-type value.
+func ParseKeyType(s string) (KeyType, error) {
+	t := KeyType(strings.ToLower(strings.TrimSpace(s)))
+	if !slices.Contains(KeyTypes, t) {
+		names := make([]string, len(KeyTypes))
+		for i, k := range KeyTypes {
+			names[i] = string(k)
+		}
+		return "", fmt.Errorf("invalid key type %q: must be one of %s", s, strings.Join(names, ", "))
+	}
+	return t, nil
+}
+
+// KeyTypeOf names the type of a key, or returns an error for one no
+// --key-type describes.
+func KeyTypeOf(key PrivateKey) (KeyType, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		switch k.N.BitLen() {
+		case 2048:
+			return KeyTypeRSA2048, nil
+		case 4096:
+			return KeyTypeRSA4096, nil
+		}
+		return "", fmt.Errorf("unsupported RSA key size %d", k.N.BitLen())
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return KeyTypeECDSAP256, nil
+		case elliptic.P384():
+			return KeyTypeECDSAP384, nil
+		}
+		return "", fmt.Errorf("unsupported ECDSA curve %s", k.Curve.Params().Name)
+	case ed25519.PrivateKey:
+		return KeyTypeEd25519, nil
+	}
+	return "", fmt.Errorf("unsupported key type %T", key)
+}
+
+func newKey(t KeyType) (PrivateKey, error) {
+	switch t {
+	case KeyTypeRSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case KeyTypeECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyTypeECDSAP384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case KeyTypeEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	}
+}
+
+func generateKey(keyPath string, opts KeyOptions) (PrivateKey, error) {
 	// 1. First, check if a key already exists at the provided path.
 	if _, err := os.Stat(keyPath); err == nil {
-		key, err := readPrivateKey(keyPath)
-		if err != nil {
-			// If there's an error reading the key, output an error message and exit CLI. User should retry.
-			return nil, err
-		}
-		return key, nil
+		// If there's an error reading the key, output an error message and exit CLI. User should retry.
+		return readExistingKey(keyPath, opts)
 	}
 
 	// 2. If no key exists at the path, generate a new one.
-	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	key, err := newKey(opts.Type)
 	if err != nil {
 		return nil, err
 	}
 
 	// 3. After generation, save the key at the specified path.
-	err = savePrivateKey(keyPath, key)
+	block, err := marshalPrivateKey(key, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = savePrivateKey(keyPath, block)
 	if err != nil {
 		return nil, err
 	}
@@ -42,20 +102,21 @@ func generateKey(keyPath string) (*rsa.PrivateKey, error) {
 	return key, nil
 }
 
-func CreateCSR(res cert.SignRequestResponse, certPath CertificatePath) ([]byte, error) {
+func CreateCSR(res cert.SignRequestResponse, certPath CertificatePath, opts KeyOptions) ([]byte, error) {
 	subject := pkix.Name{
 		Organization: []string{res.Organization},
 		CommonName:   res.CommonName,
 	}
 
+	// The signature algorithm follows the key: SHA-256 for RSA and P-256,
+	// SHA-384 for P-384, and pure Ed25519.
 	template := &x509.CertificateRequest{
-		Subject:            subject,
-		SignatureAlgorithm: x509.SHA256WithRSA,
-		DNSNames:           res.DomainList,
-		IPAddresses:        parseNetIP(res.IpList),
+		Subject:     subject,
+		DNSNames:    res.DomainList,
+		IPAddresses: parseNetIP(res.IpList),
 	}
 
-	privateKey, err := generateKey(certPath.PrivateKeyPath)
+	privateKey, err := generateKey(certPath.PrivateKeyPath, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -78,7 +139,69 @@ func CreateCSR(res cert.SignRequestResponse, certPath CertificatePath) ([]byte,
 	return csrPEM, nil
 }
 
-func readPrivateKey(keyPath string) (*rsa.PrivateKey, error) {
+// CheckKey does what CreateCSR will do with the key short of generating one:
+// it reads an existing key, holding it to opts.Type under RequireType, or
+// asks for a new key's passphrase. Run before the sign request, it keeps a
+// local fault from leaving a request behind.
+func CheckKey(keyPath string, opts KeyOptions) error {
+	if _, err := os.Stat(keyPath); err == nil {
+		_, err = readExistingKey(keyPath, opts)
+		return err
+	}
+	if opts.Encrypt {
+		if opts.Passphrase == nil {
+			return errors.New("a passphrase is required to encrypt the key")
+		}
+		if _, err := opts.Passphrase(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readExistingKey(keyPath string, opts KeyOptions) (PrivateKey, error) {
+	key, err := ReadPrivateKey(keyPath, opts.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if opts.RequireType {
+		existing, err := KeyTypeOf(key)
+		if err != nil {
+			return nil, err
+		}
+		if existing != opts.Type {
+			return nil, fmt.Errorf("the existing key at %s is %s, not %s; choose another key path to generate a new key", keyPath, existing, opts.Type)
+		}
+	}
+	return key, nil
+}
+
+// ReadCSR reads a PEM certificate signing request made elsewhere and checks
+// its signature. It returns the request and its PEM as read.
+func ReadCSR(csrPath string) (*x509.CertificateRequest, []byte, error) {
+	pemBytes, err := os.ReadFile(csrPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, _ := pem.Decode(pemBytes)
+	if block == nil || (block.Type != "CERTIFICATE REQUEST" && block.Type != "NEW CERTIFICATE REQUEST") {
+		return nil, nil, errors.New("no PEM certificate request found in the file")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the certificate request: %v", err)
+	}
+	if err = csr.CheckSignature(); err != nil {
+		return nil, nil, fmt.Errorf("the certificate request's signature is invalid: %v", err)
+	}
+	return csr, pem.EncodeToMemory(block), nil
+}
+
+// ReadPrivateKey reads a PEM private key: PKCS#1 RSA, SEC 1 EC, or PKCS#8,
+// encrypted or not. passphrase is asked only for an encrypted key. Any key
+// size or curve is accepted; generateKey holds an existing key to the
+// --key-type values only under RequireType.
+func ReadPrivateKey(keyPath string, passphrase func() ([]byte, error)) (PrivateKey, error) {
 	pemBytes, err := os.ReadFile(keyPath)
 	if err != nil {
 		return nil, err
@@ -87,16 +210,44 @@ func readPrivateKey(keyPath string) (*rsa.PrivateKey, error) {
 	if block == nil {
 		return nil, errors.New("failed to find PEM block in the key file")
 	}
-	if block.Type != "RSA PRIVATE KEY" {
-		return nil, fmt.Errorf("invalid key type: %s, expected 'RSA PRIVATE KEY'", block.Type)
+	if strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED") {
+		return nil, errors.New("legacy PEM encryption (Proc-Type: 4,ENCRYPTED) is not supported; convert the key with 'openssl pkcs8 -topk8'")
 	}
 
-	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	var key any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "ENCRYPTED PRIVATE KEY":
+		if passphrase == nil {
+			return nil, errors.New("the key is encrypted and no passphrase was given")
+		}
+		var pass, der []byte
+		if pass, err = passphrase(); err != nil {
+			return nil, err
+		}
+		if der, err = decryptPKCS8(block.Bytes, pass); err != nil {
+			return nil, err
+		}
+		if key, err = x509.ParsePKCS8PrivateKey(der); err != nil {
+			return nil, errBadPassphrase
+		}
+	default:
+		return nil, fmt.Errorf("invalid key type: %s, expected a PEM private key", block.Type)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse private key: %v", err)
 	}
 
-	return key, nil
+	signer, ok := key.(PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
 }
 
 func parseNetIP(ipList []string) []net.IP {
@@ -111,7 +262,37 @@ func parseNetIP(ipList []string) []net.IP {
 	return ipAddresses
 }
 
-func savePrivateKey(fileName string, key *rsa.PrivateKey) error {
+// marshalPrivateKey encodes a new key. Unencrypted RSA keys stay PKCS#1, as
+// earlier versions wrote them; other and encrypted keys are PKCS#8.
+func marshalPrivateKey(key PrivateKey, opts KeyOptions) (*pem.Block, error) {
+	if rsaKey, ok := key.(*rsa.PrivateKey); ok && !opts.Encrypt {
+		return &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}, nil
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.Encrypt {
+		return &pem.Block{Type: "PRIVATE KEY", Bytes: der}, nil
+	}
+	if opts.Passphrase == nil {
+		return nil, errors.New("a passphrase is required to encrypt the key")
+	}
+	pass, err := opts.Passphrase()
+	if err != nil {
+		return nil, err
+	}
+	if len(pass) == 0 {
+		return nil, errors.New("the passphrase is empty")
+	}
+	encrypted, err := encryptPKCS8(der, pass)
+	if err != nil {
+		return nil, err
+	}
+	return &pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: encrypted}, nil
+}
+
+func savePrivateKey(fileName string, block *pem.Block) error {
 	dir := filepath.Dir(fileName)
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		return fmt.Errorf("failed to create directories: %v", err)
@@ -123,12 +304,7 @@ func savePrivateKey(fileName string, key *rsa.PrivateKey) error {
 	}
 	defer func() { _ = file.Close() }()
 
-	var privateKey = &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(key),
-	}
-
-	err = pem.Encode(file, privateKey)
+	err = pem.Encode(file, block)
 	if err != nil {
 		return errors.New("failed to PEM block in the key file")
 	}
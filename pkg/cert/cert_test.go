@@ -0,0 +1,186 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alpacax/alpacon-cli/api/cert"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func staticPassphrase(s string) func() ([]byte, error) {
+	return func() ([]byte, error) { return []byte(s), nil }
+}
+
+func pemType(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	block, _ := pem.Decode(data)
+	require.NotNil(t, block)
+	return block.Type
+}
+
+func TestParseKeyType(t *testing.T) {
+	kt, err := ParseKeyType(" ECDSA-P256 ")
+	assert.NoError(t, err)
+	assert.Equal(t, KeyTypeECDSAP256, kt)
+
+	_, err = ParseKeyType("dsa")
+	assert.ErrorContains(t, err, "must be one of rsa2048")
+}
+
+func TestCreateCSR_KeyTypes(t *testing.T) {
+	res := cert.SignRequestResponse{
+		Organization: "Alpaca",
+		CommonName:   "a.com",
+		DomainList:   []string{"a.com", "b.com"},
+		IpList:       []string{"10.0.0.1"},
+	}
+	tests := []struct {
+		keyType KeyType
+		pemType string
+		algo    x509.SignatureAlgorithm
+	}{
+		{KeyTypeRSA2048, "RSA PRIVATE KEY", x509.SHA256WithRSA},
+		{KeyTypeECDSAP256, "PRIVATE KEY", x509.ECDSAWithSHA256},
+		{KeyTypeECDSAP384, "PRIVATE KEY", x509.ECDSAWithSHA384},
+		{KeyTypeEd25519, "PRIVATE KEY", x509.PureEd25519},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.keyType), func(t *testing.T) {
+			dir := t.TempDir()
+			path := CertificatePath{
+				PrivateKeyPath: filepath.Join(dir, "private", "a.key"),
+				CSRPath:        filepath.Join(dir, "a.csr"),
+			}
+			csrPEM, err := CreateCSR(res, path, KeyOptions{Type: tt.keyType})
+			require.NoError(t, err)
+
+			csr, saved, err := ReadCSR(path.CSRPath)
+			require.NoError(t, err)
+			assert.Equal(t, csrPEM, saved)
+			assert.Equal(t, tt.algo, csr.SignatureAlgorithm)
+			assert.Equal(t, "a.com", csr.Subject.CommonName)
+			assert.Equal(t, []string{"a.com", "b.com"}, csr.DNSNames)
+			assert.Equal(t, "10.0.0.1", csr.IPAddresses[0].String())
+
+			assert.Equal(t, tt.pemType, pemType(t, path.PrivateKeyPath))
+			info, err := os.Stat(path.PrivateKeyPath)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+			key, err := ReadPrivateKey(path.PrivateKeyPath, nil)
+			require.NoError(t, err)
+			got, err := KeyTypeOf(key)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.keyType, got)
+		})
+	}
+}
+
+func TestGenerateKey_ReusesExistingKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.key")
+	first, err := generateKey(path, KeyOptions{Type: KeyTypeECDSAP256})
+	require.NoError(t, err)
+
+	// Without RequireType, the existing key wins over the default type.
+	again, err := generateKey(path, KeyOptions{Type: KeyTypeRSA2048})
+	require.NoError(t, err)
+	assert.True(t, first.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(again.Public()))
+
+	_, err = generateKey(path, KeyOptions{Type: KeyTypeEd25519, RequireType: true})
+	assert.ErrorContains(t, err, "is ecdsa-p256, not ed25519")
+}
+
+func TestEncryptedKeyRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.key")
+	opts := KeyOptions{Type: KeyTypeEd25519, Encrypt: true, Passphrase: staticPassphrase("s3cret")}
+	key, err := generateKey(path, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "ENCRYPTED PRIVATE KEY", pemType(t, path))
+
+	read, err := ReadPrivateKey(path, staticPassphrase("s3cret"))
+	require.NoError(t, err)
+	assert.True(t, key.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(read.Public()))
+
+	_, err = ReadPrivateKey(path, staticPassphrase("wrong"))
+	assert.ErrorIs(t, err, errBadPassphrase)
+
+	_, err = ReadPrivateKey(path, nil)
+	assert.ErrorContains(t, err, "no passphrase")
+
+	asked := errors.New("not asked")
+	_, err = ReadPrivateKey(path, func() ([]byte, error) { return nil, asked })
+	assert.ErrorIs(t, err, asked)
+}
+
+func TestEncryptRequiresPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.key")
+	_, err := generateKey(path, KeyOptions{Type: KeyTypeRSA2048, Encrypt: true, Passphrase: staticPassphrase("")})
+	assert.ErrorContains(t, err, "passphrase is empty")
+	_, statErr := os.Stat(path)
+	assert.True(t, os.IsNotExist(statErr))
+}
+
+func TestGenerateKey_ReusesKeyOutsideKeyTypes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "p521.key")
+	p521, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalECPrivateKey(p521)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600))
+
+	key, err := generateKey(path, KeyOptions{Type: KeyTypeRSA2048})
+	require.NoError(t, err)
+	assert.True(t, p521.PublicKey.Equal(key.Public()))
+
+	_, err = generateKey(path, KeyOptions{Type: KeyTypeECDSAP256, RequireType: true})
+	assert.Error(t, err)
+}
+
+func TestCheckKey(t *testing.T) {
+	dir := t.TempDir()
+	missing := errors.New("KEY_PASS is not set")
+	noPassphrase := func() ([]byte, error) { return nil, missing }
+
+	assert.NoError(t, CheckKey(filepath.Join(dir, "new.key"), KeyOptions{Type: KeyTypeRSA2048}))
+	assert.ErrorIs(t, CheckKey(filepath.Join(dir, "new.key"), KeyOptions{Type: KeyTypeRSA2048, Encrypt: true, Passphrase: noPassphrase}), missing)
+	assert.NoFileExists(t, filepath.Join(dir, "new.key"), "nothing is generated")
+
+	path := filepath.Join(dir, "ec.key")
+	_, err := generateKey(path, KeyOptions{Type: KeyTypeECDSAP256})
+	require.NoError(t, err)
+	assert.NoError(t, CheckKey(path, KeyOptions{Type: KeyTypeRSA2048}))
+	assert.ErrorContains(t, CheckKey(path, KeyOptions{Type: KeyTypeRSA2048, RequireType: true}), "is ecdsa-p256, not rsa2048")
+}
+
+func TestReadPrivateKey_LegacyEncryption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.key")
+	block := &pem.Block{
+		Type:    "RSA PRIVATE KEY",
+		Headers: map[string]string{"Proc-Type": "4,ENCRYPTED", "DEK-Info": "AES-256-CBC,00"},
+		Bytes:   []byte{0},
+	}
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0600))
+
+	_, err := ReadPrivateKey(path, staticPassphrase("x"))
+	assert.ErrorContains(t, err, "openssl pkcs8 -topk8")
+}
+
+func TestReadCSR_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.csr")
+	require.NoError(t, os.WriteFile(path, []byte("not a csr"), 0600))
+
+	_, _, err := ReadCSR(path)
+	assert.ErrorContains(t, err, "no PEM certificate request")
+}
@@ -0,0 +1,340 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"hash"
+	"unicode/utf16"
+)
+
+// PKCS#12 (RFC 7292) archives of a private key and its certificate chain,
+// for Java services and load balancers that take a keystore rather than PEM
+// files. Only encoding is needed: the CLI writes archives and never reads
+// them.
+
+var (
+	oidDataContent          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedDataContent = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+	oidShroudedKeyBag       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidX509Certificate      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidFriendlyName         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	oidLocalKeyID           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+	oidPBEWithSHAAnd3DES    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidSHA1                 = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256               = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+)
+
+// pkcs12Iterations is the key derivation count of the legacy PBE and of the
+// MAC, the count OpenSSL and keytool write.
+const pkcs12Iterations = 2048
+
+// PKCS12Options chooses how an archive is protected.
+type PKCS12Options struct {
+	// Legacy encrypts with 3DES and a SHA-1 MAC, which every Java version
+	// reads. Otherwise it is PBES2 with AES-256 and a SHA-256 MAC, the
+	// OpenSSL 3 default, which Java reads from 8u301 and 11.0.12 on.
+	Legacy bool
+	// FriendlyName names the key entry; keytool takes it as the alias.
+	FriendlyName string
+}
+
+type pfxPdu struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue // [0] EXPLICIT, see explicitTag
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue     // [0] EXPLICIT, see explicitTag
+	Attributes []pkcs12Attribute `asn1:"set,optional"`
+}
+
+type pkcs12Attribute struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue // a SET of values
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+// EncodePKCS12 archives the key with its certificate, leaf first, then the
+// rest of the chain. The key must belong to the leaf.
+func EncodePKCS12(key PrivateKey, certs []*x509.Certificate, password []byte, opts PKCS12Options) ([]byte, error) {
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate to archive")
+	}
+	public, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if ok && !public.Equal(certs[0].PublicKey) {
+		return nil, errors.New("the private key does not match the certificate")
+	}
+
+	localKeyID := sha1.Sum(certs[0].Raw)
+	leafAttributes, err := bagAttributes(localKeyID[:], opts.FriendlyName)
+	if err != nil {
+		return nil, err
+	}
+
+	var certBags []safeBag
+	for i, c := range certs {
+		bag, err := asn1.Marshal(certBag{ID: oidX509Certificate, Data: c.Raw})
+		if err != nil {
+			return nil, err
+		}
+		sb := safeBag{ID: oidCertBag, Value: explicitTag(bag)}
+		if i == 0 {
+			sb.Attributes = leafAttributes
+		}
+		certBags = append(certBags, sb)
+	}
+	certContents, err := asn1.Marshal(certBags)
+	if err != nil {
+		return nil, err
+	}
+	certAlgorithm, encryptedCerts, err := pkcs12Encrypt(certContents, password, opts.Legacy)
+	if err != nil {
+		return nil, err
+	}
+	certInfo, err := asn1.Marshal(encryptedData{
+		EncryptedContentInfo: encryptedContentInfo{
+			ContentType:                oidDataContent,
+			ContentEncryptionAlgorithm: certAlgorithm,
+			EncryptedContent:           encryptedCerts,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	keyAlgorithm, encryptedKey, err := pkcs12Encrypt(der, password, opts.Legacy)
+	if err != nil {
+		return nil, err
+	}
+	shrouded, err := asn1.Marshal(encryptedPrivateKeyInfo{Algorithm: keyAlgorithm, EncryptedData: encryptedKey})
+	if err != nil {
+		return nil, err
+	}
+	keyContents, err := asn1.Marshal([]safeBag{{ID: oidShroudedKeyBag, Value: explicitTag(shrouded), Attributes: leafAttributes}})
+	if err != nil {
+		return nil, err
+	}
+	keyInfo, err := dataContentInfo(keyContents)
+	if err != nil {
+		return nil, err
+	}
+
+	authSafe, err := asn1.Marshal([]contentInfo{
+		{ContentType: oidEncryptedDataContent, Content: explicitTag(certInfo)},
+		keyInfo,
+	})
+	if err != nil {
+		return nil, err
+	}
+	mac, err := pkcs12MAC(authSafe, password, opts.Legacy)
+	if err != nil {
+		return nil, err
+	}
+	authSafeInfo, err := dataContentInfo(authSafe)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pfxPdu{Version: 3, AuthSafe: authSafeInfo, MacData: mac})
+}
+
+func dataContentInfo(data []byte) (contentInfo, error) {
+	octets, err := asn1.Marshal(data)
+	if err != nil {
+		return contentInfo{}, err
+	}
+	return contentInfo{ContentType: oidDataContent, Content: explicitTag(octets)}, nil
+}
+
+// explicitTag wraps DER in [0] EXPLICIT. A struct tag cannot do it: Marshal
+// writes a RawValue's bytes as they are, ignoring the field's tag.
+func explicitTag(der []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der}
+}
+
+func bagAttributes(localKeyID []byte, friendlyName string) ([]pkcs12Attribute, error) {
+	id, err := asn1.Marshal(localKeyID)
+	if err != nil {
+		return nil, err
+	}
+	attributes := []pkcs12Attribute{{ID: oidLocalKeyID, Value: asn1.RawValue{Tag: asn1.TagSet, Class: asn1.ClassUniversal, IsCompound: true, Bytes: id}}}
+	if friendlyName != "" {
+		name, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagBMPString, Class: asn1.ClassUniversal, Bytes: bmpString(friendlyName, false)})
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, pkcs12Attribute{ID: oidFriendlyName, Value: asn1.RawValue{Tag: asn1.TagSet, Class: asn1.ClassUniversal, IsCompound: true, Bytes: name}})
+	}
+	return attributes, nil
+}
+
+// pkcs12Encrypt encrypts a bag's content: PBES2 takes the password as it is,
+// the legacy PBE as a BMPString.
+func pkcs12Encrypt(data, password []byte, legacy bool) (pkix.AlgorithmIdentifier, []byte, error) {
+	if !legacy {
+		return pbes2Encrypt(data, password)
+	}
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	bmp := bmpString(string(password), true)
+	key := pkcs12KDF(sha1.New, bmp, salt, 1, pkcs12Iterations, 24)
+	iv := pkcs12KDF(sha1.New, bmp, salt, 2, pkcs12Iterations, des.BlockSize)
+	block, err := des.NewTripleDESCipher(key)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	encrypted := pad(data, des.BlockSize)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, encrypted)
+
+	params, err := asn1.Marshal(pbeParams{Salt: salt, Iterations: pkcs12Iterations})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	return pkix.AlgorithmIdentifier{Algorithm: oidPBEWithSHAAnd3DES, Parameters: asn1.RawValue{FullBytes: params}}, encrypted, nil
+}
+
+// pkcs12MAC authenticates the archive with an HMAC keyed by the PKCS#12 KDF.
+func pkcs12MAC(authSafe, password []byte, legacy bool) (macData, error) {
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return macData{}, err
+	}
+	h, oid := sha256.New, oidSHA256
+	if legacy {
+		h, oid = sha1.New, oidSHA1
+	}
+	key := pkcs12KDF(h, bmpString(string(password), true), salt, 3, pkcs12Iterations, h().Size())
+	mac := hmac.New(h, key)
+	mac.Write(authSafe)
+	return macData{
+		Mac: digestInfo{
+			Algorithm: pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: asn1.NullRawValue},
+			Digest:    mac.Sum(nil),
+		},
+		MacSalt:    salt,
+		Iterations: pkcs12Iterations,
+	}, nil
+}
+
+// bmpString encodes s as UTF-16BE, the form PKCS#12 passwords and names take;
+// a password also carries a two-byte terminator.
+func bmpString(s string, terminate bool) []byte {
+	units := utf16.Encode([]rune(s))
+	out := make([]byte, 0, 2*len(units)+2)
+	for _, u := range units {
+		out = append(out, byte(u>>8), byte(u))
+	}
+	if terminate {
+		out = append(out, 0, 0)
+	}
+	return out
+}
+
+// pkcs12KDF derives n bytes of key material (RFC 7292, appendix B.2). id is
+// 1 for a cipher key, 2 for an IV, and 3 for a MAC key.
+func pkcs12KDF(h func() hash.Hash, password, salt []byte, id byte, iterations, n int) []byte {
+	u := h().Size()
+	v := h().BlockSize()
+
+	d := make([]byte, v)
+	for i := range d {
+		d[i] = id
+	}
+	fill := func(b []byte) []byte {
+		if len(b) == 0 {
+			return nil
+		}
+		out := make([]byte, v*((len(b)+v-1)/v))
+		for i := range out {
+			out[i] = b[i%len(b)]
+		}
+		return out
+	}
+	input := append(fill(salt), fill(password)...)
+
+	var out []byte
+	for len(out) < n {
+		hh := h()
+		hh.Write(d)
+		hh.Write(input)
+		a := hh.Sum(nil)
+		for range iterations - 1 {
+			hh.Reset()
+			hh.Write(a)
+			a = hh.Sum(nil)
+		}
+		out = append(out, a...)
+		if len(out) >= n {
+			break
+		}
+
+		// Each v-byte block of the input becomes (block + B + 1) mod 2^(8v),
+		// where B is a repeated to v bytes.
+		b := make([]byte, v)
+		for i := range b {
+			b[i] = a[i%u]
+		}
+		for j := 0; j < len(input); j += v {
+			carry := 1
+			for k := v - 1; k >= 0; k-- {
+				sum := int(input[j+k]) + int(b[k]) + carry
+				input[j+k] = byte(sum)
+				carry = sum >> 8
+			}
+		}
+	}
+	return out[:n]
+}
@@ -0,0 +1,107 @@
+package cert
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpacax/alpacon-cli/api/cert"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPKCS12KDF(t *testing.T) {
+	// Vectors from the PKCS#12 tests of OpenSSL and Bouncy Castle.
+	tests := []struct {
+		password, salt string
+		id             byte
+		iterations     int
+		want           string
+	}{
+		{"smeg", "0a58cf64530d823f", 1, 1, "8aaae6297b6cb04642ab5b077851284eb7128f1a2a7fbca3"},
+		{"smeg", "0a58cf64530d823f", 2, 1, "79993dfe048d3b76"},
+		{"queeg", "05dec959acff72f7", 1, 1000, "ed2034e36328830ff09df1e1a07dd357185dac0d4f9eb3d4"},
+	}
+	for _, tt := range tests {
+		salt, _ := hex.DecodeString(tt.salt)
+		got := pkcs12KDF(sha1.New, bmpString(tt.password, true), salt, tt.id, tt.iterations, len(tt.want)/2)
+		assert.Equal(t, tt.want, hex.EncodeToString(got))
+	}
+}
+
+func TestBMPString(t *testing.T) {
+	assert.Equal(t, []byte{0, 'a', 0xac, 0x00, 0, 0}, bmpString("a가", true))
+	assert.Equal(t, []byte{0, 'a'}, bmpString("a", false))
+}
+
+func testKeyAndCertificate(t *testing.T) (PrivateKey, *x509.Certificate) {
+	dir := t.TempDir()
+	path := CertificatePath{PrivateKeyPath: filepath.Join(dir, "a.key"), CSRPath: filepath.Join(dir, "a.csr")}
+	_, err := CreateCSR(cert.SignRequestResponse{CommonName: "a.com", DomainList: []string{"a.com"}}, path, KeyOptions{Type: KeyTypeECDSAP256})
+	require.NoError(t, err)
+	key, err := ReadPrivateKey(path.PrivateKeyPath, nil)
+	require.NoError(t, err)
+	// Self-signed, so the test needs no authority.
+	crt, err := selfSign(key)
+	require.NoError(t, err)
+	return key, crt
+}
+
+func selfSign(key PrivateKey) (*x509.Certificate, error) {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "a.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
+
+func TestEncodePKCS12_KeyMismatch(t *testing.T) {
+	key, _ := testKeyAndCertificate(t)
+	_, other := testKeyAndCertificate(t)
+	_, err := EncodePKCS12(key, []*x509.Certificate{other}, []byte("pw"), PKCS12Options{})
+	assert.ErrorContains(t, err, "does not match")
+}
+
+func TestEncodePKCS12_OpenSSL(t *testing.T) {
+	openssl, err := exec.LookPath("openssl")
+	if err != nil {
+		t.Skip("openssl not installed")
+	}
+	key, crt := testKeyAndCertificate(t)
+	for _, legacy := range []bool{false, true} {
+		data, err := EncodePKCS12(key, []*x509.Certificate{crt}, []byte("s3cret"), PKCS12Options{Legacy: legacy, FriendlyName: "a.com"})
+		require.NoError(t, err)
+		path := filepath.Join(t.TempDir(), "a.p12")
+		require.NoError(t, os.WriteFile(path, data, 0600))
+
+		out, err := exec.Command(openssl, "pkcs12", "-in", path, "-passin", "pass:s3cret", "-nodes", "-info").CombinedOutput()
+		require.NoError(t, err, string(out))
+		assert.Contains(t, string(out), "friendlyName: a.com")
+		assert.Contains(t, string(out), "BEGIN CERTIFICATE")
+		assert.Contains(t, string(out), "BEGIN PRIVATE KEY")
+		if legacy {
+			assert.Contains(t, string(out), "pbeWithSHA1And3-KeyTripleDES-CBC")
+		} else {
+			assert.Contains(t, string(out), "PBES2")
+		}
+
+		out, err = exec.Command(openssl, "pkcs12", "-in", path, "-passin", "pass:wrong", "-nodes").CombinedOutput()
+		assert.Error(t, err)
+		assert.True(t, strings.Contains(strings.ToLower(string(out)), "mac"), string(out))
+	}
+}
@@ -0,0 +1,205 @@
+package cert
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// Encrypted PKCS#8 (RFC 5958) with PBES2 (RFC 8018): PBKDF2 derives an AES
+// key from the passphrase. This is what 'openssl genpkey -aes256' writes, and
+// what OpenSSL, Java, and nginx read.
+
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	errBadPassphrase  = errors.New("wrong passphrase or corrupt key")
+)
+
+// pbkdf2Iterations follows the OWASP recommendation for PBKDF2-HMAC-SHA256.
+const pbkdf2Iterations = 600000
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// encryptPKCS8 wraps a DER PKCS#8 key with PBES2: PBKDF2-HMAC-SHA256 and
+// AES-256-CBC.
+func encryptPKCS8(der, passphrase []byte) ([]byte, error) {
+	algorithm, data, err := pbes2Encrypt(der, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(encryptedPrivateKeyInfo{Algorithm: algorithm, EncryptedData: data})
+}
+
+// pbes2Encrypt encrypts data under the passphrase with PBKDF2-HMAC-SHA256
+// and AES-256-CBC, returning the PBES2 algorithm identifier that carries the
+// salt and IV.
+func pbes2Encrypt(data, passphrase []byte) (pkix.AlgorithmIdentifier, []byte, error) {
+	salt := make([]byte, 16)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(salt); err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	key, err := pbkdf2.Key(sha256.New, string(passphrase), salt, pbkdf2Iterations, 32)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	encrypted := pad(data, aes.BlockSize)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, encrypted)
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: pbkdf2Iterations,
+		PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	ivParam, err := asn1.Marshal(iv)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParam}},
+	})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	return pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}}, encrypted, nil
+}
+
+// pad returns a copy of data with PKCS#7 padding to the block size.
+func pad(data []byte, blockSize int) []byte {
+	n := blockSize - len(data)%blockSize
+	padded := make([]byte, len(data), len(data)+n)
+	copy(padded, data)
+	for range n {
+		padded = append(padded, byte(n))
+	}
+	return padded
+}
+
+// decryptPKCS8 unwraps a PBES2 key to its DER PKCS#8 form. It reads PBKDF2
+// with HMAC-SHA1/256/384/512 and AES-CBC of any key size; the older PBES1
+// schemes are refused.
+func decryptPKCS8(der, passphrase []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if rest, err := asn1.Unmarshal(der, &info); err != nil || len(rest) > 0 {
+		return nil, errors.New("malformed encrypted private key")
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported key encryption %s: only PBES2 is supported", info.Algorithm.Algorithm)
+	}
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, errors.New("malformed PBES2 parameters")
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("unsupported key derivation %s: only PBKDF2 is supported", params.KeyDerivationFunc.Algorithm)
+	}
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, errors.New("malformed PBKDF2 parameters")
+	}
+
+	var keyLen int
+	switch {
+	case params.EncryptionScheme.Algorithm.Equal(oidAES128CBC):
+		keyLen = 16
+	case params.EncryptionScheme.Algorithm.Equal(oidAES192CBC):
+		keyLen = 24
+	case params.EncryptionScheme.Algorithm.Equal(oidAES256CBC):
+		keyLen = 32
+	default:
+		return nil, fmt.Errorf("unsupported cipher %s: only AES-CBC is supported", params.EncryptionScheme.Algorithm)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil || len(iv) != aes.BlockSize {
+		return nil, errors.New("malformed AES-CBC parameters")
+	}
+
+	var key []byte
+	var err error
+	switch prf := kdf.PRF.Algorithm; {
+	case len(prf) == 0 || prf.Equal(oidHMACWithSHA1):
+		key, err = deriveKey(sha1.New, passphrase, kdf, keyLen)
+	case prf.Equal(oidHMACWithSHA256):
+		key, err = deriveKey(sha256.New, passphrase, kdf, keyLen)
+	case prf.Equal(oidHMACWithSHA384):
+		key, err = deriveKey(sha512.New384, passphrase, kdf, keyLen)
+	case prf.Equal(oidHMACWithSHA512):
+		key, err = deriveKey(sha512.New, passphrase, kdf, keyLen)
+	default:
+		return nil, fmt.Errorf("unsupported PBKDF2 function %s", prf)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data := info.EncryptedData
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, errBadPassphrase
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+	padding := int(out[len(out)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, errBadPassphrase
+	}
+	for _, b := range out[len(out)-padding:] {
+		if int(b) != padding {
+			return nil, errBadPassphrase
+		}
+	}
+	return out[:len(out)-padding], nil
+}
+
+func deriveKey[H hash.Hash](h func() H, passphrase []byte, kdf pbkdf2Params, keyLen int) ([]byte, error) {
+	if kdf.KeyLength != 0 && kdf.KeyLength != keyLen {
+		return nil, errors.New("PBKDF2 key length does not match the cipher")
+	}
+	return pbkdf2.Key(h, string(passphrase), kdf.Salt, kdf.IterationCount, keyLen)
+}
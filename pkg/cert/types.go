@@ -1,6 +1,38 @@
 package cert
 
+import "crypto"
+
 type CertificatePath struct {
 	PrivateKeyPath string
 	CSRPath        string
 }
+
+// KeyType names a private key algorithm and size, as --key-type spells it.
+type KeyType string
+
+const (
+	KeyTypeRSA2048   KeyType = "rsa2048"
+	KeyTypeRSA4096   KeyType = "rsa4096"
+	KeyTypeECDSAP256 KeyType = "ecdsa-p256"
+	KeyTypeECDSAP384 KeyType = "ecdsa-p384"
+	KeyTypeEd25519   KeyType = "ed25519"
+)
+
+// KeyTypes lists the supported key types, the default first.
+var KeyTypes = []KeyType{KeyTypeRSA2048, KeyTypeRSA4096, KeyTypeECDSAP256, KeyTypeECDSAP384, KeyTypeEd25519}
+
+// KeyOptions controls the private key behind a CSR.
+type KeyOptions struct {
+	// Type is the algorithm of a new key. An existing key of another type is
+	// an error when RequireType is set; otherwise it is used as it is.
+	Type        KeyType
+	RequireType bool
+	// Encrypt writes a new key as encrypted PKCS#8 under the passphrase.
+	Encrypt bool
+	// Passphrase returns the passphrase that encrypts a new key or decrypts
+	// an existing one. It is called only when one is needed.
+	Passphrase func() ([]byte, error)
+}
+
+// PrivateKey is a private key of any supported type.
+type PrivateKey = crypto.Signer
@@ -0,0 +1,123 @@
+package pkgmeta
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const arMagic = "!<arch>\n"
+
+// Deb reads the control file of a deb package. A control archive compressed
+// with xz or zstd cannot be read here; the metadata then comes from the file
+// name, which Debian tools write as {name}_{version}_{arch}.deb without the
+// version's epoch, and FromFileName is set.
+func Deb(filePath string) (Metadata, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer func() { _ = f.Close() }()
+
+	control, err := debControl(f)
+	if errors.Is(err, errUnreadableControl) {
+		return debFileName(filepath.Base(filePath))
+	}
+	if err != nil {
+		return Metadata{}, err
+	}
+	fields := parseControl(control)
+	m := Metadata{Name: fields["Package"], Version: fields["Version"], Arch: fields["Architecture"]}
+	if m.Name == "" || m.Version == "" {
+		return Metadata{}, errors.New("the control file has no Package or Version")
+	}
+	return m, nil
+}
+
+var errUnreadableControl = errors.New("control archive compression not supported")
+
+// debControl returns the control file from the control.tar member of the ar
+// archive r.
+func debControl(r io.Reader) ([]byte, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(arMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != arMagic {
+		return nil, errors.New("not a deb package")
+	}
+	header := make([]byte, 60)
+	for {
+		if _, err := io.ReadFull(br, header); err != nil {
+			return nil, errors.New("the deb package has no control archive")
+		}
+		name := strings.TrimSuffix(strings.TrimSpace(string(header[:16])), "/")
+		size, err := strconv.ParseInt(strings.TrimSpace(string(header[48:58])), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ar member size for %s", name)
+		}
+		member := io.LimitReader(br, size)
+
+		if strings.HasPrefix(name, "control.tar") {
+			var tr *tar.Reader
+			switch name {
+			case "control.tar":
+				tr = tar.NewReader(member)
+			case "control.tar.gz":
+				gz, err := gzip.NewReader(member)
+				if err != nil {
+					return nil, err
+				}
+				tr = tar.NewReader(gz)
+			default:
+				return nil, errUnreadableControl
+			}
+			for {
+				h, err := tr.Next()
+				if err != nil {
+					return nil, errors.New("the control archive has no control file")
+				}
+				if path.Clean(h.Name) == "control" {
+					return io.ReadAll(tr)
+				}
+			}
+		}
+
+		// Members are padded to an even size.
+		if _, err := io.CopyN(io.Discard, br, size+size%2); err != nil {
+			return nil, errors.New("the deb package has no control archive")
+		}
+	}
+}
+
+// parseControl reads the fields of a control file; continuation lines are
+// dropped, as none of the fields read here have them.
+func parseControl(data []byte) map[string]string {
+	fields := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			fields[key] = strings.TrimSpace(value)
+		}
+	}
+	return fields
+}
+
+func debFileName(fileName string) (Metadata, error) {
+	parts := strings.Split(strings.TrimSuffix(fileName, filepath.Ext(fileName)), "_")
+	if len(parts) != 3 {
+		return Metadata{}, fmt.Errorf("cannot read the control file of %s, and its name is not {name}_{version}_{arch}.deb", fileName)
+	}
+	return Metadata{Name: parts[0], Version: parts[1], Arch: parts[2], FromFileName: true}, nil
+}
@@ -0,0 +1,69 @@
+// Package pkgmeta reads the name, version, and platform of Python wheels and
+// deb and rpm packages from the files themselves, without the tools that
+// build or install them.
+package pkgmeta
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Metadata identifies a package file. Wheels fill PythonTarget, ABI, and
+// Platform; deb and rpm packages fill Arch.
+type Metadata struct {
+	Name         string
+	Version      string
+	Arch         string
+	PythonTarget string
+	ABI          string
+	Platform     string
+	// FromFileName is set on a deb whose control file could not be read, so
+	// its metadata came from the file name, which carries no version epoch.
+	FromFileName bool
+}
+
+// ErrUnsupported is returned for a file that is not a wheel, deb, or rpm.
+var ErrUnsupported = errors.New("not a wheel, deb, or rpm package")
+
+// Read returns the metadata of the package at path, chosen by its extension.
+func Read(path string) (Metadata, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".whl":
+		return Wheel(filepath.Base(path))
+	case ".deb":
+		return Deb(path)
+	case ".rpm":
+		return RPM(path)
+	}
+	return Metadata{}, ErrUnsupported
+}
+
+var nameSeparators = regexp.MustCompile(`[-_.]+`)
+
+// NormalizeName compares Python project names the way PEP 503 does: case and
+// runs of -, _, and . do not matter.
+func NormalizeName(name string) string {
+	return strings.ToLower(nameSeparators.ReplaceAllString(name, "-"))
+}
+
+// Wheel parses a wheel file name,
+// {name}-{version}(-{build})?-{python}-{abi}-{platform}.whl, as PEP 427
+// defines it.
+func Wheel(fileName string) (Metadata, error) {
+	base := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	parts := strings.Split(base, "-")
+	if len(parts) != 5 && len(parts) != 6 {
+		return Metadata{}, fmt.Errorf("%s is not a valid wheel file name", fileName)
+	}
+	n := len(parts)
+	return Metadata{
+		Name:         parts[0],
+		Version:      parts[1],
+		PythonTarget: parts[n-3],
+		ABI:          parts[n-2],
+		Platform:     parts[n-1],
+	}, nil
+}
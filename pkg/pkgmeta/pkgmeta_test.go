@@ -0,0 +1,160 @@
+package pkgmeta
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWheel(t *testing.T) {
+	m, err := Wheel("alpamon-1.1.0-py3-none-any.whl")
+	require.NoError(t, err)
+	assert.Equal(t, Metadata{Name: "alpamon", Version: "1.1.0", PythonTarget: "py3", ABI: "none", Platform: "any"}, m)
+
+	m, err = Wheel("numpy-2.0.1-1-cp312-cp312-manylinux_2_17_x86_64.whl")
+	require.NoError(t, err)
+	assert.Equal(t, Metadata{Name: "numpy", Version: "2.0.1", PythonTarget: "cp312", ABI: "cp312", Platform: "manylinux_2_17_x86_64"}, m)
+
+	_, err = Wheel("alpamon-1.1.0.whl")
+	assert.Error(t, err)
+}
+
+func TestNormalizeName(t *testing.T) {
+	assert.Equal(t, "my-package", NormalizeName("My_Package"))
+	assert.Equal(t, "my-package", NormalizeName("my.-package"))
+}
+
+// arArchive builds an ar archive of the named members, in order.
+func arArchive(members ...[2]string) []byte {
+	var b bytes.Buffer
+	b.WriteString(arMagic)
+	for _, m := range members {
+		fmt.Fprintf(&b, "%-16s%-12s%-6s%-6s%-8s%-10d`\n", m[0]+"/", "0", "0", "0", "100644", len(m[1]))
+		b.WriteString(m[1])
+		if len(m[1])%2 == 1 {
+			b.WriteByte('\n')
+		}
+	}
+	return b.Bytes()
+}
+
+func controlTarGz(t *testing.T, control string) string {
+	t.Helper()
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "./control", Mode: 0644, Size: int64(len(control))}))
+	_, err := tw.Write([]byte(control))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return b.String()
+}
+
+func TestDeb(t *testing.T) {
+	dir := t.TempDir()
+	control := "Package: osquery\nVersion: 5.10.2-1.linux\nArchitecture: amd64\nDescription: osquery\n an operating system instrumentation\n"
+	path := filepath.Join(dir, "renamed.deb")
+	require.NoError(t, os.WriteFile(path, arArchive(
+		[2]string{"debian-binary", "2.0\n"},
+		[2]string{"control.tar.gz", controlTarGz(t, control)},
+		[2]string{"data.tar.gz", "x"},
+	), 0644))
+
+	m, err := Read(path)
+	require.NoError(t, err)
+	assert.Equal(t, Metadata{Name: "osquery", Version: "5.10.2-1.linux", Arch: "amd64"}, m)
+}
+
+func TestDeb_UnreadableControlFallsBackToFileName(t *testing.T) {
+	dir := t.TempDir()
+	data := arArchive([2]string{"debian-binary", "2.0\n"}, [2]string{"control.tar.zst", "zstd"})
+
+	path := filepath.Join(dir, "osquery_5.8.2-1.linux_amd64.deb")
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	m, err := Deb(path)
+	require.NoError(t, err)
+	assert.Equal(t, Metadata{Name: "osquery", Version: "5.8.2-1.linux", Arch: "amd64", FromFileName: true}, m)
+
+	path = filepath.Join(dir, "osquery.deb")
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	_, err = Deb(path)
+	assert.Error(t, err)
+}
+
+// rpmHeader builds a header whose string tags are strs and int32 tags ints.
+func rpmHeader(strs map[int32]string, ints map[int32]int32) []byte {
+	var index, store bytes.Buffer
+	n := 0
+	for tag, v := range strs {
+		_ = binary.Write(&index, binary.BigEndian, rpmIndexEntry{Tag: tag, Type: rpmTypeString, Offset: int32(store.Len()), Count: 1})
+		store.WriteString(v + "\x00")
+		n++
+	}
+	for tag, v := range ints {
+		for store.Len()%4 != 0 {
+			store.WriteByte(0)
+		}
+		_ = binary.Write(&index, binary.BigEndian, rpmIndexEntry{Tag: tag, Type: rpmTypeInt32, Offset: int32(store.Len()), Count: 1})
+		_ = binary.Write(&store, binary.BigEndian, v)
+		n++
+	}
+	var b bytes.Buffer
+	b.Write(rpmHeaderMagic)
+	b.Write(make([]byte, 4))
+	_ = binary.Write(&b, binary.BigEndian, int32(n))
+	_ = binary.Write(&b, binary.BigEndian, int32(store.Len()))
+	b.Write(index.Bytes())
+	b.Write(store.Bytes())
+	return b.Bytes()
+}
+
+func rpmFile(sig, header []byte) []byte {
+	lead := make([]byte, rpmLeadSize)
+	copy(lead, rpmLeadMagic)
+	data := append(lead, sig...)
+	for (len(data)-rpmLeadSize)%8 != 0 {
+		data = append(data, 0)
+	}
+	return append(data, header...)
+}
+
+func TestRPM(t *testing.T) {
+	dir := t.TempDir()
+	sig := rpmHeader(map[int32]string{1004: "sig"}, nil)
+
+	path := filepath.Join(dir, "osquery.rpm")
+	require.NoError(t, os.WriteFile(path, rpmFile(sig, rpmHeader(map[int32]string{
+		rpmTagName: "osquery", rpmTagVersion: "5.10.2", rpmTagRelease: "1.linux",
+		rpmTagArch: "x86_64", rpmTagSourceRPM: "osquery-5.10.2-1.linux.src.rpm",
+	}, map[int32]int32{rpmTagEpoch: 2})), 0644))
+	m, err := Read(path)
+	require.NoError(t, err)
+	assert.Equal(t, Metadata{Name: "osquery", Version: "2:5.10.2-1.linux", Arch: "x86_64"}, m)
+
+	path = filepath.Join(dir, "osquery.src.rpm")
+	require.NoError(t, os.WriteFile(path, rpmFile(sig, rpmHeader(map[int32]string{
+		rpmTagName: "osquery", rpmTagVersion: "5.10.2", rpmTagRelease: "1", rpmTagArch: "x86_64",
+	}, nil)), 0644))
+	m, err = RPM(path)
+	require.NoError(t, err)
+	assert.Equal(t, Metadata{Name: "osquery", Version: "5.10.2-1", Arch: "src"}, m)
+
+	path = filepath.Join(dir, "broken.rpm")
+	require.NoError(t, os.WriteFile(path, []byte("not an rpm"), 0644))
+	_, err = RPM(path)
+	assert.Error(t, err)
+}
+
+func TestReadUnsupported(t *testing.T) {
+	_, err := Read("notes.txt")
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
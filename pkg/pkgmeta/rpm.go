@@ -0,0 +1,160 @@
+package pkgmeta
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	rpmLeadSize = 96
+
+	rpmTagName    = 1000
+	rpmTagVersion = 1001
+	rpmTagRelease = 1002
+	rpmTagEpoch   = 1003
+	rpmTagArch    = 1022
+	// rpmTagSourceRPM is set on binary packages only; a source package has
+	// arch src.
+	rpmTagSourceRPM = 1044
+
+	rpmTypeInt32  = 4
+	rpmTypeString = 6
+)
+
+var (
+	rpmLeadMagic   = []byte{0xed, 0xab, 0xee, 0xdb}
+	rpmHeaderMagic = []byte{0x8e, 0xad, 0xe8, 0x01}
+)
+
+// RPM reads the header of an rpm package. Version is [epoch:]version-release,
+// as rpm -q prints it.
+func RPM(filePath string) (Metadata, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer func() { _ = f.Close() }()
+	return readRPM(bufio.NewReader(f))
+}
+
+func readRPM(r io.Reader) (Metadata, error) {
+	lead := make([]byte, rpmLeadSize)
+	if _, err := io.ReadFull(r, lead); err != nil || !bytes.Equal(lead[:4], rpmLeadMagic) {
+		return Metadata{}, errors.New("not an rpm package")
+	}
+
+	// The signature header comes first, padded to a multiple of 8 bytes.
+	sigSize, err := skipRPMHeader(r)
+	if err != nil {
+		return Metadata{}, err
+	}
+	if pad := (8 - sigSize%8) % 8; pad > 0 {
+		if _, err := io.CopyN(io.Discard, r, int64(pad)); err != nil {
+			return Metadata{}, errors.New("truncated rpm signature")
+		}
+	}
+
+	tags, err := readRPMHeader(r)
+	if err != nil {
+		return Metadata{}, err
+	}
+	m := Metadata{Name: tags.str(rpmTagName), Arch: tags.str(rpmTagArch)}
+	if _, ok := tags[rpmTagSourceRPM]; !ok {
+		m.Arch = "src"
+	}
+	m.Version = tags.str(rpmTagVersion)
+	if release := tags.str(rpmTagRelease); release != "" {
+		m.Version += "-" + release
+	}
+	if epoch, ok := tags.int32(rpmTagEpoch); ok && epoch != 0 {
+		m.Version = fmt.Sprintf("%d:%s", epoch, m.Version)
+	}
+	if m.Name == "" || m.Version == "" {
+		return Metadata{}, errors.New("the rpm header has no name or version")
+	}
+	return m, nil
+}
+
+type rpmIndexEntry struct {
+	Tag, Type, Offset, Count int32
+}
+
+// rpmTags holds the raw values of the tags read, by tag.
+type rpmTags map[int32][]byte
+
+func (t rpmTags) str(tag int32) string {
+	v := t[tag]
+	if i := bytes.IndexByte(v, 0); i >= 0 {
+		v = v[:i]
+	}
+	return string(v)
+}
+
+func (t rpmTags) int32(tag int32) (int32, bool) {
+	v, ok := t[tag]
+	if !ok || len(v) < 4 {
+		return 0, false
+	}
+	return int32(binary.BigEndian.Uint32(v)), true
+}
+
+// rpmHeaderIntro reads a header's magic and sizes.
+func rpmHeaderIntro(r io.Reader) (nindex, hsize int32, err error) {
+	intro := make([]byte, 16)
+	if _, err := io.ReadFull(r, intro); err != nil || !bytes.Equal(intro[:4], rpmHeaderMagic) {
+		return 0, 0, errors.New("invalid rpm header")
+	}
+	nindex = int32(binary.BigEndian.Uint32(intro[8:12]))
+	hsize = int32(binary.BigEndian.Uint32(intro[12:16]))
+	if nindex < 0 || hsize < 0 || nindex > 1<<16 || hsize > 1<<28 {
+		return 0, 0, errors.New("invalid rpm header size")
+	}
+	return nindex, hsize, nil
+}
+
+// skipRPMHeader reads past a header and returns how many bytes it took.
+func skipRPMHeader(r io.Reader) (int, error) {
+	nindex, hsize, err := rpmHeaderIntro(r)
+	if err != nil {
+		return 0, err
+	}
+	n := int64(nindex)*16 + int64(hsize)
+	if _, err := io.CopyN(io.Discard, r, n); err != nil {
+		return 0, errors.New("truncated rpm header")
+	}
+	return 16 + int(n), nil
+}
+
+func readRPMHeader(r io.Reader) (rpmTags, error) {
+	nindex, hsize, err := rpmHeaderIntro(r)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]rpmIndexEntry, nindex)
+	if err := binary.Read(r, binary.BigEndian, entries); err != nil {
+		return nil, errors.New("truncated rpm header")
+	}
+	store := make([]byte, hsize)
+	if _, err := io.ReadFull(r, store); err != nil {
+		return nil, errors.New("truncated rpm header")
+	}
+
+	tags := rpmTags{}
+	for _, e := range entries {
+		if e.Offset < 0 || int(e.Offset) >= len(store) {
+			continue
+		}
+		switch {
+		case e.Type == rpmTypeString:
+			tags[e.Tag] = store[e.Offset:]
+		case e.Type == rpmTypeInt32 && int(e.Offset)+4 <= len(store):
+			tags[e.Tag] = store[e.Offset : e.Offset+4]
+		}
+	}
+	return tags, nil
+}
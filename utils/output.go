@@ -103,6 +103,18 @@ func FormatJSON(value any) (string, error) {
 	return strings.TrimRight(string(escapeJSONControls(buf.Bytes())), "\n"), nil
 }
 
+// FormatJSONLine is FormatJSON on a single line, for a stream of values such as
+// a follow mode's output.
+func FormatJSONLine(value any) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(escapeJSONControls(buf.Bytes())), "\n"), nil
+}
+
 func PrintJSONValue(w io.Writer, value any) error {
 	rendered, err := FormatJSON(value)
 	if err != nil {
@@ -241,6 +241,12 @@ func TestFormatJSON_DisablesHTMLEscaping(t *testing.T) {
 	assert.NotContains(t, got, "\\u003c")
 }
 
+func TestFormatJSONLine_OneLineWithControlsEscaped(t *testing.T) {
+	got, err := FormatJSONLine(map[string]string{"msg": "a\nb\u009bc"})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"msg":"a\nb\u009bc"}`, got)
+}
+
 func TestPrintJSONError(t *testing.T) {
 	var buf bytes.Buffer
 	PrintJSONError(&buf, JSONErrorEnvelope[map[string]string]{
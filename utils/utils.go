@@ -659,3 +659,22 @@ func ParsePositiveDuration(flagName, raw string) (time.Duration, error) {
 	}
 	return d, nil
 }
+
+// ParseDayDuration is ParsePositiveDuration with a trailing "d" for whole days
+// ("14d"), which time.ParseDuration lacks and which expiry and retention flags
+// are most naturally written in. Anything else goes to time.ParseDuration, so
+// "36h" and "90m" still work.
+func ParseDayDuration(flagName, raw string) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s value %q: expected a number of days such as 14d", flagName, raw)
+		}
+		if n <= 0 {
+			return 0, fmt.Errorf("invalid %s value %q: must be a positive duration", flagName, raw)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return ParsePositiveDuration(flagName, raw)
+}
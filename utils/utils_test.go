@@ -591,3 +591,31 @@ func TestRequirePositiveIntHelperProcess(t *testing.T) {
 	}
 	RequirePositiveInt("tail", 0)
 }
+
+func TestParseDayDuration(t *testing.T) {
+	cases := []struct {
+		raw     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"14d", 14 * 24 * time.Hour, false},
+		{" 1d ", 24 * time.Hour, false},
+		{"36h", 36 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"0d", 0, true},
+		{"-3d", 0, true},
+		{"1.5d", 0, true},
+		{"d", 0, true},
+		{"0s", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tc := range cases {
+		got, err := ParseDayDuration("expiring", tc.raw)
+		if tc.wantErr {
+			assert.Error(t, err, "raw %q", tc.raw)
+			continue
+		}
+		require.NoError(t, err, "raw %q", tc.raw)
+		assert.Equal(t, tc.want, got, "raw %q", tc.raw)
+	}
+}